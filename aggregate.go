@@ -0,0 +1,191 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Upstream is a server whose tools, prompts and resources are offered by an
+// aggregating server.
+type Upstream struct {
+	// Name identifies the upstream in errors.
+	Name   string
+	Client *Client
+	// Prefix is prepended to the names of the upstream's tools and prompts,
+	// and to the names and URIs of its resources, keeping them apart from
+	// those of other upstreams. It defaults to Name followed by "__", as in
+	// github__create_issue.
+	Prefix string
+	// Unprefixed lists the tools and prompts, by name, and the resources and
+	// resource templates, by URI and URI template, offered as they are by the
+	// upstream, without Prefix.
+	Unprefixed []string
+}
+
+// name returns the name under which the upstream's tool or prompt called
+// upstreamName is offered, or the URI under which the resource at
+// upstreamName is.
+func (u Upstream) name(upstreamName string) string {
+	if slices.Contains(u.Unprefixed, upstreamName) {
+		return upstreamName
+	}
+	prefix := u.Prefix
+	if prefix == "" {
+		prefix = u.Name + "__"
+	}
+	return prefix + upstreamName
+}
+
+// Aggregate lists the tools and prompts of upstreams and returns definitions
+// that forward calls to the upstream offering each one, for serving with
+// NewServer and WithPrompts. Names are prefixed per upstream, and are
+// rewritten back to those of the upstream when calls are forwarded. The
+// resulting names must be unique across upstreams.
+//
+// Tool and prompt metadata is listed once, so upstreams that change their
+// tools must be aggregated again. Resources are aggregated by
+// AggregateResources.
+func Aggregate(ctx context.Context, upstreams ...Upstream) ([]ToolDefinition, []PromptDefinition, error) {
+	var tools []ToolDefinition
+	var prompts []PromptDefinition
+	toolOwners := map[string]string{}
+	promptOwners := map[string]string{}
+
+	for _, u := range upstreams {
+		upstreamTools, err := u.Client.ListTools(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing tools of %s: %w", u.Name, err)
+		}
+		for _, t := range upstreamTools {
+			name := u.name(t.Name)
+			if owner, ok := toolOwners[name]; ok {
+				return nil, nil, fmt.Errorf("tool %s is offered by both %s and %s", name, owner, u.Name)
+			}
+			toolOwners[name] = u.Name
+			tools = append(tools, forwardTool(u.Client, t, name))
+		}
+
+		upstreamPrompts, err := u.Client.ListPrompts(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing prompts of %s: %w", u.Name, err)
+		}
+		for _, p := range upstreamPrompts {
+			name := u.name(p.Name)
+			if owner, ok := promptOwners[name]; ok {
+				return nil, nil, fmt.Errorf("prompt %s is offered by both %s and %s", name, owner, u.Name)
+			}
+			promptOwners[name] = u.Name
+			prompts = append(prompts, forwardPrompt(u.Client, p, name))
+		}
+	}
+	return tools, prompts, nil
+}
+
+// AggregateResources lists the resources and resource templates of upstreams
+// and returns definitions that forward reads to the upstream offering each
+// one, for serving with WithResources and WithResourceTemplates. Names, URIs
+// and URI templates are prefixed per upstream, as for Aggregate, as in
+// github__repo://acrmp/mcp. URIs are rewritten back to those of the upstream
+// when reads are forwarded, and the URIs of the contents read are prefixed
+// again. The resulting URIs and URI templates must be unique across
+// upstreams. Upstreams that do not offer resources are skipped.
+//
+// Resources are listed once, so upstreams that change their resources must
+// be aggregated again.
+func AggregateResources(ctx context.Context, upstreams ...Upstream) ([]ResourceDefinition, []ResourceTemplateDefinition, error) {
+	var resources []ResourceDefinition
+	var templates []ResourceTemplateDefinition
+	resourceOwners := map[string]string{}
+	templateOwners := map[string]string{}
+
+	for _, u := range upstreams {
+		upstreamResources, err := u.Client.ListResources(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing resources of %s: %w", u.Name, err)
+		}
+		for _, r := range upstreamResources {
+			uri := u.name(r.Uri)
+			if owner, ok := resourceOwners[uri]; ok {
+				return nil, nil, fmt.Errorf("resource %s is offered by both %s and %s", uri, owner, u.Name)
+			}
+			resourceOwners[uri] = u.Name
+			prefix := strings.TrimSuffix(uri, r.Uri)
+			r.Uri, r.Name = uri, prefix+r.Name
+			resources = append(resources, ResourceDefinition{Metadata: r, Read: forwardRead(u.Client, prefix)})
+		}
+
+		upstreamTemplates, err := u.Client.ListResourceTemplates(ctx)
+		var rpcErr *jsonrpc2.Error
+		if errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc2.CodeMethodNotFound {
+			// an upstream may offer resources without templates
+			upstreamTemplates, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing resource templates of %s: %w", u.Name, err)
+		}
+		for _, t := range upstreamTemplates {
+			uriTemplate := u.name(t.UriTemplate)
+			if owner, ok := templateOwners[uriTemplate]; ok {
+				return nil, nil, fmt.Errorf("resource template %s is offered by both %s and %s", uriTemplate, owner, u.Name)
+			}
+			templateOwners[uriTemplate] = u.Name
+			prefix := strings.TrimSuffix(uriTemplate, t.UriTemplate)
+			t.UriTemplate, t.Name = uriTemplate, prefix+t.Name
+			templates = append(templates, ResourceTemplateDefinition{Metadata: t, Read: forwardRead(u.Client, prefix)})
+		}
+	}
+	return resources, templates, nil
+}
+
+// forwardTool returns a tool offered as name that calls t on c. Tool errors
+// reported by the upstream are passed through; protocol errors become tool
+// errors.
+func forwardTool(c *Client, t Tool, name string) ToolDefinition {
+	metadata := t
+	metadata.Name = name
+	return ToolDefinition{
+		Metadata: metadata,
+		Process: func(ctx context.Context, params CallToolRequestParams, _ Notifier) (CallToolResult, error) {
+			params.Name = t.Name
+			return c.CallTool(ctx, params)
+		},
+	}
+}
+
+// forwardRead returns a function reading resources from c that are offered
+// with prefix prepended to their URIs.
+func forwardRead(c *Client, prefix string) func(context.Context, ReadResourceRequestParams) (ReadResourceResult, error) {
+	return func(ctx context.Context, params ReadResourceRequestParams) (ReadResourceResult, error) {
+		params.Uri = strings.TrimPrefix(params.Uri, prefix)
+		result, err := c.ReadResource(ctx, params)
+		if err != nil || prefix == "" {
+			return result, err
+		}
+		for _, content := range result.Contents {
+			if m, ok := content.(map[string]any); ok {
+				if uri, ok := m["uri"].(string); ok {
+					m["uri"] = prefix + uri
+				}
+			}
+		}
+		return result, nil
+	}
+}
+
+// forwardPrompt returns a prompt offered as name that gets p from c.
+func forwardPrompt(c *Client, p Prompt, name string) PromptDefinition {
+	metadata := p
+	metadata.Name = name
+	return PromptDefinition{
+		Metadata: metadata,
+		Render: func(ctx context.Context, params GetPromptRequestParams) (GetPromptResult, error) {
+			params.Name = p.Name
+			return c.GetPrompt(ctx, params)
+		},
+	}
+}
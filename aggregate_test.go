@@ -0,0 +1,174 @@
+package mcp_test
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Aggregate", func() {
+
+	upstream := func(name string, tool string, prompts ...mcp.PromptDefinition) mcp.Upstream {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: tool, InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult(name + " " + params.Name), nil
+			},
+		}}
+		s := mcp.NewServer(mcp.Implementation{Name: name, Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...))
+		return mcp.Upstream{Name: name, Client: newClient(s)}
+	}
+
+	welcome := mcp.PromptDefinition{
+		Metadata: mcp.Prompt{Name: "welcome"},
+		Get: func(mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+			return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome"))}, nil
+		},
+	}
+
+	It("offers the tools and prompts of every upstream under prefixed names", func() {
+		tools, prompts, err := mcp.Aggregate(context.Background(), upstream("github", "create_issue"), upstream("jira", "create_ticket", welcome))
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "Gateway", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...)))
+
+		listed, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(ConsistOf(HaveField("Name", "github__create_issue"), HaveField("Name", "jira__create_ticket")))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "jira__create_ticket"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("jira create_ticket")}))
+
+		prompt, err := c.GetPrompt(context.Background(), mcp.GetPromptRequestParams{Name: "jira__welcome"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prompt.Messages).To(Equal(mcp.UserMessage(mcp.NewTextContent("Welcome"))))
+	})
+
+	It("uses custom prefixes and leaves excluded names unprefixed", func() {
+		github := upstream("github", "create_issue")
+		github.Prefix = "gh_"
+		jira := upstream("jira", "create_ticket")
+		jira.Unprefixed = []string{"create_ticket"}
+		tools, _, err := mcp.Aggregate(context.Background(), github, jira)
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "Gateway", Version: "1.0.0"}, tools))
+
+		listed, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(ConsistOf(HaveField("Name", "gh_create_issue"), HaveField("Name", "create_ticket")))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "gh_create_issue"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("github create_issue")}))
+	})
+
+	It("rejects names offered by more than one upstream", func() {
+		github := upstream("github", "create_issue")
+		github.Unprefixed = []string{"create_issue"}
+		gitlab := upstream("gitlab", "create_issue")
+		gitlab.Unprefixed = []string{"create_issue"}
+		_, _, err := mcp.Aggregate(context.Background(), github, gitlab)
+		Expect(err).To(MatchError("tool create_issue is offered by both github and gitlab"))
+
+		github.Unprefixed, gitlab.Unprefixed = nil, nil
+		gitlab.Prefix = "github__"
+		_, _, err = mcp.Aggregate(context.Background(), github, gitlab)
+		Expect(err).To(MatchError("tool github__create_issue is offered by both github and gitlab"))
+	})
+
+	Describe("AggregateResources", func() {
+		withResources := func(name, uri, template string) mcp.Upstream {
+			read := func(_ context.Context, params mcp.ReadResourceRequestParams) (mcp.ReadResourceResult, error) {
+				return mcp.ReadResourceResult{Contents: []any{mcp.TextResourceContents{Uri: params.Uri, Text: name}}}, nil
+			}
+			s := mcp.NewServer(mcp.Implementation{Name: name, Version: "1.0.0"}, nil,
+				mcp.WithResources(mcp.ResourceDefinition{Metadata: mcp.Resource{Uri: uri, Name: "README.md"}, Read: read}),
+				mcp.WithResourceTemplates(mcp.ResourceTemplateDefinition{Metadata: mcp.ResourceTemplate{UriTemplate: template, Name: "files"}, Read: read}),
+			)
+			return mcp.Upstream{Name: name, Client: newClient(s)}
+		}
+
+		It("offers the resources of every upstream", func() {
+			resources, templates, err := mcp.AggregateResources(context.Background(),
+				withResources("github", "github://README.md", "github://{+path}"),
+				withResources("jira", "jira://README.md", "jira://{+path}"),
+				upstream("slack", "post_message"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			c := newClient(mcp.NewServer(mcp.Implementation{Name: "Gateway", Version: "1.0.0"}, nil,
+				mcp.WithResources(resources...), mcp.WithResourceTemplates(templates...)))
+
+			listed, err := c.ListResources(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listed).To(ConsistOf(
+				mcp.Resource{Uri: "github__github://README.md", Name: "github__README.md"},
+				mcp.Resource{Uri: "jira__jira://README.md", Name: "jira__README.md"},
+			))
+			listedTemplates, err := c.ListResourceTemplates(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listedTemplates).To(ConsistOf(
+				mcp.ResourceTemplate{UriTemplate: "github__github://{+path}", Name: "github__files"},
+				mcp.ResourceTemplate{UriTemplate: "jira__jira://{+path}", Name: "jira__files"},
+			))
+
+			result, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "jira__jira://README.md"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Contents).To(Equal([]any{map[string]any{"uri": "jira__jira://README.md", "text": "jira"}}))
+
+			result, err = c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "github__github://src/main.go"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Contents).To(Equal([]any{map[string]any{"uri": "github__github://src/main.go", "text": "github"}}))
+		})
+
+		It("leaves excluded URIs unprefixed", func() {
+			github := withResources("github", "github://README.md", "github://{+path}")
+			github.Unprefixed = []string{"github://README.md", "github://{+path}"}
+			resources, templates, err := mcp.AggregateResources(context.Background(), github)
+			Expect(err).ToNot(HaveOccurred())
+			c := newClient(mcp.NewServer(mcp.Implementation{Name: "Gateway", Version: "1.0.0"}, nil,
+				mcp.WithResources(resources...), mcp.WithResourceTemplates(templates...)))
+
+			listed, err := c.ListResources(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listed).To(Equal([]mcp.Resource{{Uri: "github://README.md", Name: "README.md"}}))
+
+			result, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "github://src/main.go"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Contents).To(Equal([]any{map[string]any{"uri": "github://src/main.go", "text": "github"}}))
+		})
+
+		It("rejects resources offered by more than one upstream", func() {
+			github := withResources("github", "file:///README.md", "github://{+path}")
+			github.Unprefixed = []string{"file:///README.md"}
+			gitlab := withResources("gitlab", "file:///README.md", "gitlab://{+path}")
+			gitlab.Unprefixed = []string{"file:///README.md"}
+			_, _, err := mcp.AggregateResources(context.Background(), github, gitlab)
+			Expect(err).To(MatchError("resource file:///README.md is offered by both github and gitlab"))
+		})
+	})
+
+	It("reports upstreams that have gone away as tool errors", func() {
+		// not registered for cleanup, as the spec closes it
+		serverSide, clientSide := net.Pipe()
+		s := mcp.NewServer(mcp.Implementation{Name: "github", Version: "1.0.0"}, []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "create_issue", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+		}})
+		go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+		client, err := mcp.NewClient(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), mcp.Implementation{Name: "Gateway", Version: "1.0.0"})
+		Expect(err).ToNot(HaveOccurred())
+
+		tools, _, err := mcp.Aggregate(context.Background(), mcp.Upstream{Name: "github", Client: client})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.Close()).To(Succeed())
+
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "Gateway", Version: "1.0.0"}, tools))
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "github__create_issue"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+	})
+})
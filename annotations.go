@@ -0,0 +1,41 @@
+package mcp
+
+import "time"
+
+// WithAudience returns a copy of a for content intended for roles.
+func (a Annotations) WithAudience(roles ...Role) Annotations {
+	a.Audience = roles
+	return a
+}
+
+// WithPriority returns a copy of a with priority p, from 0 (entirely
+// optional) to 1 (effectively required).
+func (a Annotations) WithPriority(p float64) Annotations {
+	a.Priority = &p
+	return a
+}
+
+// WithLastModified returns a copy of a recording that the annotated object
+// was last modified at t.
+func (a Annotations) WithLastModified(t time.Time) Annotations {
+	s := t.UTC().Format(time.RFC3339)
+	a.LastModified = &s
+	return a
+}
+
+// Annotate returns a copy of c with annotations a.
+func Annotate[C Content](c C, a Annotations) C {
+	switch c := any(&c).(type) {
+	case *TextContent:
+		c.Annotations = &a
+	case *ImageContent:
+		c.Annotations = &a
+	case *AudioContent:
+		c.Annotations = &a
+	case *EmbeddedResource:
+		c.Annotations = &a
+	case *ResourceLink:
+		c.Annotations = &a
+	}
+	return c
+}
@@ -0,0 +1,48 @@
+package mcp_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Annotations", func() {
+
+	modified := time.Date(2025, 1, 12, 15, 0, 58, 0, time.UTC)
+
+	It("builds annotations", func() {
+		a := mcp.Annotations{}.WithAudience(mcp.RoleAssistant).WithPriority(0.8).WithLastModified(modified)
+		b, err := json.Marshal(a)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"audience":["assistant"],"priority":0.8,"lastModified":"2025-01-12T15:00:58Z"}`))
+	})
+
+	It("annotates content", func() {
+		c := mcp.Annotate(mcp.TextContent{Text: "for the user"}, mcp.Annotations{}.WithAudience(mcp.RoleUser))
+		Expect(c.Text).To(Equal("for the user"))
+		Expect(c.Annotations.Audience).To(Equal([]mcp.Role{mcp.RoleUser}))
+
+		link := mcp.Annotate(mcp.NewResourceLink("file:///a", "a"), mcp.Annotations{}.WithLastModified(modified))
+		b, err := json.Marshal(link)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"type":"resource_link","uri":"file:///a","name":"a","annotations":{"lastModified":"2025-01-12T15:00:58Z"}}`))
+	})
+
+	It("decodes annotations on content", func() {
+		var result mcp.CallToolResult
+		Expect(json.Unmarshal([]byte(`{"content":[{"type":"text","text":"a","annotations":{"audience":["user"],"priority":1,"lastModified":"2025-01-12T15:00:58Z"}}]}`), &result)).To(Succeed())
+		Expect(result.Content[0]).To(Equal(mcp.Annotate(
+			mcp.TextContent{Type: "text", Text: "a"},
+			mcp.Annotations{}.WithAudience(mcp.RoleUser).WithPriority(1).WithLastModified(modified),
+		)))
+	})
+
+	It("rejects priorities outside of 0 to 1", func() {
+		var a mcp.Annotations
+		Expect(json.Unmarshal([]byte(`{"priority":2}`), &a)).To(MatchError("field priority: must be <= 1"))
+	})
+})
@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrAuditChainBroken is returned by VerifyAuditLog when a record has been
+// altered, reordered or inserted, or removed from before the last record.
+// Records removed from the end of a log leave an intact chain, so cannot be
+// detected from the log alone; see LastHash.
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// Audit outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeError   = "error"
+	AuditOutcomeDenied  = "denied"
+)
+
+// AuditRecord is a single tool invocation in an audit log, or a request
+// forwarded by a proxy, whose method is recorded as the Tool. Each record
+// includes the hash of the record before it, so that altering any record
+// breaks the chain from that point on.
+type AuditRecord struct {
+	Time      time.Time       `json:"time"`
+	Session   string          `json:"session,omitempty"`
+	RequestID json.RawMessage `json:"requestId,omitempty"`
+	Subject   string          `json:"subject,omitempty"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Outcome   string          `json:"outcome"`
+	Message   string          `json:"message,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+// hash returns the hex encoded SHA-256 of the record with its Hash unset.
+func (r AuditRecord) hash() (string, error) {
+	r.Hash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog writes hash chained AuditRecords to a writer as newline delimited
+// JSON.
+type AuditLog struct {
+	mu       sync.Mutex
+	w        io.Writer
+	lastHash string
+}
+
+// NewAuditLog returns an audit log writing to w. To append to an existing
+// log, pass the hash of its last record as returned by VerifyAuditLog;
+// otherwise pass the empty string to start a new chain.
+func NewAuditLog(w io.Writer, lastHash string) *AuditLog {
+	return &AuditLog{w: w, lastHash: lastHash}
+}
+
+// WithAuditLog records every tool invocation to l. Arguments are recorded
+// after redaction and secret masking.
+func WithAuditLog(l *AuditLog) ServerOption {
+	return func(h *handler) {
+		h.auditLog = l
+	}
+}
+
+// Record chains r to the previous record and writes it to the log.
+func (l *AuditLog) Record(r AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.PrevHash = l.lastHash
+	hash, err := r.hash()
+	if err != nil {
+		return err
+	}
+	r.Hash = hash
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	l.lastHash = hash
+	return nil
+}
+
+// LastHash returns the hash of the last record written to the log. Kept
+// somewhere the log's writer cannot alter, it anchors the end of the chain:
+// a log whose records were removed from the end no longer verifies to it.
+func (l *AuditLog) LastHash() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastHash
+}
+
+// VerifyAuditLog reads an audit log from r and checks that every record is
+// intact and chained to the one before it. It returns the hash of the last
+// record, which can be used to continue the chain with NewAuditLog. To detect
+// records removed from the end of the log, compare it with a hash returned by
+// LastHash and kept elsewhere.
+func VerifyAuditLog(r io.Reader) (string, error) {
+	var lastHash string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return "", fmt.Errorf("%w: record %d: %v", ErrAuditChainBroken, line, err)
+		}
+		if rec.PrevHash != lastHash {
+			return "", fmt.Errorf("%w: record %d does not follow the record before it", ErrAuditChainBroken, line)
+		}
+		hash, err := rec.hash()
+		if err != nil {
+			return "", err
+		}
+		if hash != rec.Hash {
+			return "", fmt.Errorf("%w: record %d has been altered", ErrAuditChainBroken, line)
+		}
+		lastHash = rec.Hash
+	}
+	return lastHash, scanner.Err()
+}
+
+func (h *handler) audit(ctx context.Context, params CallToolRequestParams, secretArgs []string, outcome, message string) {
+	if h.auditLog == nil {
+		return
+	}
+	rec := AuditRecord{
+		Time:    time.Now().UTC(),
+		Tool:    params.Name,
+		Outcome: outcome,
+		Message: h.secrets.Mask(message, secretArgs...),
+	}
+	rec.Session, _ = SessionIDFromContext(ctx)
+	if id, ok := RequestIDFromContext(ctx); ok {
+		rec.RequestID, _ = json.Marshal(id)
+	}
+	if p, ok := PrincipalFromContext(ctx); ok {
+		rec.Subject = p.Subject
+	}
+	if params.Arguments != nil {
+		args, err := json.Marshal(h.secrets.maskValue(h.redactor.Arguments(params.Arguments), secretArgs...))
+		if err != nil {
+			slog.Error("problem encoding audit arguments", "tool", params.Name, "error", err)
+		}
+		rec.Arguments = args
+	}
+	if err := h.auditLog.Record(rec); err != nil {
+		slog.Error("problem writing audit record", "tool", params.Name, "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Audit log", func() {
+
+	var (
+		buf   *bytes.Buffer
+		tools []mcp.ToolDefinition
+	)
+
+	records := func() []mcp.AuditRecord {
+		var recs []mcp.AuditRecord
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var r mcp.AuditRecord
+			Expect(json.Unmarshal([]byte(line), &r)).To(Succeed())
+			recs = append(recs, r)
+		}
+		return recs
+	}
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		tools = []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "login", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				if params.Arguments["password"] == "wrong" {
+					return mcp.CallToolResult{}, errors.New("bad password")
+				}
+				return textResult("ok"), nil
+			},
+			SecretArguments: []string{"password"},
+		}}
+	})
+
+	It("records tool invocations with secrets masked", func() {
+		ctx := mcp.ContextWithPrincipal(mcp.ContextWithSessionID(context.Background(), "session-1"), &mcp.Principal{Subject: "alice"})
+		conn := startServer(ctx, tools, mcp.WithAuditLog(mcp.NewAuditLog(buf, "")))
+		_, err := callTool(conn, "login", map[string]any{"user": "alice", "password": "hunter2"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = callTool(conn, "login", map[string]any{"user": "alice", "password": "wrong"})
+		Expect(err).ToNot(HaveOccurred())
+
+		recs := records()
+		Expect(recs).To(HaveLen(2))
+		Expect(recs[0].Session).To(Equal("session-1"))
+		Expect(recs[0].RequestID).To(MatchJSON("0"))
+		Expect(recs[1].RequestID).To(MatchJSON("1"))
+		Expect(recs[0].Subject).To(Equal("alice"))
+		Expect(recs[0].Tool).To(Equal("login"))
+		Expect(recs[0].Outcome).To(Equal(mcp.AuditOutcomeSuccess))
+		Expect(recs[0].Arguments).To(MatchJSON(`{"user":"alice","password":"[REDACTED]"}`))
+		Expect(recs[1].Outcome).To(Equal(mcp.AuditOutcomeError))
+		Expect(recs[1].Message).To(Equal("bad password"))
+		Expect(recs[1].PrevHash).To(Equal(recs[0].Hash))
+	})
+
+	It("records calls that fail after the tool returned as errors", func() {
+		tools[0].ContentLimit = &mcp.ContentLimit{MaxBytes: 1, Truncate: true, Store: func(string) (string, error) {
+			return "", errors.New("store unavailable")
+		}}
+		conn := startServer(context.Background(), tools, mcp.WithAuditLog(mcp.NewAuditLog(buf, "")))
+		result, err := callTool(conn, "login", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+		Expect(records()).To(HaveLen(1))
+		Expect(records()[0].Outcome).To(Equal(mcp.AuditOutcomeError))
+		Expect(records()[0].Message).To(Equal("storing truncated content: store unavailable"))
+	})
+
+	It("records denied calls", func() {
+		tools[0].Authorize = func(context.Context, *mcp.Principal, mcp.CallToolRequestParams) error {
+			return errors.New("not allowed")
+		}
+		conn := startServer(context.Background(), tools, mcp.WithAuditLog(mcp.NewAuditLog(buf, "")))
+		_, err := callTool(conn, "login", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records()[0].Outcome).To(Equal(mcp.AuditOutcomeDenied))
+		Expect(records()[0].Message).To(Equal("not allowed"))
+	})
+
+	Describe("VerifyAuditLog", func() {
+		var log *mcp.AuditLog
+
+		BeforeEach(func() {
+			log = mcp.NewAuditLog(buf, "")
+			for _, tool := range []string{"first", "second", "third"} {
+				Expect(log.Record(mcp.AuditRecord{Tool: tool, Outcome: mcp.AuditOutcomeSuccess})).To(Succeed())
+			}
+		})
+
+		It("returns the hash of the last record of an intact log", func() {
+			hash, err := mcp.VerifyAuditLog(bytes.NewReader(buf.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(records()[2].Hash))
+		})
+
+		It("continues an existing chain", func() {
+			hash, err := mcp.VerifyAuditLog(bytes.NewReader(buf.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mcp.NewAuditLog(buf, hash).Record(mcp.AuditRecord{Tool: "fourth"})).To(Succeed())
+			_, err = mcp.VerifyAuditLog(bytes.NewReader(buf.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("detects altered records", func() {
+			altered := strings.Replace(buf.String(), `"tool":"second"`, `"tool":"other"`, 1)
+			_, err := mcp.VerifyAuditLog(strings.NewReader(altered))
+			Expect(err).To(MatchError(mcp.ErrAuditChainBroken))
+			Expect(err).To(MatchError(ContainSubstring("record 2 has been altered")))
+		})
+
+		It("detects removed records", func() {
+			lines := strings.SplitAfter(buf.String(), "\n")
+			_, err := mcp.VerifyAuditLog(strings.NewReader(lines[0] + lines[2]))
+			Expect(err).To(MatchError(mcp.ErrAuditChainBroken))
+			Expect(err).To(MatchError(ContainSubstring("record 2 does not follow")))
+		})
+
+		It("detects records removed from the end against the last hash", func() {
+			checkpoint := log.LastHash()
+			Expect(checkpoint).To(Equal(records()[2].Hash))
+
+			lines := strings.SplitAfter(buf.String(), "\n")
+			hash, err := mcp.VerifyAuditLog(strings.NewReader(lines[0] + lines[1]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).ToNot(Equal(checkpoint))
+		})
+	})
+})
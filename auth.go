@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject  string
+	Audience []string
+	Scopes   []string
+	Groups   []string
+	Claims   map[string]any
+}
+
+// HasScopes reports whether the principal has been granted all of scopes.
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		if !slices.Contains(p.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying p.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ErrInvalidToken is returned by a TokenVerifier when an access token is
+// malformed, expired, revoked or otherwise unacceptable. Errors that do not
+// wrap it are taken to mean the token could not be verified.
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenVerifier validates an access token and returns the principal it was
+// issued to.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*Principal, error)
+}
+
+// ProtectedResourceMetadata describes an MCP server acting as an OAuth 2.1
+// resource server, as defined by RFC 9728.
+type ProtectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers,omitempty"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported,omitempty"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+	ResourceDocumentation  string   `json:"resource_documentation,omitempty"`
+}
+
+const protectedResourceWellKnown = "/.well-known/oauth-protected-resource"
+
+// ResourceServer implements the authorization requirements of the MCP
+// specification for HTTP transports. Requests must present a bearer access
+// token issued for Metadata.Resource and carrying RequiredScopes.
+type ResourceServer struct {
+	Metadata       ProtectedResourceMetadata
+	Verifier       TokenVerifier
+	RequiredScopes []string
+}
+
+// MetadataPath returns the path the protected resource metadata is served on.
+func (rs *ResourceServer) MetadataPath() string {
+	u, err := url.Parse(rs.Metadata.Resource)
+	if err != nil {
+		return protectedResourceWellKnown
+	}
+	return protectedResourceWellKnown + strings.TrimSuffix(u.Path, "/")
+}
+
+// MetadataURL returns the absolute URL of the protected resource metadata.
+func (rs *ResourceServer) MetadataURL() string {
+	u, err := url.Parse(rs.Metadata.Resource)
+	if err != nil {
+		return protectedResourceWellKnown
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: rs.MetadataPath()}).String()
+}
+
+// MetadataHandler serves the protected resource metadata document. It should
+// be mounted at MetadataPath.
+func (rs *ResourceServer) MetadataHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		md := rs.Metadata
+		if md.BearerMethodsSupported == nil {
+			md.BearerMethodsSupported = []string{"header"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(md); err != nil {
+			slog.Error("problem writing protected resource metadata", "error", err)
+		}
+	})
+}
+
+// Handler wraps next so that only requests bearing a valid access token reach
+// it. The metadata document is served from MetadataPath without
+// authorization. Tokens the Verifier rejects with ErrInvalidToken are
+// challenged; other errors, such as an authorization server that cannot be
+// reached, are answered with 503 Service Unavailable. The authenticated Principal is available to next, and to
+// tool handlers served from the request context, via PrincipalFromContext.
+func (rs *ResourceServer) Handler(next http.Handler) http.Handler {
+	metadata := rs.MetadataHandler()
+	metadataPath := rs.MetadataPath()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataPath {
+			metadata.ServeHTTP(w, r)
+			return
+		}
+
+		// OAuth 2.1 forbids access tokens in the query string
+		if r.URL.Query().Has("access_token") {
+			rs.challenge(w, http.StatusBadRequest, "invalid_request", "access tokens must be sent in the Authorization header")
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			rs.challenge(w, http.StatusUnauthorized, "", "")
+			return
+		}
+
+		p, err := rs.Verifier.VerifyToken(r.Context(), token)
+		if err == nil && p == nil {
+			err = errors.New("verifier returned no principal")
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			slog.Info("rejected access token", "error", err)
+			rs.challenge(w, http.StatusUnauthorized, "invalid_token", "")
+			return
+		}
+		if err != nil {
+			// the error may describe the authorization server, so is only
+			// logged
+			slog.Error("problem verifying access token", "error", err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		if !slices.Contains(p.Audience, rs.Metadata.Resource) {
+			rs.challenge(w, http.StatusUnauthorized, "invalid_token", "token was not issued for this resource")
+			return
+		}
+
+		if !p.HasScopes(rs.RequiredScopes...) {
+			rs.challenge(w, http.StatusForbidden, "insufficient_scope", "token is missing required scopes")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), p)))
+	})
+}
+
+func (rs *ResourceServer) challenge(w http.ResponseWriter, status int, code, description string) {
+	params := []string{fmt.Sprintf("resource_metadata=%q", rs.MetadataURL())}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	if code == "insufficient_scope" && len(rs.RequiredScopes) > 0 {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(rs.RequiredScopes, " ")))
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	w.WriteHeader(status)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// principalFromClaims builds a principal from the registered claims shared by
+// JWT access tokens (RFC 9068) and introspection responses (RFC 7662).
+func principalFromClaims(claims map[string]any) *Principal {
+	p := &Principal{Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+
+	if aud, ok := claims["aud"].(string); ok {
+		p.Audience = []string{aud}
+	} else {
+		p.Audience = stringsClaim(claims["aud"])
+	}
+	p.Groups = stringsClaim(claims["groups"])
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringsClaim(claims["scp"])
+	}
+	return p
+}
+
+func stringsClaim(claim any) []string {
+	values, _ := claim.([]any)
+	var ss []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
@@ -0,0 +1,265 @@
+package mcp_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("ResourceServer", func() {
+
+	var (
+		secret    []byte
+		rs        *mcp.ResourceServer
+		handler   http.Handler
+		principal *mcp.Principal
+	)
+
+	BeforeEach(func() {
+		secret = []byte("not-very-secret")
+		principal = nil
+		rs = &mcp.ResourceServer{
+			Metadata: mcp.ProtectedResourceMetadata{
+				Resource:             "https://mcp.example.com/mcp",
+				AuthorizationServers: []string{"https://auth.example.com"},
+			},
+			Verifier: &mcp.JWTVerifier{
+				Keys:   mcp.StaticKeys{"": secret},
+				Issuer: "https://auth.example.com",
+			},
+			RequiredScopes: []string{"mcp:tools"},
+		}
+		handler = rs.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ = mcp.PrincipalFromContext(r.Context())
+		}))
+	})
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	withToken := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "https://mcp.example.com/mcp", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss":   "https://auth.example.com",
+			"sub":   "alice",
+			"aud":   "https://mcp.example.com/mcp",
+			"scope": "mcp:tools mcp:prompts",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	It("serves the protected resource metadata", func() {
+		w := serve(httptest.NewRequest(http.MethodGet, "https://mcp.example.com/.well-known/oauth-protected-resource/mcp", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"resource":"https://mcp.example.com/mcp","authorization_servers":["https://auth.example.com"],"bearer_methods_supported":["header"]}`))
+	})
+
+	It("challenges requests without a token", func() {
+		w := serve(httptest.NewRequest(http.MethodPost, "https://mcp.example.com/mcp", nil))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(w.Header().Get("WWW-Authenticate")).To(Equal(`Bearer resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/mcp"`))
+		Expect(principal).To(BeNil())
+	})
+
+	It("rejects tokens passed in the query string", func() {
+		w := serve(httptest.NewRequest(http.MethodPost, "https://mcp.example.com/mcp?access_token=abc", nil))
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="invalid_request"`))
+	})
+
+	It("passes the principal of a valid token to the wrapped handler", func() {
+		w := serve(withToken(signHS256(secret, validClaims())))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(principal.Subject).To(Equal("alice"))
+		Expect(principal.Scopes).To(Equal([]string{"mcp:tools", "mcp:prompts"}))
+	})
+
+	Context("when the token signature is invalid", func() {
+		It("responds with an invalid_token challenge", func() {
+			w := serve(withToken(signHS256([]byte("wrong"), validClaims())))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="invalid_token"`))
+			Expect(principal).To(BeNil())
+		})
+	})
+
+	Context("when the token is signed with an unknown key", func() {
+		It("responds with an invalid_token challenge", func() {
+			rs.Verifier = &mcp.JWTVerifier{Keys: mcp.StaticKeys{"key-1": secret}}
+			w := serve(withToken(signHS256(secret, validClaims())))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="invalid_token"`))
+		})
+	})
+
+	Context("when the token cannot be verified", func() {
+		It("responds with service unavailable without describing the error", func() {
+			rs.Verifier = tokenVerifierFunc(func(context.Context, string) (*mcp.Principal, error) {
+				return nil, errors.New("introspecting token: Post \"https://internal.example.com/introspect\": connection refused")
+			})
+			w := serve(withToken("opaque-token"))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Header().Get("WWW-Authenticate")).To(BeEmpty())
+			Expect(w.Body.String()).ToNot(ContainSubstring("internal.example.com"))
+			Expect(principal).To(BeNil())
+		})
+
+		It("does not describe the error in an invalid_token challenge", func() {
+			rs.Verifier = tokenVerifierFunc(func(context.Context, string) (*mcp.Principal, error) {
+				return nil, fmt.Errorf("%w: rejected by https://internal.example.com/introspect", mcp.ErrInvalidToken)
+			})
+			w := serve(withToken("opaque-token"))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).ToNot(ContainSubstring("internal.example.com"))
+		})
+
+		It("responds with service unavailable when the verifier returns no principal", func() {
+			rs.Verifier = tokenVerifierFunc(func(context.Context, string) (*mcp.Principal, error) {
+				return nil, nil
+			})
+			w := serve(withToken("opaque-token"))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(principal).To(BeNil())
+		})
+	})
+
+	Context("when the token has expired", func() {
+		It("responds with an invalid_token challenge", func() {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			w := serve(withToken(signHS256(secret, claims)))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="invalid_token"`))
+		})
+	})
+
+	Context("when the token was issued for another resource", func() {
+		It("responds with an invalid_token challenge", func() {
+			claims := validClaims()
+			claims["aud"] = "https://other.example.com/mcp"
+			w := serve(withToken(signHS256(secret, claims)))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error_description="token was not issued for this resource"`))
+		})
+	})
+
+	Context("when the token is missing a required scope", func() {
+		It("responds with an insufficient_scope challenge", func() {
+			claims := validClaims()
+			claims["scope"] = "mcp:prompts"
+			w := serve(withToken(signHS256(secret, claims)))
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="insufficient_scope"`))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`scope="mcp:tools"`))
+		})
+	})
+
+	Context("when keys are published as a JWKS", func() {
+		It("verifies RS256 tokens", func() {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+			jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+					"kty": "RSA",
+					"kid": "key-1",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}}})
+			}))
+			defer jwks.Close()
+			rs.Verifier = &mcp.JWTVerifier{Keys: &mcp.JWKS{URL: jwks.URL}}
+
+			w := serve(withToken(signRS256(key, "key-1", validClaims())))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(principal.Subject).To(Equal("alice"))
+		})
+	})
+
+	Context("when tokens are introspected", func() {
+		var active bool
+
+		BeforeEach(func() {
+			introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.ParseForm()).To(Succeed())
+				Expect(r.PostForm.Get("token")).To(Equal("opaque-token"))
+				json.NewEncoder(w).Encode(map[string]any{
+					"active": active,
+					"sub":    "bob",
+					"aud":    []string{"https://mcp.example.com/mcp"},
+					"scope":  "mcp:tools",
+				})
+			}))
+			DeferCleanup(introspection.Close)
+			rs.Verifier = &mcp.IntrospectionVerifier{Endpoint: introspection.URL}
+		})
+
+		It("accepts active tokens", func() {
+			active = true
+			w := serve(withToken("opaque-token"))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(principal.Subject).To(Equal("bob"))
+		})
+
+		It("rejects inactive tokens", func() {
+			active = false
+			w := serve(withToken("opaque-token"))
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="invalid_token"`))
+		})
+	})
+})
+
+type tokenVerifierFunc func(ctx context.Context, token string) (*mcp.Principal, error)
+
+func (f tokenVerifierFunc) VerifyToken(ctx context.Context, token string) (*mcp.Principal, error) {
+	return f(ctx, token)
+}
+
+func jwtSigningInput(header, claims map[string]any) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	return strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(h),
+		base64.RawURLEncoding.EncodeToString(c),
+	}, ".")
+}
+
+func signHS256(secret []byte, claims map[string]any) string {
+	input := jwtSigningInput(map[string]any{"alg": "HS256", "typ": "JWT"}, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	input := jwtSigningInput(map[string]any{"alg": "RS256", "typ": "JWT", "kid": kid}, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	Expect(err).ToNot(HaveOccurred())
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Bridge serves a server that communicates over stdio to HTTP clients, so
+// that a server which cannot be modified can be shared across a network. Wrap
+// it with a ResourceServer to require authorization.
+//
+// The bridge runs a single server process. Every HTTP session is proxied to
+// the same upstream session, so the server should not keep per-client state.
+type Bridge struct {
+	*HTTPTransport
+	client *Client
+}
+
+// NewBridge starts cmd and connects to it as a client. The upstream is
+// served with NewProxy, so opts such as WithPolicy and WithAuditLog apply to
+// the requests of HTTP clients.
+func NewBridge(ctx context.Context, cmd *exec.Cmd, opts ...ServerOption) (*Bridge, error) {
+	stream, err := CommandStream(cmd)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(ctx, stream, Implementation{Name: "mcp-bridge", Version: "1.0.0"})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", cmd.Path, err)
+	}
+	s, err := NewProxy(ctx, c, opts...)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &Bridge{HTTPTransport: NewHTTPTransport(s), client: c}, nil
+}
+
+// Close ends the HTTP sessions and stops the server process.
+func (b *Bridge) Close() error {
+	return errors.Join(b.HTTPTransport.Close(), b.client.Close())
+}
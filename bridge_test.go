@@ -0,0 +1,63 @@
+package mcp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Bridge", func() {
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		bridge, err := mcp.NewBridge(context.Background(), exec.Command(exampleServerPath))
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(bridge.Close)
+		server = httptest.NewServer(bridge)
+		DeferCleanup(server.Close)
+	})
+
+	post := func(sessionID, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if sessionID != "" {
+			req.Header.Set(mcp.SessionIDHeader, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	readBody := func(resp *http.Response) string {
+		b, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(b)
+	}
+
+	It("serves a server that communicates over stdio to HTTP clients", func() {
+		resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"TestClient","version":"1.0.0"}}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(readBody(resp)).To(ContainSubstring(`"serverInfo":{"name":"ExampleServer"`))
+		session := resp.Header.Get(mcp.SessionIDHeader)
+		Expect(session).ToNot(BeEmpty())
+
+		resp = post(session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+
+		resp = post(session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":"hello"}}}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(readBody(resp)).To(ContainSubstring("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+	})
+})
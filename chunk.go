@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentLimit bounds the size of the text blocks returned by a tool, so that
+// clients with content size limits do not drop oversized results.
+type ContentLimit struct {
+	// MaxBytes is the largest text block returned. Longer blocks are split
+	// into several, breaking at line endings where possible.
+	MaxBytes int
+	// Truncate cuts longer blocks at MaxBytes and appends a hint that the
+	// text continues, rather than splitting them.
+	Truncate bool
+	// Store, if set, is called with the full text of each truncated block and
+	// returns the URI of a resource it can be read from. A link to the
+	// resource accompanies the truncated text.
+	Store func(text string) (uri string, err error)
+}
+
+func (l *ContentLimit) apply(result CallToolResult) (CallToolResult, error) {
+	if l == nil || l.MaxBytes <= 0 {
+		return result, nil
+	}
+	var content Contents
+	for _, c := range result.Content {
+		var tc TextContent
+		switch c := c.(type) {
+		case TextContent:
+			tc = c
+		case *TextContent:
+			tc = *c
+		default:
+			content = append(content, c)
+			continue
+		}
+		if len(tc.Text) <= l.MaxBytes {
+			content = append(content, c)
+			continue
+		}
+
+		if !l.Truncate {
+			for _, chunk := range splitText(tc.Text, l.MaxBytes) {
+				tc.Text = chunk
+				content = append(content, tc)
+			}
+			continue
+		}
+
+		full := tc.Text
+		tc.Text = splitText(full, l.MaxBytes)[0]
+		content = append(content, tc)
+		hint := fmt.Sprintf("[truncated %d of %d bytes]", len(full)-len(tc.Text), len(full))
+		if l.Store == nil {
+			content = append(content, TextContent{Type: "text", Text: hint})
+			continue
+		}
+		uri, err := l.Store(full)
+		if err != nil {
+			return CallToolResult{}, fmt.Errorf("storing truncated content: %w", err)
+		}
+		content = append(content,
+			TextContent{Type: "text", Text: fmt.Sprintf("%s the full text is available at %s", hint, uri)},
+			NewResourceLink(uri, "full text"),
+		)
+	}
+	result.Content = content
+	return result, nil
+}
+
+// splitText splits s into chunks of at most n bytes without splitting UTF-8
+// sequences, breaking after a newline in the second half of a chunk if there
+// is one.
+func splitText(s string, n int) []string {
+	var chunks []string
+	for len(s) > n {
+		end := n
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		if end == 0 {
+			_, end = utf8.DecodeRuneInString(s)
+		}
+		if i := strings.LastIndexByte(s[:end], '\n'); i >= end/2 {
+			end = i + 1
+		}
+		chunks = append(chunks, s[:end])
+		s = s[end:]
+	}
+	return append(chunks, s)
+}
@@ -0,0 +1,88 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Content limits", func() {
+
+	var text string
+
+	call := func(limit *mcp.ContentLimit) mcp.CallToolResult {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "read", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult(text), nil
+			},
+			ContentLimit: limit,
+		}}
+		conn := startServer(context.Background(), tools)
+		result, err := callTool(conn, "read", nil)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	texts := func(result mcp.CallToolResult) []string {
+		var ts []string
+		for _, c := range result.Content {
+			if tc, ok := c.(mcp.TextContent); ok {
+				ts = append(ts, tc.Text)
+			}
+		}
+		return ts
+	}
+
+	BeforeEach(func() {
+		text = "first line\nsecond line\nthird line\n"
+	})
+
+	It("leaves text within the limit alone", func() {
+		Expect(texts(call(&mcp.ContentLimit{MaxBytes: 100}))).To(Equal([]string{text}))
+	})
+
+	It("splits oversized text at line endings", func() {
+		Expect(texts(call(&mcp.ContentLimit{MaxBytes: 16}))).To(Equal([]string{"first line\n", "second line\n", "third line\n"}))
+	})
+
+	It("does not split multi-byte characters", func() {
+		text = strings.Repeat("é", 5)
+		chunks := texts(call(&mcp.ContentLimit{MaxBytes: 3}))
+		Expect(chunks).To(Equal([]string{"é", "é", "é", "é", "é"}))
+	})
+
+	It("truncates with a continuation hint", func() {
+		Expect(texts(call(&mcp.ContentLimit{MaxBytes: 16, Truncate: true}))).To(Equal([]string{
+			"first line\n",
+			"[truncated 23 of 34 bytes]",
+		}))
+	})
+
+	It("links to the full text when it is stored", func() {
+		var stored string
+		result := call(&mcp.ContentLimit{MaxBytes: 16, Truncate: true, Store: func(text string) (string, error) {
+			stored = text
+			return "mem://results/1", nil
+		}})
+		Expect(stored).To(Equal(text))
+		Expect(result.Content).To(Equal(mcp.Contents{
+			mcp.TextContent{Type: "text", Text: "first line\n"},
+			mcp.TextContent{Type: "text", Text: "[truncated 23 of 34 bytes] the full text is available at mem://results/1"},
+			mcp.NewResourceLink("mem://results/1", "full text"),
+		}))
+	})
+
+	It("reports failures to store the full text", func() {
+		result := call(&mcp.ContentLimit{MaxBytes: 16, Truncate: true, Store: func(string) (string, error) {
+			return "", errors.New("disk full")
+		}})
+		Expect(*result.IsError).To(BeTrue())
+		Expect(texts(result)).To(Equal([]string{"storing truncated content: disk full"}))
+	})
+})
@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Client is connected to an MCP server.
+type Client struct {
+	conn *jsonrpc2.Conn
+	init InitializeResult
+
+	mu            sync.Mutex
+	notifications map[string]func(params json.RawMessage)
+}
+
+// NewClient connects to the server at the other end of stream and performs
+// the initialization handshake, identifying as clientInfo.
+func NewClient(ctx context.Context, stream jsonrpc2.ObjectStream, clientInfo Implementation) (*Client, error) {
+	c := &Client{}
+	c.conn = jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.HandlerWithError(c.handle))
+	err := c.conn.Call(ctx, "initialize", InitializeRequestParams{
+		ProtocolVersion: SupportedProtocolVersion,
+		ClientInfo:      clientInfo,
+	}, &c.init)
+	if err == nil {
+		err = c.conn.Notify(ctx, "notifications/initialized", nil)
+	}
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("initializing: %w", err)
+	}
+	return c, nil
+}
+
+// handle rejects requests from the server, as no client capabilities are
+// offered, and passes notifications to their handlers.
+func (c *Client) handle(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+	if req.Notif {
+		c.mu.Lock()
+		f := c.notifications[req.Method]
+		c.mu.Unlock()
+		if f != nil {
+			var params json.RawMessage
+			if req.Params != nil {
+				params = *req.Params
+			}
+			f(params)
+		}
+		return nil, nil
+	}
+	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}
+}
+
+// HandleNotification sets f to be called with the params of each
+// notification of method sent by the server, replacing any previous handler.
+// Notifications are handled one at a time, in the order they arrive, so f
+// must not make requests on the client itself.
+func (c *Client) HandleNotification(method string, f func(params json.RawMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.notifications == nil {
+		c.notifications = map[string]func(json.RawMessage){}
+	}
+	c.notifications[method] = f
+}
+
+// InitializeResult returns the server's reply to initialization, describing
+// the server and its capabilities.
+func (c *Client) InitializeResult() InitializeResult {
+	return c.init
+}
+
+// ListTools lists every tool offered by the server, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	var cursor *string
+	for {
+		var result ListToolsResult
+		if err := c.conn.Call(ctx, "tools/list", ListToolsRequestParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if cursor = result.NextCursor; cursor == nil {
+			return tools, nil
+		}
+	}
+}
+
+// CallTool calls a tool offered by the server.
+func (c *Client) CallTool(ctx context.Context, params CallToolRequestParams) (CallToolResult, error) {
+	var result CallToolResult
+	err := c.conn.Call(ctx, "tools/call", params, &result)
+	return result, err
+}
+
+// ListPrompts lists every prompt offered by the server, following
+// pagination. A server that does not offer prompts has none.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	if c.init.Capabilities.Prompts == nil {
+		return nil, nil
+	}
+	var prompts []Prompt
+	var cursor *string
+	for {
+		var result ListPromptsResult
+		if err := c.conn.Call(ctx, "prompts/list", ListPromptsRequestParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, result.Prompts...)
+		if cursor = result.NextCursor; cursor == nil {
+			return prompts, nil
+		}
+	}
+}
+
+// GetPrompt renders a prompt offered by the server.
+func (c *Client) GetPrompt(ctx context.Context, params GetPromptRequestParams) (GetPromptResult, error) {
+	var result GetPromptResult
+	err := c.conn.Call(ctx, "prompts/get", params, &result)
+	return result, err
+}
+
+// ListResources lists every resource offered by the server, following
+// pagination. A server that does not offer resources has none.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	if c.init.Capabilities.Resources == nil {
+		return nil, nil
+	}
+	var resources []Resource
+	var cursor *string
+	for {
+		var result ListResourcesResult
+		if err := c.conn.Call(ctx, "resources/list", ListResourcesRequestParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		resources = append(resources, result.Resources...)
+		if cursor = result.NextCursor; cursor == nil {
+			return resources, nil
+		}
+	}
+}
+
+// ListResourceTemplates lists every resource template offered by the server,
+// following pagination. A server that does not offer resources has none.
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	if c.init.Capabilities.Resources == nil {
+		return nil, nil
+	}
+	var templates []ResourceTemplate
+	var cursor *string
+	for {
+		var result ListResourceTemplatesResult
+		if err := c.conn.Call(ctx, "resources/templates/list", ListResourceTemplatesRequestParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		templates = append(templates, result.ResourceTemplates...)
+		if cursor = result.NextCursor; cursor == nil {
+			return templates, nil
+		}
+	}
+}
+
+// ReadResource reads a resource offered by the server.
+func (c *Client) ReadResource(ctx context.Context, params ReadResourceRequestParams) (ReadResourceResult, error) {
+	var result ReadResourceResult
+	err := c.conn.Call(ctx, "resources/read", params, &result)
+	return result, err
+}
+
+// Call makes a request the other methods do not cover, decoding the result
+// into result.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	return c.conn.Call(ctx, method, params, result)
+}
+
+// Close disconnects from the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// CommandStream starts cmd and returns a stream over its stdin and stdout,
+// for connecting to a server that communicates over stdio. Closing the
+// stream closes stdin and waits for the process to exit, killing it if it
+// has not exited within a few seconds.
+func CommandStream(cmd *exec.Cmd) (jsonrpc2.ObjectStream, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", cmd.Path, err)
+	}
+	return jsonrpc2.NewPlainObjectStream(&process{cmd: cmd, stdin: stdin, stdout: stdout}), nil
+}
+
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+func (p *process) Read(b []byte) (int, error)  { return p.stdout.Read(b) }
+func (p *process) Write(b []byte) (int, error) { return p.stdin.Write(b) }
+
+func (p *process) Close() error {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
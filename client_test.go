@@ -0,0 +1,76 @@
+package mcp_test
+
+import (
+	"context"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Client", func() {
+
+	var c *mcp.Client
+
+	BeforeEach(func() {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "greet", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult("Hello, " + params.Arguments["name"].(string)), nil
+			},
+		}}
+		prompts := []mcp.PromptDefinition{{
+			Metadata: mcp.Prompt{Name: "welcome"},
+			Get: func(mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome"))}, nil
+			},
+		}}
+		c = newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...)))
+	})
+
+	It("initializes the session", func() {
+		Expect(c.InitializeResult().ServerInfo.Name).To(Equal("TestServer"))
+	})
+
+	It("lists and calls tools", func() {
+		tools, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(HaveLen(1))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "greet", Arguments: map[string]any{"name": "Ada"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("Hello, Ada")}))
+	})
+
+	It("lists and gets prompts", func() {
+		prompts, err := c.ListPrompts(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prompts).To(Equal([]mcp.Prompt{{Name: "welcome"}}))
+
+		result, err := c.GetPrompt(context.Background(), mcp.GetPromptRequestParams{Name: "welcome"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Messages).To(HaveLen(1))
+	})
+
+	It("has no prompts when the server does not offer them", func() {
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil))
+		Expect(c.ListPrompts(context.Background())).To(BeEmpty())
+	})
+
+	It("returns protocol errors", func() {
+		err := c.Call(context.Background(), "resources/list", nil, nil)
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}))
+	})
+
+	It("connects to servers that communicate over stdio", func() {
+		stream, err := mcp.CommandStream(exec.Command(exampleServerPath))
+		Expect(err).ToNot(HaveOccurred())
+		c, err := mcp.NewClient(context.Background(), stream, mcp.Implementation{Name: "TestClient", Version: "1.0.0"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.InitializeResult().ServerInfo.Name).To(Equal("ExampleServer"))
+		Expect(c.Close()).To(Succeed())
+	})
+})
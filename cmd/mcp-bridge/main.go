@@ -0,0 +1,131 @@
+// Command mcp-bridge serves an MCP server that communicates over stdio to
+// clients over HTTP, so that it can be shared across a network:
+//
+//	mcp-bridge -addr :8080 -resource https://mcp.example.com/mcp \
+//	    -issuer https://auth.example.com -jwks https://auth.example.com/.well-known/jwks.json -- ./server
+//
+// Access tokens are validated as JWTs when -jwks is set, or by token
+// introspection when -introspect is set, with the client secret read from
+// MCP_BRIDGE_CLIENT_SECRET. Without either the bridge requires -insecure and
+// serves unauthenticated requests.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/acrmp/mcp"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	resource := flag.String("resource", "", "URL clients use to reach the bridge, which access tokens must be issued for")
+	authServer := flag.String("authorization-server", "", "issuer of access tokens, advertised to clients; defaults to -issuer")
+	issuer := flag.String("issuer", "", "required issuer of JWT access tokens")
+	jwks := flag.String("jwks", "", "URL of the JSON Web Key Set that signs access tokens")
+	introspect := flag.String("introspect", "", "token introspection endpoint used to validate access tokens")
+	clientID := flag.String("client-id", "", "client ID the bridge authenticates to the introspection endpoint with")
+	scopes := flag.String("scopes", "", "comma separated scopes access tokens must carry")
+	insecure := flag.Bool("insecure", false, "serve requests without authorization")
+	origins := flag.String("allowed-origins", "", "comma separated origins browsers may make requests from")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] -- command [args...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Args()
+
+	var verifier mcp.TokenVerifier
+	switch {
+	case *jwks != "" && *introspect != "":
+		log.Fatal("only one of -jwks and -introspect may be set")
+	case *jwks != "":
+		verifier = &mcp.JWTVerifier{Keys: &mcp.JWKS{URL: *jwks}, Issuer: *issuer}
+	case *introspect != "":
+		verifier = &mcp.IntrospectionVerifier{
+			Endpoint:     *introspect,
+			ClientID:     *clientID,
+			ClientSecret: os.Getenv("MCP_BRIDGE_CLIENT_SECRET"),
+		}
+	case !*insecure:
+		log.Fatal("one of -jwks or -introspect is required, or -insecure to serve without authorization")
+	}
+	if verifier != nil && *resource == "" {
+		log.Fatal("-resource is required to validate access tokens")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	bridge, err := mcp.NewBridge(ctx, cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer bridge.Close()
+	if *origins != "" {
+		bridge.AllowedOrigins = strings.Split(*origins, ",")
+	}
+
+	path := "/"
+	if *resource != "" {
+		u, err := url.Parse(*resource)
+		if err != nil {
+			log.Fatalf("invalid -resource: %v", err)
+		}
+		if u.Path != "" {
+			path = u.Path
+		}
+	}
+	var handler http.Handler = bridge
+	mux := http.NewServeMux()
+	if verifier != nil {
+		if *authServer == "" {
+			*authServer = *issuer
+		}
+		rs := &mcp.ResourceServer{
+			Metadata: mcp.ProtectedResourceMetadata{Resource: *resource},
+			Verifier: verifier,
+		}
+		if *authServer != "" {
+			rs.Metadata.AuthorizationServers = []string{*authServer}
+		}
+		if *scopes != "" {
+			rs.RequiredScopes = strings.Split(*scopes, ",")
+			rs.Metadata.ScopesSupported = rs.RequiredScopes
+		}
+		handler = rs.Handler(bridge)
+		mux.Handle(rs.MetadataPath(), handler)
+	}
+	mux.Handle(path, handler)
+
+	server := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		// streams stay open until the client goes away, so give up waiting
+		// for them after a while
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	log.Printf("serving %s on %s%s", command[0], *addr, path)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,202 @@
+// Command mcp inspects an MCP server that communicates over stdio. It starts
+// the server, runs a single command against it and prints the result:
+//
+//	mcp tools -- ./server
+//	mcp call sha256sum '{"text":"hello"}' -- ./server
+//	mcp repl -- ./server
+//
+// Notifications sent by the server are printed to standard error as they
+// arrive.
+//
+// It also scaffolds new server projects:
+//
+//	mcp new -module example.com/weather weather
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/acrmp/mcp"
+)
+
+const usage = `usage: mcp command [args...] -- server [args...]
+       mcp new [-module path] dir
+
+commands:
+  info                        show the server's initialization result
+  tools                       list tools
+  prompts                     list prompts
+  resources                   list resources
+  call name [arguments]       call a tool with a JSON object of arguments
+  prompt name [arguments]     get a prompt with a JSON object of arguments
+  repl                        read commands from standard input
+`
+
+// notifications are the notifications printed as they arrive.
+var notifications = []string{
+	"notifications/message",
+	"notifications/progress",
+	"notifications/tools/list_changed",
+	"notifications/prompts/list_changed",
+	"notifications/resources/list_changed",
+	"notifications/resources/updated",
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "new" {
+		if err := newProject(os.Args[2:]); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	sep := slices.Index(os.Args, "--")
+	if sep < 2 || sep == len(os.Args)-1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	args, server := os.Args[1:sep], os.Args[sep+1:]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, args, server); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args, server []string) error {
+	cmd := exec.Command(server[0], server[1:]...)
+	cmd.Stderr = os.Stderr
+	stream, err := mcp.CommandStream(cmd)
+	if err != nil {
+		return err
+	}
+	c, err := mcp.NewClient(ctx, stream, mcp.Implementation{Name: "mcp", Version: "1.0.0"})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for _, method := range notifications {
+		c.HandleNotification(method, func(params json.RawMessage) {
+			fmt.Fprintf(os.Stderr, "%s %s\n", method, params)
+		})
+	}
+
+	if args[0] == "repl" {
+		return repl(ctx, c, os.Stdin, os.Stdout)
+	}
+	return execute(ctx, c, args, os.Stdout)
+}
+
+func repl(ctx context.Context, c *mcp.Client, in io.Reader, out io.Writer) error {
+	info := c.InitializeResult().ServerInfo
+	fmt.Fprintf(out, "connected to %s %s, type help for commands\n", info.Name, info.Version)
+	scanner := bufio.NewScanner(in)
+	for fmt.Fprint(out, "> "); scanner.Scan(); fmt.Fprint(out, "> ") {
+		args, err := splitLine(scanner.Text())
+		switch {
+		case err != nil:
+			fmt.Fprintln(out, err)
+			continue
+		case len(args) == 0:
+			continue
+		case args[0] == "quit" || args[0] == "exit":
+			return nil
+		case args[0] == "help":
+			fmt.Fprint(out, strings.SplitN(usage, "\n\n", 2)[1])
+			continue
+		}
+		if err := execute(ctx, c, args, out); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// splitLine splits a REPL line into a command, a name and the rest of the
+// line, which holds the arguments as JSON and may contain spaces.
+func splitLine(line string) ([]string, error) {
+	fields := strings.Fields(line)
+	if len(fields) <= 2 {
+		return fields, nil
+	}
+	rest := strings.TrimSpace(line)
+	for range 2 {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, strings.Fields(rest)[0]))
+	}
+	if !json.Valid([]byte(rest)) {
+		return nil, errors.New("arguments must be a JSON object")
+	}
+	return []string{fields[0], fields[1], rest}, nil
+}
+
+func execute(ctx context.Context, c *mcp.Client, args []string, out io.Writer) error {
+	command, args := args[0], args[1:]
+	var result any
+	var err error
+	switch command {
+	case "info":
+		result = c.InitializeResult()
+	case "tools":
+		tools, e := c.ListTools(ctx)
+		result, err = append([]mcp.Tool{}, tools...), e
+	case "prompts":
+		prompts, e := c.ListPrompts(ctx)
+		result, err = append([]mcp.Prompt{}, prompts...), e
+	case "resources":
+		var resources json.RawMessage
+		err = c.Call(ctx, "resources/list", nil, &resources)
+		result = resources
+	case "call":
+		var params mcp.CallToolRequestParams
+		if params.Name, err = nameAndArguments(args, &params.Arguments); err == nil {
+			result, err = c.CallTool(ctx, params)
+		}
+	case "prompt":
+		var params mcp.GetPromptRequestParams
+		if params.Name, err = nameAndArguments(args, &params.Arguments); err == nil {
+			result, err = c.GetPrompt(ctx, params)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s\n", b)
+	return nil
+}
+
+func nameAndArguments(args []string, arguments any) (string, error) {
+	switch len(args) {
+	case 1:
+		return args[0], nil
+	case 2:
+		if err := json.Unmarshal([]byte(args[1]), arguments); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		return args[0], nil
+	}
+	return "", errors.New("expected a name and optionally a JSON object of arguments")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// newProject implements the new command, which scaffolds a server project:
+//
+//	mcp new [-module path] dir
+func newProject(args []string) error {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	module := flags.String("module", "", "module path of the project; defaults to the directory name")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mcp new [-module path] dir")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected a single directory")
+	}
+	dir := flags.Arg(0)
+	data := struct{ Module, Name string }{Module: *module, Name: filepath.Base(dir)}
+	if data.Module == "" {
+		data.Module = data.Name
+	}
+	if err := scaffold(dir, data); err != nil {
+		return err
+	}
+	fmt.Printf("created %s; run make tidy to fetch dependencies, then make test\n", dir)
+	return nil
+}
+
+// scaffold renders each template into dir, which must not already contain
+// any of the files.
+func scaffold(dir string, data any) error {
+	entries, err := fs.ReadDir(templates, "templates")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".tmpl")
+		t, err := template.ParseFS(templates, path.Join("templates", e.Name()))
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		err = t.Execute(f, data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
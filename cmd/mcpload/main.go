@@ -0,0 +1,103 @@
+// Command mcpload generates load against an MCP server that communicates
+// over stdio, starting a server process for each session:
+//
+//	mcpload -sessions 4 -rate 50 -duration 30s -call 'sha256sum={"text":"hello"}' -- ./server
+//
+// Each -call adds a tool call to the mix, written as name[*weight][=arguments]
+// where arguments is a JSON object.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acrmp/mcp/loadtest"
+)
+
+type calls []loadtest.Call
+
+func (c *calls) String() string {
+	names := make([]string, len(*c))
+	for i, call := range *c {
+		names[i] = call.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *calls) Set(s string) error {
+	call, err := parseCall(s)
+	if err != nil {
+		return err
+	}
+	*c = append(*c, call)
+	return nil
+}
+
+func parseCall(s string) (loadtest.Call, error) {
+	spec, args, hasArgs := strings.Cut(s, "=")
+	name, weight, hasWeight := strings.Cut(spec, "*")
+	call := loadtest.Call{Name: name}
+	if name == "" {
+		return call, fmt.Errorf("call %q has no tool name", s)
+	}
+	if hasWeight {
+		w, err := strconv.Atoi(weight)
+		if err != nil || w <= 0 {
+			return call, fmt.Errorf("call %q has an invalid weight", s)
+		}
+		call.Weight = w
+	}
+	if hasArgs {
+		if err := json.Unmarshal([]byte(args), &call.Arguments); err != nil {
+			return call, fmt.Errorf("call %q has invalid arguments: %w", s, err)
+		}
+	}
+	return call, nil
+}
+
+func main() {
+	var mix calls
+	sessions := flag.Int("sessions", 1, "number of concurrent sessions")
+	rate := flag.Float64("rate", 0, "target calls per second across all sessions; 0 for unlimited")
+	duration := flag.Duration("duration", 10*time.Second, "how long to make calls for")
+	flag.Var(&mix, "call", "tool call to make, as name[*weight][=arguments]; may be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] -- command [args...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || len(mix) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Args()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, loadtest.Config{
+		Dial: loadtest.CommandDialer(func() *exec.Cmd {
+			cmd := exec.Command(command[0], command[1:]...)
+			cmd.Stderr = os.Stderr
+			return cmd
+		}),
+		Sessions: *sessions,
+		Rate:     *rate,
+		Duration: *duration,
+		Calls:    mix,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(report)
+}
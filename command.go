@@ -0,0 +1,236 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Command describes a command run by a tool created with CommandTool.
+type Command struct {
+	// Path is the program to run.
+	Path string
+	// Args are text/template templates rendered with the tool call
+	// arguments, so "{{.file}}" expands to the value of the file argument.
+	// A call is rejected if an argument whose template does not start with
+	// "-" expands to one that does, so that callers cannot pass options.
+	Args []string
+	// Sandbox restricts the resources available to the command.
+	Sandbox Sandbox
+}
+
+// Sandbox restricts the execution of a command. Limits the operating system
+// cannot enforce cause the call to fail rather than run unrestricted.
+type Sandbox struct {
+	// Timeout bounds the wall clock time the command may run for.
+	Timeout time.Duration
+	// CPUTime bounds the CPU time the command may consume.
+	CPUTime time.Duration
+	// MemoryBytes bounds the address space of the command.
+	MemoryBytes uint64
+	// Dir is the working directory of the command.
+	Dir string
+	// Chroot confines the command to Dir, which becomes its root directory.
+	// Path is then resolved within Dir, searching the PATH of the command
+	// or, if it has none, the usual system directories.
+	Chroot bool
+	// ScrubEnv starts the command with an empty environment, except for the
+	// variables named by InheritEnv and those listed in Env.
+	ScrubEnv   bool
+	InheritEnv []string
+	// Env holds additional variables in the form "key=value".
+	Env []string
+	// NoNetwork runs the command without access to the network.
+	NoNetwork bool
+	// MaxOutputBytes bounds the standard output, and the standard error,
+	// captured from the command. The call fails if the command writes more.
+	// It defaults to DefaultMaxOutputBytes.
+	MaxOutputBytes int
+}
+
+// DefaultMaxOutputBytes is the output captured from a command by default.
+const DefaultMaxOutputBytes = 1 << 20
+
+// commandWaitDelay is how long a command that has exited, or been killed, is
+// waited on for the processes it started to close its output.
+const commandWaitDelay = time.Second
+
+// chrootPath is searched for commands confined to a directory without a PATH
+// of their own.
+const chrootPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// CommandTool returns a tool that runs cmd and responds with its standard
+// output. A command that exits unsuccessfully results in a tool error
+// containing its standard error. The command is killed if the request is
+// cancelled.
+func CommandTool(metadata Tool, cmd Command) (ToolDefinition, error) {
+	tmpls := make([]*template.Template, 0, len(cmd.Args))
+	for i, a := range cmd.Args {
+		t, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=error").Parse(a)
+		if err != nil {
+			return ToolDefinition{}, fmt.Errorf("parsing argument %d of %s: %w", i, metadata.Name, err)
+		}
+		tmpls = append(tmpls, t)
+	}
+
+	return ToolDefinition{
+		Metadata: metadata,
+		Process: func(ctx context.Context, params CallToolRequestParams, _ Notifier) (CallToolResult, error) {
+			args := make([]string, 0, len(tmpls))
+			for i, t := range tmpls {
+				var b strings.Builder
+				if err := t.Execute(&b, map[string]any(params.Arguments)); err != nil {
+					return CallToolResult{}, fmt.Errorf("rendering command: %w", err)
+				}
+				if strings.HasPrefix(b.String(), "-") && !strings.HasPrefix(cmd.Args[i], "-") {
+					return CallToolResult{}, &InvalidParamsError{Message: fmt.Sprintf("argument %d of the command may not start with \"-\"", i)}
+				}
+				args = append(args, b.String())
+			}
+
+			stdout, err := cmd.Sandbox.run(ctx, cmd.Path, args)
+			if err != nil {
+				return CallToolResult{}, err
+			}
+			return CallToolResult{Content: []Content{TextContent{Type: "text", Text: stdout}}}, nil
+		},
+	}, nil
+}
+
+func (s Sandbox) run(ctx context.Context, path string, args []string) (string, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	path, err := s.lookPath(path)
+	if err != nil {
+		return "", err
+	}
+	c := exec.CommandContext(ctx, path, args...)
+	c.Cancel = func() error {
+		return killGroup(c)
+	}
+	c.Dir = s.Dir
+	c.Env = s.environ()
+	// processes started by the command may hold its output open after it
+	// exits or is killed
+	c.WaitDelay = commandWaitDelay
+	limit := s.MaxOutputBytes
+	if limit <= 0 {
+		limit = DefaultMaxOutputBytes
+	}
+	stdout, stderr := &cappedBuffer{max: limit}, &cappedBuffer{max: limit}
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	if err := s.configure(c); err != nil {
+		return "", err
+	}
+	if err := c.Start(); err != nil {
+		return "", fmt.Errorf("starting command: %w", err)
+	}
+
+	err = c.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// the command succeeded, but left processes running that held its
+		// output open
+		killGroup(c)
+		err = nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && s.Timeout > 0 {
+		return "", fmt.Errorf("command timed out after %s", s.Timeout)
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if stdout.exceeded {
+		return "", fmt.Errorf("command output exceeds %d bytes", limit)
+	}
+	if err != nil {
+		return "", fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// cappedBuffer holds up to max bytes written to it, failing writes beyond
+// that so that the command stops rather than filling memory. The buffer is
+// not embedded, so that copying into it cannot bypass Write with ReadFrom.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.exceeded = true
+		return room, errors.New("output limit exceeded")
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// lookPath returns the path of the program to run. A command confined to
+// Dir has a path without a separator looked up within Dir, and gets the path
+// within Dir. Otherwise the program is looked up on the host by exec.
+func (s Sandbox) lookPath(path string) (string, error) {
+	if !s.Chroot || s.Dir == "" {
+		return path, nil
+	}
+	if strings.Contains(path, "/") {
+		return filepath.Join("/", path), nil
+	}
+	env := s.environ()
+	if env == nil {
+		env = os.Environ()
+	}
+	search := chrootPath
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			search = v
+		}
+	}
+	for _, dir := range filepath.SplitList(search) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		p := filepath.Join(dir, path)
+		// symlinks are not followed as they resolve within Dir once confined
+		fi, err := os.Lstat(filepath.Join(s.Dir, p))
+		if err == nil && (fi.Mode()&os.ModeSymlink != 0 || fi.Mode().IsRegular() && fi.Mode().Perm()&0o111 != 0) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("finding %s within %s: %w", path, s.Dir, exec.ErrNotFound)
+}
+
+// environ returns the environment of the command, or nil to inherit the
+// environment of the server.
+func (s Sandbox) environ() []string {
+	if !s.ScrubEnv {
+		if len(s.Env) == 0 {
+			return nil
+		}
+		return append(os.Environ(), s.Env...)
+	}
+	env := []string{}
+	for _, name := range s.InheritEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return append(env, s.Env...)
+}
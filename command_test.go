@@ -0,0 +1,192 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("CommandTool", func() {
+
+	metadata := mcp.Tool{Name: "cmd", InputSchema: mcp.ToolInputSchema{Type: "object"}}
+
+	runContext := func(ctx context.Context, cmd mcp.Command, args map[string]any) (string, error) {
+		t, err := mcp.CommandTool(metadata, cmd)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := t.Process(ctx, mcp.CallToolRequestParams{Name: "cmd", Arguments: args}, nil)
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	run := func(cmd mcp.Command, args map[string]any) (string, error) {
+		return runContext(context.Background(), cmd, args)
+	}
+
+	It("renders arguments and responds with the output", func() {
+		Expect(run(mcp.Command{Path: "echo", Args: []string{"hello", "{{.name}}"}}, map[string]any{"name": "world"})).To(Equal("hello world\n"))
+	})
+
+	It("does not pass arguments through a shell", func() {
+		Expect(run(mcp.Command{Path: "echo", Args: []string{"{{.name}}"}}, map[string]any{"name": "$(id); rm -rf /"})).To(Equal("$(id); rm -rf /\n"))
+	})
+
+	It("rejects arguments that would pass options to the command", func() {
+		_, err := run(mcp.Command{Path: "echo", Args: []string{"{{.name}}"}}, map[string]any{"name": "-n"})
+		var invalid *mcp.InvalidParamsError
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+		Expect(err).To(MatchError(`argument 0 of the command may not start with "-"`))
+
+		Expect(run(mcp.Command{Path: "echo", Args: []string{"--name={{.name}}"}}, map[string]any{"name": "-n"})).To(Equal("--name=-n\n"))
+	})
+
+	It("reports failures with the standard error", func() {
+		_, err := run(mcp.Command{Path: "sh", Args: []string{"-c", "echo oops >&2; exit 3"}}, nil)
+		Expect(err).To(MatchError(ContainSubstring("exit status 3: oops")))
+	})
+
+	It("kills the command when the request is cancelled", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := runContext(ctx, mcp.Command{Path: "sleep", Args: []string{"5"}}, nil)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+	})
+
+	It("fails commands that write more output than allowed", func() {
+		_, err := run(mcp.Command{Path: "head", Args: []string{"-c", "4096", "/dev/zero"}, Sandbox: mcp.Sandbox{MaxOutputBytes: 1024}}, nil)
+		Expect(err).To(MatchError("command output exceeds 1024 bytes"))
+	})
+
+	It("rejects invalid templates", func() {
+		_, err := mcp.CommandTool(metadata, mcp.Command{Path: "echo", Args: []string{"{{.name"}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("with a sandbox", func() {
+		It("enforces a timeout", func() {
+			_, err := run(mcp.Command{Path: "sleep", Args: []string{"5"}, Sandbox: mcp.Sandbox{Timeout: 50 * time.Millisecond}}, nil)
+			Expect(err).To(MatchError("command timed out after 50ms"))
+		})
+
+		It("kills the processes the command started when it times out", func() {
+			pidFile := filepath.Join(GinkgoT().TempDir(), "pid")
+			_, err := run(mcp.Command{Path: "sh", Args: []string{"-c", "sleep 30 & echo $! > " + pidFile + "; wait"}, Sandbox: mcp.Sandbox{Timeout: 100 * time.Millisecond}}, nil)
+			Expect(err).To(MatchError("command timed out after 100ms"))
+
+			pid, err := os.ReadFile(pidFile)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() string {
+				stat, err := os.ReadFile(filepath.Join("/proc", strings.TrimSpace(string(pid)), "stat"))
+				if err != nil {
+					return "exited"
+				}
+				// a killed process may linger as a zombie until it is reaped
+				if _, after, ok := strings.Cut(string(stat), ") "); ok && strings.HasPrefix(after, "Z") {
+					return "exited"
+				}
+				return "running"
+			}).Should(Equal("exited"))
+		})
+
+		It("returns once the command exits, even if processes it started hold its output", func() {
+			start := time.Now()
+			Expect(run(mcp.Command{Path: "sh", Args: []string{"-c", "sleep 30 & echo done"}}, nil)).To(Equal("done\n"))
+			Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		})
+
+		It("runs in the configured directory", func() {
+			dir, err := filepath.EvalSymlinks(GinkgoT().TempDir())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(run(mcp.Command{Path: "pwd", Sandbox: mcp.Sandbox{Dir: dir}}, nil)).To(Equal(dir + "\n"))
+		})
+
+		It("scrubs the environment", func() {
+			GinkgoT().Setenv("MCP_TEST_SECRET", "hunter2")
+			GinkgoT().Setenv("MCP_TEST_LOCALE", "en_GB")
+			out, err := run(mcp.Command{Path: "/usr/bin/env", Sandbox: mcp.Sandbox{
+				ScrubEnv:   true,
+				InheritEnv: []string{"MCP_TEST_LOCALE"},
+				Env:        []string{"EXTRA=1"},
+			}}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Fields(out)).To(ConsistOf("MCP_TEST_LOCALE=en_GB", "EXTRA=1"))
+		})
+
+		Context("on linux", func() {
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" {
+					Skip("resource limits are only enforced on linux")
+				}
+			})
+
+			It("applies limits before the command starts", func() {
+				Expect(run(mcp.Command{Path: "sh", Args: []string{"-c", "ulimit -t; ulimit -v"}, Sandbox: mcp.Sandbox{
+					CPUTime:     time.Second,
+					MemoryBytes: 64 << 20,
+				}}, nil)).To(Equal("1\n65536\n"))
+			})
+
+			It("enforces a cpu time limit", func() {
+				_, err := run(mcp.Command{Path: "sh", Args: []string{"-c", "while :; do :; done"}, Sandbox: mcp.Sandbox{
+					CPUTime: time.Second,
+					Timeout: 10 * time.Second,
+				}}, nil)
+				Expect(err).To(MatchError(ContainSubstring("command failed: signal: killed")))
+			})
+
+			It("enforces a memory limit", func() {
+				_, err := run(mcp.Command{Path: "sh", Args: []string{"-c", "x=$(head -c 67108864 /dev/zero | tr '\\0' a); echo done"}, Sandbox: mcp.Sandbox{
+					MemoryBytes: 32 << 20,
+				}}, nil)
+				Expect(err).To(MatchError(ContainSubstring("command failed")))
+			})
+
+			It("finds the program within a chroot", func() {
+				if os.Geteuid() != 0 {
+					Skip("chroot requires root")
+				}
+				// a static binary needs nothing else within the chroot
+				dir := GinkgoT().TempDir()
+				build := exec.Command("go", "build", "-o", filepath.Join(dir, "bin", "chrooted-server"), "github.com/acrmp/mcp/testdata/sleepserver")
+				build.Env = append(os.Environ(), "CGO_ENABLED=0")
+				out, err := build.CombinedOutput()
+				Expect(err).ToNot(HaveOccurred(), string(out))
+
+				// the server exits without output once it reads EOF from stdin
+				Expect(run(mcp.Command{Path: "chrooted-server", Args: []string{"exit"}, Sandbox: mcp.Sandbox{
+					Dir:      dir,
+					Chroot:   true,
+					ScrubEnv: true,
+				}}, nil)).To(Equal(""))
+			})
+
+			It("isolates the command from the network", func() {
+				out, err := run(mcp.Command{Path: "cat", Args: []string{"/proc/net/dev"}, Sandbox: mcp.Sandbox{NoNetwork: true}}, nil)
+				if errors.Is(err, os.ErrPermission) {
+					Skip("network namespaces are not permitted")
+				}
+				Expect(err).ToNot(HaveOccurred())
+				var interfaces []string
+				for _, line := range strings.Split(out, "\n")[2:] {
+					if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+						interfaces = append(interfaces, name)
+					}
+				}
+				Expect(interfaces).To(Equal([]string{"lo"}))
+			})
+		})
+	})
+})
@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultCompressionMinSize = 1024
+
+// Compression negotiates gzip and deflate compression of HTTP request and
+// response bodies using the Content-Encoding and Accept-Encoding headers.
+type Compression struct {
+	// MinSize is the size a response body must reach before it is
+	// compressed. It defaults to 1024 bytes. Flushed responses, such as
+	// event streams, are always compressed.
+	MinSize int
+	// MaxRequestBytes, if positive, limits the decompressed size of request
+	// bodies.
+	MaxRequestBytes int64
+}
+
+// Handler wraps next so that compressed request bodies are decompressed and
+// responses are compressed for clients that accept it.
+func (c *Compression) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := c.decompressRequest(w, r); err != nil {
+			status := http.StatusBadRequest
+			var unsupported *unsupportedEncodingError
+			if errors.As(err, &unsupported) {
+				status = http.StatusUnsupportedMediaType
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: c.minSize(), status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func (c *Compression) minSize() int {
+	if c.MinSize <= 0 {
+		return defaultCompressionMinSize
+	}
+	return c.MinSize
+}
+
+func (c *Compression) decompressRequest(w http.ResponseWriter, r *http.Request) error {
+	var body io.ReadCloser
+	var err error
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(r.Body)
+	case "deflate":
+		body, err = zlib.NewReader(r.Body)
+	default:
+		return &unsupportedEncodingError{encoding: encoding}
+	}
+	if err != nil {
+		return fmt.Errorf("malformed request body: %w", err)
+	}
+	if c.MaxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, body, c.MaxRequestBytes)
+	}
+	r.Body = body
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+type unsupportedEncodingError struct {
+	encoding string
+}
+
+func (e *unsupportedEncodingError) Error() string {
+	return "unsupported content encoding " + strconv.Quote(e.encoding)
+}
+
+// acceptedEncoding returns the preferred encoding of gzip and deflate
+// accepted by the Accept-Encoding header, or the empty string if neither is.
+func acceptedEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "deflate" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if coding == "*" {
+			coding = "gzip"
+		}
+		// prefer gzip when qualities are equal
+		if q > bestQ || (q == bestQ && coding == "gzip") {
+			best, bestQ = coding, q
+		}
+	}
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it reaches minSize,
+// then compresses it if the handler has not already encoded it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	started  bool
+	cw       io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if !w.started {
+		w.status = status
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) >= w.minSize {
+			if err := w.start(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.cw != nil {
+		return w.cw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	h := w.Header()
+	if compress && h.Get("Content-Encoding") == "" && bodyAllowed(w.status) {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.cw = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.cw = zlib.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.Write(buf)
+	return err
+}
+
+// Flush implements http.Flusher.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(true)
+	}
+	if f, ok := w.cw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Close() error {
+	if !w.started {
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+	if w.cw != nil {
+		return w.cw.Close()
+	}
+	return nil
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,123 @@
+package mcp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Compression", func() {
+
+	var (
+		body    string
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		body = strings.Repeat(`{"jsonrpc":"2.0","id":1,"result":{}}`, 100)
+		handler = (&mcp.Compression{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, body)
+		}))
+	})
+
+	serve := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("compresses large responses with gzip", func() {
+		rec := serve("gzip, deflate")
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rec.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(rec.Body.Len()).To(BeNumerically("<", len(body)))
+		zr, err := gzip.NewReader(rec.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(io.ReadAll(zr)).To(BeEquivalentTo(body))
+	})
+
+	It("compresses with deflate when preferred", func() {
+		rec := serve("gzip;q=0.5, deflate")
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("deflate"))
+		zr, err := zlib.NewReader(rec.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(io.ReadAll(zr)).To(BeEquivalentTo(body))
+	})
+
+	It("does not compress for clients that do not accept it", func() {
+		for _, accept := range []string{"", "br", "gzip;q=0"} {
+			rec := serve(accept)
+			Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(rec.Body.String()).To(Equal(body))
+		}
+	})
+
+	It("does not compress small responses", func() {
+		body = `{"jsonrpc":"2.0","id":1,"result":{}}`
+		rec := serve("gzip")
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Body.String()).To(Equal(body))
+	})
+
+	It("compresses flushed responses regardless of size", func() {
+		handler = (&mcp.Compression{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: {}\n\n")
+			w.(http.Flusher).Flush()
+		}))
+		rec := serve("gzip")
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rec.Flushed).To(BeTrue())
+	})
+
+	It("decompresses request bodies", func() {
+		var received string
+		handler = (&mcp.Compression{}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			received = string(b)
+		}))
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		io.WriteString(zw, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		zw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/mcp", &buf)
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(received).To(Equal(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	})
+
+	It("rejects unsupported request encodings", func() {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader("..."))
+		req.Header.Set("Content-Encoding", "br")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+	})
+
+	It("rejects malformed request bodies", func() {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader("not gzip"))
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("malformed request body"))
+	})
+})
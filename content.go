@@ -0,0 +1,522 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Content is an item of content in a tool result or message. It is
+// implemented by TextContent, ImageContent, AudioContent, EmbeddedResource
+// and ResourceLink.
+type Content interface {
+	content()
+}
+
+func (TextContent) content()      {}
+func (ImageContent) content()     {}
+func (AudioContent) content()     {}
+func (EmbeddedResource) content() {}
+func (ResourceLink) content()     {}
+
+// Contents is a list of content that is decoded according to the type of
+// each item.
+type Contents []Content
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Contents) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*c = nil
+		return nil
+	}
+	contents := make(Contents, len(raw))
+	for i, r := range raw {
+		content, err := unmarshalContent(r)
+		if err != nil {
+			return err
+		}
+		contents[i] = content
+	}
+	*c = contents
+	return nil
+}
+
+func unmarshalContent(b []byte) (Content, error) {
+	var discriminator struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &discriminator); err != nil {
+		return nil, err
+	}
+	if discriminator.Type == nil {
+		if string(b) == "null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("field type in Content: required")
+	}
+
+	var c Content
+	var err error
+	switch *discriminator.Type {
+	case "text":
+		var t TextContent
+		err = json.Unmarshal(b, &t)
+		c = t
+	case "image":
+		var i ImageContent
+		err = json.Unmarshal(b, &i)
+		c = i
+	case "audio":
+		var a AudioContent
+		err = json.Unmarshal(b, &a)
+		c = a
+	case "resource":
+		var r EmbeddedResource
+		err = json.Unmarshal(b, &r)
+		c = r
+	case "resource_link":
+		var l ResourceLink
+		err = json.Unmarshal(b, &l)
+		c = l
+	default:
+		return nil, fmt.Errorf("unknown content type %q", *discriminator.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MarshalJSON implements json.Marshaler, defaulting Type to "text".
+func (j TextContent) MarshalJSON() ([]byte, error) {
+	type Plain TextContent
+	if j.Type == "" {
+		j.Type = "text"
+	}
+	return json.Marshal(Plain(j))
+}
+
+// MarshalJSON implements json.Marshaler, defaulting Type to "image".
+func (j ImageContent) MarshalJSON() ([]byte, error) {
+	type Plain ImageContent
+	if j.Type == "" {
+		j.Type = "image"
+	}
+	return json.Marshal(Plain(j))
+}
+
+// MarshalJSON implements json.Marshaler, defaulting Type to "audio".
+func (j AudioContent) MarshalJSON() ([]byte, error) {
+	type Plain AudioContent
+	if j.Type == "" {
+		j.Type = "audio"
+	}
+	return json.Marshal(Plain(j))
+}
+
+// MarshalJSON implements json.Marshaler, defaulting Type to "resource".
+func (j EmbeddedResource) MarshalJSON() ([]byte, error) {
+	type Plain EmbeddedResource
+	if j.Type == "" {
+		j.Type = "resource"
+	}
+	return json.Marshal(Plain(j))
+}
+
+// ResourceLink references a resource the client can read with
+// resources/read, rather than embedding its contents.
+type ResourceLink struct {
+	// Optional annotations for the client.
+	Annotations *Annotations `json:"annotations,omitempty"`
+
+	// A description of what this resource represents.
+	Description *string `json:"description,omitempty"`
+
+	// The MIME type of this resource, if known.
+	MimeType *string `json:"mimeType,omitempty"`
+
+	// A human-readable name for this resource.
+	Name string `json:"name"`
+
+	// The size of the raw resource content in bytes, if known.
+	Size *int `json:"size,omitempty"`
+
+	// Type corresponds to the JSON schema field "type".
+	Type string `json:"type"`
+
+	// The URI of this resource.
+	Uri string `json:"uri"`
+}
+
+// NewResourceLink returns content linking to the resource at uri.
+func NewResourceLink(uri, name string) ResourceLink {
+	return ResourceLink{Type: "resource_link", Uri: uri, Name: name}
+}
+
+// MarshalJSON implements json.Marshaler, defaulting Type to "resource_link".
+func (j ResourceLink) MarshalJSON() ([]byte, error) {
+	type Plain ResourceLink
+	if j.Type == "" {
+		j.Type = "resource_link"
+	}
+	return json.Marshal(Plain(j))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *ResourceLink) UnmarshalJSON(b []byte) error {
+	if err := requireFields(b, "ResourceLink", "name", "type", "uri"); err != nil {
+		return err
+	}
+	type Plain ResourceLink
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = ResourceLink(plain)
+	return nil
+}
+
+// EmbeddedResourceContents is the contents of an embedded resource. It is
+// implemented by TextResourceContents and BlobResourceContents.
+type EmbeddedResourceContents interface {
+	resourceContents()
+}
+
+func (TextResourceContents) resourceContents() {}
+func (BlobResourceContents) resourceContents() {}
+
+// NewTextResource returns content embedding the text of the resource at
+// uri. The mime type is optional.
+func NewTextResource(uri, mimeType, text string) EmbeddedResource {
+	return EmbeddedResource{
+		Type:     "resource",
+		Resource: TextResourceContents{Uri: uri, MimeType: optionalString(mimeType), Text: text},
+	}
+}
+
+// NewBlobResource returns content embedding the binary data of the resource
+// at uri. The mime type is optional.
+func NewBlobResource(uri, mimeType string, data []byte) EmbeddedResource {
+	return EmbeddedResource{
+		Type:     "resource",
+		Resource: BlobResourceContents{Uri: uri, MimeType: optionalString(mimeType), Blob: base64.StdEncoding.EncodeToString(data)},
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *EmbeddedResource) UnmarshalJSON(b []byte) error {
+	if err := requireFields(b, "EmbeddedResource", "resource", "type"); err != nil {
+		return err
+	}
+	type Plain EmbeddedResource
+	var plain struct {
+		Plain
+		Resource json.RawMessage `json:"resource"`
+	}
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(plain.Resource, &fields); err != nil {
+		return err
+	}
+	*j = EmbeddedResource(plain.Plain)
+	switch {
+	case fields["blob"] != nil:
+		var r BlobResourceContents
+		if err := json.Unmarshal(plain.Resource, &r); err != nil {
+			return err
+		}
+		j.Resource = r
+	case fields["text"] != nil:
+		var r TextResourceContents
+		if err := json.Unmarshal(plain.Resource, &r); err != nil {
+			return err
+		}
+		j.Resource = r
+	default:
+		return fmt.Errorf("field text or blob in EmbeddedResource.resource: required")
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *CreateMessageResult) UnmarshalJSON(b []byte) error {
+	if err := requireFields(b, "CreateMessageResult", "content", "model", "role"); err != nil {
+		return err
+	}
+	type Plain CreateMessageResult
+	var plain struct {
+		Plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	content, err := unmarshalContent(plain.Content)
+	if err != nil {
+		return err
+	}
+	*j = CreateMessageResult(plain.Plain)
+	j.Content = content
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *PromptMessage) UnmarshalJSON(b []byte) error {
+	if err := requireFields(b, "PromptMessage", "content", "role"); err != nil {
+		return err
+	}
+	type Plain PromptMessage
+	var plain struct {
+		Plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	content, err := unmarshalContent(plain.Content)
+	if err != nil {
+		return err
+	}
+	*j = PromptMessage(plain.Plain)
+	j.Content = content
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SamplingMessage) UnmarshalJSON(b []byte) error {
+	if err := requireFields(b, "SamplingMessage", "content", "role"); err != nil {
+		return err
+	}
+	type Plain SamplingMessage
+	var plain struct {
+		Plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	content, err := unmarshalContent(plain.Content)
+	if err != nil {
+		return err
+	}
+	*j = SamplingMessage(plain.Plain)
+	j.Content = content
+	return nil
+}
+
+// requireFields returns an error, worded as in schema.go, if the JSON object
+// b lacks any of fields.
+func requireFields(b []byte, typeName string, fields ...string) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if _, ok := raw[f]; raw != nil && !ok {
+			return fmt.Errorf("field %s in %s: required", f, typeName)
+		}
+	}
+	return nil
+}
+
+// NewImageContent returns image content holding data. If mimeType is empty
+// it is detected from data.
+func NewImageContent(data []byte, mimeType string) ImageContent {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return ImageContent{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// EncodeImageContent encodes img as "image/png" or "image/jpeg" and returns
+// it as image content.
+func EncodeImageContent(img image.Image, mimeType string) (ImageContent, error) {
+	var buf bytes.Buffer
+	var err error
+	switch mimeType {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	default:
+		return ImageContent{}, fmt.Errorf("unsupported image type %q", mimeType)
+	}
+	if err != nil {
+		return ImageContent{}, err
+	}
+	return NewImageContent(buf.Bytes(), mimeType), nil
+}
+
+// NewAudioContent returns audio content holding data. If mimeType is empty
+// it is detected from data, which recognises WAV, MP3, Ogg, AIFF and MIDI.
+func NewAudioContent(data []byte, mimeType string) AudioContent {
+	if mimeType == "" {
+		mimeType = detectContentType(data)
+	}
+	return AudioContent{
+		Type:     "audio",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+func detectContentType(data []byte) string {
+	switch t := http.DetectContentType(data); t {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	default:
+		return t
+	}
+}
+
+// EncodeWAVContent encodes 16-bit PCM samples as "audio/wav" content.
+// Samples for multiple channels are interleaved.
+func EncodeWAVContent(samples []int16, sampleRate, channels int) AudioContent {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+	dataSize := len(samples) * 2
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size                 uint32
+		Format, Channels     uint16
+		SampleRate, ByteRate uint32
+		BlockAlign, Bits     uint16
+	}{
+		Size:       16,
+		Format:     1, // PCM
+		Channels:   uint16(channels),
+		SampleRate: uint32(sampleRate),
+		ByteRate:   uint32(sampleRate * blockAlign),
+		BlockAlign: uint16(blockAlign),
+		Bits:       bitsPerSample,
+	})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return NewAudioContent(buf.Bytes(), "audio/wav")
+}
+
+// NewTextContent returns a text block.
+func NewTextContent(text string) TextContent {
+	return TextContent{Type: "text", Text: text}
+}
+
+// MarkdownContent returns a text block holding Markdown.
+func MarkdownContent(md string) TextContent {
+	return NewTextContent(md)
+}
+
+// JSONContent returns a text block holding v encoded as indented JSON.
+func JSONContent(v any) (TextContent, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return TextContent{}, err
+	}
+	return NewTextContent(string(b)), nil
+}
+
+// TableContent returns a text block holding rows as a Markdown table. The
+// first row is the header.
+func TableContent(rows [][]string) TextContent {
+	if len(rows) == 0 {
+		return NewTextContent("")
+	}
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < columns; i++ {
+			var cell string
+			if i < len(row) {
+				cell = tableCellReplacer.Replace(row[i])
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return NewTextContent(b.String())
+}
+
+var tableCellReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// ErrContentTooLarge is returned when content exceeds a size limit.
+var ErrContentTooLarge = errors.New("content too large")
+
+// BlobContentFromReader reads binary content from r, returning an error
+// wrapping ErrContentTooLarge if it holds more than maxBytes, which must be
+// positive. If mimeType is empty it is detected from the content.
+//
+// Images and audio are returned as ImageContent and AudioContent. Other
+// content is returned as an EmbeddedResource identified by uri, or by a hash
+// of the content (RFC 6920) if uri is empty. Servers choose a URI the client
+// can resolve, rather than exposing paths on the server.
+func BlobContentFromReader(r io.Reader, uri, mimeType string, maxBytes int64) (Content, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("reading content: limit of %d bytes is not positive", maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrContentTooLarge, maxBytes)
+	}
+
+	if mimeType == "" {
+		mimeType = detectContentType(data)
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return NewImageContent(data, mimeType), nil
+	case strings.HasPrefix(mimeType, "audio/"):
+		return NewAudioContent(data, mimeType), nil
+	}
+
+	if uri == "" {
+		sum := sha256.Sum256(data)
+		uri = "ni:///sha-256;" + base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return NewBlobResource(uri, mimeType, data), nil
+}
@@ -0,0 +1,298 @@
+package mcp_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Content", func() {
+
+	It("decodes content according to its type", func() {
+		var result mcp.CallToolResult
+		Expect(json.Unmarshal([]byte(`{"content":[
+			{"type":"text","text":"hello"},
+			{"type":"image","data":"aW1n","mimeType":"image/png"},
+			{"type":"audio","data":"YXVk","mimeType":"audio/wav"},
+			{"type":"resource","resource":{"uri":"file:///a.txt","text":"a"}}
+		]}`), &result)).To(Succeed())
+
+		Expect(result.Content).To(HaveLen(4))
+		Expect(result.Content[0]).To(Equal(mcp.TextContent{Type: "text", Text: "hello"}))
+		Expect(result.Content[1]).To(Equal(mcp.ImageContent{Type: "image", Data: "aW1n", MimeType: "image/png"}))
+		Expect(result.Content[2]).To(Equal(mcp.AudioContent{Type: "audio", Data: "YXVk", MimeType: "audio/wav"}))
+		Expect(result.Content[3]).To(BeAssignableToTypeOf(mcp.EmbeddedResource{}))
+	})
+
+	It("rejects unknown content types", func() {
+		var result mcp.CallToolResult
+		err := json.Unmarshal([]byte(`{"content":[{"type":"video","data":""}]}`), &result)
+		Expect(err).To(MatchError(`unknown content type "video"`))
+	})
+
+	It("rejects content without a type", func() {
+		var result mcp.CallToolResult
+		err := json.Unmarshal([]byte(`{"content":[{"text":"hello"}]}`), &result)
+		Expect(err).To(MatchError("field type in Content: required"))
+	})
+
+	It("sets the type when encoding content", func() {
+		b, err := json.Marshal(mcp.CallToolResult{Content: []mcp.Content{
+			mcp.TextContent{Text: "hello"},
+			&mcp.ImageContent{Data: "aW1n", MimeType: "image/png"},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"content":[
+			{"type":"text","text":"hello"},
+			{"type":"image","data":"aW1n","mimeType":"image/png"}
+		]}`))
+	})
+
+	It("decodes the content of prompt messages", func() {
+		var msg mcp.PromptMessage
+		Expect(json.Unmarshal([]byte(`{"role":"user","content":{"type":"text","text":"hello"}}`), &msg)).To(Succeed())
+		Expect(msg).To(Equal(mcp.PromptMessage{Role: mcp.RoleUser, Content: mcp.TextContent{Type: "text", Text: "hello"}}))
+	})
+
+	It("requires the content of messages", func() {
+		var msg mcp.SamplingMessage
+		err := json.Unmarshal([]byte(`{"role":"user"}`), &msg)
+		Expect(err).To(MatchError("field content in SamplingMessage: required"))
+	})
+})
+
+var _ = Describe("ImageContent", func() {
+
+	var img *image.RGBA
+
+	BeforeEach(func() {
+		img = image.NewRGBA(image.Rect(0, 0, 2, 2))
+		img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	})
+
+	decode := func(c mcp.ImageContent) image.Image {
+		data, err := base64.StdEncoding.DecodeString(c.Data)
+		Expect(err).ToNot(HaveOccurred())
+		decoded, format, err := image.Decode(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect("image/" + format).To(Equal(c.MimeType))
+		return decoded
+	}
+
+	It("encodes raw bytes, detecting the mime type", func() {
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, img)).To(Succeed())
+		c := mcp.NewImageContent(buf.Bytes(), "")
+		Expect(c.Type).To(Equal("image"))
+		Expect(c.MimeType).To(Equal("image/png"))
+		Expect(c.Data).To(Equal(base64.StdEncoding.EncodeToString(buf.Bytes())))
+	})
+
+	It("keeps an explicit mime type", func() {
+		c := mcp.NewImageContent([]byte("<svg/>"), "image/svg+xml")
+		Expect(c.MimeType).To(Equal("image/svg+xml"))
+	})
+
+	It("encodes images as PNG", func() {
+		c, err := mcp.EncodeImageContent(img, "image/png")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(color.RGBAModel.Convert(decode(c).At(0, 0))).To(Equal(color.RGBA{R: 255, A: 255}))
+	})
+
+	It("encodes images as JPEG", func() {
+		c, err := mcp.EncodeImageContent(img, "image/jpeg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decode(c).Bounds()).To(Equal(img.Bounds()))
+	})
+
+	It("rejects other formats", func() {
+		_, err := mcp.EncodeImageContent(img, "image/gif")
+		Expect(err).To(MatchError(`unsupported image type "image/gif"`))
+	})
+})
+
+var _ = Describe("AudioContent", func() {
+
+	It("encodes PCM samples as WAV", func() {
+		c := mcp.EncodeWAVContent([]int16{0, 1000, -1000, 0}, 8000, 2)
+		Expect(c.Type).To(Equal("audio"))
+		Expect(c.MimeType).To(Equal("audio/wav"))
+
+		data, err := base64.StdEncoding.DecodeString(c.Data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(44 + 8))
+		Expect(string(data[0:4])).To(Equal("RIFF"))
+		Expect(binary.LittleEndian.Uint32(data[4:8])).To(BeEquivalentTo(36 + 8))
+		Expect(string(data[8:16])).To(Equal("WAVEfmt "))
+		Expect(binary.LittleEndian.Uint16(data[22:24])).To(BeEquivalentTo(2))
+		Expect(binary.LittleEndian.Uint32(data[24:28])).To(BeEquivalentTo(8000))
+		Expect(binary.LittleEndian.Uint32(data[28:32])).To(BeEquivalentTo(32000))
+		Expect(string(data[36:40])).To(Equal("data"))
+		Expect(int16(binary.LittleEndian.Uint16(data[46:48]))).To(BeEquivalentTo(1000))
+	})
+
+	It("detects common audio formats", func() {
+		wav, _ := base64.StdEncoding.DecodeString(mcp.EncodeWAVContent(nil, 8000, 1).Data)
+		Expect(mcp.NewAudioContent(wav, "").MimeType).To(Equal("audio/wav"))
+		Expect(mcp.NewAudioContent([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), "").MimeType).To(Equal("audio/mpeg"))
+		Expect(mcp.NewAudioContent([]byte("OggS\x00\x02"), "").MimeType).To(Equal("audio/ogg"))
+	})
+
+	It("can be the content of prompt messages", func() {
+		b, err := json.Marshal(mcp.PromptMessage{Role: mcp.RoleUser, Content: mcp.NewAudioContent([]byte("abc"), "audio/mpeg")})
+		Expect(err).ToNot(HaveOccurred())
+		var msg mcp.PromptMessage
+		Expect(json.Unmarshal(b, &msg)).To(Succeed())
+		Expect(msg.Content).To(Equal(mcp.AudioContent{Type: "audio", Data: "YWJj", MimeType: "audio/mpeg"}))
+	})
+})
+
+var _ = Describe("EmbeddedResource", func() {
+
+	roundTrip := func(c mcp.Content) mcp.Content {
+		b, err := json.Marshal(mcp.CallToolResult{Content: []mcp.Content{c}})
+		Expect(err).ToNot(HaveOccurred())
+		var result mcp.CallToolResult
+		Expect(json.Unmarshal(b, &result)).To(Succeed())
+		return result.Content[0]
+	}
+
+	It("embeds text resources", func() {
+		c := mcp.NewTextResource("file:///README.md", "text/markdown", "# Hello")
+		b, err := json.Marshal(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"type":"resource","resource":{"uri":"file:///README.md","mimeType":"text/markdown","text":"# Hello"}}`))
+		Expect(roundTrip(c)).To(Equal(c))
+	})
+
+	It("embeds binary resources", func() {
+		c := mcp.NewBlobResource("file:///logo.bin", "", []byte{0, 1, 2})
+		b, err := json.Marshal(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"type":"resource","resource":{"uri":"file:///logo.bin","blob":"AAEC"}}`))
+		Expect(roundTrip(c)).To(Equal(c))
+	})
+
+	It("requires resources to hold text or a blob", func() {
+		var result mcp.CallToolResult
+		err := json.Unmarshal([]byte(`{"content":[{"type":"resource","resource":{"uri":"file:///a"}}]}`), &result)
+		Expect(err).To(MatchError("field text or blob in EmbeddedResource.resource: required"))
+	})
+
+	It("can be the content of prompt messages", func() {
+		var msg mcp.PromptMessage
+		Expect(json.Unmarshal([]byte(`{"role":"user","content":{"type":"resource","resource":{"uri":"file:///a","text":"a"}}}`), &msg)).To(Succeed())
+		Expect(msg.Content).To(Equal(mcp.NewTextResource("file:///a", "", "a")))
+	})
+})
+
+var _ = Describe("ResourceLink", func() {
+
+	It("links to resources from tool results", func() {
+		link := mcp.NewResourceLink("file:///report.pdf", "report.pdf")
+		mimeType := "application/pdf"
+		link.MimeType = &mimeType
+
+		b, err := json.Marshal(mcp.CallToolResult{Content: []mcp.Content{link}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"content":[{"type":"resource_link","uri":"file:///report.pdf","name":"report.pdf","mimeType":"application/pdf"}]}`))
+
+		var result mcp.CallToolResult
+		Expect(json.Unmarshal(b, &result)).To(Succeed())
+		Expect(result.Content).To(Equal(mcp.Contents{link}))
+	})
+
+	It("requires a uri and name", func() {
+		var result mcp.CallToolResult
+		err := json.Unmarshal([]byte(`{"content":[{"type":"resource_link","uri":"file:///a"}]}`), &result)
+		Expect(err).To(MatchError("field name in ResourceLink: required"))
+	})
+})
+
+var _ = Describe("Formatted text content", func() {
+
+	It("renders JSON", func() {
+		c, err := mcp.JSONContent(map[string]any{"name": "mcp", "stars": 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(mcp.TextContent{Type: "text", Text: "{\n  \"name\": \"mcp\",\n  \"stars\": 3\n}"}))
+	})
+
+	It("reports values that cannot be encoded", func() {
+		_, err := mcp.JSONContent(make(chan int))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("renders tables as Markdown", func() {
+		c := mcp.TableContent([][]string{
+			{"Name", "Notes"},
+			{"a|b", "line one\nline two"},
+			{"short"},
+		})
+		Expect(c.Text).To(Equal(
+			"| Name | Notes |\n" +
+				"| --- | --- |\n" +
+				"| a\\|b | line one<br>line two |\n" +
+				"| short |  |\n"))
+	})
+
+	It("renders Markdown", func() {
+		Expect(mcp.MarkdownContent("# Title")).To(Equal(mcp.TextContent{Type: "text", Text: "# Title"}))
+	})
+})
+
+var _ = Describe("BlobContentFromReader", func() {
+
+	It("returns images and audio as their content types", func() {
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))).To(Succeed())
+		c, err := mcp.BlobContentFromReader(bytes.NewReader(buf.Bytes()), "", "", 1<<20)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(mcp.NewImageContent(buf.Bytes(), "image/png")))
+
+		c, err = mcp.BlobContentFromReader(strings.NewReader("abc"), "", "audio/mpeg", 1<<20)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(mcp.NewAudioContent([]byte("abc"), "audio/mpeg")))
+	})
+
+	It("embeds other content identified by its hash", func() {
+		c, err := mcp.BlobContentFromReader(strings.NewReader("hello"), "", "application/octet-stream", 5)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(mcp.NewBlobResource("ni:///sha-256;LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ", "application/octet-stream", []byte("hello"))))
+	})
+
+	It("identifies content by the URI given", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "report.bin")
+		Expect(os.WriteFile(filename, []byte{0, 1}, 0o644)).To(Succeed())
+		f, err := os.Open(filename)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+
+		c, err := mcp.BlobContentFromReader(f, "reports://2024/report.bin", "", 1<<20)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(mcp.NewBlobResource("reports://2024/report.bin", "application/octet-stream", []byte{0, 1})))
+	})
+
+	It("rejects content over the limit", func() {
+		_, err := mcp.BlobContentFromReader(strings.NewReader("hello!"), "", "", 5)
+		Expect(err).To(MatchError(mcp.ErrContentTooLarge))
+		Expect(err).To(MatchError("content too large: exceeds 5 bytes"))
+	})
+
+	It("requires a limit", func() {
+		_, err := mcp.BlobContentFromReader(strings.NewReader("hello"), "", "", 0)
+		Expect(err).To(MatchError("reading content: limit of 0 bytes is not positive"))
+	})
+})
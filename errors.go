@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ToolError is returned by a tool to report that the call failed with a
+// tool result whose IsError is set, so that the model can see the failure and
+// respond to it. Content defaults to the message of Err.
+//
+// Errors of other types are reported in the same way, unless they are an
+// InvalidParamsError or an InternalError.
+type ToolError struct {
+	Err     error
+	Content Contents
+}
+
+func (e *ToolError) Error() string {
+	if e.Err == nil {
+		return "tool error"
+	}
+	return e.Err.Error()
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidParamsError is returned by a tool that rejects the arguments it was
+// called with. It is reported to the client as an invalid params protocol
+// error rather than a tool result.
+type InvalidParamsError struct {
+	Message string
+	// Data, if set, is encoded as the data of the protocol error.
+	Data any
+}
+
+func (e *InvalidParamsError) Error() string {
+	return e.Message
+}
+
+// InternalError is returned by a tool that failed because of a problem with
+// the server rather than the call. It is reported to the client as an
+// internal error protocol error rather than a tool result.
+type InternalError struct {
+	Message string
+	// Data, if set, is encoded as the data of the protocol error.
+	Data any
+}
+
+func (e *InternalError) Error() string {
+	return e.Message
+}
+
+// ErrorMapper translates an error returned by a tool into either a protocol
+// error or a tool result, so that domain errors such as
+// context.DeadlineExceeded or sql.ErrNoRows are reported consistently by every
+// tool. It returns nil for both to leave the error to the default handling.
+type ErrorMapper func(err error) (*jsonrpc2.Error, *CallToolResult)
+
+// WithErrorMapper sets how errors returned by tools are reported. m is
+// consulted before the type of the error, such as ToolError, is.
+func WithErrorMapper(m ErrorMapper) ServerOption {
+	return func(h *handler) {
+		h.errorMapper = m
+	}
+}
+
+// replyWithCallError replies to a call of tool that failed with err, choosing
+// between a protocol error and a tool result by the type of err.
+func (h *handler) replyWithCallError(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, tool string, err error, secretArgs []string) {
+	if h.errorMapper != nil {
+		rpcErr, result := h.errorMapper(err)
+		switch {
+		case rpcErr != nil:
+			var data any
+			if rpcErr.Data != nil {
+				data = rpcErr.Data
+			}
+			h.replyWithJSONRPCError(ctx, conn, req, h.protocolError(rpcErr.Code, rpcErr.Message, data, secretArgs))
+			return
+		case result != nil:
+			h.replyWithErrorResult(ctx, conn, req, tool, *result, secretArgs)
+			return
+		}
+	}
+
+	var (
+		invalidParams *InvalidParamsError
+		internal      *InternalError
+		toolErr       *ToolError
+	)
+	switch {
+	case errors.As(err, &invalidParams):
+		h.replyWithJSONRPCError(ctx, conn, req, h.protocolError(jsonrpc2.CodeInvalidParams, invalidParams.Message, invalidParams.Data, secretArgs))
+	case errors.As(err, &internal):
+		h.replyWithJSONRPCError(ctx, conn, req, h.protocolError(jsonrpc2.CodeInternalError, internal.Message, internal.Data, secretArgs))
+	case errors.As(err, &toolErr) && len(toolErr.Content) > 0:
+		h.replyWithErrorResult(ctx, conn, req, tool, CallToolResult{Content: toolErr.Content}, secretArgs)
+	default:
+		h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
+	}
+}
+
+// replyWithErrorResult replies with result marked as a tool error. The result
+// is screened and redacted as the results of successful calls are, and has
+// secrets masked as error messages do.
+func (h *handler) replyWithErrorResult(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, tool string, result CallToolResult, secretArgs []string) {
+	result, err := h.screenResult(ctx, tool, result)
+	if err != nil {
+		h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
+		return
+	}
+	if result, err = h.secrets.maskResult(result, secretArgs...); err != nil {
+		slog.Error("problem masking error result", "tool", tool, "error", err)
+		h.replyWithToolError(ctx, conn, req, "tool error")
+		return
+	}
+	if h.redactor != nil && h.redactor.Content {
+		result = h.redactor.Result(result)
+	}
+	isError := true
+	result.IsError = &isError
+	h.replyWithResult(ctx, conn, req, result)
+}
+
+// protocolError returns a JSON-RPC error with secrets masked from msg.
+func (h *handler) protocolError(code int64, msg string, data any, secretArgs []string) *jsonrpc2.Error {
+	rpcErr := &jsonrpc2.Error{Code: code, Message: h.secrets.Mask(msg, secretArgs...)}
+	if data != nil {
+		b, err := h.secrets.maskJSON(data, secretArgs...)
+		if err != nil {
+			slog.Error("problem encoding error data", "error", err)
+			return rpcErr
+		}
+		rpcErr.Data = (*json.RawMessage)(&b)
+	}
+	return rpcErr
+}
@@ -54,7 +54,7 @@ func computeSHA256(params mcp.CallToolRequestParams) (mcp.CallToolResult, error)
 	checksum := fmt.Sprintf("%x", h.Sum(nil))
 	var noError bool
 	return mcp.CallToolResult{
-		Content: []any{
+		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
 				Text: checksum,
@@ -0,0 +1,56 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/acrmp/mcp"
+)
+
+func FuzzHandleRaw(f *testing.F) {
+	required := true
+	tools := []mcp.ToolDefinition{{
+		Metadata: mcp.Tool{
+			Name: "echo",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: mcp.ToolInputSchemaProperties{"text": {"type": "string"}},
+				Required:   []string{"text"},
+			},
+		},
+		Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+			text, _ := params.Arguments["text"].(string)
+			return mcp.NewResult().Text(text).Build()
+		},
+	}}
+	prompts := []mcp.PromptDefinition{{
+		Metadata: mcp.Prompt{Name: "welcome", Arguments: []mcp.PromptArgument{{Name: "name", Required: &required}}},
+		Get: func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+			return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome " + params.Arguments["name"]))}, nil
+		},
+	}}
+	s := mcp.NewServer(mcp.Implementation{Name: "FuzzServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...))
+
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"fuzz","version":"1.0.0"}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":"a","method":"ping"}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{"cursor":"x"}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","id":4,"method":"prompts/get","params":{"name":"welcome","arguments":{"name":"Ada"}}}`))
+	f.Add([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		out := s.HandleRaw(context.Background(), msg)
+		dec := json.NewDecoder(bytes.NewReader(out))
+		for dec.More() {
+			var response map[string]json.RawMessage
+			if err := dec.Decode(&response); err != nil {
+				t.Fatalf("invalid reply to %q: %v\n%s", msg, err, out)
+			}
+			if _, ok := response["id"]; !ok {
+				t.Errorf("reply to %q has no ID: %s", msg, out)
+			}
+		}
+	})
+}
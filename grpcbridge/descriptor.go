@@ -0,0 +1,413 @@
+package grpcbridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field types of google.protobuf.FieldDescriptorProto.
+const (
+	typeDouble   = 1
+	typeFloat    = 2
+	typeInt64    = 3
+	typeUint64   = 4
+	typeInt32    = 5
+	typeFixed64  = 6
+	typeFixed32  = 7
+	typeBool     = 8
+	typeString   = 9
+	typeGroup    = 10
+	typeMessage  = 11
+	typeBytes    = 12
+	typeUint32   = 13
+	typeEnum     = 14
+	typeSfixed32 = 15
+	typeSfixed64 = 16
+	typeSint32   = 17
+	typeSint64   = 18
+)
+
+const labelRepeated = 3
+
+// Idempotency levels of google.protobuf.MethodOptions.
+const (
+	noSideEffects = 1
+	idempotent    = 2
+)
+
+type message struct {
+	fullName string
+	fields   []*field
+	byNumber map[int32]*field
+	// byName holds the fields by their JSON and their proto names.
+	byName   map[string]*field
+	mapEntry bool
+}
+
+type field struct {
+	name, jsonName string
+	number         int32
+	repeated       bool
+	kind           int
+	typeName       string
+	message        *message
+	enum           *enum
+	comment        string
+}
+
+type enum struct {
+	fullName string
+	names    []string
+	byName   map[string]int32
+	byNumber map[int32]string
+}
+
+type service struct {
+	fullName, name string
+	methods        []*method
+}
+
+type method struct {
+	name                             string
+	inputType, outputType            string
+	input, output                    *message
+	clientStreaming, serverStreaming bool
+	idempotency                      uint64
+	comment                          string
+}
+
+// registry holds the messages, enums and services of a descriptor set.
+type registry struct {
+	messages map[string]*message
+	enums    map[string]*enum
+	services []*service
+}
+
+// parseDescriptorSet parses an encoded google.protobuf.FileDescriptorSet.
+func parseDescriptorSet(b []byte) (*registry, error) {
+	r := &registry{messages: map[string]*message{}, enums: map[string]*enum{}}
+	err := parseFields(b, func(f wireField) error {
+		if f.number == 1 && f.typ == wireBytes {
+			return r.parseFile(f.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range r.messages {
+		for _, f := range m.fields {
+			if err := r.resolveField(m, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, s := range r.services {
+		for _, m := range s.methods {
+			for _, t := range []struct {
+				name string
+				m    **message
+			}{{m.inputType, &m.input}, {m.outputType, &m.output}} {
+				if *t.m = r.messages[t.name]; *t.m == nil && wellKnownTypes[t.name] == nil {
+					return nil, fmt.Errorf("method %s/%s: unknown message type %s", s.fullName, m.name, t.name)
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *registry) resolveField(m *message, f *field) error {
+	switch f.kind {
+	case typeMessage:
+		if wellKnownTypes[f.typeName] != nil {
+			return nil
+		}
+		if f.message = r.messages[f.typeName]; f.message == nil {
+			return fmt.Errorf("field %s.%s: unknown message type %s", m.fullName, f.name, f.typeName)
+		}
+	case typeEnum:
+		if f.enum = r.enums[f.typeName]; f.enum == nil {
+			return fmt.Errorf("field %s.%s: unknown enum type %s", m.fullName, f.name, f.typeName)
+		}
+	case typeGroup:
+		return fmt.Errorf("field %s.%s: groups are not supported", m.fullName, f.name)
+	}
+	return nil
+}
+
+// comments holds the leading comments of a file by the path of the element
+// they describe.
+type comments map[string]string
+
+func (c comments) get(path ...int) string {
+	s := make([]string, len(path))
+	for i, p := range path {
+		s[i] = strconv.Itoa(p)
+	}
+	return strings.TrimSpace(c[strings.Join(s, ".")])
+}
+
+func (r *registry) parseFile(b []byte) error {
+	var pkg string
+	var messages, enums, services [][]byte
+	c := comments{}
+	err := parseFields(b, func(f wireField) error {
+		if f.typ != wireBytes {
+			return nil
+		}
+		switch f.number {
+		case 2:
+			pkg = string(f.bytes)
+		case 4:
+			messages = append(messages, f.bytes)
+		case 5:
+			enums = append(enums, f.bytes)
+		case 6:
+			services = append(services, f.bytes)
+		case 9:
+			return parseSourceInfo(f.bytes, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	scope := pkg
+	if scope != "" {
+		scope += "."
+	}
+	for i, m := range messages {
+		if err := r.parseMessage(m, scope, c, []int{4, i}); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := r.parseEnum(e, scope); err != nil {
+			return err
+		}
+	}
+	for i, s := range services {
+		if err := r.parseService(s, scope, c, []int{6, i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSourceInfo adds the leading comments of a
+// google.protobuf.SourceCodeInfo to c.
+func parseSourceInfo(b []byte, c comments) error {
+	return parseFields(b, func(f wireField) error {
+		if f.number != 1 || f.typ != wireBytes {
+			return nil
+		}
+		var path []string
+		var comment string
+		err := parseFields(f.bytes, func(f wireField) error {
+			switch {
+			case f.number == 1 && f.typ == wireBytes:
+				for b := f.bytes; len(b) > 0; {
+					v, n := consumeVarint(b)
+					if n < 0 {
+						return errTruncated
+					}
+					path = append(path, strconv.FormatUint(v, 10))
+					b = b[n:]
+				}
+			case f.number == 1 && f.typ == wireVarint:
+				path = append(path, strconv.FormatUint(f.value, 10))
+			case f.number == 3 && f.typ == wireBytes:
+				comment = string(f.bytes)
+			}
+			return nil
+		})
+		if comment != "" {
+			c[strings.Join(path, ".")] = comment
+		}
+		return err
+	})
+}
+
+func (r *registry) parseMessage(b []byte, scope string, c comments, path []int) error {
+	m := &message{byNumber: map[int32]*field{}, byName: map[string]*field{}}
+	var nested, enums [][]byte
+	fieldIndex := 0
+	err := parseFields(b, func(f wireField) error {
+		if f.typ != wireBytes {
+			return nil
+		}
+		switch f.number {
+		case 1:
+			m.fullName = scope + string(f.bytes)
+		case 2:
+			fd, err := parseField(f.bytes)
+			if err != nil {
+				return err
+			}
+			fd.comment = c.get(append(path, 2, fieldIndex)...)
+			fieldIndex++
+			m.fields = append(m.fields, fd)
+		case 3:
+			nested = append(nested, f.bytes)
+		case 4:
+			enums = append(enums, f.bytes)
+		case 7:
+			return parseFields(f.bytes, func(f wireField) error {
+				if f.number == 7 && f.typ == wireVarint {
+					m.mapEntry = f.value != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range m.fields {
+		m.byNumber[f.number] = f
+		m.byName[f.name] = f
+		m.byName[f.jsonName] = f
+	}
+	r.messages[m.fullName] = m
+	for i, n := range nested {
+		if err := r.parseMessage(n, m.fullName+".", c, append(path[:len(path):len(path)], 3, i)); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := r.parseEnum(e, m.fullName+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseField(b []byte) (*field, error) {
+	f := &field{}
+	err := parseFields(b, func(w wireField) error {
+		switch {
+		case w.number == 1 && w.typ == wireBytes:
+			f.name = string(w.bytes)
+		case w.number == 3 && w.typ == wireVarint:
+			f.number = int32(w.value)
+		case w.number == 4 && w.typ == wireVarint:
+			f.repeated = w.value == labelRepeated
+		case w.number == 5 && w.typ == wireVarint:
+			f.kind = int(w.value)
+		case w.number == 6 && w.typ == wireBytes:
+			f.typeName = strings.TrimPrefix(string(w.bytes), ".")
+		case w.number == 10 && w.typ == wireBytes:
+			f.jsonName = string(w.bytes)
+		}
+		return nil
+	})
+	if f.jsonName == "" {
+		f.jsonName = jsonName(f.name)
+	}
+	return f, err
+}
+
+// jsonName returns the lowerCamelCase JSON name protoc derives from the
+// field name name.
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			b.WriteRune(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(c)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+func (r *registry) parseEnum(b []byte, scope string) error {
+	e := &enum{byName: map[string]int32{}, byNumber: map[int32]string{}}
+	err := parseFields(b, func(f wireField) error {
+		switch {
+		case f.number == 1 && f.typ == wireBytes:
+			e.fullName = scope + string(f.bytes)
+		case f.number == 2 && f.typ == wireBytes:
+			var name string
+			var number int32
+			err := parseFields(f.bytes, func(f wireField) error {
+				switch {
+				case f.number == 1 && f.typ == wireBytes:
+					name = string(f.bytes)
+				case f.number == 2 && f.typ == wireVarint:
+					number = int32(f.value)
+				}
+				return nil
+			})
+			e.names = append(e.names, name)
+			e.byName[name] = number
+			// the first name wins for aliased numbers
+			if _, ok := e.byNumber[number]; !ok {
+				e.byNumber[number] = name
+			}
+			return err
+		}
+		return nil
+	})
+	r.enums[e.fullName] = e
+	return err
+}
+
+func (r *registry) parseService(b []byte, scope string, c comments, path []int) error {
+	s := &service{}
+	methodIndex := 0
+	err := parseFields(b, func(f wireField) error {
+		switch {
+		case f.number == 1 && f.typ == wireBytes:
+			s.name = string(f.bytes)
+			s.fullName = scope + s.name
+		case f.number == 2 && f.typ == wireBytes:
+			m, err := parseMethod(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.comment = c.get(append(path, 2, methodIndex)...)
+			methodIndex++
+			s.methods = append(s.methods, m)
+		}
+		return nil
+	})
+	r.services = append(r.services, s)
+	return err
+}
+
+func parseMethod(b []byte) (*method, error) {
+	m := &method{}
+	err := parseFields(b, func(f wireField) error {
+		switch {
+		case f.number == 1 && f.typ == wireBytes:
+			m.name = string(f.bytes)
+		case f.number == 2 && f.typ == wireBytes:
+			m.inputType = strings.TrimPrefix(string(f.bytes), ".")
+		case f.number == 3 && f.typ == wireBytes:
+			m.outputType = strings.TrimPrefix(string(f.bytes), ".")
+		case f.number == 4 && f.typ == wireBytes:
+			return parseFields(f.bytes, func(f wireField) error {
+				if f.number == 34 && f.typ == wireVarint {
+					m.idempotency = f.value
+				}
+				return nil
+			})
+		case f.number == 5 && f.typ == wireVarint:
+			m.clientStreaming = f.value != 0
+		case f.number == 6 && f.typ == wireVarint:
+			m.serverStreaming = f.value != 0
+		}
+		return nil
+	})
+	return m, err
+}
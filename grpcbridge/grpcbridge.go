@@ -0,0 +1,314 @@
+// Package grpcbridge converts the methods of gRPC services into MCP tools
+// that call them, translating between the JSON arguments and results of
+// tools and protobuf messages.
+package grpcbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/acrmp/mcp"
+)
+
+// DefaultMaxMessageBytes is the largest response message read when
+// Config.MaxMessageBytes is not set, which is the default of gRPC servers.
+const DefaultMaxMessageBytes = 4 << 20
+
+// maxNameLength is the longest tool name generated, which is the longest
+// accepted by common model APIs.
+const maxNameLength = 64
+
+// errClientStreaming is returned for methods that take a stream of
+// messages, which are skipped.
+var errClientStreaming = errors.New("client streaming methods are not supported")
+
+// statusNames are the names of gRPC status codes.
+var statusNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// Config configures the tools generated from a descriptor set.
+type Config struct {
+	// Target is the URL of the gRPC server, such as http://localhost:50051.
+	Target string
+	// Methods selects the methods offered as tools by their full names, as
+	// in echo.v1.EchoService/Echo. It defaults to every method of the
+	// descriptor set that does not take a stream of messages.
+	Methods []string
+	// Client makes the calls. It must speak HTTP/2, and defaults to a client
+	// that does so, without TLS if Target is an http URL.
+	Client *http.Client
+	// Header is sent as metadata with every call, for example to set
+	// Authorization.
+	Header http.Header
+	// MaxMessageBytes limits the size of the response messages read. Longer
+	// messages fail the call. It defaults to DefaultMaxMessageBytes.
+	MaxMessageBytes int
+}
+
+// Tools returns a tool for each method selected by cfg from the services of
+// descriptorSet, an encoded google.protobuf.FileDescriptorSet such as those
+// written by protoc --descriptor_set_out --include_imports or buf build.
+// Descriptor sets that include source info give tools and their arguments
+// the comments of methods and fields as descriptions.
+//
+// A tool is named after the service and method it calls, as in
+// EchoService_Echo, and takes the fields of the request message as
+// arguments in their proto3 JSON form. Calling the tool calls the method and
+// returns the response message as structured content. Methods that return
+// a stream of messages return the messages received, in order, as the
+// "messages" array. Calls that fail with a gRPC status are tool errors.
+// Methods that take a stream of messages are not supported.
+func Tools(descriptorSet []byte, cfg Config) ([]mcp.ToolDefinition, error) {
+	r, err := parseDescriptorSet(descriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("parsing descriptor set: %w", err)
+	}
+	if cfg.Target == "" {
+		return nil, errors.New("no target configured")
+	}
+	if cfg.Client == nil {
+		cfg.Client = defaultClient(cfg.Target)
+	}
+	if cfg.MaxMessageBytes <= 0 {
+		cfg.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+
+	var tools []mcp.ToolDefinition
+	names := map[string]bool{}
+	found := map[string]bool{}
+	for _, s := range r.services {
+		for _, m := range s.methods {
+			fullName := s.fullName + "/" + m.name
+			if cfg.Methods != nil && !slices.Contains(cfg.Methods, fullName) {
+				continue
+			}
+			found[fullName] = true
+			if m.clientStreaming {
+				if cfg.Methods == nil {
+					slog.Warn("skipping method", "method", fullName, "error", errClientStreaming)
+					continue
+				}
+				return nil, fmt.Errorf("%s: %w", fullName, errClientStreaming)
+			}
+			c := call{path: "/" + fullName, method: m, name: toolName(s.name + "_" + m.name)}
+			if names[c.name] {
+				return nil, fmt.Errorf("%s: duplicate tool name %s", fullName, c.name)
+			}
+			names[c.name] = true
+			tools = append(tools, c.tool(cfg))
+		}
+	}
+	for _, name := range cfg.Methods {
+		if !found[name] {
+			return nil, fmt.Errorf("method %s is not in the descriptor set", name)
+		}
+	}
+	return tools, nil
+}
+
+// defaultClient returns a client speaking HTTP/2 to target, without TLS if
+// it is an http URL.
+func defaultClient(target string) *http.Client {
+	if !strings.HasPrefix(target, "http://") {
+		return &http.Client{Transport: &http2.Transport{}}
+	}
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// toolName returns s with the characters not allowed in tool names replaced,
+// cut to maxNameLength.
+func toolName(s string) string {
+	s = strings.Trim(invalidNameChars.ReplaceAllString(s, "_"), "_")
+	if len(s) > maxNameLength {
+		s = s[:maxNameLength]
+	}
+	return s
+}
+
+type call struct {
+	name, path string
+	method     *method
+}
+
+func (c call) tool(cfg Config) mcp.ToolDefinition {
+	schema := messageSchema(c.method.input, c.method.inputType, nil)
+	properties, _ := schema["properties"].(map[string]any)
+	t := mcp.Tool{
+		Name:        c.name,
+		InputSchema: mcp.ToolInputSchema{Type: "object", Properties: mcp.ToolInputSchemaProperties{}},
+	}
+	for k, v := range properties {
+		t.InputSchema.Properties[k] = v.(map[string]any)
+	}
+	if c.method.comment != "" {
+		t.Description = &c.method.comment
+	}
+	switch c.method.idempotency {
+	case noSideEffects:
+		readOnly := true
+		t.Annotations = &mcp.ToolAnnotations{ReadOnlyHint: &readOnly}
+	case idempotent:
+		idempotent := true
+		t.Annotations = &mcp.ToolAnnotations{IdempotentHint: &idempotent}
+	}
+	return mcp.ToolDefinition{
+		Metadata: t,
+		Process: func(ctx context.Context, params mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+			return c.call(ctx, cfg, params.Arguments)
+		},
+	}
+}
+
+func (c call) call(ctx context.Context, cfg Config, args map[string]any) (mcp.CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	msg, err := marshalMessage(c.method.input, c.method.inputType, args)
+	if err != nil {
+		return mcp.CallToolResult{}, &mcp.InvalidParamsError{Message: fmt.Sprintf("invalid arguments: %v", err)}
+	}
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(cfg.Target, "/")+c.path, bytes.NewReader(body))
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	for k, v := range cfg.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := max(time.Until(deadline).Milliseconds(), 1)
+		req.Header.Set("Grpc-Timeout", strconv.FormatInt(min(ms, 99999999), 10)+"m")
+	}
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return mcp.CallToolResult{}, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var messages []any
+	for {
+		msg, err := readMessage(resp.Body, cfg.MaxMessageBytes)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return mcp.CallToolResult{}, fmt.Errorf("reading response: %w", err)
+		}
+		v, err := unmarshalMessage(c.method.output, c.method.outputType, msg)
+		if err != nil {
+			return mcp.CallToolResult{}, fmt.Errorf("decoding response: %w", err)
+		}
+		messages = append(messages, v)
+	}
+
+	// trailers-only responses carry the status in their headers
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return statusResult(status, message)
+	}
+
+	if c.method.serverStreaming {
+		if messages == nil {
+			messages = []any{}
+		}
+		return mcp.NewResult().Structured(map[string]any{"messages": messages}).Build()
+	}
+	if len(messages) != 1 {
+		return mcp.CallToolResult{}, fmt.Errorf("expected one response message, got %d", len(messages))
+	}
+	if obj, ok := messages[0].(map[string]any); ok {
+		return mcp.NewResult().Structured(obj).Build()
+	}
+	b, err := json.Marshal(messages[0])
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	return mcp.NewResult().Text(string(b)).Build()
+}
+
+// readMessage reads a length prefixed message from r, returning io.EOF if
+// there are no more.
+func readMessage(r io.Reader, maxBytes int) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:1]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, prefix[1:]); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if uint64(length) > uint64(maxBytes) {
+		return nil, fmt.Errorf("message is larger than %d bytes", maxBytes)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return msg, nil
+}
+
+// statusResult returns the tool error reporting the gRPC status code and
+// its percent encoded message.
+func statusResult(code, message string) (mcp.CallToolResult, error) {
+	if code == "" {
+		return mcp.CallToolResult{}, errors.New("response has no grpc-status")
+	}
+	name := code
+	if n, err := strconv.Atoi(code); err == nil && n >= 0 && n < len(statusNames) {
+		name = statusNames[n]
+	}
+	if m, err := url.PathUnescape(message); err == nil {
+		message = m
+	}
+	text := name
+	if message != "" {
+		text += ": " + message
+	}
+	return mcp.NewResult().Text(text).Error(true).Build()
+}
@@ -0,0 +1,13 @@
+package grpcbridge_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGrpcbridge(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "grpcbridge Suite")
+}
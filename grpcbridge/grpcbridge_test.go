@@ -0,0 +1,260 @@
+package grpcbridge_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/grpcbridge"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Tools", func() {
+
+	type request struct {
+		*http.Request
+		body []byte
+	}
+
+	var (
+		descriptorSet []byte
+		requests      chan request
+		server        *httptest.Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		descriptorSet, err = os.ReadFile("testdata/echo.binpb")
+		Expect(err).ToNot(HaveOccurred())
+
+		// the server echoes the messages it is sent, once for Echo and twice
+		// for Repeat
+		requests = make(chan request, 1)
+		server = httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			requests <- request{Request: r, body: b}
+			w.Header().Set("Content-Type", "application/grpc")
+			if r.Header.Get("X-Fail") != "" {
+				w.Header().Set("Grpc-Status", "5")
+				w.Header().Set("Grpc-Message", "no message%3A named a")
+				return
+			}
+			w.Header().Set("Trailer", "Grpc-Status")
+			w.Write(b)
+			if r.URL.Path == "/echo.v1.EchoService/Repeat" {
+				w.Write(b)
+			}
+			w.Header().Set("Grpc-Status", "0")
+		}), &http2.Server{}))
+		DeferCleanup(server.Close)
+	})
+
+	find := func(tools []mcp.ToolDefinition, name string) mcp.ToolDefinition {
+		for _, t := range tools {
+			if t.Metadata.Name == name {
+				return t
+			}
+		}
+		Fail("no tool named " + name)
+		return mcp.ToolDefinition{}
+	}
+
+	call := func(t mcp.ToolDefinition, args map[string]any) mcp.CallToolResult {
+		result, err := t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: args}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	names := func(tools []mcp.ToolDefinition) []string {
+		names := make([]string, len(tools))
+		for i, t := range tools {
+			names[i] = t.Metadata.Name
+		}
+		return names
+	}
+
+	It("generates a tool for each method that does not take a stream", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(tools)).To(Equal([]string{"EchoService_Echo", "EchoService_Repeat"}))
+
+		metadata := make([]mcp.Tool, len(tools))
+		for i, t := range tools {
+			metadata[i] = t.Metadata
+		}
+		Expect(mcptest.ValidateToolSchemas(metadata...)).To(Succeed())
+	})
+
+	It("describes methods and request fields in the input schema", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+
+		echo := find(tools, "EchoService_Echo").Metadata
+		Expect(*echo.Description).To(Equal("Echo returns the message it is sent."))
+		Expect(*echo.Annotations.ReadOnlyHint).To(BeTrue())
+		Expect(echo.InputSchema.Properties).To(Equal(mcp.ToolInputSchemaProperties{
+			"name":   {"type": "string", "description": "The name to echo."},
+			"count":  {"type": []any{"string", "integer"}},
+			"values": {"type": "array", "items": map[string]any{"type": "integer"}},
+			"flag":   {"type": "boolean"},
+			"ratio":  {"type": "number"},
+			"data":   {"type": "string", "contentEncoding": "base64"},
+			"color":  {"type": "string", "enum": []any{"COLOR_UNSPECIFIED", "COLOR_RED"}},
+			"inner": {"type": "object", "properties": map[string]any{
+				"note": map[string]any{"type": "string"},
+			}},
+			"labels":   {"type": "object", "additionalProperties": map[string]any{"type": "integer"}},
+			"offset":   {"type": "integer"},
+			"sentAt":   {"type": "string", "format": "date-time"},
+			"nickname": {"type": "string"},
+			"replies":  {"type": "array", "items": map[string]any{}},
+		}))
+
+		Expect(find(tools, "EchoService_Repeat").Metadata.Annotations).To(BeNil())
+	})
+
+	It("calls the method with the encoded arguments and configured metadata", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{
+			Target: server.URL,
+			Header: http.Header{"Authorization": {"Bearer token"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "EchoService_Echo"), map[string]any{"name": "a", "count": "5"})
+		r := <-requests
+		Expect(r.ProtoMajor).To(Equal(2))
+		Expect(r.Method).To(Equal(http.MethodPost))
+		Expect(r.URL.Path).To(Equal("/echo.v1.EchoService/Echo"))
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/grpc"))
+		Expect(r.Header.Get("Te")).To(Equal("trailers"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+		Expect(r.body).To(Equal([]byte{0, 0, 0, 0, 5, 0x0a, 1, 'a', 0x10, 5}))
+
+		Expect(result.IsError).To(BeNil())
+		Expect(result.StructuredContent).To(Equal(map[string]any{"name": "a", "count": "5"}))
+	})
+
+	It("translates arguments and results in their proto3 JSON form", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "EchoService_Echo"), map[string]any{
+			"name":     "a",
+			"count":    float64(7),
+			"values":   []any{float64(1), float64(-2)},
+			"flag":     true,
+			"ratio":    0.5,
+			"data":     "aGk=",
+			"color":    "COLOR_RED",
+			"inner":    map[string]any{"note": "n"},
+			"labels":   map[string]any{"x": float64(1)},
+			"offset":   float64(-3),
+			"sent_at":  "2024-01-02T03:04:05.5Z",
+			"nickname": "nick",
+			"replies":  []any{map[string]any{"name": "r"}},
+		})
+		<-requests
+		Expect(result.StructuredContent).To(Equal(map[string]any{
+			"name":     "a",
+			"count":    "7",
+			"values":   []any{float64(1), float64(-2)},
+			"flag":     true,
+			"ratio":    0.5,
+			"data":     "aGk=",
+			"color":    "COLOR_RED",
+			"inner":    map[string]any{"note": "n"},
+			"labels":   map[string]any{"x": float64(1)},
+			"offset":   float64(-3),
+			"sentAt":   "2024-01-02T03:04:05.500Z",
+			"nickname": "nick",
+			"replies":  []any{map[string]any{"name": "r"}},
+		}))
+	})
+
+	It("returns the messages of methods that return a stream", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "EchoService_Repeat"), map[string]any{"name": "a"})
+		<-requests
+		Expect(result.StructuredContent).To(Equal(map[string]any{
+			"messages": []any{map[string]any{"name": "a"}, map[string]any{"name": "a"}},
+		}))
+	})
+
+	It("reports failed calls as tool errors with their status", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{
+			Target: server.URL,
+			Header: http.Header{"X-Fail": {"true"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "EchoService_Echo"), map[string]any{"name": "a"})
+		<-requests
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("NOT_FOUND: no message: named a")}))
+	})
+
+	It("rejects arguments that do not match the request message", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+		t := find(tools, "EchoService_Echo")
+
+		for args, message := range map[string]string{
+			"nmae":   `invalid arguments: unknown field "nmae"`,
+			"values": "invalid arguments: values: 0: 1.5 is not a 32-bit integer",
+			"color":  `invalid arguments: color: unknown value "PURPLE" of echo.v1.Color`,
+		} {
+			value := map[string]any{"nmae": "a", "values": []any{1.5}, "color": "PURPLE"}[args]
+			_, err := t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: map[string]any{args: value}}, nil)
+			Expect(err).To(MatchError(&mcp.InvalidParamsError{Message: message}))
+		}
+		Expect(requests).ToNot(Receive())
+	})
+
+	It("offers only the selected methods", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{
+			Target:  server.URL,
+			Methods: []string{"echo.v1.EchoService/Repeat"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(tools)).To(Equal([]string{"EchoService_Repeat"}))
+
+		_, err = grpcbridge.Tools(descriptorSet, grpcbridge.Config{
+			Target:  server.URL,
+			Methods: []string{"echo.v1.EchoService/Shout"},
+		})
+		Expect(err).To(MatchError("method echo.v1.EchoService/Shout is not in the descriptor set"))
+
+		_, err = grpcbridge.Tools(descriptorSet, grpcbridge.Config{
+			Target:  server.URL,
+			Methods: []string{"echo.v1.EchoService/Collect"},
+		})
+		Expect(err).To(MatchError("echo.v1.EchoService/Collect: client streaming methods are not supported"))
+	})
+
+	It("fails calls whose response is too large", func() {
+		tools, err := grpcbridge.Tools(descriptorSet, grpcbridge.Config{Target: server.URL, MaxMessageBytes: 2})
+		Expect(err).ToNot(HaveOccurred())
+
+		t := find(tools, "EchoService_Echo")
+		_, err = t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: map[string]any{"name": "abc"}}, nil)
+		Expect(err).To(MatchError("reading response: message is larger than 2 bytes"))
+	})
+
+	It("rejects descriptor sets it cannot convert", func() {
+		_, err := grpcbridge.Tools([]byte{0x0a, 0x05}, grpcbridge.Config{Target: server.URL})
+		Expect(err).To(MatchError("parsing descriptor set: truncated message"))
+
+		_, err = grpcbridge.Tools(descriptorSet, grpcbridge.Config{})
+		Expect(err).To(MatchError("no target configured"))
+	})
+})
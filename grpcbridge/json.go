@@ -0,0 +1,551 @@
+package grpcbridge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// marshalMessage encodes v, the proto3 JSON form of a message of type
+// typeName described by m, in the protobuf binary format.
+func marshalMessage(m *message, typeName string, v any) ([]byte, error) {
+	if t := wellKnownTypes[typeName]; t != nil {
+		return t.fromJSON(v)
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %s", describe(v))
+	}
+	return m.marshal(obj)
+}
+
+// unmarshalMessage decodes the message of type typeName described by m from
+// b, returning its proto3 JSON form.
+func unmarshalMessage(m *message, typeName string, b []byte) (any, error) {
+	if t := wellKnownTypes[typeName]; t != nil {
+		return t.toJSON(b)
+	}
+	return m.unmarshal(b)
+}
+
+func (m *message) marshal(obj map[string]any) ([]byte, error) {
+	for k := range obj {
+		if m.byName[k] == nil {
+			return nil, fmt.Errorf("unknown field %q", k)
+		}
+	}
+	// fields are written in the order they are declared, whether they are
+	// set by their JSON or their proto names
+	var b []byte
+	for _, f := range m.fields {
+		k := f.jsonName
+		v, ok := obj[k]
+		if other, set := obj[f.name]; set && f.name != k {
+			if ok {
+				return nil, fmt.Errorf("field %q is set more than once", f.name)
+			}
+			k, v = f.name, other
+		}
+		if v == nil {
+			continue
+		}
+		var err error
+		if b, err = f.append(b, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return b, nil
+}
+
+func (f *field) append(b []byte, v any) ([]byte, error) {
+	switch {
+	case f.message != nil && f.message.mapEntry:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %s", describe(v))
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		key, value := f.message.byNumber[1], f.message.byNumber[2]
+		for _, k := range keys {
+			entry, err := key.appendSingle(nil, mapKey(key.kind, k))
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k, err)
+			}
+			if obj[k] != nil {
+				if entry, err = value.appendSingle(entry, obj[k]); err != nil {
+					return nil, fmt.Errorf("%s: %w", k, err)
+				}
+			}
+			b = appendBytes(b, f.number, entry)
+		}
+		return b, nil
+	case f.repeated:
+		list, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an array, got %s", describe(v))
+		}
+		if !packable(f.kind) {
+			for i, e := range list {
+				var err error
+				if b, err = f.appendSingle(b, e); err != nil {
+					return nil, fmt.Errorf("%d: %w", i, err)
+				}
+			}
+			return b, nil
+		}
+		var packed []byte
+		for i, e := range list {
+			x, err := f.scalar(e)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			packed = appendScalar(packed, f.kind, x)
+		}
+		return appendBytes(b, f.number, packed), nil
+	}
+	return f.appendSingle(b, v)
+}
+
+// mapKey returns the map key k, which is always a string in JSON, as the
+// JSON value of a field of kind.
+func mapKey(kind int, k string) any {
+	if kind == typeBool {
+		return k == "true"
+	}
+	return k
+}
+
+func (f *field) appendSingle(b []byte, v any) ([]byte, error) {
+	switch f.kind {
+	case typeMessage:
+		data, err := marshalMessage(f.message, f.typeName, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, f.number, data), nil
+	case typeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %s", describe(v))
+		}
+		return appendBytes(b, f.number, []byte(s)), nil
+	case typeBytes:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a base64 string, got %s", describe(v))
+		}
+		data, err := decodeBase64(s)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, f.number, data), nil
+	}
+	x, err := f.scalar(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendScalar(appendTag(b, f.number, wireType(f.kind)), f.kind, x), nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// packable reports whether repeated fields of kind are packed.
+func packable(kind int) bool {
+	switch kind {
+	case typeString, typeBytes, typeMessage, typeGroup:
+		return false
+	}
+	return true
+}
+
+func wireType(kind int) int {
+	switch kind {
+	case typeDouble, typeFixed64, typeSfixed64:
+		return wireFixed64
+	case typeFloat, typeFixed32, typeSfixed32:
+		return wireFixed32
+	case typeString, typeBytes, typeMessage:
+		return wireBytes
+	}
+	return wireVarint
+}
+
+func appendScalar(b []byte, kind int, x uint64) []byte {
+	switch wireType(kind) {
+	case wireFixed64:
+		return appendFixed(b, x, 8)
+	case wireFixed32:
+		return appendFixed(b, x, 4)
+	}
+	return appendVarint(b, x)
+}
+
+// scalar returns the JSON value v of a numeric, boolean or enum field as the
+// bits that are encoded.
+func (f *field) scalar(v any) (uint64, error) {
+	switch f.kind {
+	case typeDouble:
+		x, err := toFloat(v)
+		return math.Float64bits(x), err
+	case typeFloat:
+		x, err := toFloat(v)
+		return uint64(math.Float32bits(float32(x))), err
+	case typeInt32, typeSfixed32:
+		x, err := toInt(v, 32)
+		if f.kind == typeSfixed32 {
+			return uint64(uint32(x)), err
+		}
+		return uint64(x), err
+	case typeInt64, typeSfixed64:
+		x, err := toInt(v, 64)
+		return uint64(x), err
+	case typeSint32:
+		x, err := toInt(v, 32)
+		return encodeZigZag(x), err
+	case typeSint64:
+		x, err := toInt(v, 64)
+		return encodeZigZag(x), err
+	case typeUint32, typeFixed32:
+		return toUint(v, 32)
+	case typeUint64, typeFixed64:
+		return toUint(v, 64)
+	case typeBool:
+		x, ok := v.(bool)
+		if !ok {
+			return 0, fmt.Errorf("expected a boolean, got %s", describe(v))
+		}
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	case typeEnum:
+		if s, ok := v.(string); ok {
+			x, ok := f.enum.byName[s]
+			if !ok {
+				return 0, fmt.Errorf("unknown value %q of %s", s, f.enum.fullName)
+			}
+			return uint64(x), nil
+		}
+		x, err := toInt(v, 32)
+		return uint64(x), err
+	}
+	return 0, fmt.Errorf("unsupported field type %d", f.kind)
+}
+
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		switch v {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %s", describe(v))
+}
+
+func toInt(v any, bits int) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < -math.Exp2(float64(bits-1)) || v >= math.Exp2(float64(bits-1)) {
+			return 0, fmt.Errorf("%v is not a %d-bit integer", v, bits)
+		}
+		return int64(v), nil
+	case json.Number:
+		return strconv.ParseInt(v.String(), 10, bits)
+	case string:
+		return strconv.ParseInt(v, 10, bits)
+	}
+	return 0, fmt.Errorf("expected an integer, got %s", describe(v))
+}
+
+func toUint(v any, bits int) (uint64, error) {
+	switch v := v.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v >= math.Exp2(float64(bits)) {
+			return 0, fmt.Errorf("%v is not an unsigned %d-bit integer", v, bits)
+		}
+		return uint64(v), nil
+	case json.Number:
+		return strconv.ParseUint(v.String(), 10, bits)
+	case string:
+		return strconv.ParseUint(v, 10, bits)
+	}
+	return 0, fmt.Errorf("expected an integer, got %s", describe(v))
+}
+
+// describe names the JSON type of v for errors.
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (m *message) unmarshal(b []byte) (map[string]any, error) {
+	out := map[string]any{}
+	err := parseFields(b, func(w wireField) error {
+		f := m.byNumber[w.number]
+		if f == nil {
+			return nil
+		}
+		switch {
+		case f.message != nil && f.message.mapEntry:
+			entry, err := f.message.unmarshal(w.bytes)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.jsonName, err)
+			}
+			key, value := f.message.byNumber[1], f.message.byNumber[2]
+			k, ok := entry[key.jsonName]
+			if !ok {
+				k = key.zero()
+			}
+			v, ok := entry[value.jsonName]
+			if !ok {
+				v = value.zero()
+			}
+			obj, _ := out[f.jsonName].(map[string]any)
+			if obj == nil {
+				obj = map[string]any{}
+				out[f.jsonName] = obj
+			}
+			obj[fmt.Sprint(k)] = v
+		case f.repeated:
+			list, _ := out[f.jsonName].([]any)
+			if w.typ == wireBytes && packable(f.kind) {
+				values, err := f.unpack(w.bytes)
+				if err != nil {
+					return fmt.Errorf("%s: %w", f.jsonName, err)
+				}
+				out[f.jsonName] = append(list, values...)
+				return nil
+			}
+			v, err := f.value(w)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.jsonName, err)
+			}
+			out[f.jsonName] = append(list, v)
+		default:
+			v, err := f.value(w)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.jsonName, err)
+			}
+			out[f.jsonName] = v
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (f *field) unpack(b []byte) ([]any, error) {
+	var values []any
+	for len(b) > 0 {
+		w := wireField{number: f.number, typ: wireType(f.kind)}
+		var n int
+		switch w.typ {
+		case wireFixed64:
+			w.value, n = consumeFixed(b, 8)
+		case wireFixed32:
+			w.value, n = consumeFixed(b, 4)
+		default:
+			w.value, n = consumeVarint(b)
+		}
+		if n < 0 {
+			return nil, errTruncated
+		}
+		b = b[n:]
+		v, err := f.value(w)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// value returns the JSON value of the field read as w.
+func (f *field) value(w wireField) (any, error) {
+	if w.typ != wireType(f.kind) {
+		return nil, fmt.Errorf("unexpected wire type %d", w.typ)
+	}
+	switch f.kind {
+	case typeDouble:
+		return jsonFloat(math.Float64frombits(w.value), 64), nil
+	case typeFloat:
+		return jsonFloat(float64(math.Float32frombits(uint32(w.value))), 32), nil
+	case typeInt32:
+		return int64(int32(w.value)), nil
+	case typeSint32:
+		return int64(int32(decodeZigZag(w.value))), nil
+	case typeSfixed32:
+		return int64(int32(uint32(w.value))), nil
+	case typeUint32, typeFixed32:
+		return int64(uint32(w.value)), nil
+	case typeInt64, typeSfixed64:
+		return strconv.FormatInt(int64(w.value), 10), nil
+	case typeSint64:
+		return strconv.FormatInt(decodeZigZag(w.value), 10), nil
+	case typeUint64, typeFixed64:
+		return strconv.FormatUint(w.value, 10), nil
+	case typeBool:
+		return w.value != 0, nil
+	case typeEnum:
+		if name, ok := f.enum.byNumber[int32(w.value)]; ok {
+			return name, nil
+		}
+		return int64(int32(w.value)), nil
+	case typeString:
+		return string(w.bytes), nil
+	case typeBytes:
+		return base64.StdEncoding.EncodeToString(w.bytes), nil
+	case typeMessage:
+		return unmarshalMessage(f.message, f.typeName, w.bytes)
+	}
+	return nil, fmt.Errorf("unsupported field type %d", f.kind)
+}
+
+// zero returns the JSON value of a field that is not present.
+func (f *field) zero() any {
+	switch f.kind {
+	case typeString, typeBytes:
+		return ""
+	case typeBool:
+		return false
+	case typeInt64, typeUint64, typeFixed64, typeSfixed64, typeSint64:
+		return "0"
+	case typeEnum:
+		if name, ok := f.enum.byNumber[0]; ok {
+			return name
+		}
+		return int64(0)
+	case typeMessage:
+		if t := wellKnownTypes[f.typeName]; t != nil {
+			v, _ := t.toJSON(nil)
+			return v
+		}
+		return map[string]any{}
+	}
+	return int64(0)
+}
+
+// jsonFloat returns x as a JSON value, with the special values proto3 JSON
+// represents as strings. Floats are rounded to the shortest decimal that
+// represents them.
+func jsonFloat(x float64, bits int) any {
+	switch {
+	case math.IsNaN(x):
+		return "NaN"
+	case math.IsInf(x, 1):
+		return "Infinity"
+	case math.IsInf(x, -1):
+		return "-Infinity"
+	}
+	x, _ = strconv.ParseFloat(strconv.FormatFloat(x, 'g', -1, bits), 64)
+	return x
+}
+
+// messageSchema returns the JSON schema of the proto3 JSON form of messages
+// of type typeName described by m. Recursive messages are cut off, leaving
+// an unconstrained schema.
+func messageSchema(m *message, typeName string, seen []string) map[string]any {
+	if t := wellKnownTypes[typeName]; t != nil {
+		return t.schema()
+	}
+	for _, s := range seen {
+		if s == typeName {
+			return map[string]any{}
+		}
+	}
+	seen = append(seen, typeName)
+	return map[string]any{"type": "object", "properties": m.properties(seen)}
+}
+
+func (m *message) properties(seen []string) map[string]any {
+	properties := make(map[string]any, len(m.fields))
+	for _, f := range m.fields {
+		properties[f.jsonName] = f.schema(seen)
+	}
+	return properties
+}
+
+func (f *field) schema(seen []string) map[string]any {
+	var s map[string]any
+	switch {
+	case f.message != nil && f.message.mapEntry:
+		s = map[string]any{"type": "object", "additionalProperties": f.message.byNumber[2].valueSchema(seen)}
+	case f.repeated:
+		s = map[string]any{"type": "array", "items": f.valueSchema(seen)}
+	default:
+		s = f.valueSchema(seen)
+	}
+	if f.comment != "" {
+		s["description"] = f.comment
+	}
+	return s
+}
+
+// valueSchema returns the schema of a single value of the field.
+func (f *field) valueSchema(seen []string) map[string]any {
+	switch f.kind {
+	case typeMessage:
+		return messageSchema(f.message, f.typeName, seen)
+	case typeEnum:
+		names := make([]any, len(f.enum.names))
+		for i, n := range f.enum.names {
+			names[i] = n
+		}
+		return map[string]any{"type": "string", "enum": names}
+	}
+	return scalarSchema(f.kind)
+}
+
+func scalarSchema(kind int) map[string]any {
+	switch kind {
+	case typeDouble, typeFloat:
+		return map[string]any{"type": "number"}
+	case typeInt32, typeSint32, typeSfixed32, typeUint32, typeFixed32:
+		return map[string]any{"type": "integer"}
+	case typeInt64, typeSint64, typeSfixed64, typeUint64, typeFixed64:
+		// 64-bit integers are strings in proto3 JSON, as they may not fit a
+		// double
+		return map[string]any{"type": []any{"string", "integer"}}
+	case typeBool:
+		return map[string]any{"type": "boolean"}
+	case typeBytes:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	}
+	return map[string]any{"type": "string"}
+}
@@ -0,0 +1,331 @@
+package grpcbridge
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wellKnownType is a google.protobuf type with a proto3 JSON form other than
+// an object of its fields. These types are handled without their
+// descriptors.
+type wellKnownType struct {
+	schema   func() map[string]any
+	toJSON   func([]byte) (any, error)
+	fromJSON func(any) ([]byte, error)
+}
+
+var wellKnownTypes map[string]*wellKnownType
+
+func init() {
+	wellKnownTypes = map[string]*wellKnownType{
+		"google.protobuf.Timestamp": {
+			schema:   func() map[string]any { return map[string]any{"type": "string", "format": "date-time"} },
+			toJSON:   timestampToJSON,
+			fromJSON: timestampFromJSON,
+		},
+		"google.protobuf.Duration": {
+			schema: func() map[string]any {
+				return map[string]any{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?s$`}
+			},
+			toJSON:   durationToJSON,
+			fromJSON: durationFromJSON,
+		},
+		"google.protobuf.Empty": {
+			schema: func() map[string]any { return map[string]any{"type": "object"} },
+			toJSON: func([]byte) (any, error) { return map[string]any{}, nil },
+			fromJSON: func(v any) ([]byte, error) {
+				if _, ok := v.(map[string]any); !ok {
+					return nil, fmt.Errorf("expected an object, got %s", describe(v))
+				}
+				return nil, nil
+			},
+		},
+		"google.protobuf.Struct": {
+			schema:   func() map[string]any { return map[string]any{"type": "object"} },
+			toJSON:   structToJSON,
+			fromJSON: structFromJSON,
+		},
+		"google.protobuf.ListValue": {
+			schema:   func() map[string]any { return map[string]any{"type": "array"} },
+			toJSON:   listToJSON,
+			fromJSON: listFromJSON,
+		},
+		"google.protobuf.Value": {
+			schema:   func() map[string]any { return map[string]any{} },
+			toJSON:   valueToJSON,
+			fromJSON: valueFromJSON,
+		},
+	}
+	for name, kind := range map[string]int{
+		"DoubleValue": typeDouble,
+		"FloatValue":  typeFloat,
+		"Int64Value":  typeInt64,
+		"UInt64Value": typeUint64,
+		"Int32Value":  typeInt32,
+		"UInt32Value": typeUint32,
+		"BoolValue":   typeBool,
+		"StringValue": typeString,
+		"BytesValue":  typeBytes,
+	} {
+		wellKnownTypes["google.protobuf."+name] = wrapper(kind)
+	}
+}
+
+// wrapper returns the wrapper type of a scalar of kind, whose JSON form is
+// that of the scalar.
+func wrapper(kind int) *wellKnownType {
+	value := &field{name: "value", jsonName: "value", number: 1, kind: kind}
+	m := &message{
+		fields:   []*field{value},
+		byNumber: map[int32]*field{1: value},
+		byName:   map[string]*field{"value": value},
+	}
+	return &wellKnownType{
+		schema: func() map[string]any { return scalarSchema(kind) },
+		toJSON: func(b []byte) (any, error) {
+			obj, err := m.unmarshal(b)
+			if v, ok := obj["value"]; ok || err != nil {
+				return v, err
+			}
+			return value.zero(), nil
+		},
+		fromJSON: func(v any) ([]byte, error) {
+			return m.marshal(map[string]any{"value": v})
+		},
+	}
+}
+
+// secondsAndNanos decodes the seconds and nanos fields shared by Timestamp
+// and Duration.
+func secondsAndNanos(b []byte) (int64, int32, error) {
+	var seconds int64
+	var nanos int32
+	err := parseFields(b, func(f wireField) error {
+		switch {
+		case f.number == 1 && f.typ == wireVarint:
+			seconds = int64(f.value)
+		case f.number == 2 && f.typ == wireVarint:
+			nanos = int32(f.value)
+		}
+		return nil
+	})
+	return seconds, nanos, err
+}
+
+func appendSecondsAndNanos(seconds int64, nanos int32) []byte {
+	var b []byte
+	if seconds != 0 {
+		b = appendVarint(appendTag(b, 1, wireVarint), uint64(seconds))
+	}
+	if nanos != 0 {
+		b = appendVarint(appendTag(b, 2, wireVarint), uint64(int64(nanos)))
+	}
+	return b
+}
+
+// formatNanos formats nanos as a fraction of 0, 3, 6 or 9 digits.
+func formatNanos(nanos int32) string {
+	switch {
+	case nanos == 0:
+		return ""
+	case nanos%1e6 == 0:
+		return fmt.Sprintf(".%03d", nanos/1e6)
+	case nanos%1e3 == 0:
+		return fmt.Sprintf(".%06d", nanos/1e3)
+	}
+	return fmt.Sprintf(".%09d", nanos)
+}
+
+func timestampToJSON(b []byte) (any, error) {
+	seconds, nanos, err := secondsAndNanos(b)
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02T15:04:05") + formatNanos(nanos) + "Z", nil
+}
+
+func timestampFromJSON(v any) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected an RFC 3339 timestamp, got %s", describe(v))
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return appendSecondsAndNanos(t.Unix(), int32(t.Nanosecond())), nil
+}
+
+func durationToJSON(b []byte) (any, error) {
+	seconds, nanos, err := secondsAndNanos(b)
+	if err != nil {
+		return nil, err
+	}
+	sign := ""
+	if seconds < 0 || nanos < 0 {
+		sign = "-"
+	}
+	if seconds < 0 {
+		seconds = -seconds
+	}
+	if nanos < 0 {
+		nanos = -nanos
+	}
+	return sign + strconv.FormatInt(seconds, 10) + formatNanos(nanos) + "s", nil
+}
+
+func durationFromJSON(v any) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok || !strings.HasSuffix(s, "s") {
+		return nil, fmt.Errorf("expected a duration in seconds such as \"1.5s\", got %v", v)
+	}
+	s = strings.TrimSuffix(s, "s")
+	negative := strings.HasPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q", v)
+	}
+	var nanos int64
+	if fraction != "" {
+		if len(fraction) > 9 {
+			return nil, fmt.Errorf("invalid duration %q", v)
+		}
+		if nanos, err = strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 32); err != nil {
+			return nil, fmt.Errorf("invalid duration %q", v)
+		}
+	}
+	if negative {
+		seconds, nanos = -seconds, -nanos
+	}
+	return appendSecondsAndNanos(seconds, int32(nanos)), nil
+}
+
+func structToJSON(b []byte) (any, error) {
+	out := map[string]any{}
+	err := parseFields(b, func(f wireField) error {
+		if f.number != 1 || f.typ != wireBytes {
+			return nil
+		}
+		var key string
+		var value any
+		err := parseFields(f.bytes, func(f wireField) error {
+			switch {
+			case f.number == 1 && f.typ == wireBytes:
+				key = string(f.bytes)
+			case f.number == 2 && f.typ == wireBytes:
+				var err error
+				value, err = valueToJSON(f.bytes)
+				return err
+			}
+			return nil
+		})
+		out[key] = value
+		return err
+	})
+	return out, err
+}
+
+func structFromJSON(v any) ([]byte, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %s", describe(v))
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b []byte
+	for _, k := range keys {
+		value, err := valueFromJSON(obj[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		entry := appendBytes(appendBytes(nil, 1, []byte(k)), 2, value)
+		b = appendBytes(b, 1, entry)
+	}
+	return b, nil
+}
+
+func listToJSON(b []byte) (any, error) {
+	out := []any{}
+	err := parseFields(b, func(f wireField) error {
+		if f.number != 1 || f.typ != wireBytes {
+			return nil
+		}
+		v, err := valueToJSON(f.bytes)
+		out = append(out, v)
+		return err
+	})
+	return out, err
+}
+
+func listFromJSON(v any) ([]byte, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array, got %s", describe(v))
+	}
+	var b []byte
+	for i, e := range list {
+		value, err := valueFromJSON(e)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+		b = appendBytes(b, 1, value)
+	}
+	return b, nil
+}
+
+func valueToJSON(b []byte) (any, error) {
+	var v any
+	err := parseFields(b, func(f wireField) error {
+		var err error
+		switch {
+		case f.number == 1 && f.typ == wireVarint:
+			v = nil
+		case f.number == 2 && f.typ == wireFixed64:
+			v = jsonFloat(math.Float64frombits(f.value), 64)
+		case f.number == 3 && f.typ == wireBytes:
+			v = string(f.bytes)
+		case f.number == 4 && f.typ == wireVarint:
+			v = f.value != 0
+		case f.number == 5 && f.typ == wireBytes:
+			v, err = structToJSON(f.bytes)
+		case f.number == 6 && f.typ == wireBytes:
+			v, err = listToJSON(f.bytes)
+		}
+		return err
+	})
+	return v, err
+}
+
+func valueFromJSON(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return appendVarint(appendTag(nil, 1, wireVarint), 0), nil
+	case bool:
+		x := uint64(0)
+		if v {
+			x = 1
+		}
+		return appendVarint(appendTag(nil, 4, wireVarint), x), nil
+	case string:
+		return appendBytes(nil, 3, []byte(v)), nil
+	case map[string]any:
+		b, err := structFromJSON(v)
+		return appendBytes(nil, 5, b), err
+	case []any:
+		b, err := listFromJSON(v)
+		return appendBytes(nil, 6, b), err
+	}
+	x, err := toFloat(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendFixed(appendTag(nil, 2, wireFixed64), math.Float64bits(x), 8), nil
+}
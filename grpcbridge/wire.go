@@ -0,0 +1,179 @@
+package grpcbridge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Wire types of the protobuf binary encoding.
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// wireField is a field read from an encoded message. Varint and fixed values
+// are held in value, and length delimited values in bytes.
+type wireField struct {
+	number int32
+	typ    int
+	value  uint64
+	bytes  []byte
+}
+
+// parseFields calls f with each field of the encoded message b, in order.
+// Groups are skipped.
+func parseFields(b []byte, f func(wireField) error) error {
+	for len(b) > 0 {
+		tag, n := consumeVarint(b)
+		if n < 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		field := wireField{number: int32(tag >> 3), typ: int(tag & 7)}
+		if field.number <= 0 {
+			return fmt.Errorf("invalid field number %d", field.number)
+		}
+		switch field.typ {
+		case wireVarint:
+			field.value, n = consumeVarint(b)
+		case wireFixed64:
+			field.value, n = consumeFixed(b, 8)
+		case wireFixed32:
+			field.value, n = consumeFixed(b, 4)
+		case wireBytes:
+			var length uint64
+			length, n = consumeVarint(b)
+			if n >= 0 {
+				if length > uint64(len(b)-n) {
+					return errTruncated
+				}
+				field.bytes = b[n : n+int(length)]
+				n += int(length)
+			}
+		case wireStartGroup:
+			n = skipGroup(b, field.number)
+		default:
+			return fmt.Errorf("field %d has invalid wire type %d", field.number, field.typ)
+		}
+		if n < 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		if field.typ == wireStartGroup {
+			continue
+		}
+		if err := f(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipGroup returns the length of the group numbered number at the start of
+// b, including its end tag, or -1 if it is malformed.
+func skipGroup(b []byte, number int32) int {
+	start := len(b)
+	for len(b) > 0 {
+		tag, n := consumeVarint(b)
+		if n < 0 {
+			return -1
+		}
+		b = b[n:]
+		switch int(tag & 7) {
+		case wireVarint:
+			_, n = consumeVarint(b)
+		case wireFixed64:
+			_, n = consumeFixed(b, 8)
+		case wireFixed32:
+			_, n = consumeFixed(b, 4)
+		case wireBytes:
+			var length uint64
+			length, n = consumeVarint(b)
+			if n >= 0 {
+				if length > uint64(len(b)-n) {
+					return -1
+				}
+				n += int(length)
+			}
+		case wireStartGroup:
+			n = skipGroup(b, int32(tag>>3))
+		case wireEndGroup:
+			if int32(tag>>3) != number {
+				return -1
+			}
+			return start - len(b)
+		default:
+			return -1
+		}
+		if n < 0 {
+			return -1
+		}
+		b = b[n:]
+	}
+	return -1
+}
+
+// consumeVarint returns the varint at the start of b and its length, or a
+// negative length if it is malformed.
+func consumeVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, -1
+}
+
+// consumeFixed returns the little endian value of size bytes at the start of
+// b and its length, or a negative length if b is too short.
+func consumeFixed(b []byte, size int) (uint64, int) {
+	if len(b) < size {
+		return 0, -1
+	}
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, size
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendFixed(b []byte, v uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func appendTag(b []byte, number int32, typ int) []byte {
+	return appendVarint(b, uint64(number)<<3|uint64(typ))
+}
+
+func appendBytes(b []byte, number int32, v []byte) []byte {
+	b = appendTag(b, number, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func encodeZigZag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func decodeZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,632 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// SessionIDHeader is the HTTP header carrying the session ID of the
+// streamable HTTP transport.
+const SessionIDHeader = "Mcp-Session-Id"
+
+const defaultMaxMessageBytes = 4 << 20
+
+// HTTPTransport serves a Server over HTTP. It speaks both the streamable
+// HTTP transport of protocol version 2025-03-26 and the HTTP+SSE transport
+// of version 2024-11-05 on a single endpoint, telling clients apart by the
+// requests they make, so that servers need not choose between them while
+// clients migrate:
+//
+//   - a GET without a session ID opens an HTTP+SSE session, whose messages
+//     are posted to the endpoint with a sessionId query parameter
+//   - a POST without that parameter, a GET with a session ID and a DELETE
+//     belong to the streamable HTTP transport
+//
+// Each session is served with the context values of the request that
+// started it, so the Principal established by a ResourceServer is visible
+// to handlers. Later requests in the session must be made by the same
+// principal.
+type HTTPTransport struct {
+	server *Server
+	// MaxMessageBytes limits the size of request bodies. It defaults to
+	// 4 MiB.
+	MaxMessageBytes int64
+	// IdleTimeout ends sessions of the streamable HTTP transport that no
+	// request has been made in for the duration, for clients that go away
+	// without deleting their session. Sessions never expire if it is zero.
+	IdleTimeout time.Duration
+	// AllowedOrigins lists the origins, such as "https://app.example.com",
+	// that browsers may make requests from, guarding servers reachable on
+	// localhost against DNS rebinding. Requests with any other Origin header
+	// are forbidden; "*" allows every origin. Requests without an Origin
+	// header, which clients other than browsers make, are always allowed.
+	AllowedOrigins []string
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+// NewHTTPTransport returns a transport serving s.
+func NewHTTPTransport(s *Server) *HTTPTransport {
+	return &HTTPTransport{server: s, sessions: map[string]*httpSession{}}
+}
+
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !t.allowedOrigin(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.Header.Get(SessionIDHeader) == "":
+		t.serveLegacyStream(w, r)
+	case r.Method == http.MethodGet:
+		t.serveStream(w, r)
+	case r.Method == http.MethodPost && r.URL.Query().Has("sessionId"):
+		t.postLegacy(w, r)
+	case r.Method == http.MethodPost:
+		t.post(w, r)
+	case r.Method == http.MethodDelete:
+		t.delete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *HTTPTransport) allowedOrigin(origin string) bool {
+	for _, o := range t.AllowedOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close ends every session.
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	sessions := make([]*httpSession, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		sessions = append(sessions, s)
+	}
+	t.mu.Unlock()
+	for _, s := range sessions {
+		s.Close()
+	}
+	return nil
+}
+
+// start begins serving a new session with the values of r's context.
+func (t *HTTPTransport) start(r *http.Request, legacy bool) *httpSession {
+	s := &httpSession{
+		id:      newID(),
+		legacy:  legacy,
+		in:      make(chan json.RawMessage),
+		done:    make(chan struct{}),
+		waiting: map[string]*httpStream{},
+	}
+	if !legacy {
+		s.idleTimeout = t.IdleTimeout
+	}
+	s.principal, _ = PrincipalFromContext(r.Context())
+	s.acquire()
+	t.mu.Lock()
+	t.sessions[s.id] = s
+	t.mu.Unlock()
+
+	// handlers still running when the session ends are cancelled
+	ctx, cancel := context.WithCancel(ContextWithSessionID(context.WithoutCancel(r.Context()), s.id))
+	go func() {
+		<-s.done
+		cancel()
+	}()
+	go func() {
+		t.server.ServeStream(ctx, s)
+		s.Close()
+		t.mu.Lock()
+		delete(t.sessions, s.id)
+		t.mu.Unlock()
+	}()
+	return s
+}
+
+// session returns the session with id, replying with an error if there is
+// none or it does not belong to the caller. The session is held until
+// released, so that it does not expire while the request is served.
+func (t *HTTPTransport) session(w http.ResponseWriter, r *http.Request, id string, legacy bool) (*httpSession, bool) {
+	t.mu.Lock()
+	s, ok := t.sessions[id]
+	t.mu.Unlock()
+	if !ok || s.legacy != legacy {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return nil, false
+	}
+	p, _ := PrincipalFromContext(r.Context())
+	if subject(p) != subject(s.principal) {
+		http.Error(w, "session belongs to another principal", http.StatusForbidden)
+		return nil, false
+	}
+	s.acquire()
+	return s, true
+}
+
+func subject(p *Principal) string {
+	if p == nil {
+		return ""
+	}
+	return p.Subject
+}
+
+func (t *HTTPTransport) maxMessageBytes() int64 {
+	if t.MaxMessageBytes <= 0 {
+		return defaultMaxMessageBytes
+	}
+	return t.MaxMessageBytes
+}
+
+// httpMessage is a JSON-RPC message posted by a client.
+type httpMessage struct {
+	raw    json.RawMessage
+	method string
+	id     *jsonrpc2.ID
+}
+
+func (m httpMessage) isRequest() bool {
+	return m.method != "" && m.id != nil
+}
+
+// readMessages reads the message or batch of messages in the body of r.
+func (t *HTTPTransport) readMessages(w http.ResponseWriter, r *http.Request) ([]httpMessage, bool, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, t.maxMessageBytes()))
+	if err != nil {
+		return nil, false, err
+	}
+	body = bytes.TrimSpace(body)
+	raws := []json.RawMessage{body}
+	batch := len(body) > 0 && body[0] == '['
+	if batch {
+		if err := json.Unmarshal(body, &raws); err != nil || len(raws) == 0 {
+			return nil, true, errors.New("invalid batch")
+		}
+	}
+	messages := make([]httpMessage, len(raws))
+	for i, raw := range raws {
+		var m struct {
+			Method string          `json:"method"`
+			ID     json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &m); err != nil || !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			return nil, batch, errors.New("invalid message")
+		}
+		messages[i] = httpMessage{raw: raw, method: m.Method}
+		if len(m.ID) > 0 && string(m.ID) != "null" {
+			var id jsonrpc2.ID
+			if err := json.Unmarshal(m.ID, &id); err != nil {
+				return nil, batch, errors.New("invalid message ID")
+			}
+			messages[i].id = &id
+		}
+	}
+	return messages, batch, nil
+}
+
+func replyParseError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `{"jsonrpc":"2.0","id":null,"error":{"code":%d,"message":"Parse error"}}`, jsonrpc2.CodeParseError)
+}
+
+// post serves messages posted to the streamable HTTP transport. Responses
+// are streamed as server-sent events, along with any other messages sent
+// while the requests are handled, if the client accepts them, and are
+// otherwise returned together as JSON.
+func (t *HTTPTransport) post(w http.ResponseWriter, r *http.Request) {
+	messages, batch, err := t.readMessages(w, r)
+	if err != nil {
+		replyParseError(w)
+		return
+	}
+
+	var s *httpSession
+	if id := r.Header.Get(SessionIDHeader); id != "" {
+		var ok bool
+		if s, ok = t.session(w, r, id, false); !ok {
+			return
+		}
+		defer s.release()
+	} else {
+		initialize := false
+		for _, m := range messages {
+			initialize = initialize || (m.isRequest() && m.method == "initialize")
+		}
+		if !initialize {
+			http.Error(w, "missing "+SessionIDHeader+" header", http.StatusBadRequest)
+			return
+		}
+		s = t.start(r, false)
+		defer s.release()
+		w.Header().Set(SessionIDHeader, s.id)
+	}
+
+	events := acceptsEvents(r)
+	stream := newHTTPStream()
+	defer stream.close()
+	pending := 0
+	for _, m := range messages {
+		if m.isRequest() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		s.register(stream, messages, events)
+		defer s.unregister(stream)
+	}
+
+	// messages are sent while replies are collected, as the server handles
+	// each message before reading the next
+	sent := make(chan bool, 1)
+	go func() {
+		for _, m := range messages {
+			if !s.send(r.Context(), m.raw) {
+				sent <- false
+				return
+			}
+		}
+		sent <- true
+	}()
+	if pending == 0 {
+		if !<-sent {
+			http.Error(w, "session ended", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if events {
+		startEvents(w)
+	}
+	var responses []json.RawMessage
+	for pending > 0 {
+		select {
+		case msg := <-stream.messages:
+			if isResponse(msg) {
+				pending--
+			}
+			if events {
+				writeEvent(w, "message", msg)
+			} else {
+				responses = append(responses, msg)
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			if !events {
+				http.Error(w, "session ended", http.StatusNotFound)
+			}
+			return
+		}
+	}
+	if events {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if batch {
+		json.NewEncoder(w).Encode(responses)
+	} else {
+		w.Write(append(responses[0], '\n'))
+	}
+}
+
+// serveStream serves the stream a client of the streamable HTTP transport
+// opens for messages not sent in reply to one of its posts.
+func (t *HTTPTransport) serveStream(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEvents(r) {
+		http.Error(w, "only text/event-stream is supported", http.StatusNotAcceptable)
+		return
+	}
+	s, ok := t.session(w, r, r.Header.Get(SessionIDHeader), false)
+	if !ok {
+		return
+	}
+	defer s.release()
+	stream := newHTTPStream()
+	defer stream.close()
+	if !s.setStandalone(stream) {
+		http.Error(w, "a stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer s.setStandalone(nil)
+	startEvents(w)
+	t.serveEvents(w, r, s, stream)
+}
+
+func (t *HTTPTransport) delete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(SessionIDHeader)
+	if id == "" {
+		http.Error(w, "missing "+SessionIDHeader+" header", http.StatusBadRequest)
+		return
+	}
+	s, ok := t.session(w, r, id, false)
+	if !ok {
+		return
+	}
+	defer s.release()
+	s.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveLegacyStream starts an HTTP+SSE session. The first event names the
+// endpoint the client posts its messages to; every message from the server
+// follows on the stream, which ends the session when closed.
+func (t *HTTPTransport) serveLegacyStream(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEvents(r) {
+		http.Error(w, "only text/event-stream is supported", http.StatusNotAcceptable)
+		return
+	}
+	s := t.start(r, true)
+	defer s.release()
+	defer s.Close()
+	stream := newHTTPStream()
+	defer stream.close()
+	s.setStandalone(stream)
+
+	startEvents(w)
+	writeEvent(w, "endpoint", []byte(r.URL.Path+"?sessionId="+s.id))
+	t.serveEvents(w, r, s, stream)
+}
+
+func (t *HTTPTransport) postLegacy(w http.ResponseWriter, r *http.Request) {
+	s, ok := t.session(w, r, r.URL.Query().Get("sessionId"), true)
+	if !ok {
+		return
+	}
+	defer s.release()
+	messages, batch, err := t.readMessages(w, r)
+	if err != nil || batch {
+		replyParseError(w)
+		return
+	}
+	if !s.send(r.Context(), messages[0].raw) {
+		http.Error(w, "session ended", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveEvents writes the messages routed to stream as server-sent events
+// until the client goes away or the session ends.
+func (t *HTTPTransport) serveEvents(w http.ResponseWriter, r *http.Request, s *httpSession, stream *httpStream) {
+	for {
+		select {
+		case msg := <-stream.messages:
+			writeEvent(w, "message", msg)
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func acceptsEvents(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		if strings.Contains(v, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+func startEvents(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+}
+
+func writeEvent(w io.Writer, event string, data []byte) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flush(w)
+}
+
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func isResponse(msg json.RawMessage) bool {
+	var m struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(msg, &m) == nil && m.Method == ""
+}
+
+// httpStream receives the messages the server sends on a session that are
+// routed to one HTTP response.
+type httpStream struct {
+	messages chan json.RawMessage
+	done     chan struct{}
+}
+
+func newHTTPStream() *httpStream {
+	return &httpStream{messages: make(chan json.RawMessage), done: make(chan struct{})}
+}
+
+func (s *httpStream) close() {
+	close(s.done)
+}
+
+// httpSession is the stream a session is served on. Messages posted by the
+// client are read from in; messages from the server are routed to the
+// stream of the post holding the request they reply to, and otherwise to
+// the most recent post streaming events or to the standalone stream.
+type httpSession struct {
+	id        string
+	principal *Principal
+	legacy    bool
+	in        chan json.RawMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu          sync.Mutex
+	waiting     map[string]*httpStream
+	streams     []*httpStream
+	standalone  *httpStream
+	idleTimeout time.Duration
+	active      int
+	expiry      *time.Timer
+}
+
+// acquire marks the session as in use by a request until it is released.
+func (s *httpSession) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active++
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+}
+
+// release ends a use of the session, which expires after its idle timeout
+// once no request is using it.
+func (s *httpSession) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.active > 0 || s.idleTimeout <= 0 {
+		return
+	}
+	if s.expiry == nil {
+		s.expiry = time.AfterFunc(s.idleTimeout, func() { s.Close() })
+	} else {
+		s.expiry.Reset(s.idleTimeout)
+	}
+}
+
+func (s *httpSession) ReadObject(v interface{}) error {
+	select {
+	case msg := <-s.in:
+		return json.Unmarshal(msg, v)
+	case <-s.done:
+		return io.EOF
+	}
+}
+
+func (s *httpSession) WriteObject(obj interface{}) error {
+	msg, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	stream := s.route(msg)
+	if stream == nil {
+		// the client has no stream open to receive the message
+		return nil
+	}
+	select {
+	case stream.messages <- msg:
+	case <-stream.done:
+	case <-s.done:
+		return io.ErrClosedPipe
+	}
+	return nil
+}
+
+func (s *httpSession) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		if s.expiry != nil {
+			s.expiry.Stop()
+		}
+		s.mu.Unlock()
+	})
+	return nil
+}
+
+func (s *httpSession) route(msg json.RawMessage) *httpStream {
+	var m struct {
+		Method string       `json:"method"`
+		ID     *jsonrpc2.ID `json:"id"`
+	}
+	json.Unmarshal(msg, &m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.Method == "" && m.ID != nil {
+		if stream, ok := s.waiting[m.ID.String()]; ok {
+			delete(s.waiting, m.ID.String())
+			return stream
+		}
+	}
+	if n := len(s.streams); n > 0 {
+		return s.streams[n-1]
+	}
+	return s.standalone
+}
+
+// register routes the responses to the requests among messages to stream,
+// and other messages too if events is set.
+func (s *httpSession) register(stream *httpStream, messages []httpMessage, events bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range messages {
+		if m.isRequest() {
+			s.waiting[m.id.String()] = stream
+		}
+	}
+	if events {
+		s.streams = append(s.streams, stream)
+	}
+}
+
+func (s *httpSession) unregister(stream *httpStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, w := range s.waiting {
+		if w == stream {
+			delete(s.waiting, id)
+		}
+	}
+	for i, st := range s.streams {
+		if st == stream {
+			s.streams = append(s.streams[:i], s.streams[i+1:]...)
+			break
+		}
+	}
+}
+
+// setStandalone sets the stream for messages no post is waiting for,
+// reporting false if one is already set.
+func (s *httpSession) setStandalone(stream *httpStream) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stream != nil && s.standalone != nil {
+		return false
+	}
+	s.standalone = stream
+	return true
+}
+
+// send passes a message from the client to the server, reporting false if
+// the session has ended.
+func (s *httpSession) send(ctx context.Context, msg json.RawMessage) bool {
+	select {
+	case s.in <- msg:
+		return true
+	case <-s.done:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,275 @@
+package mcp_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("HTTPTransport", func() {
+
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"TestClient","version":"1.0.0"}}}`
+
+	var (
+		transport   *mcp.HTTPTransport
+		server      *httptest.Server
+		startedCh   chan bool
+		cancelledCh chan bool
+		endedCh     chan string
+	)
+
+	BeforeEach(func() {
+		// sessions end after each test, so the tools and cleanup use the
+		// channels of their own test
+		started, cancelled, ended := make(chan bool, 1), make(chan bool, 1), make(chan string, 1)
+		tools := []mcp.ToolDefinition{
+			{
+				Metadata: mcp.Tool{Name: "greet", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					return textResult("Hello"), nil
+				},
+			},
+			{
+				Metadata: mcp.Tool{Name: "whoami", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Process: func(ctx context.Context, _ mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+					n.Notify(ctx, "notifications/progress", map[string]any{"progressToken": "t", "progress": 1})
+					p, _ := mcp.PrincipalFromContext(ctx)
+					return textResult(p.Subject), nil
+				},
+			},
+			{
+				Metadata: mcp.Tool{Name: "wait", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+					started <- true
+					<-ctx.Done()
+					cancelled <- true
+					return textResult("cancelled"), nil
+				},
+			},
+		}
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools,
+			mcp.WithSessionCleanup(func(ctx context.Context) {
+				id, _ := mcp.SessionIDFromContext(ctx)
+				select {
+				case ended <- id:
+				default:
+				}
+			}))
+		transport = mcp.NewHTTPTransport(s)
+		startedCh, cancelledCh, endedCh = started, cancelled, ended
+		DeferCleanup(transport.Close)
+		// the caller is identified by a header, as a ResourceServer would do
+		// with an access token
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(mcp.ContextWithPrincipal(r.Context(), &mcp.Principal{Subject: user}))
+			}
+			transport.ServeHTTP(w, r)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	send := func(method, url string, header http.Header, body string) *http.Response {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	readBody := func(resp *http.Response) string {
+		b, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(b)
+	}
+
+	// readEvent reads the next server-sent event.
+	readEvent := func(r *bufio.Reader) (string, string) {
+		var event, data string
+		for {
+			line, err := r.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				return event, data
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	Describe("streamable HTTP", func() {
+
+		jsonOnly := http.Header{"Accept": {"application/json"}}
+
+		startSession := func(header http.Header) http.Header {
+			resp := send(http.MethodPost, server.URL, header, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			id := resp.Header.Get(mcp.SessionIDHeader)
+			Expect(id).ToNot(BeEmpty())
+			Expect(readBody(resp)).To(ContainSubstring(`"serverInfo":{"name":"TestServer","version":"1.0.0"}`))
+
+			session := header.Clone()
+			session.Set(mcp.SessionIDHeader, id)
+			resp = send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			return session
+		}
+
+		It("replies to requests with JSON", func() {
+			session := startSession(jsonOnly)
+			resp := send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet"}}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(readBody(resp)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"Hello"}]}}`))
+		})
+
+		It("replies to batches", func() {
+			session := startSession(jsonOnly)
+			resp := send(http.MethodPost, server.URL, session, `[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":"three","method":"ping"}]`)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(readBody(resp)).To(MatchJSON(`[{"jsonrpc":"2.0","id":2,"result":{}},{"jsonrpc":"2.0","id":"three","result":{}}]`))
+		})
+
+		It("streams notifications sent while handling requests", func() {
+			session := startSession(http.Header{"Accept": {"application/json, text/event-stream"}, "X-User": {"ada"}})
+			resp := send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			events := bufio.NewReader(resp.Body)
+			event, data := readEvent(events)
+			Expect(event).To(Equal("message"))
+			Expect(data).To(MatchJSON(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":"t","progress":1}}`))
+			_, data = readEvent(events)
+			Expect(data).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"ada"}]}}`))
+			_, err := events.ReadByte()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("requires a session after initialization", func() {
+			resp := send(http.MethodPost, server.URL, jsonOnly, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			unknown := jsonOnly.Clone()
+			unknown.Set(mcp.SessionIDHeader, "unknown")
+			resp = send(http.MethodPost, server.URL, unknown, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("ends sessions that are deleted", func() {
+			session := startSession(jsonOnly)
+			Expect(send(http.MethodDelete, server.URL, session, "").StatusCode).To(Equal(http.StatusNoContent))
+			Eventually(func() int {
+				return send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode
+			}).Should(Equal(http.StatusNotFound))
+		})
+
+		It("cancels requests in progress and cleans up when sessions end", func() {
+			session := startSession(jsonOnly)
+			go func() {
+				req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"wait"}}`))
+				req.Header = session.Clone()
+				if resp, err := http.DefaultClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}()
+			Eventually(startedCh).Should(Receive())
+			Expect(cancelledCh).ToNot(Receive())
+
+			Expect(send(http.MethodDelete, server.URL, session, "").StatusCode).To(Equal(http.StatusNoContent))
+			Eventually(cancelledCh).Should(Receive())
+			Eventually(endedCh).Should(Receive(Equal(session.Get(mcp.SessionIDHeader))))
+		})
+
+		It("ends sessions that are idle", func() {
+			transport.IdleTimeout = 50 * time.Millisecond
+			session := startSession(jsonOnly)
+			Expect(send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode).To(Equal(http.StatusOK))
+			Eventually(endedCh).Should(Receive(Equal(session.Get(mcp.SessionIDHeader))))
+			Expect(send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":3,"method":"ping"}`).StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("rejects requests from other principals", func() {
+			session := startSession(http.Header{"Accept": {"application/json"}, "X-User": {"ada"}})
+			session.Set("X-User", "mallory")
+			resp := send(http.MethodPost, server.URL, session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("rejects messages that are not JSON-RPC", func() {
+			resp := send(http.MethodPost, server.URL, jsonOnly, `{"jsonrpc":`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(readBody(resp)).To(MatchJSON(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`))
+		})
+	})
+
+	Describe("origins", func() {
+		It("allows requests without an origin", func() {
+			resp := send(http.MethodPost, server.URL, http.Header{"Accept": {"application/json"}}, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("forbids requests from origins that are not allowed", func() {
+			for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+				resp := send(method, server.URL, http.Header{"Accept": {"application/json"}, "Origin": {"http://attacker.example"}}, initialize)
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			}
+		})
+
+		It("allows requests from allowed origins", func() {
+			transport.AllowedOrigins = []string{"https://app.example.com/"}
+			resp := send(http.MethodPost, server.URL, http.Header{"Accept": {"application/json"}, "Origin": {"https://App.example.com"}}, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			resp = send(http.MethodPost, server.URL, http.Header{"Accept": {"application/json"}, "Origin": {"https://app.example.com.attacker.example"}}, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			transport.AllowedOrigins = []string{"*"}
+			resp = send(http.MethodPost, server.URL, http.Header{"Accept": {"application/json"}, "Origin": {"http://attacker.example"}}, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("HTTP+SSE", func() {
+
+		It("sends messages from the server on the event stream", func() {
+			stream := send(http.MethodGet, server.URL+"/mcp", http.Header{"Accept": {"text/event-stream"}}, "")
+			Expect(stream.StatusCode).To(Equal(http.StatusOK))
+			events := bufio.NewReader(stream.Body)
+			event, endpoint := readEvent(events)
+			Expect(event).To(Equal("endpoint"))
+			Expect(endpoint).To(HavePrefix("/mcp?sessionId="))
+
+			resp := send(http.MethodPost, server.URL+endpoint, nil, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			event, data := readEvent(events)
+			Expect(event).To(Equal("message"))
+			Expect(data).To(ContainSubstring(`"serverInfo":{"name":"TestServer","version":"1.0.0"}`))
+
+			resp = send(http.MethodPost, server.URL+endpoint, nil, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet"}}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			_, data = readEvent(events)
+			Expect(data).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"Hello"}]}}`))
+		})
+
+		It("rejects posts to unknown sessions", func() {
+			resp := send(http.MethodPost, server.URL+"/mcp?sessionId=unknown", nil, initialize)
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
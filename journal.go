@@ -0,0 +1,216 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Journal outcomes.
+const (
+	JournalOutcomeSuccess   = "success"
+	JournalOutcomeError     = "error"
+	JournalOutcomeRecovered = "recovered"
+)
+
+// JournalEntry is a tool call accepted by the server. It is journaled before
+// the tool is executed, without an Outcome, and again with the outcome once
+// the call has finished.
+//
+// Arguments are journaled as they are audited, with redacted and secret
+// arguments masked, so that the journal never holds secrets at rest. A call
+// that must be replayed or undone should be identifiable from its other
+// arguments, such as an idempotency key or the ID of the record it changes.
+type JournalEntry struct {
+	ID        string          `json:"id"`
+	Time      time.Time       `json:"time"`
+	Session   string          `json:"session,omitempty"`
+	RequestID json.RawMessage `json:"requestId,omitempty"`
+	Tool      string          `json:"tool,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Outcome   string          `json:"outcome,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// JournalStore persists a journal of tool calls.
+type JournalStore interface {
+	// Begin records that the call e is about to be executed. It must not
+	// return until e is durable.
+	Begin(ctx context.Context, e JournalEntry) error
+	// Finish records the outcome of the call with id.
+	Finish(ctx context.Context, id, outcome, message string) error
+	// Unfinished returns the calls that were begun but never finished.
+	Unfinished(ctx context.Context) ([]JournalEntry, error)
+}
+
+// WithJournal records each tool call in store before it is executed, and its
+// outcome once it has finished, so that calls interrupted by a crash can be
+// found with RecoverJournal. A call is rejected with an internal error if it
+// cannot be journaled. Arguments are recorded after redaction and secret
+// masking; see JournalEntry.
+func WithJournal(store JournalStore) ServerOption {
+	return func(h *handler) {
+		h.journal = store
+	}
+}
+
+// RecoverJournal passes each call in store that was begun but never finished,
+// such as one interrupted by a crash, to reconcile, and records the call as
+// recovered once reconcile returns without error. It should be called at
+// startup, before the server is serving.
+func RecoverJournal(ctx context.Context, store JournalStore, reconcile func(context.Context, JournalEntry) error) error {
+	entries, err := store.Unfinished(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := reconcile(ctx, e); err != nil {
+			return fmt.Errorf("reconciling call %s of %s: %w", e.ID, e.Tool, err)
+		}
+		if err := store.Finish(ctx, e.ID, JournalOutcomeRecovered, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// beginCall journals a call that is about to be executed, returning its ID.
+func (h *handler) beginCall(ctx context.Context, params CallToolRequestParams, secretArgs []string) (string, error) {
+	e := JournalEntry{
+		ID:   newID(),
+		Time: time.Now().UTC(),
+		Tool: params.Name,
+	}
+	e.Session, _ = SessionIDFromContext(ctx)
+	if id, ok := RequestIDFromContext(ctx); ok {
+		e.RequestID, _ = json.Marshal(id)
+	}
+	if params.Arguments != nil {
+		args, err := json.Marshal(h.secrets.maskValue(h.redactor.Arguments(params.Arguments), secretArgs...))
+		if err != nil {
+			return "", err
+		}
+		e.Arguments = args
+	}
+	return e.ID, h.journal.Begin(ctx, e)
+}
+
+// finishCall journals the outcome of a call.
+func (h *handler) finishCall(ctx context.Context, id string, err error, secretArgs []string) {
+	outcome, message := JournalOutcomeSuccess, ""
+	if err != nil {
+		outcome, message = JournalOutcomeError, h.secrets.Mask(err.Error(), secretArgs...)
+	}
+	if err := h.journal.Finish(context.WithoutCancel(ctx), id, outcome, message); err != nil {
+		slog.Error("problem journaling tool call outcome", "call", id, "error", err)
+	}
+}
+
+// replyUnjournaled replies to a call that could not be journaled.
+func (h *handler) replyUnjournaled(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, tool string, err error) {
+	slog.Error("problem journaling tool call", "tool", tool, "error", err)
+	h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+		Code:    jsonrpc2.CodeInternalError,
+		Message: "Internal error",
+	})
+}
+
+// FileJournal is a JournalStore that appends entries to a file as newline
+// delimited JSON, syncing each call to disk before it is executed.
+type FileJournal struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenFileJournal opens the journal at path, creating it if it does not
+// exist.
+func OpenFileJournal(path string) (*FileJournal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// end an entry cut short by a crash, so that it does not run into the
+	// next one
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			f.Write([]byte{'\n'})
+		}
+	}
+	return &FileJournal{f: f}, nil
+}
+
+func (j *FileJournal) Begin(_ context.Context, e JournalEntry) error {
+	return j.append(e, true)
+}
+
+func (j *FileJournal) Finish(_ context.Context, id, outcome, message string) error {
+	return j.append(JournalEntry{ID: id, Time: time.Now().UTC(), Outcome: outcome, Message: message}, false)
+}
+
+func (j *FileJournal) append(e JournalEntry, sync bool) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if sync {
+		return j.f.Sync()
+	}
+	return nil
+}
+
+func (j *FileJournal) Unfinished(_ context.Context) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	var (
+		begun    []JournalEntry
+		finished = map[string]bool{}
+	)
+	scanner := bufio.NewScanner(j.f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// the last entry may have been cut short by a crash
+			slog.Warn("skipping unreadable journal entry", "file", j.f.Name(), "line", line, "error", err)
+			continue
+		}
+		if e.Outcome == "" {
+			begun = append(begun, e)
+		} else {
+			finished[e.ID] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var unfinished []JournalEntry
+	for _, e := range begun {
+		if !finished[e.ID] {
+			unfinished = append(unfinished, e)
+		}
+	}
+	return unfinished, nil
+}
+
+// Close closes the journal file.
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
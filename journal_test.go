@@ -0,0 +1,122 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Journal", func() {
+
+	var (
+		path    string
+		journal *mcp.FileJournal
+		tools   []mcp.ToolDefinition
+		release chan bool
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "journal")
+		var err error
+		journal, err = mcp.OpenFileJournal(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		release = make(chan bool)
+		blocked := release
+		tools = []mcp.ToolDefinition{
+			{
+				Metadata: mcp.Tool{Name: "transfer", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					if params.Arguments["amount"] == float64(0) {
+						return mcp.CallToolResult{}, errors.New("nothing to transfer")
+					}
+					return textResult("transferred"), nil
+				},
+			},
+			{
+				Metadata: mcp.Tool{Name: "hang", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					<-blocked
+					return textResult("done"), nil
+				},
+			},
+		}
+	})
+
+	It("journals calls and their outcomes", func() {
+		DeferCleanup(journal.Close)
+		conn := startServer(mcp.ContextWithSessionID(context.Background(), "session-1"), tools, mcp.WithJournal(journal))
+		_, err := callTool(conn, "transfer", map[string]any{"amount": 10})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = callTool(conn, "transfer", map[string]any{"amount": 0})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(journal.Unfinished(context.Background())).To(BeEmpty())
+		b, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"session":"session-1","requestId":0,"tool":"transfer","arguments":{"amount":10}`))
+		Expect(string(b)).To(ContainSubstring(`"outcome":"success"`))
+		Expect(string(b)).To(ContainSubstring(`"outcome":"error","message":"nothing to transfer"`))
+	})
+
+	It("finds and reconciles calls that never finished", func() {
+		conn := startServer(context.Background(), tools, mcp.WithJournal(journal))
+		go callTool(conn, "hang", map[string]any{"account": "A-1"})
+		Eventually(func() ([]mcp.JournalEntry, error) {
+			return journal.Unfinished(context.Background())
+		}).Should(HaveLen(1))
+		close(release)
+		Eventually(func() ([]mcp.JournalEntry, error) {
+			return journal.Unfinished(context.Background())
+		}).Should(BeEmpty())
+
+		// a crash leaves an unfinished call in the journal
+		Expect(journal.Begin(context.Background(), mcp.JournalEntry{ID: "interrupted", Tool: "transfer"})).To(Succeed())
+		Expect(journal.Close()).To(Succeed())
+		reopened, err := mcp.OpenFileJournal(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(reopened.Close)
+
+		var reconciled []string
+		Expect(mcp.RecoverJournal(context.Background(), reopened, func(_ context.Context, e mcp.JournalEntry) error {
+			reconciled = append(reconciled, e.ID)
+			return nil
+		})).To(Succeed())
+		Expect(reconciled).To(ConsistOf("interrupted"))
+		Expect(reopened.Unfinished(context.Background())).To(BeEmpty())
+	})
+
+	It("stops recovering when a call cannot be reconciled", func() {
+		DeferCleanup(journal.Close)
+		Expect(journal.Begin(context.Background(), mcp.JournalEntry{ID: "interrupted", Tool: "transfer"})).To(Succeed())
+		err := mcp.RecoverJournal(context.Background(), journal, func(context.Context, mcp.JournalEntry) error {
+			return errors.New("bank unavailable")
+		})
+		Expect(err).To(MatchError("reconciling call interrupted of transfer: bank unavailable"))
+		Expect(journal.Unfinished(context.Background())).To(HaveLen(1))
+	})
+
+	It("skips an entry cut short by a crash", func() {
+		Expect(journal.Begin(context.Background(), mcp.JournalEntry{ID: "first", Tool: "transfer"})).To(Succeed())
+		Expect(journal.Close()).To(Succeed())
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		Expect(err).ToNot(HaveOccurred())
+		f.Write([]byte(`{"id":"second","to`))
+		f.Close()
+
+		reopened, err := mcp.OpenFileJournal(path)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(reopened.Close)
+		Expect(reopened.Begin(context.Background(), mcp.JournalEntry{ID: "third", Tool: "transfer"})).To(Succeed())
+		Expect(reopened.Unfinished(context.Background())).To(ConsistOf(
+			HaveField("ID", "first"),
+			HaveField("ID", "third"),
+		))
+	})
+})
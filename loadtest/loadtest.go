@@ -0,0 +1,303 @@
+// Package loadtest generates synthetic load against an MCP server, to size
+// worker pools and rate limits.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"golang.org/x/time/rate"
+
+	"github.com/acrmp/mcp"
+)
+
+// Call is a tool call made by a load test.
+type Call struct {
+	Name      string
+	Arguments map[string]any
+	// Weight is the frequency of the call relative to the others in the
+	// mix. Zero is treated as one.
+	Weight int
+}
+
+// Dialer opens a session with the server under test.
+type Dialer func(ctx context.Context) (jsonrpc2.ObjectStream, error)
+
+// ServerDialer returns a Dialer that serves each session with s in-memory.
+func ServerDialer(s *mcp.Server) Dialer {
+	return func(ctx context.Context) (jsonrpc2.ObjectStream, error) {
+		serverSide, clientSide := net.Pipe()
+		go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+		return jsonrpc2.NewPlainObjectStream(clientSide), nil
+	}
+}
+
+// CommandDialer returns a Dialer that starts a process for each session,
+// communicating over its stdin and stdout. The process is stopped when the
+// session ends.
+func CommandDialer(newCmd func() *exec.Cmd) Dialer {
+	return func(ctx context.Context) (jsonrpc2.ObjectStream, error) {
+		return mcp.CommandStream(newCmd())
+	}
+}
+
+// Config configures a load test.
+type Config struct {
+	Dial Dialer
+	// Sessions is the number of sessions calling tools concurrently.
+	Sessions int
+	// Rate is the target number of calls per second across all sessions.
+	// Zero makes calls as fast as the server answers them.
+	Rate float64
+	// Duration is how long calls are made for.
+	Duration time.Duration
+	// Calls is the mix of tool calls made.
+	Calls []Call
+}
+
+// Run opens the configured sessions and calls tools until the duration
+// elapses or ctx is done, then reports what happened. Run fails if a session
+// cannot be opened or initialized; failed calls are counted in the report.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	if cfg.Sessions <= 0 {
+		cfg.Sessions = 1
+	}
+	if len(cfg.Calls) == 0 {
+		return Report{}, errors.New("no calls configured")
+	}
+	if cfg.Duration <= 0 {
+		return Report{}, errors.New("duration must be positive")
+	}
+	limit := rate.Inf
+	if cfg.Rate > 0 {
+		limit = rate.Limit(cfg.Rate)
+	}
+	r := &runner{
+		cfg:     cfg,
+		limiter: rate.NewLimiter(limit, 1),
+		total:   &stats{},
+		tools:   map[string]*stats{},
+	}
+
+	conns := make([]*jsonrpc2.Conn, cfg.Sessions)
+	defer func() {
+		for _, conn := range conns {
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}()
+	for i := range conns {
+		conn, err := r.open(ctx)
+		if err != nil {
+			return Report{}, fmt.Errorf("opening session %d: %w", i+1, err)
+		}
+		conns[i] = conn
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.session(ctx, runCtx, conn)
+		}()
+	}
+	wg.Wait()
+	return r.report(time.Since(start)), nil
+}
+
+type runner struct {
+	cfg     Config
+	limiter *rate.Limiter
+
+	mu    sync.Mutex
+	total *stats
+	tools map[string]*stats
+}
+
+func (r *runner) open(ctx context.Context) (*jsonrpc2.Conn, error) {
+	stream, err := r.cfg.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// notifications from the server are ignored
+	conn := jsonrpc2.NewConn(ctx, stream, jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	}))
+	var result mcp.InitializeResult
+	err = conn.Call(ctx, "initialize", mcp.InitializeRequestParams{
+		ProtocolVersion: mcp.SupportedProtocolVersion,
+		ClientInfo:      mcp.Implementation{Name: "loadtest", Version: "1.0.0"},
+	}, &result)
+	if err == nil {
+		err = conn.Notify(ctx, "notifications/initialized", nil)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("initializing: %w", err)
+	}
+	return conn, nil
+}
+
+// session makes calls until runCtx is done. Calls are made with ctx so that
+// those in flight when the run ends complete, as closing a connection with
+// calls pending is not safe.
+func (r *runner) session(ctx, runCtx context.Context, conn *jsonrpc2.Conn) {
+	for {
+		if err := r.limiter.Wait(runCtx); err != nil {
+			return
+		}
+		call := r.pick()
+		var result mcp.CallToolResult
+		start := time.Now()
+		err := conn.Call(ctx, "tools/call", mcp.CallToolRequestParams{Name: call.Name, Arguments: call.Arguments}, &result)
+		elapsed := time.Since(start)
+		if runCtx.Err() != nil {
+			// calls that complete after the end of the run are not counted
+			return
+		}
+		r.record(call.Name, elapsed, err, result.IsError != nil && *result.IsError)
+	}
+}
+
+func (r *runner) pick() Call {
+	total := 0
+	for _, c := range r.cfg.Calls {
+		total += max(c.Weight, 1)
+	}
+	n := rand.IntN(total)
+	for _, c := range r.cfg.Calls {
+		if n -= max(c.Weight, 1); n < 0 {
+			return c
+		}
+	}
+	return r.cfg.Calls[len(r.cfg.Calls)-1]
+}
+
+func (r *runner) record(tool string, latency time.Duration, err error, toolError bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.tools[tool]
+	if !ok {
+		s = &stats{}
+		r.tools[tool] = s
+	}
+	for _, s := range []*stats{r.total, s} {
+		s.latencies = append(s.latencies, latency)
+		switch {
+		case err != nil:
+			s.errors++
+		case toolError:
+			s.toolErrors++
+		}
+	}
+}
+
+func (r *runner) report(elapsed time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := Report{Duration: elapsed, Stats: r.total.summary(), Tools: map[string]Stats{}}
+	for name, s := range r.tools {
+		report.Tools[name] = s.summary()
+	}
+	return report
+}
+
+type stats struct {
+	latencies  []time.Duration
+	errors     int
+	toolErrors int
+}
+
+func (s *stats) summary() Stats {
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return Stats{
+		Calls:      len(sorted),
+		Errors:     s.errors,
+		ToolErrors: s.toolErrors,
+		P50:        percentile(sorted, 50),
+		P90:        percentile(sorted, 90),
+		P99:        percentile(sorted, 99),
+		Max:        percentile(sorted, 100),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// Stats summarizes the calls made during a load test.
+type Stats struct {
+	Calls int
+	// Errors counts calls that failed with a protocol or transport error.
+	Errors int
+	// ToolErrors counts calls whose result reported a tool error.
+	ToolErrors int
+	// Latency percentiles of all calls, including those that failed.
+	P50, P90, P99, Max time.Duration
+}
+
+// ErrorRate returns the fraction of calls that failed for any reason.
+func (s Stats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors+s.ToolErrors) / float64(s.Calls)
+}
+
+// Report is the outcome of a load test.
+type Report struct {
+	Duration time.Duration
+	// Stats covers every call made.
+	Stats
+	// Tools breaks the calls down by tool name.
+	Tools map[string]Stats
+}
+
+// Throughput returns the calls completed per second.
+func (r Report) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Calls) / r.Duration.Seconds()
+}
+
+// String formats the report as a table.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d calls in %s (%.1f/s)\n", r.Calls, r.Duration.Round(time.Millisecond), r.Throughput())
+	fmt.Fprintf(&b, "%-20s %8s %8s %8s %10s %10s %10s %10s\n", "TOOL", "CALLS", "ERRORS", "ERROR%", "P50", "P90", "P99", "MAX")
+	names := make([]string, 0, len(r.Tools))
+	for name := range r.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	row := func(name string, s Stats) {
+		fmt.Fprintf(&b, "%-20s %8d %8d %7.2f%% %10s %10s %10s %10s\n", name, s.Calls, s.Errors+s.ToolErrors, 100*s.ErrorRate(),
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	for _, name := range names {
+		row(name, r.Tools[name])
+	}
+	row("total", r.Stats)
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package loadtest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/loadtest"
+)
+
+var _ = Describe("Run", func() {
+
+	var s *mcp.Server
+
+	BeforeEach(func() {
+		tools := []mcp.ToolDefinition{
+			{
+				Metadata: mcp.Tool{Name: "echo", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					return mcp.NewResult().Text(params.Arguments["text"].(string)).Build()
+				},
+			},
+			{
+				Metadata: mcp.Tool{Name: "fail", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					return mcp.CallToolResult{}, errors.New("failed")
+				},
+			},
+		}
+		s = mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools)
+	})
+
+	It("reports calls broken down by tool", func() {
+		report, err := loadtest.Run(context.Background(), loadtest.Config{
+			Dial:     loadtest.ServerDialer(s),
+			Sessions: 4,
+			Duration: 100 * time.Millisecond,
+			Calls: []loadtest.Call{
+				{Name: "echo", Arguments: map[string]any{"text": "hello"}, Weight: 3},
+				{Name: "fail"},
+				{Name: "missing"},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Calls).To(BeNumerically(">", 10))
+		Expect(report.Tools).To(HaveKey("echo"))
+		Expect(report.Tools["echo"].Calls).To(BeNumerically(">", report.Tools["fail"].Calls))
+		Expect(report.Tools["echo"].ErrorRate()).To(BeZero())
+		Expect(report.Tools["fail"].ToolErrors).To(Equal(report.Tools["fail"].Calls))
+		Expect(report.Tools["missing"].Errors).To(Equal(report.Tools["missing"].Calls))
+		Expect(report.Calls).To(Equal(report.Tools["echo"].Calls + report.Tools["fail"].Calls + report.Tools["missing"].Calls))
+		Expect(report.P50).To(BeNumerically("<=", report.P90))
+		Expect(report.P99).To(BeNumerically("<=", report.Max))
+	})
+
+	It("limits calls to the target rate", func() {
+		report, err := loadtest.Run(context.Background(), loadtest.Config{
+			Dial:     loadtest.ServerDialer(s),
+			Sessions: 2,
+			Rate:     50,
+			Duration: 200 * time.Millisecond,
+			Calls:    []loadtest.Call{{Name: "echo", Arguments: map[string]any{"text": "hello"}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Calls).To(BeNumerically("~", 10, 3))
+	})
+
+	It("formats the report as a table", func() {
+		report, err := loadtest.Run(context.Background(), loadtest.Config{
+			Dial:     loadtest.ServerDialer(s),
+			Duration: 10 * time.Millisecond,
+			Calls:    []loadtest.Call{{Name: "echo", Arguments: map[string]any{"text": "hello"}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+		Expect(lines).To(HaveLen(4))
+		Expect(lines[1]).To(HavePrefix("TOOL"))
+		Expect(lines[2]).To(HavePrefix("echo"))
+		Expect(lines[3]).To(HavePrefix("total"))
+	})
+
+	It("fails when a session cannot be opened", func() {
+		_, err := loadtest.Run(context.Background(), loadtest.Config{
+			Dial: func(context.Context) (jsonrpc2.ObjectStream, error) {
+				return nil, errors.New("connection refused")
+			},
+			Duration: time.Second,
+			Calls:    []loadtest.Call{{Name: "echo"}},
+		})
+		Expect(err).To(MatchError("opening session 1: connection refused"))
+	})
+
+	It("requires calls to make", func() {
+		_, err := loadtest.Run(context.Background(), loadtest.Config{Dial: loadtest.ServerDialer(s), Duration: time.Second})
+		Expect(err).To(MatchError("no calls configured"))
+	})
+})
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+type localeKey struct{}
+
+// ContextWithLocale returns a copy of ctx carrying the locale the client
+// prefers, as a BCP 47 language tag such as "fr-CA". Locales requested by the
+// client in the _meta of a request, or of its initialize request, take
+// precedence.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale the client prefers, so that handlers
+// can localize their results.
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok && locale != ""
+}
+
+// requestLocale returns the locale asked for in the _meta of params, or in
+// the client info of an initialize request.
+func requestLocale(params *json.RawMessage) string {
+	if params == nil {
+		return ""
+	}
+	var p struct {
+		Meta struct {
+			Locale string `json:"locale"`
+		} `json:"_meta"`
+		ClientInfo struct {
+			Locale string `json:"locale"`
+		} `json:"clientInfo"`
+	}
+	if json.Unmarshal(*params, &p) != nil {
+		return ""
+	}
+	if p.Meta.Locale != "" {
+		return p.Meta.Locale
+	}
+	return p.ClientInfo.Locale
+}
+
+// localize returns the description in descriptions that best matches the
+// locale of ctx, falling back to less specific tags, such as "fr" for
+// "fr-CA", and then to description.
+func localize(ctx context.Context, description *string, descriptions map[string]string) *string {
+	locale, ok := LocaleFromContext(ctx)
+	if !ok || len(descriptions) == 0 {
+		return description
+	}
+	tag := normalizeLocale(locale)
+	for {
+		for l, d := range descriptions {
+			if normalizeLocale(l) == tag {
+				return &d
+			}
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			return description
+		}
+		tag = tag[:i]
+	}
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
@@ -0,0 +1,77 @@
+package mcp_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Locales", func() {
+
+	var conn *jsonrpc2.Conn
+
+	BeforeEach(func() {
+		desc := "Look up the weather"
+		tools := []mcp.ToolDefinition{{
+			Metadata:     mcp.Tool{Name: "weather", Description: &desc, InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Descriptions: map[string]string{"fr": "Consulter la météo", "pt-BR": "Consultar o tempo"},
+			Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+				locale, _ := mcp.LocaleFromContext(ctx)
+				return textResult(locale), nil
+			},
+		}}
+		promptDesc := "Summarize the forecast"
+		prompts := []mcp.PromptDefinition{{
+			Metadata:     mcp.Prompt{Name: "forecast", Description: &promptDesc},
+			Descriptions: map[string]string{"fr": "Résumer les prévisions"},
+			Get: func(mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Summarize"))}, nil
+			},
+		}}
+		conn = startServer(context.Background(), tools, mcp.WithPrompts(prompts...))
+	})
+
+	toolDescription := func(params any) string {
+		var result mcp.ListToolsResult
+		Expect(conn.Call(context.Background(), "tools/list", params, &result)).To(Succeed())
+		return *result.Tools[0].Description
+	}
+
+	It("describes tools in the default language unless a locale is asked for", func() {
+		Expect(toolDescription(nil)).To(Equal("Look up the weather"))
+		Expect(toolDescription(map[string]any{"_meta": map[string]any{"locale": "de"}})).To(Equal("Look up the weather"))
+	})
+
+	It("describes tools in the locale asked for by a request", func() {
+		Expect(toolDescription(map[string]any{"_meta": map[string]any{"locale": "pt_br"}})).To(Equal("Consultar o tempo"))
+		Expect(toolDescription(map[string]any{"_meta": map[string]any{"locale": "fr-CA"}})).To(Equal("Consulter la météo"))
+	})
+
+	It("uses the locale of the client info for the rest of the session", func() {
+		var result mcp.InitializeResult
+		Expect(conn.Call(context.Background(), "initialize", map[string]any{
+			"protocolVersion": mcp.SupportedProtocolVersion,
+			"capabilities":    map[string]any{},
+			"clientInfo":      map[string]any{"name": "TestClient", "version": "1.0.0", "locale": "fr-FR"},
+		}, &result)).To(Succeed())
+
+		Expect(toolDescription(nil)).To(Equal("Consulter la météo"))
+		Expect(toolDescription(map[string]any{"_meta": map[string]any{"locale": "pt-BR"}})).To(Equal("Consultar o tempo"))
+
+		var prompts mcp.ListPromptsResult
+		Expect(conn.Call(context.Background(), "prompts/list", nil, &prompts)).To(Succeed())
+		Expect(*prompts.Prompts[0].Description).To(Equal("Résumer les prévisions"))
+
+		var prompt mcp.GetPromptResult
+		Expect(conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "forecast"}, &prompt)).To(Succeed())
+		Expect(*prompt.Description).To(Equal("Résumer les prévisions"))
+
+		called, err := callTool(conn, "weather", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(called).To(Equal(textResult("fr-FR")))
+	})
+})
@@ -1,19 +1,27 @@
 package mcp_test
 
 import (
+	"context"
+	"net"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
 )
 
-var exampleServerPath string
+var exampleServerPath, sleepServerPath string
 
 var _ = BeforeSuite(func() {
 	var err error
 	exampleServerPath, err = gexec.Build("github.com/acrmp/mcp/example")
 	Expect(err).ToNot(HaveOccurred())
+	sleepServerPath, err = gexec.Build("github.com/acrmp/mcp/testdata/sleepserver")
+	Expect(err).ToNot(HaveOccurred())
 })
 
 var _ = AfterSuite(func() {
@@ -24,3 +32,30 @@ func TestMcp(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "MCP Suite")
 }
+
+// startServer serves tools in-process and returns a client connection to it.
+// Values carried by ctx are visible to the server's handlers.
+func startServer(ctx context.Context, tools []mcp.ToolDefinition, opts ...mcp.ServerOption) *jsonrpc2.Conn {
+	s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, opts...)
+	return mcptest.NewPairContext(GinkgoT(), ctx, s).Conn
+}
+
+func callTool(conn *jsonrpc2.Conn, name string, args map[string]any) (mcp.CallToolResult, error) {
+	var result mcp.CallToolResult
+	err := conn.Call(context.Background(), "tools/call", mcp.CallToolRequestParams{Name: name, Arguments: args}, &result)
+	return result, err
+}
+
+func textResult(text string) mcp.CallToolResult {
+	return mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: text}}}
+}
+
+// newClient serves s in-process and returns a client connected to it.
+func newClient(s *mcp.Server) *mcp.Client {
+	serverSide, clientSide := net.Pipe()
+	go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+	c, err := mcp.NewClient(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), mcp.Implementation{Name: "TestClient", Version: "1.0.0"})
+	Expect(err).ToNot(HaveOccurred())
+	DeferCleanup(c.Close)
+	return c
+}
@@ -0,0 +1,225 @@
+package mcptest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+// conformanceTimeout bounds each request made by RunConformance so that a
+// server that never replies fails rather than hangs.
+const conformanceTimeout = 10 * time.Second
+
+// RunConformance checks that the server returned by newServer behaves as the
+// MCP specification requires. Each check runs as a subtest of t against a
+// new server, covering the lifecycle, ping, listing, tool calls, prompts,
+// pagination and error cases. Tools are never called with valid arguments,
+// so servers with side effects are safe to check.
+func RunConformance(t *testing.T, newServer func() *mcp.Server) {
+	t.Helper()
+	for _, c := range conformanceChecks {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+			defer cancel()
+
+			p := NewPair(t, newServer())
+			if !c.handshake {
+				init, err := p.Initialize(ctx)
+				if err != nil {
+					t.Fatalf("initialize: %v", err)
+				}
+				if c.prompts && init.Capabilities.Prompts == nil {
+					t.Skip("server does not offer prompts")
+				}
+			}
+			c.check(ctx, t, p)
+		})
+	}
+}
+
+type conformanceCheck struct {
+	name string
+	// handshake is set for checks that perform initialization themselves.
+	handshake bool
+	// prompts is set for checks that only apply to servers offering prompts.
+	prompts bool
+	check   func(ctx context.Context, t *testing.T, p *Pair)
+}
+
+var conformanceChecks = []conformanceCheck{
+	{name: "initialize", handshake: true, check: checkInitialize},
+	{name: "initialize with unsupported protocol version", handshake: true, check: checkInitializeUnsupportedVersion},
+	{name: "ping", check: checkPing},
+	{name: "unknown method", check: checkUnknownMethod},
+	{name: "tools/list", check: checkListTools},
+	{name: "tools/list with invalid cursor", check: checkInvalidCursor("tools/list")},
+	{name: "tools/call with unknown tool", check: checkCallUnknownTool},
+	{name: "tools/call without params", check: checkCallWithoutParams},
+	{name: "tools/call without required arguments", check: checkCallWithoutRequiredArguments},
+	{name: "prompts/list", prompts: true, check: checkListPrompts},
+	{name: "prompts/list with invalid cursor", prompts: true, check: checkInvalidCursor("prompts/list")},
+	{name: "prompts/get with unknown prompt", prompts: true, check: checkGetUnknownPrompt},
+	{name: "prompts/get without required arguments", prompts: true, check: checkGetWithoutRequiredArguments},
+}
+
+func checkInitialize(ctx context.Context, t *testing.T, p *Pair) {
+	result, err := p.Initialize(ctx)
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if result.ProtocolVersion != mcp.SupportedProtocolVersion {
+		t.Errorf("protocol version: got %q, want %q", result.ProtocolVersion, mcp.SupportedProtocolVersion)
+	}
+	if result.ServerInfo.Name == "" {
+		t.Errorf("server info has no name")
+	}
+	if result.Capabilities.Tools == nil {
+		t.Errorf("tools capability is not advertised")
+	}
+}
+
+func checkInitializeUnsupportedVersion(ctx context.Context, t *testing.T, p *Pair) {
+	var result mcp.InitializeResult
+	err := p.Conn.Call(ctx, "initialize", mcp.InitializeRequestParams{
+		ProtocolVersion: "1970-01-01",
+		ClientInfo:      mcp.Implementation{Name: "mcptest", Version: "1.0.0"},
+	}, &result)
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if result.ProtocolVersion != mcp.SupportedProtocolVersion {
+		t.Errorf("protocol version: got %q, want %q", result.ProtocolVersion, mcp.SupportedProtocolVersion)
+	}
+}
+
+func checkPing(ctx context.Context, t *testing.T, p *Pair) {
+	var result map[string]any
+	if err := p.Conn.Call(ctx, "ping", nil, &result); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if result == nil || len(result) != 0 {
+		t.Errorf("ping result: got %v, want an empty object", result)
+	}
+}
+
+func checkUnknownMethod(ctx context.Context, t *testing.T, p *Pair) {
+	err := p.Conn.Call(ctx, "mcptest/unknown", nil, nil)
+	expectCode(t, err, jsonrpc2.CodeMethodNotFound)
+}
+
+func checkListTools(ctx context.Context, t *testing.T, p *Pair) {
+	tools, err := p.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	names := map[string]bool{}
+	for _, tool := range tools {
+		if tool.Name == "" {
+			t.Errorf("tool has no name")
+		}
+		if names[tool.Name] {
+			t.Errorf("tool %q is listed more than once", tool.Name)
+		}
+		names[tool.Name] = true
+	}
+	if err := ValidateToolSchemas(tools...); err != nil {
+		t.Errorf("invalid tool schemas:\n%v", err)
+	}
+}
+
+func checkInvalidCursor(method string) func(ctx context.Context, t *testing.T, p *Pair) {
+	return func(ctx context.Context, t *testing.T, p *Pair) {
+		err := p.Conn.Call(ctx, method, map[string]any{"cursor": "mcptest-invalid-cursor"}, nil)
+		expectCode(t, err, jsonrpc2.CodeInvalidParams)
+	}
+}
+
+func checkCallUnknownTool(ctx context.Context, t *testing.T, p *Pair) {
+	_, err := p.CallTool(ctx, "mcptest-unknown-tool", nil)
+	expectCode(t, err, jsonrpc2.CodeInvalidParams)
+}
+
+func checkCallWithoutParams(ctx context.Context, t *testing.T, p *Pair) {
+	err := p.Conn.Call(ctx, "tools/call", nil, nil)
+	expectCode(t, err, jsonrpc2.CodeInvalidParams)
+}
+
+func checkCallWithoutRequiredArguments(ctx context.Context, t *testing.T, p *Pair) {
+	tools, err := p.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	for _, tool := range tools {
+		if len(tool.InputSchema.Required) == 0 {
+			continue
+		}
+		_, err := p.CallTool(ctx, tool.Name, map[string]any{})
+		if !hasCode(err, jsonrpc2.CodeInvalidParams) {
+			t.Errorf("tool %q called without required arguments: got %v, want error code %d", tool.Name, err, jsonrpc2.CodeInvalidParams)
+		}
+	}
+}
+
+func checkListPrompts(ctx context.Context, t *testing.T, p *Pair) {
+	prompts, err := p.ListPrompts(ctx)
+	if err != nil {
+		t.Fatalf("prompts/list: %v", err)
+	}
+	names := map[string]bool{}
+	for _, prompt := range prompts {
+		if prompt.Name == "" {
+			t.Errorf("prompt has no name")
+		}
+		if names[prompt.Name] {
+			t.Errorf("prompt %q is listed more than once", prompt.Name)
+		}
+		names[prompt.Name] = true
+		for _, arg := range prompt.Arguments {
+			if arg.Name == "" {
+				t.Errorf("prompt %q has an argument with no name", prompt.Name)
+			}
+		}
+	}
+}
+
+func checkGetUnknownPrompt(ctx context.Context, t *testing.T, p *Pair) {
+	_, err := p.GetPrompt(ctx, "mcptest-unknown-prompt", nil)
+	expectCode(t, err, jsonrpc2.CodeInvalidParams)
+}
+
+func checkGetWithoutRequiredArguments(ctx context.Context, t *testing.T, p *Pair) {
+	prompts, err := p.ListPrompts(ctx)
+	if err != nil {
+		t.Fatalf("prompts/list: %v", err)
+	}
+	for _, prompt := range prompts {
+		required := false
+		for _, arg := range prompt.Arguments {
+			required = required || (arg.Required != nil && *arg.Required)
+		}
+		if !required {
+			continue
+		}
+		_, err := p.GetPrompt(ctx, prompt.Name, map[string]string{})
+		if !hasCode(err, jsonrpc2.CodeInvalidParams) {
+			t.Errorf("prompt %q got without required arguments: got %v, want error code %d", prompt.Name, err, jsonrpc2.CodeInvalidParams)
+		}
+	}
+}
+
+func expectCode(t *testing.T, err error, code int64) {
+	t.Helper()
+	if !hasCode(err, code) {
+		t.Errorf("got %v, want error code %d", err, code)
+	}
+}
+
+func hasCode(err error, code int64) bool {
+	var rpcErr *jsonrpc2.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
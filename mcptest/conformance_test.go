@@ -0,0 +1,34 @@
+package mcptest_test
+
+import (
+	"testing"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+func TestConformance(t *testing.T) {
+	required := true
+	mcptest.RunConformance(t, func() *mcp.Server {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{
+				Name: "greet",
+				InputSchema: mcp.ToolInputSchema{
+					Type:       "object",
+					Properties: map[string]map[string]any{"name": {"type": "string"}},
+					Required:   []string{"name"},
+				},
+			},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return mcp.NewResult().Text("Hello, " + params.Arguments["name"].(string)).Build()
+			},
+		}}
+		prompts := []mcp.PromptDefinition{{
+			Metadata: mcp.Prompt{Name: "welcome", Arguments: []mcp.PromptArgument{{Name: "name", Required: &required}}},
+			Get: func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome " + params.Arguments["name"]))}, nil
+			},
+		}}
+		return mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...))
+	})
+}
@@ -0,0 +1,297 @@
+package mcptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+// Generator generates random requests for property-based tests. Calls to
+// tools and prompts usually name one of Tools or Prompts, when set, with
+// arguments generated from their declarations, so that properties reach the
+// code behind them.
+type Generator struct {
+	Tools   []mcp.Tool
+	Prompts []mcp.Prompt
+}
+
+// Request is a valid MCP request. It implements quick.Generator, so it can be
+// the argument of a property checked with testing/quick.
+type Request struct {
+	ID     jsonrpc2.ID
+	Method string
+	// Params is omitted from the request when nil.
+	Params any
+}
+
+// MarshalJSON encodes the request as a JSON-RPC message.
+func (req Request) MarshalJSON() ([]byte, error) {
+	msg := map[string]any{"jsonrpc": "2.0", "id": req.ID, "method": req.Method}
+	if req.Params != nil {
+		msg["params"] = req.Params
+	}
+	return json.Marshal(msg)
+}
+
+// Generate implements quick.Generator.
+func (Request) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Generator{}.Request(r, size))
+}
+
+// MalformedRequest is an encoded request that violates the protocol: it may
+// not be valid JSON, or its params may be missing or of the wrong type. It
+// implements quick.Generator.
+type MalformedRequest []byte
+
+// Generate implements quick.Generator.
+func (MalformedRequest) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Generator{}.MalformedRequest(r, size))
+}
+
+// Request returns a random valid request.
+func (g Generator) Request(r *rand.Rand, size int) Request {
+	req := Request{ID: randomID(r, size)}
+	switch r.Intn(7) {
+	case 0:
+		req.Method = "initialize"
+		version := mcp.SupportedProtocolVersion
+		if r.Intn(2) == 0 {
+			version = fmt.Sprintf("%04d-%02d-%02d", 2000+r.Intn(1000), 1+r.Intn(12), 1+r.Intn(28))
+		}
+		req.Params = mcp.InitializeRequestParams{
+			ProtocolVersion: version,
+			ClientInfo:      mcp.Implementation{Name: randomString(r, size), Version: randomString(r, size)},
+		}
+	case 1:
+		req.Method = "ping"
+	case 2:
+		req.Method = "tools/list"
+		if r.Intn(2) == 0 {
+			req.Params = mcp.ListToolsRequestParams{}
+		}
+	case 3:
+		req.Method = "tools/call"
+		req.Params = g.callToolParams(r, size)
+	case 4:
+		req.Method = "prompts/list"
+		if r.Intn(2) == 0 {
+			req.Params = mcp.ListPromptsRequestParams{}
+		}
+	case 5:
+		req.Method = "prompts/get"
+		req.Params = g.getPromptParams(r, size)
+	default:
+		// an unknown method is a valid request that must be rejected
+		req.Method = "mcptest/" + randomString(r, size)
+		if r.Intn(2) == 0 {
+			req.Params = randomObject(r, size, 2)
+		}
+	}
+	return req
+}
+
+func (g Generator) callToolParams(r *rand.Rand, size int) mcp.CallToolRequestParams {
+	if len(g.Tools) == 0 || r.Intn(4) == 0 {
+		return mcp.CallToolRequestParams{Name: randomString(r, size), Arguments: randomObject(r, size, 2)}
+	}
+	t := g.Tools[r.Intn(len(g.Tools))]
+	args := map[string]any{}
+	for name, schema := range t.InputSchema.Properties {
+		if r.Intn(2) == 0 {
+			args[name] = schemaValue(r, size, schema, 2)
+		}
+	}
+	for _, name := range t.InputSchema.Required {
+		if _, ok := args[name]; !ok {
+			args[name] = schemaValue(r, size, t.InputSchema.Properties[name], 2)
+		}
+	}
+	return mcp.CallToolRequestParams{Name: t.Name, Arguments: args}
+}
+
+func (g Generator) getPromptParams(r *rand.Rand, size int) mcp.GetPromptRequestParams {
+	if len(g.Prompts) == 0 || r.Intn(4) == 0 {
+		args := map[string]string{}
+		for i := r.Intn(3); i > 0; i-- {
+			args[randomString(r, size)] = randomString(r, size)
+		}
+		return mcp.GetPromptRequestParams{Name: randomString(r, size), Arguments: args}
+	}
+	p := g.Prompts[r.Intn(len(g.Prompts))]
+	args := map[string]string{}
+	for _, arg := range p.Arguments {
+		if (arg.Required != nil && *arg.Required) || r.Intn(2) == 0 {
+			args[arg.Name] = randomString(r, size)
+		}
+	}
+	return mcp.GetPromptRequestParams{Name: p.Name, Arguments: args}
+}
+
+// MalformedRequest returns a random request that violates the protocol.
+func (g Generator) MalformedRequest(r *rand.Rand, size int) MalformedRequest {
+	id := randomID(r, size)
+	message := func(method string, params any) MalformedRequest {
+		b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+		return b
+	}
+	wrongTypes := []any{42, true, randomString(r, size), []any{randomString(r, size)}}
+
+	switch r.Intn(6) {
+	case 0:
+		// a truncated request is not valid JSON
+		b, _ := json.Marshal(g.Request(r, size))
+		return b[:1+r.Intn(len(b)-1)]
+	case 1:
+		b := make([]byte, 1+r.Intn(size+1))
+		r.Read(b)
+		return b
+	case 2:
+		methods := []string{"initialize", "tools/list", "tools/call", "prompts/list", "prompts/get"}
+		return message(methods[r.Intn(len(methods))], wrongTypes[r.Intn(len(wrongTypes))])
+	case 3:
+		methods := []string{"initialize", "tools/call", "prompts/get"}
+		b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": methods[r.Intn(len(methods))]})
+		return b
+	case 4:
+		if r.Intn(2) == 0 {
+			return message("tools/call", map[string]any{"name": wrongTypes[r.Intn(2)]})
+		}
+		return message("tools/call", map[string]any{"name": randomString(r, size), "arguments": wrongTypes[r.Intn(len(wrongTypes))]})
+	default:
+		if r.Intn(2) == 0 {
+			return message("prompts/get", map[string]any{"name": randomString(r, size), "arguments": map[string]any{randomString(r, size): wrongTypes[r.Intn(2)]}})
+		}
+		methods := []string{"tools/list", "prompts/list"}
+		return message(methods[r.Intn(len(methods))], map[string]any{"cursor": wrongTypes[r.Intn(2)]})
+	}
+}
+
+// ValidateResponse returns an error if response is not a well formed JSON-RPC
+// response to request: it must carry the same ID and exactly one of a result
+// or an error with a code and message.
+func ValidateResponse(request, response []byte) error {
+	var req struct {
+		ID jsonrpc2.ID `json:"id"`
+	}
+	if err := json.Unmarshal(request, &req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	var resp struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      *jsonrpc2.ID    `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *struct {
+			Code    *int64 `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	switch {
+	case resp.JSONRPC != "2.0":
+		return fmt.Errorf("response has jsonrpc %q, want %q", resp.JSONRPC, "2.0")
+	case resp.ID == nil || *resp.ID != req.ID:
+		return fmt.Errorf("response ID does not match request ID %s", req.ID)
+	case (resp.Result == nil) == (resp.Error == nil):
+		return fmt.Errorf("response must have exactly one of result or error")
+	case resp.Error != nil && resp.Error.Code == nil:
+		return fmt.Errorf("error has no code")
+	case resp.Error != nil && resp.Error.Message == "":
+		return fmt.Errorf("error has no message")
+	}
+	return nil
+}
+
+func randomID(r *rand.Rand, size int) jsonrpc2.ID {
+	if r.Intn(2) == 0 {
+		return jsonrpc2.ID{Num: uint64(r.Int63n(1 << 53))}
+	}
+	return jsonrpc2.ID{Str: randomString(r, size), IsString: true}
+}
+
+func randomString(r *rand.Rand, size int) string {
+	runes := make([]rune, r.Intn(size+1))
+	for i := range runes {
+		if r.Intn(8) == 0 {
+			// include characters outside ASCII, excluding surrogates
+			runes[i] = rune(0x80 + r.Intn(0xd800-0x80))
+		} else {
+			runes[i] = rune(0x20 + r.Intn(0x5f))
+		}
+	}
+	return string(runes)
+}
+
+func randomObject(r *rand.Rand, size, depth int) map[string]any {
+	obj := map[string]any{}
+	for i := r.Intn(size/4 + 1); i > 0; i-- {
+		obj[randomString(r, size)] = randomValue(r, size, depth-1)
+	}
+	return obj
+}
+
+func randomValue(r *rand.Rand, size, depth int) any {
+	n := 6
+	if depth <= 0 {
+		n = 4
+	}
+	switch r.Intn(n) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 0
+	case 2:
+		return r.NormFloat64() * float64(size)
+	case 3:
+		return randomString(r, size)
+	case 4:
+		arr := make([]any, r.Intn(size/4+1))
+		for i := range arr {
+			arr[i] = randomValue(r, size, depth-1)
+		}
+		return arr
+	default:
+		return randomObject(r, size, depth)
+	}
+}
+
+// schemaValue returns a random value of the type declared by schema.
+func schemaValue(r *rand.Rand, size int, schema any, depth int) any {
+	s, _ := schema.(map[string]any)
+	if enum, ok := s["enum"].([]any); ok && len(enum) > 0 {
+		return enum[r.Intn(len(enum))]
+	}
+	switch s["type"] {
+	case "string":
+		return randomString(r, size)
+	case "integer":
+		return r.Intn(2*size+1) - size
+	case "number":
+		return r.NormFloat64() * float64(size)
+	case "boolean":
+		return r.Intn(2) == 0
+	case "null":
+		return nil
+	case "array":
+		arr := make([]any, r.Intn(size/4+1))
+		for i := range arr {
+			arr[i] = schemaValue(r, size, s["items"], depth-1)
+		}
+		return arr
+	case "object":
+		obj := map[string]any{}
+		if props, ok := s["properties"].(map[string]any); ok {
+			for name, prop := range props {
+				obj[name] = schemaValue(r, size, prop, depth-1)
+			}
+		}
+		return obj
+	}
+	return randomValue(r, size, depth)
+}
@@ -0,0 +1,108 @@
+package mcptest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing/quick"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Generator", func() {
+
+	required := true
+	g := mcptest.Generator{
+		Tools: []mcp.Tool{{
+			Name: "echo",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: mcp.ToolInputSchemaProperties{
+					"text":  {"type": "string"},
+					"count": {"type": "integer"},
+				},
+				Required: []string{"text"},
+			},
+		}},
+		Prompts: []mcp.Prompt{{Name: "welcome", Arguments: []mcp.PromptArgument{{Name: "name", Required: &required}}}},
+	}
+
+	newServer := func() *mcp.Server {
+		tools := []mcp.ToolDefinition{{
+			Metadata: g.Tools[0],
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return mcp.NewResult().Text(params.Arguments["text"].(string)).Build()
+			},
+		}}
+		prompts := []mcp.PromptDefinition{{
+			Metadata: g.Prompts[0],
+			Get: func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome " + params.Arguments["name"]))}, nil
+			},
+		}}
+		return mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...))
+	}
+
+	It("generates calls to declared tools with their required arguments", func() {
+		r := rand.New(rand.NewSource(GinkgoRandomSeed()))
+		for i := 0; i < 100; i++ {
+			req := g.Request(r, 10)
+			if params, ok := req.Params.(mcp.CallToolRequestParams); ok && params.Name == "echo" {
+				Expect(params.Arguments).To(HaveKey("text"))
+				Expect(params.Arguments["text"]).To(BeAssignableToTypeOf(""))
+			}
+		}
+	})
+
+	It("replies to every valid request with a well formed response", func() {
+		property := func(req mcptest.Request) bool {
+			msg, err := json.Marshal(req)
+			Expect(err).ToNot(HaveOccurred())
+			responses := exchange(newServer(), msg)
+			Expect(responses).To(HaveLen(1), string(msg))
+			Expect(mcptest.ValidateResponse(msg, responses[0])).To(Succeed(), string(msg))
+			return true
+		}
+		Expect(quick.Check(property, &quick.Config{Rand: rand.New(rand.NewSource(GinkgoRandomSeed())), Values: func(v []reflect.Value, r *rand.Rand) {
+			v[0] = reflect.ValueOf(g.Request(r, 20))
+		}})).To(Succeed())
+	})
+
+	It("rejects malformed requests with protocol errors", func() {
+		property := func(msg mcptest.MalformedRequest) bool {
+			for _, response := range exchange(newServer(), msg) {
+				Expect(mcptest.ValidateResponse(msg, response)).To(Succeed(), string(msg))
+				var resp struct {
+					Error *jsonrpc2.Error `json:"error"`
+				}
+				Expect(json.Unmarshal(response, &resp)).To(Succeed())
+				Expect(resp.Error).ToNot(BeNil(), fmt.Sprintf("%s -> %s", msg, response))
+				Expect(resp.Error.Code).To(BeElementOf([]int64{
+					jsonrpc2.CodeParseError, jsonrpc2.CodeInvalidRequest, jsonrpc2.CodeMethodNotFound, jsonrpc2.CodeInvalidParams,
+				}), string(msg))
+			}
+			return true
+		}
+		Expect(quick.Check(property, &quick.Config{Rand: rand.New(rand.NewSource(GinkgoRandomSeed()))})).To(Succeed())
+	})
+})
+
+// exchange returns the messages s writes in reply to msg.
+func exchange(s *mcp.Server, msg []byte) []json.RawMessage {
+	var responses []json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(s.HandleRaw(context.Background(), msg)))
+	for dec.More() {
+		var response json.RawMessage
+		Expect(dec.Decode(&response)).To(Succeed())
+		responses = append(responses, response)
+	}
+	return responses
+}
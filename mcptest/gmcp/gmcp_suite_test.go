@@ -0,0 +1,13 @@
+package gmcp_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGmcp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gmcp Suite")
+}
@@ -0,0 +1,226 @@
+// Package gmcp provides Gomega matchers for MCP messages, so tests can
+// assert on what a message means rather than its exact encoding.
+package gmcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/onsi/gomega/types"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+// BeToolResultWithText succeeds if actual is a successful mcp.CallToolResult
+// with a text block equal to, or matching, expected.
+func BeToolResultWithText(expected any) types.GomegaMatcher {
+	return &toolResultMatcher{expected: expected}
+}
+
+// BeToolErrorWithText succeeds if actual is an mcp.CallToolResult reporting
+// a tool error with a text block equal to, or matching, expected.
+func BeToolErrorWithText(expected any) types.GomegaMatcher {
+	return &toolResultMatcher{expected: expected, isError: true}
+}
+
+type toolResultMatcher struct {
+	expected any
+	isError  bool
+}
+
+func (m *toolResultMatcher) Match(actual any) (bool, error) {
+	var result mcp.CallToolResult
+	switch a := actual.(type) {
+	case mcp.CallToolResult:
+		result = a
+	case *mcp.CallToolResult:
+		if a == nil {
+			return false, nil
+		}
+		result = *a
+	default:
+		return false, fmt.Errorf("expected an mcp.CallToolResult, got %T", actual)
+	}
+
+	if (result.IsError != nil && *result.IsError) != m.isError {
+		return false, nil
+	}
+	for _, c := range result.Content {
+		var text string
+		switch c := c.(type) {
+		case mcp.TextContent:
+			text = c.Text
+		case *mcp.TextContent:
+			text = c.Text
+		default:
+			continue
+		}
+		if ok, err := matches(m.expected, text); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (m *toolResultMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%#v\nto be a %s with text %s", actual, m.kind(), describe(m.expected))
+}
+
+func (m *toolResultMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%#v\nnot to be a %s with text %s", actual, m.kind(), describe(m.expected))
+}
+
+func (m *toolResultMatcher) kind() string {
+	if m.isError {
+		return "tool error"
+	}
+	return "successful tool result"
+}
+
+// BeJSONRPCError succeeds if actual is a JSON-RPC error with code. Actual
+// may be an error returned by jsonrpc2.Conn.Call, a *jsonrpc2.Error, or an
+// encoded response.
+func BeJSONRPCError(code int64) types.GomegaMatcher {
+	return &jsonrpcErrorMatcher{code: code}
+}
+
+type jsonrpcErrorMatcher struct {
+	code int64
+}
+
+func (m *jsonrpcErrorMatcher) Match(actual any) (bool, error) {
+	var rpcErr *jsonrpc2.Error
+	switch a := actual.(type) {
+	case nil:
+		return false, nil
+	case error:
+		if !errors.As(a, &rpcErr) {
+			return false, nil
+		}
+	case []byte, string, json.RawMessage:
+		var resp struct {
+			Error *jsonrpc2.Error `json:"error"`
+		}
+		if err := json.Unmarshal(toBytes(a), &resp); err != nil {
+			return false, fmt.Errorf("expected a JSON-RPC response: %w", err)
+		}
+		rpcErr = resp.Error
+	default:
+		return false, fmt.Errorf("expected an error or encoded JSON-RPC response, got %T", actual)
+	}
+	return rpcErr != nil && rpcErr.Code == m.code, nil
+}
+
+func (m *jsonrpcErrorMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%s\nto be a JSON-RPC error with code %d", describe(actual), m.code)
+}
+
+func (m *jsonrpcErrorMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%s\nnot to be a JSON-RPC error with code %d", describe(actual), m.code)
+}
+
+// HaveNotification succeeds if actual has received a notification with
+// method. Actual may be an *mcptest.Pair, an *mcptest.FakeNotifier or a
+// []mcptest.Notification. If params is given, the notification's encoded
+// params must also equal, or match, params[0].
+func HaveNotification(method string, params ...any) types.GomegaMatcher {
+	m := &notificationMatcher{method: method}
+	if len(params) > 0 {
+		m.params = params[0]
+	}
+	return m
+}
+
+type notificationMatcher struct {
+	method string
+	params any
+}
+
+func (m *notificationMatcher) Match(actual any) (bool, error) {
+	var notifications []mcptest.Notification
+	switch a := actual.(type) {
+	case interface{ Notifications() []mcptest.Notification }:
+		notifications = a.Notifications()
+	case []mcptest.Notification:
+		notifications = a
+	default:
+		return false, fmt.Errorf("expected notifications, got %T", actual)
+	}
+	for _, n := range notifications {
+		if n.Method != m.method {
+			continue
+		}
+		if m.params == nil {
+			return true, nil
+		}
+		if ok, err := matches(m.params, n.Params); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (m *notificationMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%s\nto have a %q notification%s", describe(actual), m.method, m.paramsDescription())
+}
+
+func (m *notificationMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf("Expected\n\t%s\nnot to have a %q notification%s", describe(actual), m.method, m.paramsDescription())
+}
+
+func (m *notificationMatcher) paramsDescription() string {
+	if m.params == nil {
+		return ""
+	}
+	return " with params " + describe(m.params)
+}
+
+// matches reports whether actual equals expected or, if expected is a
+// matcher, whether it matches actual.
+func matches(expected, actual any) (bool, error) {
+	if matcher, ok := expected.(types.GomegaMatcher); ok {
+		return matcher.Match(actual)
+	}
+	if raw, ok := actual.(json.RawMessage); ok {
+		if _, isString := expected.(string); isString {
+			return string(raw) == expected, nil
+		}
+	}
+	return reflect.DeepEqual(expected, actual), nil
+}
+
+func toBytes(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case json.RawMessage:
+		return v
+	default:
+		return v.([]byte)
+	}
+}
+
+func describe(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case json.RawMessage:
+		return string(v)
+	case interface{ Notifications() []mcptest.Notification }:
+		return describe(v.Notifications())
+	case []mcptest.Notification:
+		s := "["
+		for i, n := range v {
+			if i > 0 {
+				s += ", "
+			}
+			s += n.Method + " " + string(n.Params)
+		}
+		return s + "]"
+	}
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,88 @@
+package gmcp_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+	. "github.com/acrmp/mcp/mcptest/gmcp"
+)
+
+var _ = Describe("Matchers", func() {
+
+	Describe("BeToolResultWithText", func() {
+		It("matches text content of a successful result", func() {
+			result, err := mcp.NewResult().ImageData([]byte{1}, "image/png").Text("hello world").Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(BeToolResultWithText("hello world"))
+			Expect(&result).To(BeToolResultWithText(ContainSubstring("world")))
+			Expect(result).ToNot(BeToolResultWithText("goodbye"))
+		})
+
+		It("does not match tool errors", func() {
+			result, err := mcp.NewResult().Text("boom").Error(true).Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).ToNot(BeToolResultWithText("boom"))
+			Expect(result).To(BeToolErrorWithText("boom"))
+		})
+
+		It("errors on values that are not results", func() {
+			_, err := BeToolResultWithText("hello").Match("hello")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("BeJSONRPCError", func() {
+		It("matches errors returned by a call", func() {
+			err := error(&jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"})
+			Expect(err).To(BeJSONRPCError(jsonrpc2.CodeMethodNotFound))
+			Expect(err).ToNot(BeJSONRPCError(jsonrpc2.CodeInvalidParams))
+			Expect(errors.New("method not found")).ToNot(BeJSONRPCError(jsonrpc2.CodeMethodNotFound))
+		})
+
+		It("matches encoded responses", func() {
+			Expect(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`).To(BeJSONRPCError(jsonrpc2.CodeInvalidParams))
+			Expect([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)).ToNot(BeJSONRPCError(jsonrpc2.CodeInvalidParams))
+		})
+
+		It("does not match a nil error", func() {
+			Expect(nil).ToNot(BeJSONRPCError(jsonrpc2.CodeInternalError))
+		})
+
+		It("matches errors from a server", func() {
+			pair := mcptest.NewPair(GinkgoT(), mcp.NewServer(mcp.Implementation{Name: "test"}, nil))
+			_, err := pair.CallTool(context.Background(), "missing", nil)
+			Expect(err).To(BeJSONRPCError(jsonrpc2.CodeInvalidParams))
+		})
+	})
+
+	Describe("HaveNotification", func() {
+		var n *mcptest.FakeNotifier
+
+		BeforeEach(func() {
+			n = &mcptest.FakeNotifier{}
+			Expect(n.Notify(context.Background(), "notifications/progress", map[string]any{"progressToken": "t", "progress": 1})).To(Succeed())
+		})
+
+		It("matches notifications by method", func() {
+			Expect(n).To(HaveNotification("notifications/progress"))
+			Expect(n).ToNot(HaveNotification("notifications/message"))
+			Expect(n.Notifications()).To(HaveNotification("notifications/progress"))
+		})
+
+		It("matches notifications by params", func() {
+			Expect(n).To(HaveNotification("notifications/progress", MatchJSON(`{"progress":1,"progressToken":"t"}`)))
+			Expect(n).ToNot(HaveNotification("notifications/progress", MatchJSON(`{"progress":2,"progressToken":"t"}`)))
+		})
+
+		It("errors on values without notifications", func() {
+			_, err := HaveNotification("notifications/progress").Match("notifications/progress")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package mcptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to any value,
+// makes ExpectGolden write golden files instead of comparing against them.
+const UpdateGoldenEnv = "MCPTEST_UPDATE_GOLDEN"
+
+// snapshotLists are the list methods rendered by Snapshot, with the result
+// field holding each list and the field each list is sorted by.
+var snapshotLists = []struct {
+	method, field, key string
+}{
+	{"tools/list", "tools", "name"},
+	{"prompts/list", "prompts", "name"},
+	{"resources/list", "resources", "uri"},
+}
+
+// Snapshot renders the tools, prompts and resources offered by the server to
+// stable, pretty-printed JSON for comparison with a golden file. Lists are
+// sorted and object keys are ordered, so the output changes only when the
+// metadata does. Lists the server does not support are left out.
+func (p *Pair) Snapshot(ctx context.Context) ([]byte, error) {
+	snapshot := map[string]any{}
+	for _, l := range snapshotLists {
+		var result map[string]any
+		if err := p.Conn.Call(ctx, l.method, nil, &result); err != nil {
+			var rpcErr *jsonrpc2.Error
+			if errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc2.CodeMethodNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", l.method, err)
+		}
+		items, _ := result[l.field].([]any)
+		sort.SliceStable(items, func(i, j int) bool {
+			return sortKey(items[i], l.key) < sortKey(items[j], l.key)
+		})
+		if items == nil {
+			items = []any{}
+		}
+		snapshot[l.field] = items
+	}
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func sortKey(item any, key string) string {
+	m, _ := item.(map[string]any)
+	s, _ := m[key].(string)
+	return s
+}
+
+// ExpectGolden fails the test if got differs from the contents of the golden
+// file at path. When UpdateGoldenEnv is set the file is written with got
+// instead, so that a reviewer sees the change in the diff.
+func ExpectGolden(t T, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s does not match (set %s=1 to update it)\ngot:\n%s\nwant:\n%s", path, UpdateGoldenEnv, got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package mcptest_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Golden snapshots", func() {
+
+	var pair *mcptest.Pair
+
+	BeforeEach(func() {
+		desc := "Say hello"
+		tools := []mcp.ToolDefinition{
+			{Metadata: mcp.Tool{Name: "greet", Description: &desc, InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: mcp.ToolInputSchemaProperties{"name": {"type": "string", "description": "Who to greet"}},
+				Required:   []string{"name"},
+			}}},
+			{Metadata: mcp.Tool{Name: "count", InputSchema: mcp.ToolInputSchema{Type: "object"}}},
+		}
+		prompts := []mcp.PromptDefinition{{Metadata: mcp.Prompt{Name: "welcome"}}}
+		pair = mcptest.NewPair(GinkgoT(), mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...)))
+	})
+
+	It("renders list responses to stable JSON", func() {
+		snapshot, err := pair.Snapshot(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		mcptest.ExpectGolden(GinkgoT(), filepath.Join("testdata", "snapshot.golden"), snapshot)
+	})
+
+	Describe("ExpectGolden", func() {
+		var (
+			t    *fakeT
+			path string
+		)
+
+		BeforeEach(func() {
+			t = &fakeT{}
+			dir, err := os.MkdirTemp("", "golden")
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, dir)
+			path = filepath.Join(dir, "list.golden")
+
+			if update, ok := os.LookupEnv(mcptest.UpdateGoldenEnv); ok {
+				os.Unsetenv(mcptest.UpdateGoldenEnv)
+				DeferCleanup(os.Setenv, mcptest.UpdateGoldenEnv, update)
+			}
+		})
+
+		It("fails when the golden file differs", func() {
+			Expect(os.WriteFile(path, []byte("{}\n"), 0o644)).To(Succeed())
+			mcptest.ExpectGolden(t, path, []byte(`{"tools":[]}`+"\n"))
+			Expect(t.failure).To(ContainSubstring("list.golden does not match"))
+		})
+
+		It("fails when the golden file is missing", func() {
+			mcptest.ExpectGolden(t, path, []byte("{}\n"))
+			Expect(t.failure).To(ContainSubstring("set MCPTEST_UPDATE_GOLDEN=1 to create it"))
+		})
+
+		It("writes the golden file when updating", func() {
+			os.Setenv(mcptest.UpdateGoldenEnv, "1")
+			DeferCleanup(os.Unsetenv, mcptest.UpdateGoldenEnv)
+
+			mcptest.ExpectGolden(t, path, []byte("{}\n"))
+			Expect(t.failure).To(BeEmpty())
+			Expect(os.ReadFile(path)).To(Equal([]byte("{}\n")))
+		})
+	})
+})
+
+// fakeT records the failure reported by a helper rather than failing the
+// spec.
+type fakeT struct {
+	failure string
+}
+
+func (t *fakeT) Helper()        {}
+func (t *fakeT) Cleanup(func()) {}
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.failure = fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,13 @@
+package mcptest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMcptest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "mcptest Suite")
+}
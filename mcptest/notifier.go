@@ -0,0 +1,55 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// FakeNotifier is an mcp.Notifier that records the notifications sent to it,
+// so that ToolDefinition.Process functions can be tested without a
+// connection.
+type FakeNotifier struct {
+	// Err, if set, is returned by Notify instead of recording the
+	// notification.
+	Err error
+
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (f *FakeNotifier) Notify(_ context.Context, method string, params any) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	n := Notification{Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		n.Params = b
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+// Notifications returns the notifications recorded, in the order they were
+// sent.
+func (f *FakeNotifier) Notifications() []Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Notification(nil), f.notifications...)
+}
+
+// Methods returns the methods of the notifications recorded, in the order
+// they were sent.
+func (f *FakeNotifier) Methods() []string {
+	var methods []string
+	for _, n := range f.Notifications() {
+		methods = append(methods, n.Method)
+	}
+	return methods
+}
@@ -0,0 +1,61 @@
+package mcptest_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+// countDown notifies its progress before returning.
+func countDown(ctx context.Context, params mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+	for i := 1; i <= 3; i++ {
+		if err := n.Notify(ctx, "notifications/progress", map[string]any{"progressToken": "t", "progress": i, "total": 3}); err != nil {
+			return mcp.CallToolResult{}, err
+		}
+	}
+	return mcp.NewResult().Text("done").Build()
+}
+
+var _ = Describe("FakeNotifier", func() {
+
+	It("records notifications in order", func() {
+		n := &mcptest.FakeNotifier{}
+		result, err := countDown(context.Background(), mcp.CallToolRequestParams{}, n)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("done")}))
+
+		Expect(n.Methods()).To(Equal([]string{"notifications/progress", "notifications/progress", "notifications/progress"}))
+		Expect(n.Notifications()[2].Params).To(MatchJSON(`{"progressToken":"t","progress":3,"total":3}`))
+	})
+
+	It("can be configured to fail", func() {
+		n := &mcptest.FakeNotifier{Err: errors.New("connection closed")}
+		_, err := countDown(context.Background(), mcp.CallToolRequestParams{}, n)
+		Expect(err).To(MatchError("connection closed"))
+		Expect(n.Notifications()).To(BeEmpty())
+	})
+
+	It("matches the notifications a connected client receives", func() {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "count_down", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Process:  countDown,
+		}}
+		pair := mcptest.NewPair(GinkgoT(), mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools))
+		_, err := pair.CallTool(context.Background(), "count_down", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(pair.Notifications).Should(HaveLen(3))
+		Expect(pair.Notifications()[0]).To(Equal(mcptest.Notification{
+			Method: "notifications/progress",
+			Params: []byte(`{"progress":1,"progressToken":"t","total":3}`),
+		}))
+		n, err := pair.WaitForNotification(context.Background(), "notifications/progress")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n.Params).To(MatchJSON(`{"progressToken":"t","progress":1,"total":3}`))
+	})
+})
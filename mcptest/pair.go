@@ -0,0 +1,144 @@
+// Package mcptest provides utilities for testing MCP servers built with the
+// mcp package.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+// T is the subset of testing.TB used by this package. It is satisfied by
+// *testing.T and by GinkgoT().
+type T interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// Notification is a notification received from the server.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Pair is an in-memory client connected to a server.
+type Pair struct {
+	// Conn is the client side of the connection.
+	Conn *jsonrpc2.Conn
+
+	mu            sync.Mutex
+	notifications []Notification
+	notified      chan struct{}
+}
+
+// NewPair serves s in-memory and returns a client connected to it. The
+// connection is closed when the test completes.
+func NewPair(t T, s *mcp.Server) *Pair {
+	t.Helper()
+	return NewPairContext(t, context.Background(), s)
+}
+
+// NewPairContext is like NewPair but serves s with ctx, so values such as
+// the Principal or session ID it carries are visible to handlers.
+func NewPairContext(t T, ctx context.Context, s *mcp.Server) *Pair {
+	t.Helper()
+	serverSide, clientSide := net.Pipe()
+	go s.ServeStream(ctx, jsonrpc2.NewPlainObjectStream(serverSide))
+
+	p := &Pair{notified: make(chan struct{}, 1)}
+	p.Conn = jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), jsonrpc2.HandlerWithError(p.handle))
+	t.Cleanup(func() {
+		p.Conn.Close()
+	})
+	return p
+}
+
+func (p *Pair) handle(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+	if !req.Notif {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}
+	}
+	n := Notification{Method: req.Method}
+	if req.Params != nil {
+		n.Params = *req.Params
+	}
+	p.mu.Lock()
+	p.notifications = append(p.notifications, n)
+	p.mu.Unlock()
+	select {
+	case p.notified <- struct{}{}:
+	default:
+	}
+	return nil, nil
+}
+
+// Notifications returns the notifications received from the server so far,
+// in the order they were received.
+func (p *Pair) Notifications() []Notification {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Notification(nil), p.notifications...)
+}
+
+// WaitForNotification waits until a notification with method has been
+// received and returns the first one.
+func (p *Pair) WaitForNotification(ctx context.Context, method string) (Notification, error) {
+	for {
+		for _, n := range p.Notifications() {
+			if n.Method == method {
+				return n, nil
+			}
+		}
+		select {
+		case <-p.notified:
+		case <-ctx.Done():
+			return Notification{}, ctx.Err()
+		}
+	}
+}
+
+// Initialize performs the initialization handshake.
+func (p *Pair) Initialize(ctx context.Context) (mcp.InitializeResult, error) {
+	var result mcp.InitializeResult
+	err := p.Conn.Call(ctx, "initialize", mcp.InitializeRequestParams{
+		ProtocolVersion: mcp.SupportedProtocolVersion,
+		ClientInfo:      mcp.Implementation{Name: "mcptest", Version: "1.0.0"},
+	}, &result)
+	if err != nil {
+		return result, err
+	}
+	return result, p.Conn.Notify(ctx, "notifications/initialized", nil)
+}
+
+// ListTools lists the tools offered by the server.
+func (p *Pair) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	var result mcp.ListToolsResult
+	err := p.Conn.Call(ctx, "tools/list", nil, &result)
+	return result.Tools, err
+}
+
+// CallTool calls the tool name with args.
+func (p *Pair) CallTool(ctx context.Context, name string, args map[string]any) (mcp.CallToolResult, error) {
+	var result mcp.CallToolResult
+	err := p.Conn.Call(ctx, "tools/call", mcp.CallToolRequestParams{Name: name, Arguments: args}, &result)
+	return result, err
+}
+
+// ListPrompts lists the prompts offered by the server.
+func (p *Pair) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	var result mcp.ListPromptsResult
+	err := p.Conn.Call(ctx, "prompts/list", nil, &result)
+	return result.Prompts, err
+}
+
+// GetPrompt renders the prompt name with args.
+func (p *Pair) GetPrompt(ctx context.Context, name string, args map[string]string) (mcp.GetPromptResult, error) {
+	var result mcp.GetPromptResult
+	err := p.Conn.Call(ctx, "prompts/get", mcp.GetPromptRequestParams{Name: name, Arguments: args}, &result)
+	return result, err
+}
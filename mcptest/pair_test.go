@@ -0,0 +1,64 @@
+package mcptest_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Pair", func() {
+
+	var pair *mcptest.Pair
+
+	BeforeEach(func() {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "greet", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return mcp.NewResult().Text("Hello, " + params.Arguments["name"].(string)).Build()
+			},
+		}}
+		prompts := []mcp.PromptDefinition{{
+			Metadata: mcp.Prompt{Name: "welcome"},
+			Get: func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Welcome " + params.Arguments["name"]))}, nil
+			},
+		}}
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithPrompts(prompts...))
+		pair = mcptest.NewPair(GinkgoT(), s)
+	})
+
+	It("initializes", func() {
+		result, err := pair.Initialize(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.ServerInfo.Name).To(Equal("TestServer"))
+	})
+
+	It("lists and calls tools", func() {
+		tools, err := pair.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(HaveLen(1))
+
+		result, err := pair.CallTool(context.Background(), "greet", map[string]any{"name": "Ada"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("Hello, Ada")}))
+	})
+
+	It("lists and gets prompts", func() {
+		prompts, err := pair.ListPrompts(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(prompts).To(Equal([]mcp.Prompt{{Name: "welcome"}}))
+
+		result, err := pair.GetPrompt(context.Background(), "welcome", map[string]string{"name": "Ada"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Messages).To(Equal(mcp.UserMessage(mcp.NewTextContent("Welcome Ada"))))
+	})
+
+	It("reports protocol errors", func() {
+		_, err := pair.CallTool(context.Background(), "unknown", nil)
+		Expect(err).To(MatchError(ContainSubstring("Unknown tool: unknown")))
+	})
+})
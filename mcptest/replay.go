@@ -0,0 +1,252 @@
+package mcptest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+// Sides of a transcript.
+const (
+	FromClient = "client"
+	FromServer = "server"
+)
+
+// TranscriptEntry is a message exchanged during a session. A transcript is
+// encoded as JSON lines, one entry per line.
+type TranscriptEntry struct {
+	From    string          `json:"from"`
+	Message json.RawMessage `json:"message"`
+}
+
+// ReadTranscript decodes the transcript read from r.
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var e TranscriptEntry
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("reading transcript entry %d: %w", len(entries)+1, err)
+		}
+		if e.From != FromClient && e.From != FromServer {
+			return nil, fmt.Errorf("transcript entry %d is from %q, want %q or %q", len(entries)+1, e.From, FromClient, FromServer)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+type recordingStream struct {
+	stream jsonrpc2.ObjectStream
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// RecordingStream wraps the stream a server is served on so that every
+// message exchanged is written to w as a transcript, for replay with Replay.
+func RecordingStream(stream jsonrpc2.ObjectStream, w io.Writer) jsonrpc2.ObjectStream {
+	return &recordingStream{stream: stream, enc: json.NewEncoder(w)}
+}
+
+func (s *recordingStream) WriteObject(obj interface{}) error {
+	msg, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	s.record(FromServer, msg)
+	return s.stream.WriteObject(json.RawMessage(msg))
+}
+
+func (s *recordingStream) ReadObject(v interface{}) error {
+	var msg json.RawMessage
+	if err := s.stream.ReadObject(&msg); err != nil {
+		return err
+	}
+	s.record(FromClient, msg)
+	return json.Unmarshal(msg, v)
+}
+
+func (s *recordingStream) Close() error {
+	return s.stream.Close()
+}
+
+func (s *recordingStream) record(from string, msg json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(TranscriptEntry{From: from, Message: msg})
+}
+
+// Replayer replays transcripts against a server.
+type Replayer struct {
+	// IgnoreFields names object keys whose values are not compared, at any
+	// depth, such as those holding generated identifiers.
+	IgnoreFields []string
+	// Timeout bounds how long to wait for each message from the server.
+	// It defaults to five seconds.
+	Timeout time.Duration
+}
+
+// Mismatch is a server message that differs from the one recorded.
+type Mismatch struct {
+	// Entry is the index of the recorded entry in the transcript.
+	Entry int
+	Want  json.RawMessage
+	// Got is nil if the server sent no message in time.
+	Got json.RawMessage
+}
+
+func (m Mismatch) String() string {
+	got := "nothing"
+	if m.Got != nil {
+		got = string(m.Got)
+	}
+	return fmt.Sprintf("entry %d:\n  want: %s\n  got:  %s", m.Entry, m.Want, got)
+}
+
+// Replay sends the client messages of transcript to s in order, and compares
+// each message s sends with the next one recorded from the server. The IDs
+// of messages and values that look like timestamps are not compared, nor are
+// the fields named by IgnoreFields.
+func (rp Replayer) Replay(ctx context.Context, s *mcp.Server, transcript []TranscriptEntry) ([]Mismatch, error) {
+	timeout := rp.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	stream := jsonrpc2.NewPlainObjectStream(replayStream{inR, outW})
+	go s.ServeStream(ctx, stream)
+	defer stream.Close()
+	defer inW.Close()
+
+	received := make(chan json.RawMessage)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		dec := json.NewDecoder(bufio.NewReader(outR))
+		for {
+			var msg json.RawMessage
+			if err := dec.Decode(&msg); err != nil {
+				return
+			}
+			select {
+			case received <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var mismatches []Mismatch
+	for i, e := range transcript {
+		if e.From == FromClient {
+			if _, err := inW.Write(append(slices.Clone(e.Message), '\n')); err != nil {
+				return mismatches, fmt.Errorf("sending entry %d: %w", i, err)
+			}
+			continue
+		}
+		var got json.RawMessage
+		select {
+		case got = <-received:
+		case <-time.After(timeout):
+		case <-ctx.Done():
+			return mismatches, ctx.Err()
+		}
+		if !rp.equal(e.Message, got) {
+			mismatches = append(mismatches, Mismatch{Entry: i, Want: e.Message, Got: got})
+		}
+	}
+	return mismatches, nil
+}
+
+// ExpectReplay fails the test if replaying the transcript at path against s
+// produces any mismatches.
+func (rp Replayer) ExpectReplay(t T, s *mcp.Server, path string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening transcript: %v", err)
+		return
+	}
+	defer f.Close()
+	transcript, err := ReadTranscript(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+		return
+	}
+	mismatches, err := rp.Replay(context.Background(), s, transcript)
+	if err != nil {
+		t.Fatalf("replaying %s: %v", path, err)
+		return
+	}
+	if len(mismatches) > 0 {
+		diffs := make([]string, len(mismatches))
+		for i, m := range mismatches {
+			diffs[i] = m.String()
+		}
+		t.Fatalf("replaying %s: %d messages differ\n%s", path, len(mismatches), strings.Join(diffs, "\n"))
+	}
+}
+
+func (rp Replayer) equal(want, got json.RawMessage) bool {
+	if got == nil {
+		return false
+	}
+	var w, g any
+	if json.Unmarshal(want, &w) != nil || json.Unmarshal(got, &g) != nil {
+		return false
+	}
+	if wm, ok := w.(map[string]any); ok {
+		delete(wm, "id")
+	}
+	if gm, ok := g.(map[string]any); ok {
+		delete(gm, "id")
+	}
+	return reflect.DeepEqual(rp.normalize(w), rp.normalize(g))
+}
+
+func (rp Replayer) normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if slices.Contains(rp.IgnoreFields, k) {
+				delete(v, k)
+			} else {
+				v[k] = rp.normalize(e)
+			}
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = rp.normalize(e)
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "<timestamp>"
+		}
+	}
+	return v
+}
+
+type replayStream struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (s replayStream) Close() error {
+	s.PipeReader.Close()
+	return s.PipeWriter.Close()
+}
@@ -0,0 +1,101 @@
+package mcptest_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Replayer", func() {
+
+	newServer := func(greeting string) *mcp.Server {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "greet", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return mcp.NewResult().
+					Text(greeting + ", " + params.Arguments["name"].(string)).
+					Text(time.Now().UTC().Format(time.RFC3339)).
+					Build()
+			},
+		}}
+		return mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools)
+	}
+
+	It("replays a recorded session", func() {
+		mcptest.Replayer{}.ExpectReplay(GinkgoT(), newServer("Hello"), filepath.Join("testdata", "greet.transcript"))
+	})
+
+	It("reports messages that differ from those recorded", func() {
+		transcript, err := mcptest.ReadTranscript(strings.NewReader(`
+{"from":"client","message":{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Ada"}}}}
+{"from":"server","message":{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"Hello, Ada"},{"type":"text","text":"2024-01-02T03:04:05Z"}]}}}
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		mismatches, err := mcptest.Replayer{}.Replay(context.Background(), newServer("Goodbye"), transcript)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Entry).To(Equal(1))
+		Expect(mismatches[0].Got).To(ContainSubstring("Goodbye, Ada"))
+	})
+
+	It("reports recorded messages the server does not send", func() {
+		transcript := []mcptest.TranscriptEntry{
+			{From: mcptest.FromClient, Message: []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)},
+			{From: mcptest.FromServer, Message: []byte(`{"jsonrpc":"2.0","method":"notifications/message"}`)},
+		}
+		mismatches, err := mcptest.Replayer{Timeout: 50 * time.Millisecond}.Replay(context.Background(), newServer("Hello"), transcript)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Got).To(BeNil())
+		Expect(mismatches[0].String()).To(ContainSubstring("got:  nothing"))
+	})
+
+	It("ignores the named fields", func() {
+		transcript := []mcptest.TranscriptEntry{
+			{From: mcptest.FromClient, Message: []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)},
+			{From: mcptest.FromServer, Message: []byte(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"OtherServer","version":"2.0.0"}}}`)},
+		}
+		mismatches, err := mcptest.Replayer{IgnoreFields: []string{"serverInfo"}}.Replay(context.Background(), newServer("Hello"), transcript)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mismatches).To(BeEmpty())
+	})
+
+	It("rejects transcripts with unknown sides", func() {
+		_, err := mcptest.ReadTranscript(strings.NewReader(`{"from":"proxy","message":{}}`))
+		Expect(err).To(MatchError(`transcript entry 1 is from "proxy", want "client" or "server"`))
+	})
+
+	It("records transcripts that can be replayed", func() {
+		var transcript bytes.Buffer
+		serverSide, clientSide := net.Pipe()
+		s := newServer("Hello")
+		go s.ServeStream(context.Background(), mcptest.RecordingStream(jsonrpc2.NewPlainObjectStream(serverSide), &transcript))
+		conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+			return nil, nil
+		}))
+		var result mcp.CallToolResult
+		Expect(conn.Call(context.Background(), "tools/call", mcp.CallToolRequestParams{Name: "greet", Arguments: map[string]any{"name": "Ada"}}, &result)).To(Succeed())
+		Expect(conn.Close()).To(Succeed())
+
+		entries, err := mcptest.ReadTranscript(&transcript)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].From).To(Equal(mcptest.FromClient))
+		Expect(entries[1].From).To(Equal(mcptest.FromServer))
+
+		mismatches, err := mcptest.Replayer{}.Replay(context.Background(), s, entries)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mismatches).To(BeEmpty())
+	})
+})
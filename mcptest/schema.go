@@ -0,0 +1,256 @@
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/acrmp/mcp"
+)
+
+// ValidateToolSchemas returns an error describing every problem found in the
+// input schemas of tools. Each schema must be a valid JSON Schema describing
+// an object, and every required property must be declared. Unknown keywords
+// are reported too, since they are almost always misspellings; keywords
+// prefixed with "x-" are allowed as extensions.
+//
+// Schemas are checked as clients see them, after encoding to JSON.
+func ValidateToolSchemas(tools ...mcp.Tool) error {
+	var errs []error
+	for _, t := range tools {
+		b, err := json.Marshal(t.InputSchema)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tool %q: encoding input schema: %w", t.Name, err))
+			continue
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(b, &schema); err != nil {
+			errs = append(errs, fmt.Errorf("tool %q: decoding input schema: %w", t.Name, err))
+			continue
+		}
+		v := &schemaValidator{tool: t.Name}
+		if schema["type"] != "object" {
+			v.errorf("inputSchema.type", "must be %q, got %v", "object", schema["type"])
+		}
+		v.validate("inputSchema", schema)
+		errs = append(errs, v.errs...)
+	}
+	return errors.Join(errs...)
+}
+
+// ExpectValidToolSchemas fails the test if the input schema of any tool
+// registered with s is invalid, as reported by ValidateToolSchemas.
+func ExpectValidToolSchemas(t T, s *mcp.Server) {
+	t.Helper()
+	tools, err := NewPair(t, s).ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("listing tools: %v", err)
+	}
+	if err := ValidateToolSchemas(tools...); err != nil {
+		t.Fatalf("invalid tool schemas:\n%v", err)
+	}
+}
+
+var schemaTypes = []string{"array", "boolean", "integer", "null", "number", "object", "string"}
+
+// schemaKeywords maps the keywords of JSON Schema to the kind of value each
+// takes.
+var schemaKeywords = map[string]string{
+	"$schema": "string", "$id": "string", "$ref": "string", "$anchor": "string",
+	"$dynamicRef": "string", "$dynamicAnchor": "string", "$comment": "string",
+	"$defs": "schemas", "definitions": "schemas", "$vocabulary": "object",
+
+	"type": "type", "enum": "array", "const": "any",
+
+	"title": "string", "description": "string", "default": "any",
+	"examples": "array", "deprecated": "boolean", "readOnly": "boolean",
+	"writeOnly": "boolean",
+
+	"allOf": "schemaArray", "anyOf": "schemaArray", "oneOf": "schemaArray",
+	"not": "schema", "if": "schema", "then": "schema", "else": "schema",
+
+	"properties": "schemas", "patternProperties": "schemas",
+	"additionalProperties": "schema", "unevaluatedProperties": "schema",
+	"propertyNames": "schema", "dependentSchemas": "schemas",
+	"dependentRequired": "object", "dependencies": "object",
+	"required": "stringArray", "minProperties": "count", "maxProperties": "count",
+
+	"items": "items", "prefixItems": "schemaArray", "additionalItems": "schema",
+	"unevaluatedItems": "schema", "contains": "schema", "minContains": "count",
+	"maxContains": "count", "minItems": "count", "maxItems": "count",
+	"uniqueItems": "boolean",
+
+	"minLength": "count", "maxLength": "count", "pattern": "string",
+	"format": "string", "contentEncoding": "string", "contentMediaType": "string",
+	"contentSchema": "schema",
+
+	"minimum": "number", "maximum": "number", "exclusiveMinimum": "number",
+	"exclusiveMaximum": "number", "multipleOf": "positive",
+}
+
+type schemaValidator struct {
+	tool string
+	errs []error
+}
+
+func (v *schemaValidator) errorf(path, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("tool %q: %s: %s", v.tool, path, fmt.Sprintf(format, args...)))
+}
+
+func (v *schemaValidator) validate(path string, schema any) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		if _, ok := schema.(bool); !ok {
+			v.errorf(path, "schema must be an object or boolean, got %s", kindOf(schema))
+		}
+		return
+	}
+
+	for _, k := range sortedKeys(s) {
+		value, p := s[k], path+"."+k
+		kind, known := schemaKeywords[k]
+		switch {
+		case !known && strings.HasPrefix(k, "x-"):
+		case !known:
+			v.errorf(path, "unknown keyword %q", k)
+		case kind == "any":
+		case kind == "string" || kind == "boolean" || kind == "number" || kind == "object" || kind == "array":
+			if kindOf(value) != kind {
+				v.errorf(p, "must be %s %s, got %s", article(kind), kind, kindOf(value))
+			} else if k == "enum" && len(value.([]any)) == 0 {
+				v.errorf(p, "must not be empty")
+			}
+		case kind == "count":
+			if n, ok := value.(float64); !ok || n < 0 || n != math.Trunc(n) {
+				v.errorf(p, "must be a non-negative integer, got %v", value)
+			}
+		case kind == "positive":
+			if n, ok := value.(float64); !ok || n <= 0 {
+				v.errorf(p, "must be a number greater than zero, got %v", value)
+			}
+		case kind == "type":
+			v.validateType(p, value)
+		case kind == "stringArray":
+			v.validateStrings(p, value)
+		case kind == "schema":
+			v.validate(p, value)
+		case kind == "schemas":
+			props, ok := value.(map[string]any)
+			if !ok {
+				v.errorf(p, "must be an object, got %s", kindOf(value))
+				continue
+			}
+			for _, name := range sortedKeys(props) {
+				v.validate(p+"."+name, props[name])
+			}
+		case kind == "schemaArray":
+			schemas, ok := value.([]any)
+			if !ok || len(schemas) == 0 {
+				v.errorf(p, "must be a non-empty array of schemas")
+				continue
+			}
+			for i, sub := range schemas {
+				v.validate(fmt.Sprintf("%s[%d]", p, i), sub)
+			}
+		case kind == "items":
+			if schemas, ok := value.([]any); ok {
+				for i, sub := range schemas {
+					v.validate(fmt.Sprintf("%s[%d]", p, i), sub)
+				}
+			} else {
+				v.validate(p, value)
+			}
+		}
+	}
+
+	if required, ok := s["required"].([]any); ok {
+		props, _ := s["properties"].(map[string]any)
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, declared := props[name]; !declared {
+					v.errorf(path+".required", "property %q is required but not declared", name)
+				}
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateType(path string, value any) {
+	types, ok := value.([]any)
+	if !ok {
+		types = []any{value}
+	} else if len(types) == 0 {
+		v.errorf(path, "must not be empty")
+	}
+	seen := map[string]bool{}
+	for _, t := range types {
+		name, ok := t.(string)
+		switch {
+		case !ok:
+			v.errorf(path, "must be a string or array of strings, got %s", kindOf(t))
+		case !slices.Contains(schemaTypes, name):
+			v.errorf(path, "unknown type %q", name)
+		case seen[name]:
+			v.errorf(path, "type %q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+}
+
+func (v *schemaValidator) validateStrings(path string, value any) {
+	values, ok := value.([]any)
+	if !ok {
+		v.errorf(path, "must be an array of strings, got %s", kindOf(value))
+		return
+	}
+	seen := map[string]bool{}
+	for _, s := range values {
+		name, ok := s.(string)
+		switch {
+		case !ok:
+			v.errorf(path, "must be an array of strings, got an element that is %s", kindOf(s))
+		case seen[name]:
+			v.errorf(path, "%q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+}
+
+func kindOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func article(kind string) string {
+	if strings.IndexByte("aeiou", kind[0]) >= 0 {
+		return "an"
+	}
+	return "a"
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package mcptest_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("ValidateToolSchemas", func() {
+
+	tool := func(properties mcp.ToolInputSchemaProperties, required ...string) mcp.Tool {
+		return mcp.Tool{
+			Name:        "greet",
+			InputSchema: mcp.ToolInputSchema{Type: "object", Properties: properties, Required: required},
+		}
+	}
+
+	It("accepts valid schemas", func() {
+		Expect(mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{
+			"name":     {"type": "string", "description": "Who to greet", "minLength": 1},
+			"language": {"type": "string", "enum": []string{"en", "fr"}, "default": "en"},
+			"times":    {"type": []string{"integer", "null"}, "minimum": 1},
+			"tags": {"type": "array", "items": map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"key": map[string]any{"type": "string"}},
+				"required":             []string{"key"},
+				"additionalProperties": false,
+			}},
+			"x-internal": {"x-owner": "greeters"},
+		}, "name"))).To(Succeed())
+	})
+
+	It("reports unknown types", func() {
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"name": {"type": "strnig"}}))
+		Expect(err).To(MatchError(`tool "greet": inputSchema.properties.name.type: unknown type "strnig"`))
+	})
+
+	It("reports required properties that are not declared", func() {
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"name": {"type": "string"}}, "nmae"))
+		Expect(err).To(MatchError(`tool "greet": inputSchema.required: property "nmae" is required but not declared`))
+	})
+
+	It("reports required properties of nested objects that are not declared", func() {
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"address": {
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			"required":   []string{"town"},
+		}}))
+		Expect(err).To(MatchError(ContainSubstring(`inputSchema.properties.address.required: property "town" is required but not declared`)))
+	})
+
+	It("reports misspelled keywords", func() {
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"name": {"type": "string", "descripton": "Who to greet"}}))
+		Expect(err).To(MatchError(`tool "greet": inputSchema.properties.name: unknown keyword "descripton"`))
+	})
+
+	It("reports keywords with values of the wrong type", func() {
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"name": {"type": "string", "minLength": -1, "description": 5}}))
+		Expect(err).To(MatchError(ContainSubstring(`inputSchema.properties.name.description: must be a string, got number`)))
+		Expect(err).To(MatchError(ContainSubstring(`inputSchema.properties.name.minLength: must be a non-negative integer, got -1`)))
+	})
+
+	It("requires the input schema to describe an object", func() {
+		t := tool(nil)
+		t.InputSchema.Type = "array"
+		Expect(mcptest.ValidateToolSchemas(t)).To(MatchError(ContainSubstring(`inputSchema.type: must be "object", got array`)))
+	})
+
+	It("reports problems with every tool", func() {
+		other := tool(mcp.ToolInputSchemaProperties{"count": {"type": "int"}})
+		other.Name = "count"
+		err := mcptest.ValidateToolSchemas(tool(mcp.ToolInputSchemaProperties{"name": {"type": "strnig"}}), other)
+		Expect(err).To(MatchError(ContainSubstring(`tool "greet"`)))
+		Expect(err).To(MatchError(ContainSubstring(`tool "count"`)))
+	})
+
+	It("checks the tools registered with a server", func() {
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, []mcp.ToolDefinition{{
+			Metadata: tool(mcp.ToolInputSchemaProperties{"name": {"type": "string"}}, "name"),
+		}})
+		mcptest.ExpectValidToolSchemas(GinkgoT(), s)
+	})
+})
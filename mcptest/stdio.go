@@ -0,0 +1,222 @@
+package mcptest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// exitTimeout is how long Close waits for a process to exit after its stdin
+// is closed before killing it.
+const exitTimeout = 5 * time.Second
+
+// StdioClient drives a server process over its stdin and stdout, exchanging
+// newline delimited JSON-RPC messages.
+type StdioClient struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	mu            sync.Mutex
+	messages      []json.RawMessage
+	responses     map[jsonrpc2.ID][]json.RawMessage
+	notifications []Notification
+	received      chan struct{}
+	closed        chan struct{}
+	readErr       error
+	closeOnce     sync.Once
+	waitErr       error
+}
+
+// StartStdio starts cmd and returns a client connected to its stdin and
+// stdout. Stderr is left as configured on cmd. The process is stopped when
+// the test completes.
+func StartStdio(t T, cmd *exec.Cmd) *StdioClient {
+	t.Helper()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("creating stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("creating stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting %s: %v", cmd.Path, err)
+	}
+
+	c := &StdioClient{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: map[jsonrpc2.ID][]json.RawMessage{},
+		received:  make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+	go c.read(stdout)
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func (c *StdioClient) read(stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			c.receive(line)
+		}
+		if err != nil {
+			c.mu.Lock()
+			if !errors.Is(err, io.EOF) {
+				c.readErr = err
+			}
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+	}
+}
+
+func (c *StdioClient) receive(line []byte) {
+	var msg struct {
+		ID     *jsonrpc2.ID    `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	// messages that are not valid JSON are still recorded so that tests can
+	// assert on them
+	_ = json.Unmarshal(line, &msg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, json.RawMessage(line))
+	switch {
+	case msg.Method != "" && msg.ID == nil:
+		c.notifications = append(c.notifications, Notification{Method: msg.Method, Params: msg.Params})
+	case msg.Method == "" && msg.ID != nil:
+		c.responses[*msg.ID] = append(c.responses[*msg.ID], json.RawMessage(line))
+	}
+	close(c.received)
+	c.received = make(chan struct{})
+}
+
+// Send writes msg to the process followed by a newline. A string, []byte or
+// json.RawMessage is written as is; anything else is encoded as JSON first.
+func (c *StdioClient) Send(msg any) error {
+	b, err := marshalMessage(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(append(bytes.TrimSpace(b), '\n'))
+	return err
+}
+
+// Request sends the request msg and returns the response with the same ID.
+func (c *StdioClient) Request(ctx context.Context, msg any) (json.RawMessage, error) {
+	b, err := marshalMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	var req struct {
+		ID *jsonrpc2.ID `json:"id"`
+	}
+	if err := json.Unmarshal(b, &req); err != nil || req.ID == nil {
+		return nil, fmt.Errorf("request has no ID: %s", b)
+	}
+	if err := c.Send(b); err != nil {
+		return nil, err
+	}
+	return c.Response(ctx, *req.ID)
+}
+
+// Response waits for a response with id and returns it. Each response is
+// returned once, in the order received, so repeated requests may reuse an
+// ID.
+func (c *StdioClient) Response(ctx context.Context, id jsonrpc2.ID) (json.RawMessage, error) {
+	for {
+		c.mu.Lock()
+		if rs := c.responses[id]; len(rs) > 0 {
+			c.responses[id] = rs[1:]
+			c.mu.Unlock()
+			return rs[0], nil
+		}
+		received := c.received
+		c.mu.Unlock()
+
+		select {
+		case <-received:
+		case <-c.closed:
+			// the process may have written the response before exiting
+			c.mu.Lock()
+			rs, err := c.responses[id], c.readErr
+			c.mu.Unlock()
+			if len(rs) > 0 {
+				continue
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, fmt.Errorf("waiting for response %s: %w", id, err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Messages returns every message received from the process so far, in the
+// order they were received.
+func (c *StdioClient) Messages() []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]json.RawMessage(nil), c.messages...)
+}
+
+// Notifications returns the notifications received from the process so far,
+// in the order they were received.
+func (c *StdioClient) Notifications() []Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Notification(nil), c.notifications...)
+}
+
+// Exited returns a channel that is closed once the process closes its
+// stdout, normally because it has exited.
+func (c *StdioClient) Exited() <-chan struct{} {
+	return c.closed
+}
+
+// Close closes the process's stdin and waits for it to exit, killing it if
+// it has not exited within a few seconds.
+func (c *StdioClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		select {
+		case <-c.closed:
+		case <-time.After(exitTimeout):
+			c.cmd.Process.Kill()
+		}
+		c.waitErr = c.cmd.Wait()
+	})
+	return c.waitErr
+}
+
+func marshalMessage(msg any) ([]byte, error) {
+	switch m := msg.(type) {
+	case string:
+		return []byte(m), nil
+	case []byte:
+		return m, nil
+	case json.RawMessage:
+		return m, nil
+	}
+	return json.Marshal(msg)
+}
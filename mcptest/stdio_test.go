@@ -0,0 +1,66 @@
+package mcptest_test
+
+import (
+	"context"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("StdioClient", func() {
+
+	// cat echoes each message back, so the messages sent are the messages
+	// received
+	var client *mcptest.StdioClient
+
+	BeforeEach(func() {
+		client = mcptest.StartStdio(GinkgoT(), exec.Command("cat"))
+	})
+
+	It("matches responses by ID", func() {
+		Expect(client.Send(`{"jsonrpc":"2.0","id":1,"result":"first"}`)).To(Succeed())
+		Expect(client.Send(map[string]any{"jsonrpc": "2.0", "id": "two", "result": "second"})).To(Succeed())
+
+		response, err := client.Response(context.Background(), jsonrpc2.ID{Str: "two", IsString: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).To(MatchJSON(`{"jsonrpc":"2.0","id":"two","result":"second"}`))
+
+		response, err = client.Response(context.Background(), jsonrpc2.ID{Num: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).To(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":"first"}`))
+	})
+
+	It("returns each response once", func() {
+		Expect(client.Send(`{"jsonrpc":"2.0","id":1,"result":"first"}`)).To(Succeed())
+		Expect(client.Send(`{"jsonrpc":"2.0","id":1,"result":"second"}`)).To(Succeed())
+
+		response, err := client.Response(context.Background(), jsonrpc2.ID{Num: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).To(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":"first"}`))
+		response, err = client.Response(context.Background(), jsonrpc2.ID{Num: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response).To(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":"second"}`))
+	})
+
+	It("requires requests to have an ID", func() {
+		_, err := client.Request(context.Background(), `{"jsonrpc":"2.0","method":"ping"}`)
+		Expect(err).To(MatchError(ContainSubstring("request has no ID")))
+	})
+
+	It("collects notifications", func() {
+		Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`)).To(Succeed())
+		Eventually(client.Notifications).Should(HaveLen(1))
+		Expect(client.Notifications()[0].Method).To(Equal("notifications/progress"))
+		Expect(client.Notifications()[0].Params).To(MatchJSON(`{"progress":1}`))
+	})
+
+	It("stops waiting when the process exits", func() {
+		Expect(client.Close()).To(Succeed())
+		_, err := client.Response(context.Background(), jsonrpc2.ID{Num: 1})
+		Expect(err).To(MatchError(ContainSubstring("waiting for response 1")))
+	})
+})
@@ -0,0 +1,104 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+)
+
+// Mirror offers the tools of an upstream server as if they were local,
+// forwarding calls to it. The tools are listed again whenever the upstream
+// reports that they have changed.
+type Mirror struct {
+	client *Client
+
+	mu    sync.RWMutex
+	defs  []ToolDefinition
+	tools map[string]ToolDefinition
+	// started counts the refreshes started and applied is the number of
+	// the last refresh applied, so that a refresh finishing after a later
+	// one does not replace the newer list.
+	started uint64
+	applied uint64
+}
+
+// NewMirror lists the tools of the server c is connected to, for serving
+// with WithMirror.
+func NewMirror(ctx context.Context, c *Client) (*Mirror, error) {
+	m := &Mirror{client: c}
+	if err := m.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	c.HandleNotification("notifications/tools/list_changed", func(json.RawMessage) {
+		// the client cannot make requests while handling a notification
+		go func() {
+			if err := m.Refresh(context.Background()); err != nil {
+				slog.Error("problem refreshing mirrored tools", "server", c.InitializeResult().ServerInfo.Name, "error", err)
+			}
+		}()
+	})
+	return m, nil
+}
+
+// Refresh lists the upstream tools again. Refreshes may run concurrently,
+// as when the upstream reports several changes in quick succession; the
+// list of the refresh started last is kept.
+func (m *Mirror) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	m.started++
+	n := m.started
+	m.mu.Unlock()
+
+	upstreamTools, err := m.client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	defs := make([]ToolDefinition, len(upstreamTools))
+	tools := make(map[string]ToolDefinition, len(upstreamTools))
+	for i, t := range upstreamTools {
+		defs[i] = forwardTool(m.client, t, t.Name)
+		tools[t.Name] = defs[i]
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n < m.applied {
+		return nil
+	}
+	m.applied = n
+	m.defs = defs
+	m.tools = tools
+	return nil
+}
+
+// Tools returns the metadata of the tools currently mirrored.
+func (m *Mirror) Tools() []Tool {
+	defs := m.definitions()
+	tools := make([]Tool, len(defs))
+	for i, t := range defs {
+		tools[i] = t.Metadata
+	}
+	return tools
+}
+
+func (m *Mirror) definitions() []ToolDefinition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.defs
+}
+
+func (m *Mirror) tool(name string) (ToolDefinition, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.tools[name]
+	return t, ok
+}
+
+// WithMirror offers the tools of m alongside those passed to NewServer,
+// which take precedence over mirrored tools of the same name. Clients are
+// not notified when the mirrored tools change.
+func WithMirror(m *Mirror) ServerOption {
+	return func(h *handler) {
+		h.toolSources = append(h.toolSources, m)
+	}
+}
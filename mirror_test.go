@@ -0,0 +1,157 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Mirror", func() {
+
+	var (
+		mu            sync.Mutex
+		upstreamTools []mcp.Tool
+		listTools     func() []mcp.Tool
+		upstreamConn  *jsonrpc2.Conn
+		upstream      *mcp.Client
+	)
+
+	BeforeEach(func() {
+		upstreamTools = []mcp.Tool{{Name: "search", InputSchema: mcp.ToolInputSchema{Type: "object"}}}
+		listTools = nil
+		serverSide, clientSide := net.Pipe()
+		handler := jsonrpc2.HandlerWithError(func(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+			switch req.Method {
+			case "initialize":
+				return mcp.InitializeResult{
+					ProtocolVersion: mcp.SupportedProtocolVersion,
+					ServerInfo:      mcp.Implementation{Name: "Community", Version: "1.0.0"},
+					Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ServerCapabilitiesTools{}},
+				}, nil
+			case "tools/list":
+				mu.Lock()
+				list, tools := listTools, upstreamTools
+				mu.Unlock()
+				if list != nil {
+					tools = list()
+				}
+				return mcp.ListToolsResult{Tools: tools}, nil
+			case "tools/call":
+				var params mcp.CallToolRequestParams
+				json.Unmarshal(*req.Params, &params)
+				return textResult("community " + params.Name), nil
+			}
+			return nil, nil
+		})
+		upstreamConn = jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide), jsonrpc2.AsyncHandler(handler))
+		// the upstream disconnects once the client is closed
+		DeferCleanup(func(conn *jsonrpc2.Conn) { <-conn.DisconnectNotify() }, upstreamConn)
+		var err error
+		upstream, err = mcp.NewClient(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), mcp.Implementation{Name: "Mirror", Version: "1.0.0"})
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(upstream.Close)
+	})
+
+	listedNames := func(c *mcp.Client) []string {
+		tools, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, len(tools))
+		for i, t := range tools {
+			names[i] = t.Name
+		}
+		return names
+	}
+
+	It("offers the upstream tools alongside local ones", func() {
+		m, err := mcp.NewMirror(context.Background(), upstream)
+		Expect(err).ToNot(HaveOccurred())
+		local := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "greet", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult("local greet"), nil
+			},
+		}}
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, local, mcp.WithMirror(m)))
+		Expect(listedNames(c)).To(Equal([]string{"greet", "search"}))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "search"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("community search")}))
+	})
+
+	It("prefers local tools of the same name", func() {
+		m, err := mcp.NewMirror(context.Background(), upstream)
+		Expect(err).ToNot(HaveOccurred())
+		local := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "search", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult("local search"), nil
+			},
+		}}
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, local, mcp.WithMirror(m)))
+		Expect(listedNames(c)).To(Equal([]string{"search"}))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "search"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("local search")}))
+	})
+
+	It("refreshes the tools when the upstream reports a change", func() {
+		m, err := mcp.NewMirror(context.Background(), upstream)
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil, mcp.WithMirror(m)))
+
+		mu.Lock()
+		upstreamTools = append(upstreamTools, mcp.Tool{Name: "fetch", InputSchema: mcp.ToolInputSchema{Type: "object"}})
+		mu.Unlock()
+		Expect(listedNames(c)).To(Equal([]string{"search"}))
+
+		Expect(upstreamConn.Notify(context.Background(), "notifications/tools/list_changed", nil)).To(Succeed())
+		Eventually(func() []string { return listedNames(c) }).Should(Equal([]string{"search", "fetch"}))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "fetch"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("community fetch")}))
+	})
+
+	It("keeps the newer list when refreshes finish out of order", func() {
+		m, err := mcp.NewMirror(context.Background(), upstream)
+		Expect(err).ToNot(HaveOccurred())
+
+		listing, release := make(chan struct{}), make(chan struct{})
+		var calls int
+		mu.Lock()
+		listTools = func() []mcp.Tool {
+			mu.Lock()
+			calls++
+			first := calls == 1
+			mu.Unlock()
+			if first {
+				// the first refresh is answered once the second has finished
+				close(listing)
+				<-release
+				return []mcp.Tool{{Name: "stale", InputSchema: mcp.ToolInputSchema{Type: "object"}}}
+			}
+			return []mcp.Tool{{Name: "fresh", InputSchema: mcp.ToolInputSchema{Type: "object"}}}
+		}
+		mu.Unlock()
+
+		refreshed := make(chan error, 1)
+		go func() {
+			refreshed <- m.Refresh(context.Background())
+		}()
+		Eventually(listing).Should(BeClosed())
+		Expect(m.Refresh(context.Background())).To(Succeed())
+		close(release)
+		Eventually(refreshed).Should(Receive(BeNil()))
+
+		Expect(m.Tools()).To(Equal([]mcp.Tool{{Name: "fresh", InputSchema: mcp.ToolInputSchema{Type: "object"}}}))
+	})
+})
@@ -0,0 +1,31 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Notifier sends notifications to the client.
+type Notifier interface {
+	Notify(ctx context.Context, method string, params any) error
+}
+
+// connNotifier sends notifications on conn with secrets, and the values of
+// secretArgs, masked from their params.
+type connNotifier struct {
+	conn       *jsonrpc2.Conn
+	secrets    *Secrets
+	secretArgs []string
+}
+
+func (n connNotifier) Notify(ctx context.Context, method string, params any) error {
+	if params == nil {
+		return n.conn.Notify(ctx, method, nil)
+	}
+	masked, err := n.secrets.maskJSON(params, n.secretArgs...)
+	if err != nil {
+		return err
+	}
+	return n.conn.Notify(ctx, method, masked)
+}
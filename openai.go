@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// OpenAIFunction is a function definition in the form accepted by OpenAI
+// compatible function calling APIs. The Chat Completions API expects each
+// one wrapped as {"type": "function", "function": ...}.
+type OpenAIFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON Schema of the function's arguments.
+	Parameters map[string]any `json:"parameters"`
+}
+
+var openAIFunctionName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ToOpenAIFunctions converts tools to function definitions, so that the same
+// tools can be offered to OpenAI compatible APIs. It fails if a tool name is
+// not one those APIs accept.
+func ToOpenAIFunctions(tools []Tool) ([]OpenAIFunction, error) {
+	functions := make([]OpenAIFunction, len(tools))
+	for i, t := range tools {
+		if !openAIFunctionName.MatchString(t.Name) {
+			return nil, fmt.Errorf("tool name %q is not a valid function name", t.Name)
+		}
+		b, err := json.Marshal(t.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		f := OpenAIFunction{Name: t.Name}
+		if err := json.Unmarshal(b, &f.Parameters); err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		if t.Description != nil {
+			f.Description = *t.Description
+		}
+		functions[i] = f
+	}
+	return functions, nil
+}
+
+// FromOpenAIFunctions converts function definitions to tools. Parameters
+// default to an object with no properties, and must otherwise describe an
+// object.
+func FromOpenAIFunctions(functions []OpenAIFunction) ([]Tool, error) {
+	tools := make([]Tool, len(functions))
+	for i, f := range functions {
+		t := Tool{Name: f.Name, InputSchema: ToolInputSchema{Type: "object"}}
+		if f.Parameters != nil {
+			b, err := json.Marshal(f.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("function %s: %w", f.Name, err)
+			}
+			if err := json.Unmarshal(b, &t.InputSchema); err != nil {
+				return nil, fmt.Errorf("function %s: %w", f.Name, err)
+			}
+			if t.InputSchema.Type != "object" {
+				return nil, fmt.Errorf("function %s: parameters must be an object, not %q", f.Name, t.InputSchema.Type)
+			}
+		}
+		if f.Description != "" {
+			t.Description = &f.Description
+		}
+		tools[i] = t
+	}
+	return tools, nil
+}
@@ -0,0 +1,54 @@
+package mcp_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("OpenAI functions", func() {
+
+	description := "Greets someone"
+	greet := mcp.Tool{
+		Name:        "greet",
+		Description: &description,
+		InputSchema: mcp.ToolInputSchema{
+			Type:       "object",
+			Properties: mcp.ToolInputSchemaProperties{"name": {"type": "string"}},
+			Required:   []string{"name"},
+		},
+	}
+
+	It("exports tools as function definitions", func() {
+		functions, err := mcp.ToOpenAIFunctions([]mcp.Tool{greet})
+		Expect(err).ToNot(HaveOccurred())
+		b, err := json.Marshal(functions)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`[{
+			"name": "greet",
+			"description": "Greets someone",
+			"parameters": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+		}]`))
+	})
+
+	It("rejects tool names that are not valid function names", func() {
+		_, err := mcp.ToOpenAIFunctions([]mcp.Tool{{Name: "greet.someone", InputSchema: mcp.ToolInputSchema{Type: "object"}}})
+		Expect(err).To(MatchError(`tool name "greet.someone" is not a valid function name`))
+	})
+
+	It("imports function definitions as tools", func() {
+		functions, err := mcp.ToOpenAIFunctions([]mcp.Tool{greet})
+		Expect(err).ToNot(HaveOccurred())
+		tools, err := mcp.FromOpenAIFunctions(append(functions, mcp.OpenAIFunction{Name: "now"}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(Equal([]mcp.Tool{greet, {Name: "now", InputSchema: mcp.ToolInputSchema{Type: "object"}}}))
+	})
+
+	It("rejects parameters that are not an object", func() {
+		_, err := mcp.FromOpenAIFunctions([]mcp.OpenAIFunction{{Name: "greet", Parameters: map[string]any{"type": "string"}}})
+		Expect(err).To(MatchError(`function greet: parameters must be an object, not "string"`))
+	})
+})
@@ -0,0 +1,423 @@
+// Package openapi converts the operations of an OpenAPI 3 document into MCP
+// tools that call the described HTTP API.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/acrmp/mcp"
+)
+
+// BodyArgument is the argument holding the JSON request body of operations
+// that accept one.
+const BodyArgument = "body"
+
+// DefaultMaxResponseBytes is the largest response body read when
+// Config.MaxResponseBytes is not set.
+const DefaultMaxResponseBytes = 10 << 20
+
+// maxNameLength is the longest tool name generated, which is the longest
+// accepted by common model APIs.
+const maxNameLength = 64
+
+// errUnsupportedBody is returned for operations whose request body cannot be
+// sent as JSON, which are skipped.
+var errUnsupportedBody = errors.New("request body is not JSON")
+
+// methods are the operations of a path item, in the order tools are
+// generated.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Config configures the tools generated from a document.
+type Config struct {
+	// BaseURL is the URL operation paths are relative to. It defaults to the
+	// first server declared by the document.
+	BaseURL string
+	// Client makes the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, for example to set Authorization.
+	// Header parameters of the same names are not offered as arguments.
+	Header http.Header
+	// Prepare, if set, is called with every request before it is sent, for
+	// authentication schemes that need more than a fixed header.
+	Prepare func(*http.Request) error
+	// MaxResponseBytes limits the size of the response bodies read. Longer
+	// responses fail the call. It defaults to DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+}
+
+// Tools returns a tool for each operation in the JSON encoded OpenAPI 3
+// document doc. A tool is named after the operationId of its operation, or
+// its method and path if it has none, with characters other than letters,
+// digits, '_' and '-' replaced. It takes the operation's path, query and
+// header parameters as arguments, along with BodyArgument for a JSON request
+// body. Operations whose request body is not JSON are skipped. Calling the
+// tool makes the request and returns the response body as text; responses
+// with an error status are tool errors.
+//
+// Local references ("#/...") are resolved; others are not supported.
+func Tools(doc []byte, cfg Config) ([]mcp.ToolDefinition, error) {
+	var d document
+	if err := json.Unmarshal(doc, &d.root); err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	if v, _ := d.root["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", v)
+	}
+	if cfg.BaseURL == "" {
+		servers, _ := d.root["servers"].([]any)
+		if len(servers) == 0 {
+			return nil, errors.New("no base URL configured and the document declares no servers")
+		}
+		server, _ := d.resolve(servers[0]).(map[string]any)
+		cfg.BaseURL, _ = server["url"].(string)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.MaxResponseBytes <= 0 {
+		cfg.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+
+	paths, _ := d.root["paths"].(map[string]any)
+	pathNames := make([]string, 0, len(paths))
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	sort.Strings(pathNames)
+
+	var tools []mcp.ToolDefinition
+	names := map[string]bool{}
+	for _, p := range pathNames {
+		item, _ := d.resolve(paths[p]).(map[string]any)
+		for _, method := range methods {
+			op, ok := d.resolve(item[method]).(map[string]any)
+			if !ok {
+				continue
+			}
+			o, err := d.operation(method, p, item, op)
+			if errors.Is(err, errUnsupportedBody) {
+				slog.Warn("skipping operation", "method", strings.ToUpper(method), "path", p, "error", err)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), p, err)
+			}
+			if names[o.name] {
+				return nil, fmt.Errorf("%s %s: duplicate tool name %s", strings.ToUpper(method), p, o.name)
+			}
+			names[o.name] = true
+			tools = append(tools, o.tool(cfg))
+		}
+	}
+	return tools, nil
+}
+
+type document struct {
+	root map[string]any
+}
+
+// resolve follows local references until v is not a reference.
+func (d document) resolve(v any) any {
+	for seen := 0; seen < 32; seen++ {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		v = d.lookup(ref)
+	}
+	return nil
+}
+
+func (d document) lookup(ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v any = d.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[token]
+	}
+	return v
+}
+
+// schema returns v with every local reference replaced by the schema it
+// refers to. Recursive schemas are cut off, leaving an unconstrained schema.
+func (d document) schema(v any, refs []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			for _, r := range refs {
+				if r == ref {
+					return map[string]any{}
+				}
+			}
+			return d.schema(d.lookup(ref), append(refs, ref))
+		}
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = d.schema(e, refs)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = d.schema(e, refs)
+		}
+		return out
+	}
+	return v
+}
+
+type parameter struct {
+	name, in string
+}
+
+type operation struct {
+	name, method, path string
+	description        string
+	schema             mcp.ToolInputSchema
+	parameters         []parameter
+	hasBody            bool
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func (d document) operation(method, path string, item, op map[string]any) (operation, error) {
+	o := operation{method: strings.ToUpper(method), path: path}
+	id, _ := op["operationId"].(string)
+	if o.name = toolName(id); o.name == "" {
+		o.name = toolName(method + path)
+	}
+	var description []string
+	for _, key := range []string{"summary", "description"} {
+		if s, _ := op[key].(string); s != "" {
+			description = append(description, s)
+		}
+	}
+	o.description = strings.Join(description, "\n\n")
+
+	o.schema = mcp.ToolInputSchema{Type: "object", Properties: mcp.ToolInputSchemaProperties{}}
+	// parameters of the operation override those of the path item
+	params := map[parameter]map[string]any{}
+	var order []parameter
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		entries, _ := list.([]any)
+		for _, e := range entries {
+			p, _ := d.resolve(e).(map[string]any)
+			name, _ := p["name"].(string)
+			in, _ := p["in"].(string)
+			key := parameter{name: name, in: in}
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = p
+		}
+	}
+	for _, key := range order {
+		p := params[key]
+		switch key.in {
+		case "path", "query", "header":
+		case "cookie":
+			continue
+		default:
+			return operation{}, fmt.Errorf("parameter %s is in unknown location %q", key.name, key.in)
+		}
+		if _, ok := o.schema.Properties[key.name]; ok {
+			return operation{}, fmt.Errorf("parameter %s is declared more than once", key.name)
+		}
+		schema, _ := d.schema(p["schema"], nil).(map[string]any)
+		if schema == nil {
+			schema = map[string]any{"type": "string"}
+		}
+		if desc, ok := p["description"].(string); ok {
+			schema["description"] = desc
+		}
+		o.schema.Properties[key.name] = schema
+		if required, _ := p["required"].(bool); required || key.in == "path" {
+			o.schema.Required = append(o.schema.Required, key.name)
+		}
+		o.parameters = append(o.parameters, key)
+	}
+
+	if body, ok := d.resolve(op["requestBody"]).(map[string]any); ok {
+		content, _ := body["content"].(map[string]any)
+		media, ok := d.resolve(content["application/json"]).(map[string]any)
+		if !ok {
+			return operation{}, errUnsupportedBody
+		}
+		if _, ok := o.schema.Properties[BodyArgument]; ok {
+			return operation{}, fmt.Errorf("parameter %s clashes with the request body", BodyArgument)
+		}
+		schema, _ := d.schema(media["schema"], nil).(map[string]any)
+		if schema == nil {
+			schema = map[string]any{}
+		}
+		if desc, ok := body["description"].(string); ok {
+			schema["description"] = desc
+		}
+		o.schema.Properties[BodyArgument] = schema
+		if required, _ := body["required"].(bool); required {
+			o.schema.Required = append(o.schema.Required, BodyArgument)
+		}
+		o.hasBody = true
+	}
+	return o, nil
+}
+
+// toolName returns s with the characters not allowed in tool names replaced,
+// cut to maxNameLength.
+func toolName(s string) string {
+	s = strings.Trim(invalidNameChars.ReplaceAllString(s, "_"), "_")
+	if len(s) > maxNameLength {
+		s = s[:maxNameLength]
+	}
+	return s
+}
+
+func (o operation) tool(cfg Config) mcp.ToolDefinition {
+	o = o.withoutConfiguredHeaders(cfg.Header)
+	t := mcp.Tool{Name: o.name, InputSchema: o.schema}
+	if o.description != "" {
+		t.Description = &o.description
+	}
+	if o.method == http.MethodGet || o.method == http.MethodHead {
+		readOnly := true
+		t.Annotations = &mcp.ToolAnnotations{ReadOnlyHint: &readOnly}
+	}
+	return mcp.ToolDefinition{
+		Metadata: t,
+		Process: func(ctx context.Context, params mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+			return o.call(ctx, cfg, params.Arguments)
+		},
+	}
+}
+
+// withoutConfiguredHeaders returns o without the header parameters set by
+// header, so that arguments cannot replace credentials.
+func (o operation) withoutConfiguredHeaders(header http.Header) operation {
+	properties := make(mcp.ToolInputSchemaProperties, len(o.schema.Properties))
+	for k, v := range o.schema.Properties {
+		properties[k] = v
+	}
+	var params []parameter
+	for _, p := range o.parameters {
+		if p.in == "header" && header.Get(p.name) != "" {
+			delete(properties, p.name)
+			continue
+		}
+		params = append(params, p)
+	}
+	var required []string
+	for _, r := range o.schema.Required {
+		if _, ok := properties[r]; ok {
+			required = append(required, r)
+		}
+	}
+	o.parameters = params
+	o.schema.Properties = properties
+	o.schema.Required = required
+	return o
+}
+
+func (o operation) call(ctx context.Context, cfg Config, args map[string]any) (mcp.CallToolResult, error) {
+	path := o.path
+	query := url.Values{}
+	header := http.Header{}
+	for _, p := range o.parameters {
+		v, ok := args[p.name]
+		if !ok {
+			continue
+		}
+		s := formatValue(v)
+		switch p.in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.name+"}", url.PathEscape(s))
+		case "query":
+			query.Set(p.name, s)
+		case "header":
+			header.Set(p.name, s)
+		}
+	}
+	u := strings.TrimSuffix(cfg.BaseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if v, ok := args[BodyArgument]; ok && o.hasBody {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return mcp.CallToolResult{}, err
+		}
+		body = bytes.NewReader(b)
+		header.Set("Content-Type", "application/json")
+	}
+	req, err := http.NewRequestWithContext(ctx, o.method, u, body)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	for k, v := range cfg.Header {
+		req.Header[k] = v
+	}
+	if cfg.Prepare != nil {
+		if err := cfg.Prepare(req); err != nil {
+			return mcp.CallToolResult{}, err
+		}
+	}
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, cfg.MaxResponseBytes+1))
+	if err != nil {
+		return mcp.CallToolResult{}, fmt.Errorf("reading response: %w", err)
+	}
+	if int64(len(b)) > cfg.MaxResponseBytes {
+		return mcp.CallToolResult{}, fmt.Errorf("reading response: response is larger than %d bytes", cfg.MaxResponseBytes)
+	}
+	if resp.StatusCode >= 400 {
+		return mcp.NewResult().Text(fmt.Sprintf("%s: %s", resp.Status, b)).Error(true).Build()
+	}
+	return mcp.NewResult().Text(string(b)).Build()
+}
+
+// formatValue formats an argument for a path, query or header parameter.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		s := make([]string, len(v))
+		for i, e := range v {
+			s[i] = formatValue(e)
+		}
+		return strings.Join(s, ",")
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
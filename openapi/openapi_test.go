@@ -0,0 +1,222 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+	"github.com/acrmp/mcp/openapi"
+)
+
+var _ = Describe("Tools", func() {
+
+	var (
+		doc      []byte
+		requests chan *http.Request
+		body     string
+		api      *httptest.Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		doc, err = os.ReadFile("testdata/petstore.json")
+		Expect(err).ToNot(HaveOccurred())
+
+		requests = make(chan *http.Request, 1)
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+			requests <- r
+			if r.Method == http.MethodDelete {
+				http.Error(w, "pet not found", http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		}))
+		DeferCleanup(api.Close)
+	})
+
+	find := func(tools []mcp.ToolDefinition, name string) mcp.ToolDefinition {
+		for _, t := range tools {
+			if t.Metadata.Name == name {
+				return t
+			}
+		}
+		Fail("no tool named " + name)
+		return mcp.ToolDefinition{}
+	}
+
+	call := func(t mcp.ToolDefinition, args map[string]any) mcp.CallToolResult {
+		result, err := t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: args}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	It("generates a tool for each operation", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, len(tools))
+		for i, t := range tools {
+			names[i] = t.Metadata.Name
+		}
+		Expect(names).To(Equal([]string{"listPets", "createPet", "get_pets_petId", "deletePet"}))
+
+		metadata := make([]mcp.Tool, len(tools))
+		for i, t := range tools {
+			metadata[i] = t.Metadata
+		}
+		Expect(mcptest.ValidateToolSchemas(metadata...)).To(Succeed())
+	})
+
+	It("describes parameters and request bodies in the input schema", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{})
+		Expect(err).ToNot(HaveOccurred())
+
+		list := find(tools, "listPets").Metadata
+		Expect(*list.Description).To(Equal("List all pets"))
+		Expect(*list.Annotations.ReadOnlyHint).To(BeTrue())
+		Expect(list.InputSchema.Properties).To(Equal(mcp.ToolInputSchemaProperties{
+			"limit": {"type": "integer", "description": "How many pets to return"},
+		}))
+		Expect(list.InputSchema.Required).To(BeEmpty())
+
+		get := find(tools, "get_pets_petId").Metadata
+		Expect(get.InputSchema.Properties).To(HaveKey("petId"))
+		Expect(get.InputSchema.Properties).To(HaveKey("X-Request-Id"))
+		Expect(get.InputSchema.Required).To(Equal([]string{"petId"}))
+
+		create := find(tools, "createPet").Metadata
+		Expect(create.Annotations).To(BeNil())
+		Expect(create.InputSchema.Required).To(Equal([]string{openapi.BodyArgument}))
+		body := create.InputSchema.Properties[openapi.BodyArgument]
+		Expect(body).To(HaveKeyWithValue("required", []any{"name"}))
+		Expect(body).To(HaveKeyWithValue("properties", HaveKeyWithValue("friends", HaveKeyWithValue("items", map[string]any{}))))
+	})
+
+	It("calls the API with the arguments and configured headers", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{
+			BaseURL: api.URL,
+			Header:  http.Header{"Authorization": {"Bearer token"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "get_pets_petId"), map[string]any{"petId": "a b", "X-Request-Id": "123"})
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent(`{"ok":true}`)}))
+		r := <-requests
+		Expect(r.Method).To(Equal(http.MethodGet))
+		Expect(r.URL.EscapedPath()).To(Equal("/pets/a%20b"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+		Expect(r.Header.Get("X-Request-Id")).To(Equal("123"))
+
+		call(find(tools, "listPets"), map[string]any{"limit": float64(10)})
+		Expect((<-requests).URL.RawQuery).To(Equal("limit=10"))
+
+		call(find(tools, "createPet"), map[string]any{"body": map[string]any{"name": "Rex"}})
+		r = <-requests
+		Expect(r.Method).To(Equal(http.MethodPost))
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(body).To(MatchJSON(`{"name":"Rex"}`))
+	})
+
+	It("does not let arguments replace configured headers", func() {
+		var d map[string]any
+		Expect(json.Unmarshal(doc, &d)).To(Succeed())
+		get := d["paths"].(map[string]any)["/pets/{petId}"].(map[string]any)["get"].(map[string]any)
+		get["parameters"] = append(get["parameters"].([]any), map[string]any{"name": "authorization", "in": "header", "required": true})
+		doc, _ = json.Marshal(d)
+		tools, err := openapi.Tools(doc, openapi.Config{
+			BaseURL: api.URL,
+			Header:  http.Header{"Authorization": {"Bearer token"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		t := find(tools, "get_pets_petId")
+		Expect(t.Metadata.InputSchema.Properties).ToNot(HaveKey("authorization"))
+		Expect(t.Metadata.InputSchema.Required).To(Equal([]string{"petId"}))
+
+		call(t, map[string]any{"petId": "1", "authorization": "Bearer stolen"})
+		Expect((<-requests).Header.Values("Authorization")).To(Equal([]string{"Bearer token"}))
+	})
+
+	It("fails calls whose response is too large", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{BaseURL: api.URL, MaxResponseBytes: 4})
+		Expect(err).ToNot(HaveOccurred())
+
+		t := find(tools, "listPets")
+		_, err = t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name}, nil)
+		Expect(err).To(MatchError("reading response: response is larger than 4 bytes"))
+	})
+
+	It("replaces characters not allowed in tool names", func() {
+		var d map[string]any
+		Expect(json.Unmarshal(doc, &d)).To(Succeed())
+		d["paths"].(map[string]any)["/pets"].(map[string]any)["get"].(map[string]any)["operationId"] = "pets.list (v2)"
+		doc, _ = json.Marshal(d)
+		tools, err := openapi.Tools(doc, openapi.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools[0].Metadata.Name).To(Equal("pets_list_v2"))
+	})
+
+	It("skips operations whose request body is not JSON", func() {
+		var d map[string]any
+		Expect(json.Unmarshal(doc, &d)).To(Succeed())
+		post := d["paths"].(map[string]any)["/pets"].(map[string]any)["post"].(map[string]any)
+		post["requestBody"] = map[string]any{"content": map[string]any{"multipart/form-data": map[string]any{}}}
+		doc, _ = json.Marshal(d)
+		tools, err := openapi.Tools(doc, openapi.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, len(tools))
+		for i, t := range tools {
+			names[i] = t.Metadata.Name
+		}
+		Expect(names).To(Equal([]string{"listPets", "get_pets_petId", "deletePet"}))
+	})
+
+	It("prepares each request", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{
+			BaseURL: api.URL,
+			Prepare: func(r *http.Request) error {
+				r.SetBasicAuth("user", "pass")
+				return nil
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		call(find(tools, "listPets"), nil)
+		user, pass, ok := (<-requests).BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(user + ":" + pass).To(Equal("user:pass"))
+	})
+
+	It("reports error responses as tool errors", func() {
+		tools, err := openapi.Tools(doc, openapi.Config{BaseURL: api.URL})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := call(find(tools, "deletePet"), map[string]any{"petId": "1"})
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("404 Not Found: pet not found\n")}))
+	})
+
+	It("rejects documents it cannot convert", func() {
+		_, err := openapi.Tools([]byte(`{"swagger":"2.0"}`), openapi.Config{})
+		Expect(err).To(MatchError(`unsupported OpenAPI version ""`))
+
+		_, err = openapi.Tools([]byte(`{"openapi":"3.1.0","paths":{}}`), openapi.Config{})
+		Expect(err).To(MatchError("no base URL configured and the document declares no servers"))
+
+		var d map[string]any
+		Expect(json.Unmarshal(doc, &d)).To(Succeed())
+		d["paths"].(map[string]any)["/pets"].(map[string]any)["post"].(map[string]any)["operationId"] = "listPets"
+		doc, _ = json.Marshal(d)
+		_, err = openapi.Tools(doc, openapi.Config{})
+		Expect(err).To(MatchError("POST /pets: duplicate tool name listPets"))
+	})
+})
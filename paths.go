@@ -0,0 +1,143 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathOutsideRoots is returned when a path resolves to a location outside
+// every allowed root.
+var ErrPathOutsideRoots = errors.New("path is outside the allowed roots")
+
+// RootFromPath returns a root for the local directory dir.
+func RootFromPath(dir string) (Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return Root{}, err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	return Root{Uri: u.String()}, nil
+}
+
+// ResolvePath resolves a client supplied path, which may be a file:// URI, to
+// an absolute local path with symlinks evaluated. Relative paths are resolved
+// against the first root. The path need not exist, but the resolved location
+// must lie within one of roots, so ".." components and symlinks cannot be
+// used to escape them.
+func ResolvePath(roots []Root, path string) (string, error) {
+	if len(roots) == 0 {
+		return "", ErrPathOutsideRoots
+	}
+	if strings.ContainsRune(path, 0) {
+		return "", fmt.Errorf("invalid path %q", path)
+	}
+
+	dirs := make([]string, 0, len(roots))
+	for _, r := range roots {
+		dir, err := rootDir(r)
+		if err != nil {
+			return "", err
+		}
+		dirs = append(dirs, dir)
+	}
+
+	if strings.HasPrefix(path, "file:") {
+		p, err := uriPath(path)
+		if err != nil {
+			return "", err
+		}
+		path = p
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dirs[0], path)
+	}
+
+	resolved, err := evalSymlinksAllowMissing(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		if within(dir, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", ErrPathOutsideRoots
+}
+
+func rootDir(r Root) (string, error) {
+	dir, err := uriPath(r.Uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid root %q: %w", r.Uri, err)
+	}
+	return evalSymlinksAllowMissing(dir)
+}
+
+func uriPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("unsupported host %q", u.Host)
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path)), nil
+}
+
+// maxSymlinks bounds the dangling symlinks followed when resolving a path, as
+// a symlink may point at itself.
+const maxSymlinks = 255
+
+// evalSymlinksAllowMissing evaluates symlinks in the longest existing prefix
+// of path and appends the remaining components unchanged. A dangling symlink
+// is followed to its target, so that creating the path cannot write outside
+// the location it resolves to.
+func evalSymlinksAllowMissing(path string) (string, error) {
+	var missing []string
+	links := 0
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if fi, lerr := os.Lstat(path); lerr == nil && fi.Mode()&fs.ModeSymlink != 0 {
+			if links++; links > maxSymlinks {
+				return "", fmt.Errorf("resolving %s: too many symlinks", path)
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			path = filepath.Clean(target)
+			continue
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
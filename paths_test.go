@@ -0,0 +1,89 @@
+package mcp_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("ResolvePath", func() {
+
+	var (
+		root    string
+		outside string
+		roots   []mcp.Root
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = filepath.EvalSymlinks(GinkgoT().TempDir())
+		Expect(err).ToNot(HaveOccurred())
+		outside, err = filepath.EvalSymlinks(GinkgoT().TempDir())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(root, "docs"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "docs", "README.md"), nil, 0o644)).To(Succeed())
+		Expect(os.Symlink(outside, filepath.Join(root, "escape"))).To(Succeed())
+		Expect(os.Symlink(filepath.Join(root, "docs"), filepath.Join(root, "manual"))).To(Succeed())
+
+		r, err := mcp.RootFromPath(root)
+		Expect(err).ToNot(HaveOccurred())
+		roots = []mcp.Root{r}
+	})
+
+	It("resolves relative paths against the first root", func() {
+		Expect(mcp.ResolvePath(roots, "docs/README.md")).To(Equal(filepath.Join(root, "docs", "README.md")))
+	})
+
+	It("accepts absolute paths and file URIs within a root", func() {
+		Expect(mcp.ResolvePath(roots, filepath.Join(root, "docs"))).To(Equal(filepath.Join(root, "docs")))
+		Expect(mcp.ResolvePath(roots, "file://"+filepath.ToSlash(filepath.Join(root, "docs")))).To(Equal(filepath.Join(root, "docs")))
+	})
+
+	It("accepts paths that do not exist yet", func() {
+		Expect(mcp.ResolvePath(roots, "docs/new/file.txt")).To(Equal(filepath.Join(root, "docs", "new", "file.txt")))
+	})
+
+	It("evaluates symlinks that stay within a root", func() {
+		Expect(mcp.ResolvePath(roots, "manual/README.md")).To(Equal(filepath.Join(root, "docs", "README.md")))
+	})
+
+	It("rejects .. escapes", func() {
+		_, err := mcp.ResolvePath(roots, "../"+filepath.Base(outside))
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+	})
+
+	It("rejects symlinks that escape a root", func() {
+		_, err := mcp.ResolvePath(roots, "escape/secrets.txt")
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+	})
+
+	It("rejects dangling symlinks that escape a root", func() {
+		Expect(os.Symlink(filepath.Join(outside, "created.txt"), filepath.Join(root, "dangling"))).To(Succeed())
+		_, err := mcp.ResolvePath(roots, "dangling")
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+
+		Expect(os.Symlink(filepath.Join(outside, "missing"), filepath.Join(root, "dangling-dir"))).To(Succeed())
+		_, err = mcp.ResolvePath(roots, "dangling-dir/created.txt")
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+	})
+
+	It("follows dangling symlinks that stay within a root", func() {
+		Expect(os.Symlink("docs/new.md", filepath.Join(root, "draft"))).To(Succeed())
+		Expect(mcp.ResolvePath(roots, "draft")).To(Equal(filepath.Join(root, "docs", "new.md")))
+	})
+
+	It("rejects absolute paths outside every root", func() {
+		_, err := mcp.ResolvePath(roots, outside)
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+	})
+
+	It("rejects everything when there are no roots", func() {
+		_, err := mcp.ResolvePath(nil, "docs")
+		Expect(err).To(MatchError(mcp.ErrPathOutsideRoots))
+	})
+})
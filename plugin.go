@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Plugin is a program that provides tools by serving MCP over its standard
+// input and output, so that tools can be built, versioned and sandboxed
+// separately from the server that offers them.
+type Plugin struct {
+	// Path is the program to run.
+	Path string
+	Args []string
+	// Version, if set, must match the version the plugin reports in its
+	// server info.
+	Version string
+	// Sandbox restricts the plugin process. Timeout does not apply, as the
+	// process runs until the plugin is closed.
+	Sandbox Sandbox
+}
+
+// StartPlugin starts p and connects to it. The tools of the plugin are
+// offered by passing the client to NewMirror and serving the mirror with
+// WithMirror. Closing the client stops the plugin.
+func StartPlugin(ctx context.Context, p Plugin) (*Client, error) {
+	path, err := p.Sandbox.lookPath(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(path, p.Args...)
+	cmd.Dir = p.Sandbox.Dir
+	cmd.Env = p.Sandbox.environ()
+	if err := p.Sandbox.configure(cmd); err != nil {
+		return nil, err
+	}
+	stream, err := CommandStream(cmd)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(ctx, stream, Implementation{Name: "mcp-plugin-host", Version: "1.0.0"})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.Path, err)
+	}
+	if info := c.InitializeResult().ServerInfo; p.Version != "" && info.Version != p.Version {
+		c.Close()
+		return nil, fmt.Errorf("plugin %s is version %s, want %s", p.Path, info.Version, p.Version)
+	}
+	return c, nil
+}
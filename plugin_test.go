@@ -0,0 +1,40 @@
+package mcp_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Plugin", func() {
+
+	It("offers the tools of a plugin process", func() {
+		plugin, err := mcp.StartPlugin(context.Background(), mcp.Plugin{
+			Path:    exampleServerPath,
+			Version: "1.0.0",
+			Sandbox: mcp.Sandbox{ScrubEnv: true, Dir: GinkgoT().TempDir()},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(plugin.Close)
+		m, err := mcp.NewMirror(context.Background(), plugin)
+		Expect(err).ToNot(HaveOccurred())
+
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "Host", Version: "1.0.0"}, nil, mcp.WithMirror(m)))
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "sha256sum", Arguments: map[string]any{"text": "hello"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")}))
+	})
+
+	It("rejects plugins of another version", func() {
+		_, err := mcp.StartPlugin(context.Background(), mcp.Plugin{Path: exampleServerPath, Version: "2.0.0"})
+		Expect(err).To(MatchError(ContainSubstring("is version 1.0.0, want 2.0.0")))
+	})
+
+	It("fails if the plugin cannot be started", func() {
+		_, err := mcp.StartPlugin(context.Background(), mcp.Plugin{Path: "/nonexistent"})
+		Expect(err).To(MatchError(ContainSubstring("starting /nonexistent")))
+	})
+})
@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+)
+
+// Policy declares which principals may use which tools and prompts. A tool or
+// prompt is available to a principal if a matching rule allows its name and
+// no matching rule denies it. Tools and prompts the policy does not make
+// available are hidden from the principal. A proxy checks the requests it
+// forwards against the policy by method, such as "resources/read".
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+	// DefaultAllow makes tools and prompts that no rule allows or denies
+	// available.
+	DefaultAllow bool `json:"defaultAllow,omitempty"`
+}
+
+// PolicyRule applies to principals whose subject is listed in Principals or
+// who belong to one of Groups. A rule listing neither applies to every
+// caller, including unauthenticated ones. The subject "*" matches any
+// authenticated principal. Allow and Deny hold tool and prompt name patterns
+// in the syntax of path.Match.
+type PolicyRule struct {
+	Principals []string `json:"principals,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+	Allow      []string `json:"allow,omitempty"`
+	Deny       []string `json:"deny,omitempty"`
+}
+
+// LoadPolicy reads a policy from the JSON file at filename.
+func LoadPolicy(filename string) (*Policy, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parsing policy %s: %w", filename, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("parsing policy %s: %w", filename, err)
+	}
+	return &p, nil
+}
+
+// WithPolicy restricts the tools and prompts available to each principal
+// according to p.
+func WithPolicy(p *Policy) ServerOption {
+	return func(h *handler) {
+		h.policy = p
+	}
+}
+
+// Validate checks that every name pattern in the policy is well formed.
+func (p *Policy) Validate() error {
+	for i, r := range p.Rules {
+		for _, pattern := range append(slices.Clone(r.Allow), r.Deny...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d: invalid pattern %q", i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether principal, which is nil for unauthenticated
+// callers, may use the tool or prompt called name.
+func (p *Policy) Allowed(principal *Principal, name string) bool {
+	allowed := p.DefaultAllow
+	for _, r := range p.Rules {
+		if !r.appliesTo(principal) {
+			continue
+		}
+		if matchesAny(r.Deny, name) {
+			return false
+		}
+		if matchesAny(r.Allow, name) {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+func (r PolicyRule) appliesTo(p *Principal) bool {
+	if len(r.Principals) == 0 && len(r.Groups) == 0 {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	if slices.Contains(r.Principals, "*") || slices.Contains(r.Principals, p.Subject) {
+		return true
+	}
+	for _, g := range p.Groups {
+		if slices.Contains(r.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
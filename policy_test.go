@@ -0,0 +1,115 @@
+package mcp_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Policy", func() {
+
+	var policy *mcp.Policy
+
+	alice := &mcp.Principal{Subject: "alice", Groups: []string{"developers"}}
+	bob := &mcp.Principal{Subject: "bob", Groups: []string{"support"}}
+
+	BeforeEach(func() {
+		policy = &mcp.Policy{Rules: []mcp.PolicyRule{
+			{Allow: []string{"search_*"}},
+			{Principals: []string{"*"}, Allow: []string{"issues_*"}},
+			{Groups: []string{"developers"}, Allow: []string{"deploy_*"}, Deny: []string{"deploy_production"}},
+			{Principals: []string{"bob"}, Deny: []string{"issues_delete"}},
+		}}
+	})
+
+	It("applies rules without principals or groups to every caller", func() {
+		Expect(policy.Allowed(nil, "search_code")).To(BeTrue())
+		Expect(policy.Allowed(nil, "issues_list")).To(BeFalse())
+	})
+
+	It("applies wildcard principal rules to authenticated callers", func() {
+		Expect(policy.Allowed(alice, "issues_list")).To(BeTrue())
+		Expect(policy.Allowed(bob, "issues_list")).To(BeTrue())
+	})
+
+	It("applies group rules to members", func() {
+		Expect(policy.Allowed(alice, "deploy_staging")).To(BeTrue())
+		Expect(policy.Allowed(bob, "deploy_staging")).To(BeFalse())
+	})
+
+	It("gives denials precedence over allows", func() {
+		Expect(policy.Allowed(alice, "deploy_production")).To(BeFalse())
+		Expect(policy.Allowed(bob, "issues_delete")).To(BeFalse())
+		Expect(policy.Allowed(alice, "issues_delete")).To(BeTrue())
+	})
+
+	It("denies tools no rule allows unless configured otherwise", func() {
+		Expect(policy.Allowed(alice, "unlisted")).To(BeFalse())
+		policy.DefaultAllow = true
+		Expect(policy.Allowed(alice, "unlisted")).To(BeTrue())
+	})
+
+	It("loads policies from a file", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "policy.json")
+		Expect(os.WriteFile(filename, []byte(`{"rules":[{"groups":["support"],"allow":["issues_*"]}]}`), 0o644)).To(Succeed())
+		p, err := mcp.LoadPolicy(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Allowed(bob, "issues_list")).To(BeTrue())
+	})
+
+	It("rejects malformed patterns", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "policy.json")
+		Expect(os.WriteFile(filename, []byte(`{"rules":[{"allow":["issues_["]}]}`), 0o644)).To(Succeed())
+		_, err := mcp.LoadPolicy(filename)
+		Expect(err).To(MatchError(ContainSubstring(`invalid pattern "issues_["`)))
+	})
+
+	Context("when configured on a server", func() {
+		tool := func(name string) mcp.ToolDefinition {
+			return mcp.ToolDefinition{
+				Metadata: mcp.Tool{Name: name, InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					return textResult(name), nil
+				},
+			}
+		}
+
+		It("evaluates the policy for both listing and calling tools", func() {
+			ctx := mcp.ContextWithPrincipal(context.Background(), alice)
+			conn := startServer(ctx, []mcp.ToolDefinition{tool("deploy_staging"), tool("deploy_production")}, mcp.WithPolicy(policy))
+
+			var result mcp.ListToolsResult
+			Expect(conn.Call(context.Background(), "tools/list", nil, &result)).To(Succeed())
+			Expect(result.Tools).To(HaveLen(1))
+			Expect(result.Tools[0].Name).To(Equal("deploy_staging"))
+
+			_, err := callTool(conn, "deploy_production", nil)
+			Expect(err).To(MatchError(ContainSubstring("Unknown tool: deploy_production")))
+		})
+
+		It("evaluates the policy for both listing and getting prompts", func() {
+			prompt := func(name string) mcp.PromptDefinition {
+				return mcp.PromptDefinition{
+					Metadata: mcp.Prompt{Name: name},
+					Get: func(mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+						return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent(name))}, nil
+					},
+				}
+			}
+			ctx := mcp.ContextWithPrincipal(context.Background(), alice)
+			conn := startServer(ctx, nil, mcp.WithPolicy(policy), mcp.WithPrompts(prompt("deploy_checklist"), prompt("release_notes")))
+
+			var result mcp.ListPromptsResult
+			Expect(conn.Call(context.Background(), "prompts/list", nil, &result)).To(Succeed())
+			Expect(result.Prompts).To(ConsistOf(HaveField("Name", "deploy_checklist")))
+
+			err := conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "release_notes"}, &mcp.GetPromptResult{})
+			Expect(err).To(MatchError(ContainSubstring("Unknown prompt: release_notes")))
+		})
+	})
+})
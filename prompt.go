@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PromptDefinition is a prompt offered by a Server.
+type PromptDefinition struct {
+	Metadata Prompt
+	// Get renders the prompt for the given arguments. Required arguments
+	// declared in Metadata are checked before Get is called. The description
+	// of the result defaults to that of the prompt, in the locale of the
+	// client, and its _meta is passed to the client.
+	Get func(GetPromptRequestParams) (GetPromptResult, error)
+	// Render, if set, is called instead of Get. It receives the context of
+	// the request, for prompts that render from remote sources.
+	Render func(ctx context.Context, params GetPromptRequestParams) (GetPromptResult, error)
+	// Authorize, if set, is called before the prompt is rendered. A non-nil
+	// error denies the request and is reported to the client as a permission
+	// denied error. The principal is nil if the transport did not
+	// authenticate the caller.
+	Authorize func(ctx context.Context, principal *Principal, params GetPromptRequestParams) error
+	// RequiredScopes lists the scopes a principal must hold for the prompt to
+	// be listed or rendered.
+	RequiredScopes []string
+	// Descriptions translates the description of the prompt, keyed by BCP 47
+	// language tag, for clients that ask for a locale.
+	Descriptions map[string]string
+	// CacheTTL, if positive, caches the results of rendering by argument values
+	// for the duration, for prompts that are expensive to render and
+	// fetched repeatedly. Errors are not cached. Results are cached for each
+	// principal and locale, which Render may depend on, but Render must not
+	// depend on other values of its context.
+	CacheTTL time.Duration
+}
+
+// WithPrompts sets the prompts offered by the server.
+func WithPrompts(prompts ...PromptDefinition) ServerOption {
+	return func(h *handler) {
+		if h.prompts == nil {
+			h.prompts = make(map[string]PromptDefinition, len(prompts))
+		}
+		for _, p := range prompts {
+			if p.CacheTTL > 0 {
+				p.Render = newPromptCache(p.render, p.CacheTTL).get
+			}
+			h.promptMetadata = append(h.promptMetadata, p.Metadata)
+			h.prompts[p.Metadata.Name] = p
+		}
+	}
+}
+
+// render renders the prompt with Render, or Get if it is not set.
+func (p PromptDefinition) render(ctx context.Context, params GetPromptRequestParams) (GetPromptResult, error) {
+	if p.Render != nil {
+		return p.Render(ctx, params)
+	}
+	return p.Get(params)
+}
+
+// promptCache caches the results of rendering a prompt by argument values.
+type promptCache struct {
+	render func(context.Context, GetPromptRequestParams) (GetPromptResult, error)
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	results map[string]cachedPrompt
+}
+
+type cachedPrompt struct {
+	result  GetPromptResult
+	expires time.Time
+}
+
+func newPromptCache(render func(context.Context, GetPromptRequestParams) (GetPromptResult, error), ttl time.Duration) *promptCache {
+	return &promptCache{render: render, ttl: ttl, results: map[string]cachedPrompt{}}
+}
+
+// promptCacheKey identifies the results of rendering a prompt that may be
+// shared. Subject is nil for callers that were not authenticated.
+type promptCacheKey struct {
+	Subject   *string           `json:"subject"`
+	Locale    string            `json:"locale"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+func (c *promptCache) get(ctx context.Context, params GetPromptRequestParams) (GetPromptResult, error) {
+	k := promptCacheKey{Arguments: params.Arguments}
+	if p, ok := PrincipalFromContext(ctx); ok && p != nil {
+		k.Subject = &p.Subject
+	}
+	k.Locale, _ = LocaleFromContext(ctx)
+	// map keys are encoded in sorted order, so equal arguments share a key
+	b, err := json.Marshal(k)
+	if err != nil {
+		return c.render(ctx, params)
+	}
+	key := string(b)
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.results[key]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.result, nil
+	}
+
+	result, err := c.render(ctx, params)
+	if err != nil {
+		return result, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, r := range c.results {
+		if !now.Before(r.expires) {
+			delete(c.results, k)
+		}
+	}
+	c.results[key] = cachedPrompt{result: result, expires: now.Add(c.ttl)}
+	return result, nil
+}
+
+// WithPromptErrorMessages reports the errors returned when rendering a prompt
+// to clients as the message of an internal error, with secrets masked, so that
+// they can be told apart from prompts that rendered. By default clients are
+// sent a generic message and the error is only logged.
+func WithPromptErrorMessages() ServerOption {
+	return func(h *handler) {
+		h.promptErrorMessages = true
+	}
+}
+
+// UserMessage returns a prompt message from the user for each item of
+// content. A prompt result can be assembled from several calls:
+//
+//	result := mcp.GetPromptResult{Messages: slices.Concat(
+//		mcp.UserMessage(mcp.NewTextContent("Review this file"), file),
+//		mcp.AssistantMessage(mcp.NewTextContent("Here is my review")),
+//	)}
+func UserMessage(content ...Content) []PromptMessage {
+	return messages(RoleUser, content)
+}
+
+// AssistantMessage returns a prompt message from the assistant for each item
+// of content.
+func AssistantMessage(content ...Content) []PromptMessage {
+	return messages(RoleAssistant, content)
+}
+
+func messages(role Role, content []Content) []PromptMessage {
+	msgs := make([]PromptMessage, 0, len(content))
+	for _, c := range content {
+		msgs = append(msgs, PromptMessage{Role: role, Content: c})
+	}
+	return msgs
+}
+
+// Valid reports whether r is a role defined by the protocol.
+func (r Role) Valid() bool {
+	return slices.Contains(enumValues_Role, any(string(r)))
+}
+
+// Validate checks that every message of the result has a legal role and
+// content.
+func (j GetPromptResult) Validate() error {
+	for i, m := range j.Messages {
+		if !m.Role.Valid() {
+			return fmt.Errorf("message %d: invalid role %q", i, m.Role)
+		}
+		if m.Content == nil {
+			return fmt.Errorf("message %d: content required", i)
+		}
+	}
+	return nil
+}
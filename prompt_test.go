@@ -0,0 +1,275 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Prompt messages", func() {
+
+	It("assembles messages for each role", func() {
+		result := mcp.GetPromptResult{Messages: slices.Concat(
+			mcp.UserMessage(mcp.NewTextContent("Review this"), mcp.NewTextResource("file:///main.go", "text/x-go", "package main")),
+			mcp.AssistantMessage(mcp.NewTextContent("Looks good")),
+		)}
+		Expect(result.Validate()).To(Succeed())
+
+		b, err := json.Marshal(result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"messages":[
+			{"role":"user","content":{"type":"text","text":"Review this"}},
+			{"role":"user","content":{"type":"resource","resource":{"uri":"file:///main.go","mimeType":"text/x-go","text":"package main"}}},
+			{"role":"assistant","content":{"type":"text","text":"Looks good"}}
+		]}`))
+	})
+
+	It("validates roles", func() {
+		Expect(mcp.RoleUser.Valid()).To(BeTrue())
+		Expect(mcp.Role("system").Valid()).To(BeFalse())
+
+		result := mcp.GetPromptResult{Messages: []mcp.PromptMessage{{Role: "system", Content: mcp.NewTextContent("be nice")}}}
+		Expect(result.Validate()).To(MatchError(`message 0: invalid role "system"`))
+	})
+
+	It("requires content", func() {
+		result := mcp.GetPromptResult{Messages: []mcp.PromptMessage{{Role: mcp.RoleUser}}}
+		Expect(result.Validate()).To(MatchError("message 0: content required"))
+	})
+})
+
+var _ = Describe("Prompts", func() {
+
+	var (
+		conn    *jsonrpc2.Conn
+		prompts []mcp.PromptDefinition
+		opts    []mcp.ServerOption
+	)
+
+	getPrompt := func(name string, args map[string]string) (mcp.GetPromptResult, error) {
+		var result mcp.GetPromptResult
+		err := conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: name, Arguments: args}, &result)
+		return result, err
+	}
+
+	BeforeEach(func() {
+		opts = nil
+		desc := "Review a change"
+		required := true
+		prompts = []mcp.PromptDefinition{{
+			Metadata: mcp.Prompt{
+				Name:        "review",
+				Description: &desc,
+				Arguments:   []mcp.PromptArgument{{Name: "change", Required: &required}},
+			},
+			Get: func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				if params.Arguments["change"] == "broken" {
+					return mcp.GetPromptResult{}, errors.New("change not found")
+				}
+				result := mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Review " + params.Arguments["change"]))}
+				if params.Arguments["change"] == "#42" {
+					instance := "Review of #42"
+					result.Description = &instance
+					result.Meta = mcp.GetPromptResultMeta{"change": "#42"}
+				}
+				return result, nil
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		conn = startServer(context.Background(), nil, append(opts, mcp.WithPrompts(prompts...))...)
+	})
+
+	It("advertises the prompts capability", func() {
+		var result mcp.InitializeResult
+		Expect(conn.Call(context.Background(), "initialize", mcp.InitializeRequestParams{ProtocolVersion: mcp.SupportedProtocolVersion}, &result)).To(Succeed())
+		Expect(result.Capabilities.Prompts).ToNot(BeNil())
+	})
+
+	It("lists prompts", func() {
+		var result mcp.ListPromptsResult
+		Expect(conn.Call(context.Background(), "prompts/list", nil, &result)).To(Succeed())
+		Expect(result.Prompts).To(Equal([]mcp.Prompt{prompts[0].Metadata}))
+	})
+
+	It("renders prompts with the prompt description by default", func() {
+		result, err := getPrompt("review", map[string]string{"change": "#7"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.Description).To(Equal("Review a change"))
+		Expect(result.Meta).To(BeNil())
+		Expect(result.Messages).To(Equal(mcp.UserMessage(mcp.TextContent{Type: "text", Text: "Review #7"})))
+	})
+
+	It("surfaces the description and metadata of the rendered prompt", func() {
+		result, err := getPrompt("review", map[string]string{"change": "#42"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.Description).To(Equal("Review of #42"))
+		Expect(result.Meta).To(Equal(mcp.GetPromptResultMeta{"change": "#42"}))
+	})
+
+	It("rejects unknown prompts", func() {
+		_, err := getPrompt("unknown", nil)
+		Expect(err).To(MatchError(ContainSubstring("Unknown prompt: unknown")))
+	})
+
+	It("rejects calls missing required arguments", func() {
+		_, err := getPrompt("review", nil)
+		Expect(err).To(MatchError(ContainSubstring("Missing required argument: change")))
+	})
+
+	It("reports prompts that fail to render", func() {
+		_, err := getPrompt("review", map[string]string{"change": "broken"})
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "Internal error"}))
+	})
+
+	Context("when prompts render with the request context", func() {
+		BeforeEach(func() {
+			prompts[0].Render = func(ctx context.Context, params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				_, ok := mcp.RequestIDFromContext(ctx)
+				Expect(ok).To(BeTrue())
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Rendered " + params.Arguments["change"]))}, nil
+			}
+		})
+
+		It("renders with Render instead of Get", func() {
+			result, err := getPrompt("review", map[string]string{"change": "#7"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Messages).To(Equal(mcp.UserMessage(mcp.NewTextContent("Rendered #7"))))
+		})
+	})
+
+	Context("when results are cached", func() {
+		var renders int
+
+		BeforeEach(func() {
+			renders = 0
+			get := prompts[0].Get
+			prompts[0].Get = func(params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				renders++
+				return get(params)
+			}
+			prompts[0].CacheTTL = 100 * time.Millisecond
+		})
+
+		It("renders prompts once for each argument values until they expire", func() {
+			for i := 0; i < 2; i++ {
+				result, err := getPrompt("review", map[string]string{"change": "#7"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Messages).To(Equal(mcp.UserMessage(mcp.TextContent{Type: "text", Text: "Review #7"})))
+			}
+			Expect(renders).To(Equal(1))
+
+			_, err := getPrompt("review", map[string]string{"change": "#8"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renders).To(Equal(2))
+
+			time.Sleep(100 * time.Millisecond)
+			_, err = getPrompt("review", map[string]string{"change": "#7"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(renders).To(Equal(3))
+		})
+
+		It("caches results separately for each principal and locale", func() {
+			prompts[0].Render = func(ctx context.Context, params mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+				renders++
+				subject := "anonymous"
+				if p, ok := mcp.PrincipalFromContext(ctx); ok {
+					subject = p.Subject
+				}
+				locale, _ := mcp.LocaleFromContext(ctx)
+				return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent(subject + " " + locale))}, nil
+			}
+			s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil, mcp.WithPrompts(prompts...))
+			render := func(ctx context.Context) string {
+				c := mcptest.NewPairContext(GinkgoT(), ctx, s).Conn
+				var result mcp.GetPromptResult
+				Expect(c.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "review", Arguments: map[string]string{"change": "#7"}}, &result)).To(Succeed())
+				return result.Messages[0].Content.(mcp.TextContent).Text
+			}
+
+			alice := mcp.ContextWithPrincipal(context.Background(), &mcp.Principal{Subject: "alice"})
+			bob := mcp.ContextWithPrincipal(context.Background(), &mcp.Principal{Subject: "bob"})
+			Expect(render(alice)).To(Equal("alice "))
+			Expect(render(bob)).To(Equal("bob "))
+			Expect(render(mcp.ContextWithLocale(alice, "de"))).To(Equal("alice de"))
+			Expect(render(context.Background())).To(Equal("anonymous "))
+			Expect(renders).To(Equal(4))
+
+			Expect(render(bob)).To(Equal("bob "))
+			Expect(renders).To(Equal(4))
+		})
+
+		It("does not cache errors", func() {
+			for i := 0; i < 2; i++ {
+				_, err := getPrompt("review", map[string]string{"change": "broken"})
+				Expect(err).To(HaveOccurred())
+			}
+			Expect(renders).To(Equal(2))
+		})
+	})
+
+	Context("when prompt error messages are reported", func() {
+		BeforeEach(func() {
+			opts = append(opts, mcp.WithPromptErrorMessages())
+		})
+
+		It("replies with the message of the error", func() {
+			_, err := getPrompt("review", map[string]string{"change": "broken"})
+			Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "change not found"}))
+		})
+	})
+})
+
+var _ = Describe("Prompt authorization", func() {
+
+	var conn *jsonrpc2.Conn
+
+	BeforeEach(func() {
+		render := func(mcp.GetPromptRequestParams) (mcp.GetPromptResult, error) {
+			return mcp.GetPromptResult{Messages: mcp.UserMessage(mcp.NewTextContent("Triage"))}, nil
+		}
+		prompts := []mcp.PromptDefinition{
+			{Metadata: mcp.Prompt{Name: "triage"}, Get: render},
+			{Metadata: mcp.Prompt{Name: "close"}, Get: render, RequiredScopes: []string{"issues:write"}},
+			{
+				Metadata: mcp.Prompt{Name: "escalate"},
+				Get:      render,
+				Authorize: func(_ context.Context, p *mcp.Principal, params mcp.GetPromptRequestParams) error {
+					if params.Arguments["severity"] == "critical" && p.Subject != "oncall" {
+						return errors.New("only on-call may escalate critical issues")
+					}
+					return nil
+				},
+			},
+		}
+		principal := &mcp.Principal{Subject: "alice", Scopes: []string{"issues:read"}}
+		conn = startServer(mcp.ContextWithPrincipal(context.Background(), principal), nil, mcp.WithPrompts(prompts...))
+	})
+
+	It("hides prompts the principal lacks the scopes for", func() {
+		var result mcp.ListPromptsResult
+		Expect(conn.Call(context.Background(), "prompts/list", nil, &result)).To(Succeed())
+		Expect(result.Prompts).To(ConsistOf(HaveField("Name", "triage"), HaveField("Name", "escalate")))
+
+		err := conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "close"}, &mcp.GetPromptResult{})
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "Unknown prompt: close"}))
+	})
+
+	It("denies requests the callback rejects", func() {
+		err := conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "escalate", Arguments: map[string]string{"severity": "critical"}}, &mcp.GetPromptResult{})
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: "permission denied: only on-call may escalate critical issues"}))
+
+		var result mcp.GetPromptResult
+		Expect(conn.Call(context.Background(), "prompts/get", mcp.GetPromptRequestParams{Name: "escalate", Arguments: map[string]string{"severity": "low"}}, &result)).To(Succeed())
+	})
+})
@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/acrmp/mcp/spec20241105"
+	"github.com/acrmp/mcp/spec20250618"
+)
+
+// SupportedProtocolVersions are the protocol versions the server negotiates,
+// latest first. Each session is sent results as defined by the revision it
+// negotiated.
+var SupportedProtocolVersions = []string{spec20250618.ProtocolVersion, spec20241105.ProtocolVersion}
+
+type protocolVersionKey struct{}
+
+func contextWithProtocolVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, protocolVersionKey{}, version)
+}
+
+// ProtocolVersionFromContext returns the protocol version negotiated by the
+// session a request belongs to.
+func ProtocolVersionFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(protocolVersionKey{}).(string)
+	return v, ok && v != ""
+}
+
+// negotiateProtocolVersion returns the version requested by the client if it
+// is supported, and otherwise the latest version.
+func negotiateProtocolVersion(requested string) string {
+	if slices.Contains(SupportedProtocolVersions, requested) {
+		return requested
+	}
+	return SupportedProtocolVersion
+}
+
+// encodeResult returns result as defined for method by the revision of
+// version. Results the revisions do not define, and those of sessions yet to
+// negotiate a version, are returned unchanged.
+func encodeResult(version, method string, result any) (any, error) {
+	if version == "" {
+		return result, nil
+	}
+	v, ok := spec20250618.NewResult(method)
+	if !ok {
+		return result, nil
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, err
+	}
+	if version == spec20241105.ProtocolVersion {
+		return spec20241105.FromSpec20250618(v)
+	}
+	return v, nil
+}
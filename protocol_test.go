@@ -0,0 +1,70 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Protocol versions", func() {
+
+	var conn *jsonrpc2.Conn
+
+	// connect initializes a session asking for version, returning the
+	// version the server answered with.
+	connect := func(version string) string {
+		GinkgoHelper()
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "add", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return mcp.NewResult().Structured(map[string]any{"sum": 3}).Resource("file:///sums/a.txt").Build()
+			},
+		}})
+		serverSide, clientSide := net.Pipe()
+		go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+		conn = jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), jsonrpc2.HandlerWithError(
+			func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+				return nil, nil
+			}))
+		DeferCleanup(conn.Close)
+		var result mcp.InitializeResult
+		Expect(conn.Call(context.Background(), "initialize", mcp.InitializeRequestParams{
+			ProtocolVersion: version,
+			ClientInfo:      mcp.Implementation{Name: "TestClient", Version: "1.0.0"},
+		}, &result)).To(Succeed())
+		Expect(conn.Notify(context.Background(), "notifications/initialized", nil)).To(Succeed())
+		return result.ProtocolVersion
+	}
+
+	call := func() string {
+		GinkgoHelper()
+		var result json.RawMessage
+		Expect(conn.Call(context.Background(), "tools/call", mcp.CallToolRequestParams{Name: "add"}, &result)).To(Succeed())
+		return string(result)
+	}
+
+	DescribeTable("negotiates the version asked for if it is supported",
+		func(asked, answered string) {
+			Expect(connect(asked)).To(Equal(answered))
+		},
+		Entry("2025-06-18", "2025-06-18", "2025-06-18"),
+		Entry("2024-11-05", "2024-11-05", "2024-11-05"),
+		Entry("unsupported", "2025-03-26", mcp.SupportedProtocolVersion),
+	)
+
+	It("sends results as defined by 2025-06-18", func() {
+		connect("2025-06-18")
+		Expect(call()).To(MatchJSON(`{"content":[{"type":"text","text":"{\"sum\":3}"},{"type":"resource_link","uri":"file:///sums/a.txt","name":"a.txt"}],"structuredContent":{"sum":3}}`))
+	})
+
+	It("sends results as defined by 2024-11-05", func() {
+		connect("2024-11-05")
+		Expect(call()).To(MatchJSON(`{"content":[{"type":"text","text":"{\"sum\":3}"},{"type":"text","text":"file:///sums/a.txt"}]}`))
+	})
+})
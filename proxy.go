@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// NewProxy returns a server that offers the tools and prompts of upstream,
+// identifying as it, and forwards any other request, such as those for
+// resources. Tool calls pass through the server's usual handling, so opts
+// such as WithPolicy, WithAuditLog, WithRedactor and WithRateLimit put policy
+// in front of a server that cannot be modified.
+//
+// Forwarded requests are subject to the same options: the policy is checked
+// against their method, such as "resources/read", they are audited with the
+// method in place of the tool name, and their results are redacted if the
+// redactor redacts content. Requests the policy does not allow are answered
+// as if the method were not found.
+//
+// Tools and prompts are listed once, when the proxy is created.
+func NewProxy(ctx context.Context, upstream *Client, opts ...ServerOption) (*Server, error) {
+	info := upstream.InitializeResult().ServerInfo
+	upstreamTools, err := upstream.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tools: %w", err)
+	}
+	upstreamPrompts, err := upstream.ListPrompts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing prompts: %w", err)
+	}
+	tools := make([]ToolDefinition, len(upstreamTools))
+	for i, t := range upstreamTools {
+		tools[i] = forwardTool(upstream, t, t.Name)
+	}
+	prompts := make([]PromptDefinition, len(upstreamPrompts))
+	for i, p := range upstreamPrompts {
+		prompts[i] = forwardPrompt(upstream, p, p.Name)
+	}
+	opts = append([]ServerOption{WithPrompts(prompts...), withUpstream(upstream)}, opts...)
+	return NewServer(info, tools, opts...), nil
+}
+
+func withUpstream(c *Client) ServerOption {
+	return func(h *handler) {
+		h.upstream = c
+	}
+}
+
+// forward makes req on the upstream and replies with its result or error.
+// Notifications are not forwarded.
+func (h *handler) forward(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Notif {
+		return
+	}
+	var params any
+	audited := CallToolRequestParams{Name: req.Method}
+	if req.Params != nil {
+		params = req.Params
+		// params that are not an object are audited without arguments
+		json.Unmarshal(*req.Params, &audited.Arguments)
+	}
+
+	if !h.available(ctx, req.Method, nil) {
+		h.audit(ctx, audited, nil, AuditOutcomeDenied, "not allowed by policy")
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeMethodNotFound,
+			Message: "Method not found",
+		})
+		return
+	}
+	if h.rateLimit != nil && !h.rateLimit.Allow() {
+		h.audit(ctx, audited, nil, AuditOutcomeDenied, "rate limit exceeded")
+		h.replyWithJSONRPCError(ctx, conn, req, errRateLimited)
+		return
+	}
+
+	var result json.RawMessage
+	if err := h.upstream.Call(ctx, req.Method, params, &result); err != nil {
+		var rpcErr *jsonrpc2.Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "Upstream unavailable"}
+		}
+		h.audit(ctx, audited, nil, AuditOutcomeError, rpcErr.Message)
+		h.replyWithJSONRPCError(ctx, conn, req, rpcErr)
+		return
+	}
+	h.audit(ctx, audited, nil, AuditOutcomeSuccess, "")
+
+	if h.redactor != nil && h.redactor.Content {
+		redacted, err := redactJSON(h.redactor, result)
+		if err != nil {
+			slog.Error("problem redacting forwarded result", "method", req.Method, "error", err)
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInternalError,
+				Message: "Internal error",
+			})
+			return
+		}
+		result = redacted
+	}
+	h.replyWithResult(ctx, conn, req, result)
+}
+
+// redactJSON returns the JSON value b with r applied to the strings and
+// fields it holds.
+func redactJSON(r *Redactor, b json.RawMessage) (json.RawMessage, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(r.Value(v))
+}
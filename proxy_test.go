@@ -0,0 +1,152 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+	"golang.org/x/time/rate"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Proxy", func() {
+
+	// upstream answers as a server offering resources, which this package
+	// does not serve itself.
+	upstream := func() *mcp.Client {
+		serverSide, clientSide := net.Pipe()
+		handler := jsonrpc2.HandlerWithError(func(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+			if req.Notif {
+				return nil, nil
+			}
+			switch req.Method {
+			case "initialize":
+				return mcp.InitializeResult{
+					ProtocolVersion: mcp.SupportedProtocolVersion,
+					ServerInfo:      mcp.Implementation{Name: "ThirdParty", Version: "2.0.0"},
+					Capabilities: mcp.ServerCapabilities{
+						Tools:     &mcp.ServerCapabilitiesTools{},
+						Resources: &mcp.ServerCapabilitiesResources{},
+					},
+				}, nil
+			case "tools/list":
+				return mcp.ListToolsResult{Tools: []mcp.Tool{
+					{Name: "deploy_staging", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+					{Name: "deploy_production", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				}}, nil
+			case "tools/call":
+				var params mcp.CallToolRequestParams
+				json.Unmarshal(*req.Params, &params)
+				return textResult("deployed with token s3cr3t by " + params.Name), nil
+			case "resources/list":
+				return map[string]any{"resources": []any{map[string]any{"uri": "file:///README.md", "name": "README.md"}}}, nil
+			}
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}
+		})
+		conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide), handler)
+		// the upstream disconnects once the client is closed; closing it as
+		// well would race with that
+		DeferCleanup(func() { <-conn.DisconnectNotify() })
+		c, err := mcp.NewClient(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), mcp.Implementation{Name: "Proxy", Version: "1.0.0"})
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(c.Close)
+		return c
+	}
+
+	It("identifies as the upstream and forwards tool calls", func() {
+		s, err := mcp.NewProxy(context.Background(), upstream())
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+		Expect(c.InitializeResult().ServerInfo).To(Equal(mcp.Implementation{Name: "ThirdParty", Version: "2.0.0"}))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "deploy_staging"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("deployed with token s3cr3t by deploy_staging")}))
+	})
+
+	It("forwards requests it does not handle itself", func() {
+		s, err := mcp.NewProxy(context.Background(), upstream())
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+		Expect(c.InitializeResult().Capabilities.Resources).ToNot(BeNil())
+
+		var result map[string]any
+		Expect(c.Call(context.Background(), "resources/list", nil, &result)).To(Succeed())
+		Expect(result).To(HaveKeyWithValue("resources", HaveLen(1)))
+
+		err = c.Call(context.Background(), "completion/complete", nil, nil)
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}))
+	})
+
+	It("applies the policy to forwarded requests", func() {
+		policy := &mcp.Policy{Rules: []mcp.PolicyRule{{Allow: []string{"deploy_*", "resources/list"}}}}
+		s, err := mcp.NewProxy(context.Background(), upstream(), mcp.WithPolicy(policy))
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+
+		Expect(c.Call(context.Background(), "resources/list", nil, nil)).To(Succeed())
+		err = c.Call(context.Background(), "resources/read", map[string]any{"uri": "file:///etc/passwd"}, nil)
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "Method not found"}))
+	})
+
+	It("audits and redacts forwarded requests", func() {
+		buf := &bytes.Buffer{}
+		redactor := &mcp.Redactor{Content: true, Patterns: []*regexp.Regexp{regexp.MustCompile(`README`)}}
+		s, err := mcp.NewProxy(context.Background(), upstream(), mcp.WithAuditLog(mcp.NewAuditLog(buf, "")), mcp.WithRedactor(redactor))
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+
+		var result map[string]any
+		Expect(c.Call(context.Background(), "resources/list", map[string]any{"cursor": "abc"}, &result)).To(Succeed())
+		Expect(result).To(Equal(map[string]any{"resources": []any{map[string]any{"uri": "file:///[REDACTED].md", "name": "[REDACTED].md"}}}))
+
+		var rec mcp.AuditRecord
+		Expect(json.Unmarshal(buf.Bytes(), &rec)).To(Succeed())
+		Expect(rec.Tool).To(Equal("resources/list"))
+		Expect(rec.Arguments).To(MatchJSON(`{"cursor":"abc"}`))
+		Expect(rec.Outcome).To(Equal(mcp.AuditOutcomeSuccess))
+	})
+
+	It("rate limits forwarded requests", func() {
+		s, err := mcp.NewProxy(context.Background(), upstream(), mcp.WithRateLimit(rate.NewLimiter(rate.Every(time.Hour), 2)))
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+
+		Expect(c.Call(context.Background(), "resources/list", nil, nil)).To(Succeed())
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "deploy_staging"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsError).To(BeNil())
+
+		err = c.Call(context.Background(), "resources/list", nil, nil)
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: "rate limit exceeded"}))
+		result, err = c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "deploy_staging"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+	})
+
+	It("applies server options to forwarded calls", func() {
+		policy := &mcp.Policy{Rules: []mcp.PolicyRule{{Allow: []string{"deploy_staging"}}}}
+		redactor := &mcp.Redactor{Content: true, Patterns: []*regexp.Regexp{regexp.MustCompile(`s3cr3t`)}}
+		s, err := mcp.NewProxy(context.Background(), upstream(), mcp.WithPolicy(policy), mcp.WithRedactor(redactor))
+		Expect(err).ToNot(HaveOccurred())
+		c := newClient(s)
+
+		tools, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(ConsistOf(HaveField("Name", "deploy_staging")))
+
+		_, err = c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "deploy_production"})
+		Expect(err).To(MatchError(ContainSubstring("Unknown tool: deploy_production")))
+
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "deploy_staging"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content[0].(mcp.TextContent).Text).ToNot(ContainSubstring("s3cr3t"))
+	})
+})
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Usage is the consumption of a session.
+type Usage struct {
+	Calls         int64
+	ExecutionTime time.Duration
+	Bytes         int64
+}
+
+func (u Usage) add(delta Usage) Usage {
+	return Usage{
+		Calls:         u.Calls + delta.Calls,
+		ExecutionTime: u.ExecutionTime + delta.ExecutionTime,
+		Bytes:         u.Bytes + delta.Bytes,
+	}
+}
+
+// Budget limits the usage of a session. Zero values are unlimited.
+type Budget struct {
+	MaxCalls         int64
+	MaxExecutionTime time.Duration
+	MaxBytes         int64
+}
+
+// exhausted describes the first limit of the budget reached by u, or returns
+// the empty string if none has been.
+func (b Budget) exhausted(u Usage) string {
+	switch {
+	case b.MaxCalls > 0 && u.Calls >= b.MaxCalls:
+		return fmt.Sprintf("call limit of %d reached", b.MaxCalls)
+	case b.MaxExecutionTime > 0 && u.ExecutionTime >= b.MaxExecutionTime:
+		return fmt.Sprintf("execution time limit of %s reached", b.MaxExecutionTime)
+	case b.MaxBytes > 0 && u.Bytes >= b.MaxBytes:
+		return fmt.Sprintf("limit of %d bytes returned reached", b.MaxBytes)
+	}
+	return ""
+}
+
+// UsageStore persists the usage of sessions. Stores that also implement
+// DeleteUsage(ctx, sessionID) error are asked to forget the usage of a
+// session when it ends.
+type UsageStore interface {
+	Usage(ctx context.Context, sessionID string) (Usage, error)
+	AddUsage(ctx context.Context, sessionID string, delta Usage) error
+}
+
+// MemoryUsageStore is a UsageStore that keeps usage in memory.
+type MemoryUsageStore struct {
+	mu    sync.Mutex
+	usage map[string]Usage
+}
+
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{usage: map[string]Usage{}}
+}
+
+func (m *MemoryUsageStore) Usage(_ context.Context, sessionID string) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage[sessionID], nil
+}
+
+func (m *MemoryUsageStore) AddUsage(_ context.Context, sessionID string, delta Usage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage[sessionID] = m.usage[sessionID].add(delta)
+	return nil
+}
+
+// DeleteUsage forgets the usage of a session.
+func (m *MemoryUsageStore) DeleteUsage(_ context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.usage, sessionID)
+	return nil
+}
+
+type quota struct {
+	budget Budget
+	store  UsageStore
+}
+
+// WithQuota tracks the tool calls of each session in store and rejects calls
+// once the session has exhausted budget.
+func WithQuota(budget Budget, store UsageStore) ServerOption {
+	return func(h *handler) {
+		h.quota = &quota{budget: budget, store: store}
+	}
+}
+
+// release forgets the usage of a session that has ended, if the store
+// supports it.
+func (q *quota) release(ctx context.Context, sessionID string) {
+	store, ok := q.store.(interface {
+		DeleteUsage(ctx context.Context, sessionID string) error
+	})
+	if !ok {
+		return
+	}
+	if err := store.DeleteUsage(ctx, sessionID); err != nil {
+		slog.Error("problem deleting session usage", "session", sessionID, "error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package mcp_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Quotas", func() {
+
+	var (
+		store *mcp.MemoryUsageStore
+		tools []mcp.ToolDefinition
+	)
+
+	BeforeEach(func() {
+		store = mcp.NewMemoryUsageStore()
+		tools = []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "repeat", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				time.Sleep(10 * time.Millisecond)
+				return textResult(strings.Repeat("a", 100)), nil
+			},
+		}}
+	})
+
+	quotaError := func(reason string) mcp.Contents {
+		return mcp.Contents{mcp.TextContent{Type: "text", Text: "quota exceeded: " + reason}}
+	}
+
+	It("rejects calls once the call limit is reached", func() {
+		ctx := mcp.ContextWithSessionID(context.Background(), "session-1")
+		conn := startServer(ctx, tools, mcp.WithQuota(mcp.Budget{MaxCalls: 2}, store))
+		for i := 0; i < 2; i++ {
+			result, err := callTool(conn, "repeat", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.IsError).To(BeNil())
+		}
+		result, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(quotaError("call limit of 2 reached")))
+	})
+
+	It("tracks execution time and bytes returned", func() {
+		ctx := mcp.ContextWithSessionID(context.Background(), "session-1")
+		conn := startServer(ctx, tools, mcp.WithQuota(mcp.Budget{}, store))
+		_, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		usage, err := store.Usage(context.Background(), "session-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage.Calls).To(BeEquivalentTo(1))
+		Expect(usage.ExecutionTime).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(usage.Bytes).To(BeNumerically(">", 100))
+	})
+
+	It("rejects calls once the bytes budget is exhausted", func() {
+		ctx := mcp.ContextWithSessionID(context.Background(), "session-1")
+		conn := startServer(ctx, tools, mcp.WithQuota(mcp.Budget{MaxBytes: 100}, store))
+		_, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(quotaError("limit of 100 bytes returned reached")))
+	})
+
+	It("rejects calls once the execution time budget is exhausted", func() {
+		ctx := mcp.ContextWithSessionID(context.Background(), "session-1")
+		conn := startServer(ctx, tools, mcp.WithQuota(mcp.Budget{MaxExecutionTime: 5 * time.Millisecond}, store))
+		_, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(quotaError("execution time limit of 5ms reached")))
+	})
+
+	It("tracks each session separately", func() {
+		budget := mcp.Budget{MaxCalls: 1}
+		first := startServer(mcp.ContextWithSessionID(context.Background(), "session-1"), tools, mcp.WithQuota(budget, store))
+		second := startServer(mcp.ContextWithSessionID(context.Background(), "session-2"), tools, mcp.WithQuota(budget, store))
+
+		result, err := callTool(first, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsError).To(BeNil())
+
+		result, err = callTool(second, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsError).To(BeNil())
+	})
+
+	It("forgets the usage of sessions that end", func() {
+		conn := startServer(mcp.ContextWithSessionID(context.Background(), "session-1"), tools, mcp.WithQuota(mcp.Budget{}, store))
+		_, err := callTool(conn, "repeat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Usage(context.Background(), "session-1")).To(HaveField("Calls", BeEquivalentTo(1)))
+
+		Expect(conn.Close()).To(Succeed())
+		Eventually(func() (mcp.Usage, error) {
+			return store.Usage(context.Background(), "session-1")
+		}).Should(BeZero())
+	})
+})
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"regexp"
+	"strings"
+)
+
+const defaultRedactionReplacement = "[REDACTED]"
+
+// RedactMatcher reports whether the value stored under key is sensitive. Key
+// is empty for values that are not stored under a field, such as the
+// elements of a list.
+type RedactMatcher func(key string, value any) bool
+
+// Redactor removes sensitive data from tool arguments before they are logged
+// and, if Content is set, from the text content of tool results.
+type Redactor struct {
+	// Fields are the names of fields whose values are always redacted. Names
+	// are matched case-insensitively at any depth.
+	Fields []string
+	// Patterns are matched against string values and the matching substrings
+	// redacted.
+	Patterns []*regexp.Regexp
+	// Matchers redact whole values they report as sensitive.
+	Matchers []RedactMatcher
+	// Replacement is substituted for redacted data. It defaults to
+	// "[REDACTED]".
+	Replacement string
+	// Content enables redaction of text content returned to clients.
+	Content bool
+}
+
+// WithRedactor sets the Redactor applied to logged tool arguments and,
+// optionally, to outgoing tool result content.
+func WithRedactor(r *Redactor) ServerOption {
+	return func(h *handler) {
+		h.redactor = r
+	}
+}
+
+// Arguments returns a redacted copy of args.
+func (r *Redactor) Arguments(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	return r.fields(args)
+}
+
+// Value returns a redacted copy of v, descending into maps and slices.
+func (r *Redactor) Value(v any) any {
+	return r.value("", v)
+}
+
+func (r *Redactor) value(key string, v any) any {
+	if r == nil {
+		return v
+	}
+	if key != "" && r.sensitiveField(key) {
+		return r.replacement()
+	}
+	for _, m := range r.Matchers {
+		if m(key, v) {
+			return r.replacement()
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]any:
+		return r.fields(v)
+	case CallToolRequestParamsArguments:
+		return CallToolRequestParamsArguments(r.fields(v))
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = r.value("", e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// fields returns a redacted copy of m. Matchers see each field, but not m
+// itself, which is not stored under a field.
+func (r *Redactor) fields(m map[string]any) map[string]any {
+	if r == nil {
+		return m
+	}
+	out := make(map[string]any, len(m))
+	for k, e := range m {
+		out[k] = r.value(k, e)
+	}
+	return out
+}
+
+// String redacts the substrings of s matching Patterns.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.Patterns {
+		s = p.ReplaceAllLiteralString(s, r.replacement())
+	}
+	return s
+}
+
+// Result returns a copy of result with its text and structured content
+// redacted.
+func (r *Redactor) Result(result CallToolResult) CallToolResult {
+	if r == nil {
+		return result
+	}
+	if result.StructuredContent != nil {
+		result.StructuredContent = r.fields(result.StructuredContent)
+	}
+	if len(result.Content) == 0 {
+		return result
+	}
+	content := make(Contents, len(result.Content))
+	for i, c := range result.Content {
+		switch c := c.(type) {
+		case TextContent:
+			c.Text = r.String(c.Text)
+			content[i] = c
+		case *TextContent:
+			redacted := *c
+			redacted.Text = r.String(c.Text)
+			content[i] = &redacted
+		default:
+			content[i] = c
+		}
+	}
+	result.Content = content
+	return result
+}
+
+func (r *Redactor) sensitiveField(key string) bool {
+	for _, f := range r.Fields {
+		if strings.EqualFold(f, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) replacement() string {
+	if r.Replacement == "" {
+		return defaultRedactionReplacement
+	}
+	return r.Replacement
+}
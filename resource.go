@@ -0,0 +1,221 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// CodeResourceNotFound is the code of the protocol error returned for reads
+// of resources the server does not offer.
+const CodeResourceNotFound = -32002
+
+// ResourceDefinition is a resource offered by a Server.
+type ResourceDefinition struct {
+	Metadata Resource
+	// Read returns the contents of the resource. A *jsonrpc2.Error it returns
+	// is passed to the client with secrets masked; other errors are logged
+	// and reported as internal errors.
+	Read func(ctx context.Context, params ReadResourceRequestParams) (ReadResourceResult, error)
+	// RequiredScopes lists the scopes a principal must hold for the resource
+	// to be listed or read.
+	RequiredScopes []string
+}
+
+// ResourceTemplateDefinition is a template describing resources a Server
+// offers without listing them, such as the files of a repository.
+type ResourceTemplateDefinition struct {
+	Metadata ResourceTemplate
+	// Matches reports whether uri is one of the resources described by the
+	// template. It defaults to matching the URI template of Metadata.
+	Matches func(uri string) bool
+	// Read returns the contents of a resource described by the template, as
+	// for ResourceDefinition.
+	Read func(ctx context.Context, params ReadResourceRequestParams) (ReadResourceResult, error)
+	// RequiredScopes lists the scopes a principal must hold for the template
+	// to be listed or its resources read.
+	RequiredScopes []string
+}
+
+// WithResources sets the resources offered by the server. The policy set by
+// WithPolicy is checked against the names of resources.
+func WithResources(resources ...ResourceDefinition) ServerOption {
+	return func(h *handler) {
+		if h.resources == nil {
+			h.resources = make(map[string]ResourceDefinition, len(resources))
+		}
+		for _, r := range resources {
+			h.resourceMetadata = append(h.resourceMetadata, r.Metadata)
+			h.resources[r.Metadata.Uri] = r
+		}
+	}
+}
+
+// WithResourceTemplates sets the resource templates offered by the server.
+// Reads of URIs that are not those of a resource set by WithResources are
+// passed to the first template that matches them.
+func WithResourceTemplates(templates ...ResourceTemplateDefinition) ServerOption {
+	return func(h *handler) {
+		for _, t := range templates {
+			if t.Matches == nil {
+				t.Matches = uriTemplateMatcher(t.Metadata.UriTemplate)
+			}
+			h.resourceTemplates = append(h.resourceTemplates, t)
+		}
+	}
+}
+
+// servesResources reports whether the server offers resources of its own, as
+// opposed to forwarding requests for them to an upstream.
+func (h *handler) servesResources() bool {
+	return len(h.resources) > 0 || len(h.resourceTemplates) > 0
+}
+
+func (h *handler) handleListResources(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ListResourcesRequestParams
+	if req.Params != nil {
+		// cursors are not supported so any cursor provided is invalid
+		if err := json.Unmarshal(*req.Params, &params); err != nil || params.Cursor != nil {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInvalidParams,
+				Message: "Invalid params",
+			})
+			return
+		}
+	}
+	resources := make([]Resource, 0, len(h.resourceMetadata))
+	for _, r := range h.resourceMetadata {
+		if h.available(ctx, r.Name, h.resources[r.Uri].RequiredScopes) {
+			resources = append(resources, r)
+		}
+	}
+	h.replyWithResult(ctx, conn, req, ListResourcesResult{Resources: resources})
+}
+
+func (h *handler) handleListResourceTemplates(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ListResourceTemplatesRequestParams
+	if req.Params != nil {
+		if err := json.Unmarshal(*req.Params, &params); err != nil || params.Cursor != nil {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInvalidParams,
+				Message: "Invalid params",
+			})
+			return
+		}
+	}
+	templates := make([]ResourceTemplate, 0, len(h.resourceTemplates))
+	for _, t := range h.resourceTemplates {
+		if h.available(ctx, t.Metadata.Name, t.RequiredScopes) {
+			templates = append(templates, t.Metadata)
+		}
+	}
+	h.replyWithResult(ctx, conn, req, ListResourceTemplatesResult{ResourceTemplates: templates})
+}
+
+func (h *handler) handleReadResource(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ReadResourceRequestParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "Invalid params",
+		})
+		return
+	}
+
+	read := h.resourceReader(ctx, params.Uri)
+	if read == nil {
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    CodeResourceNotFound,
+			Message: "Resource not found",
+		})
+		return
+	}
+	if h.rateLimit != nil && !h.rateLimit.Allow() {
+		h.replyWithJSONRPCError(ctx, conn, req, errRateLimited)
+		return
+	}
+
+	result, err := read(ctx, params)
+	if err != nil {
+		var rpcErr *jsonrpc2.Error
+		if errors.As(err, &rpcErr) {
+			h.replyWithJSONRPCError(ctx, conn, req, h.protocolError(rpcErr.Code, rpcErr.Message, nil, nil))
+			return
+		}
+		slog.Error("problem reading resource", "uri", params.Uri, "error", h.secrets.Mask(err.Error()))
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInternalError,
+			Message: "Internal error",
+		})
+		return
+	}
+	if result.Contents == nil {
+		result.Contents = []any{}
+	}
+	h.replyWithResult(ctx, conn, req, result)
+}
+
+// resourceReader returns the function reading the resource at uri, or nil if
+// the caller may not read it.
+func (h *handler) resourceReader(ctx context.Context, uri string) func(context.Context, ReadResourceRequestParams) (ReadResourceResult, error) {
+	if r, ok := h.resources[uri]; ok {
+		if !h.available(ctx, r.Metadata.Name, r.RequiredScopes) {
+			return nil
+		}
+		return r.Read
+	}
+	for _, t := range h.resourceTemplates {
+		if t.Matches(uri) && h.available(ctx, t.Metadata.Name, t.RequiredScopes) {
+			return t.Read
+		}
+	}
+	return nil
+}
+
+// uriTemplateMatcher returns a function reporting whether a URI could have
+// been expanded from the RFC 6570 template tmpl. Simple expressions match a
+// single path segment; reserved and fragment expansion match anything.
+func uriTemplateMatcher(tmpl string) func(string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for tmpl != "" {
+		start := strings.IndexByte(tmpl, '{')
+		end := strings.IndexByte(tmpl, '}')
+		if start < 0 || end < start {
+			b.WriteString(regexp.QuoteMeta(tmpl))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(tmpl[:start]))
+		expr := tmpl[start+1 : end]
+		tmpl = tmpl[end+1:]
+		var op byte
+		if expr != "" {
+			op = expr[0]
+		}
+		switch op {
+		case '+', '#':
+			b.WriteString(`.*`)
+		case '/':
+			b.WriteString(`(/[^/?#]*)*`)
+		case '.':
+			b.WriteString(`(\.[^/?#.]*)*`)
+		case ';':
+			b.WriteString(`(;[^/?#]*)*`)
+		case '?', '&':
+			b.WriteString(`([?&][^#]*)?`)
+		default:
+			b.WriteString(`[^/?#]*`)
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return func(string) bool { return false }
+	}
+	return re.MatchString
+}
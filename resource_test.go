@@ -0,0 +1,104 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Resources", func() {
+
+	var (
+		c         *mcp.Client
+		resources []mcp.ResourceDefinition
+		templates []mcp.ResourceTemplateDefinition
+		opts      []mcp.ServerOption
+	)
+
+	text := func(uri, text string) mcp.ReadResourceResult {
+		return mcp.ReadResourceResult{Contents: []any{mcp.TextResourceContents{Uri: uri, Text: text}}}
+	}
+
+	BeforeEach(func() {
+		opts = nil
+		resources = []mcp.ResourceDefinition{{
+			Metadata: mcp.Resource{Uri: "file:///README.md", Name: "README.md"},
+			Read: func(_ context.Context, params mcp.ReadResourceRequestParams) (mcp.ReadResourceResult, error) {
+				return text(params.Uri, "# Project"), nil
+			},
+		}, {
+			Metadata: mcp.Resource{Uri: "file:///secrets.env", Name: "secrets.env"},
+			Read: func(context.Context, mcp.ReadResourceRequestParams) (mcp.ReadResourceResult, error) {
+				return mcp.ReadResourceResult{}, errors.New("reading /srv/secrets.env: permission denied")
+			},
+			RequiredScopes: []string{"secrets:read"},
+		}}
+		templates = []mcp.ResourceTemplateDefinition{{
+			Metadata: mcp.ResourceTemplate{UriTemplate: "repo://{owner}/{repo}/issues{/number}", Name: "issues"},
+			Read: func(_ context.Context, params mcp.ReadResourceRequestParams) (mcp.ReadResourceResult, error) {
+				if strings.HasSuffix(params.Uri, "/0") {
+					return mcp.ReadResourceResult{}, &jsonrpc2.Error{Code: mcp.CodeResourceNotFound, Message: "no issue 0"}
+				}
+				return text(params.Uri, "issue"), nil
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		opts = append(opts, mcp.WithResources(resources...), mcp.WithResourceTemplates(templates...))
+		c = newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil, opts...))
+	})
+
+	It("advertises and lists the resources and templates the caller may read", func() {
+		Expect(c.InitializeResult().Capabilities.Resources).ToNot(BeNil())
+
+		listed, err := c.ListResources(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(Equal([]mcp.Resource{{Uri: "file:///README.md", Name: "README.md"}}))
+
+		listedTemplates, err := c.ListResourceTemplates(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listedTemplates).To(Equal([]mcp.ResourceTemplate{templates[0].Metadata}))
+	})
+
+	It("reads resources", func() {
+		result, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "file:///README.md"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Contents).To(Equal([]any{map[string]any{"uri": "file:///README.md", "text": "# Project"}}))
+	})
+
+	It("reads resources matching a template", func() {
+		result, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "repo://acrmp/mcp/issues/12"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Contents).To(Equal([]any{map[string]any{"uri": "repo://acrmp/mcp/issues/12", "text": "issue"}}))
+
+		_, err = c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "repo://acrmp/mcp/issues/0"})
+		Expect(err).To(MatchError(&jsonrpc2.Error{Code: mcp.CodeResourceNotFound, Message: "no issue 0"}))
+	})
+
+	It("does not find resources that are not offered or that the caller may not read", func() {
+		for _, uri := range []string{"file:///etc/passwd", "repo://acrmp/mcp/pulls/1", "file:///secrets.env"} {
+			_, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: uri})
+			Expect(err).To(MatchError(&jsonrpc2.Error{Code: mcp.CodeResourceNotFound, Message: "Resource not found"}))
+		}
+	})
+
+	Context("when the caller holds the required scopes", func() {
+		BeforeEach(func() {
+			opts = append(opts, mcp.WithScopeMapper(func(*mcp.Principal) []string {
+				return []string{"secrets:read"}
+			}))
+		})
+
+		It("reports errors reading resources without their message", func() {
+			_, err := c.ReadResource(context.Background(), mcp.ReadResourceRequestParams{Uri: "file:///secrets.env"})
+			Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "Internal error"}))
+		})
+	})
+})
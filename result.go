@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"net/url"
+	"path"
+)
+
+// StructuredResult returns a result holding v, which must encode as a JSON
+// object, as structured content. The encoded JSON is also returned as text
+// content for clients that do not support structured content.
+func StructuredResult(v any) (CallToolResult, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return CallToolResult{}, err
+	}
+	var structured map[string]any
+	if err := json.Unmarshal(b, &structured); err != nil || structured == nil {
+		return CallToolResult{}, fmt.Errorf("structured content must be a JSON object, got %s", b)
+	}
+	return CallToolResult{
+		Content:           []Content{TextContent{Type: "text", Text: string(b)}},
+		StructuredContent: structured,
+	}, nil
+}
+
+// ResultBuilder assembles a CallToolResult from content added in order. It
+// is created by NewResult.
+type ResultBuilder struct {
+	result CallToolResult
+	err    error
+}
+
+// NewResult returns a builder for a tool result, for example:
+//
+//	return mcp.NewResult().Text("rendered chart").Image(chart).Build()
+func NewResult() *ResultBuilder {
+	return &ResultBuilder{result: CallToolResult{Content: Contents{}}}
+}
+
+// Content appends c.
+func (b *ResultBuilder) Content(c ...Content) *ResultBuilder {
+	b.result.Content = append(b.result.Content, c...)
+	return b
+}
+
+// Text appends a text block.
+func (b *ResultBuilder) Text(text string) *ResultBuilder {
+	return b.Content(NewTextContent(text))
+}
+
+// Image appends img encoded as PNG.
+func (b *ResultBuilder) Image(img image.Image) *ResultBuilder {
+	c, err := EncodeImageContent(img, "image/png")
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Content(c)
+}
+
+// ImageData appends encoded image data. If mimeType is empty it is detected
+// from data.
+func (b *ResultBuilder) ImageData(data []byte, mimeType string) *ResultBuilder {
+	return b.Content(NewImageContent(data, mimeType))
+}
+
+// Audio appends encoded audio data. If mimeType is empty it is detected from
+// data.
+func (b *ResultBuilder) Audio(data []byte, mimeType string) *ResultBuilder {
+	return b.Content(NewAudioContent(data, mimeType))
+}
+
+// Resource appends a link to the resource at uri, named by the last element
+// of its path.
+func (b *ResultBuilder) Resource(uri string) *ResultBuilder {
+	name := uri
+	if u, err := url.Parse(uri); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		name = path.Base(u.Path)
+	}
+	return b.Content(NewResourceLink(uri, name))
+}
+
+// Structured sets the structured content of the result to v, which must
+// encode as a JSON object, and appends its JSON as a text block.
+func (b *ResultBuilder) Structured(v any) *ResultBuilder {
+	r, err := StructuredResult(v)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	b.result.StructuredContent = r.StructuredContent
+	return b.Content(r.Content...)
+}
+
+// Error sets whether the result reports a tool error.
+func (b *ResultBuilder) Error(isError bool) *ResultBuilder {
+	b.result.IsError = &isError
+	return b
+}
+
+// Build returns the result, or the first error encountered adding content.
+func (b *ResultBuilder) Build() (CallToolResult, error) {
+	if b.err != nil {
+		return CallToolResult{}, b.err
+	}
+	return b.result, nil
+}
+
+func (b *ResultBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
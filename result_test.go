@@ -0,0 +1,89 @@
+package mcp_test
+
+import (
+	"encoding/json"
+	"image"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("StructuredResult", func() {
+
+	type forecast struct {
+		City        string  `json:"city"`
+		Temperature float64 `json:"temperature"`
+	}
+
+	It("returns structured content with an equivalent text block", func() {
+		result, err := mcp.StructuredResult(forecast{City: "London", Temperature: 12.5})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.StructuredContent).To(Equal(map[string]any{"city": "London", "temperature": 12.5}))
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.TextContent{Type: "text", Text: `{"city":"London","temperature":12.5}`}}))
+
+		b, err := json.Marshal(result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{
+			"content":[{"type":"text","text":"{\"city\":\"London\",\"temperature\":12.5}"}],
+			"structuredContent":{"city":"London","temperature":12.5}
+		}`))
+	})
+
+	It("requires a JSON object", func() {
+		_, err := mcp.StructuredResult([]string{"a"})
+		Expect(err).To(MatchError(`structured content must be a JSON object, got ["a"]`))
+		_, err = mcp.StructuredResult(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ResultBuilder", func() {
+
+	It("assembles content in order", func() {
+		img := image.NewGray(image.Rect(0, 0, 1, 1))
+		result, err := mcp.NewResult().
+			Text("chart rendered").
+			Image(img).
+			Resource("file:///reports/q1.pdf").
+			Error(false).
+			Build()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeFalse())
+		Expect(result.Content).To(HaveLen(3))
+		Expect(result.Content[0]).To(Equal(mcp.TextContent{Type: "text", Text: "chart rendered"}))
+		Expect(result.Content[1]).To(BeAssignableToTypeOf(mcp.ImageContent{}))
+		Expect(result.Content[1].(mcp.ImageContent).MimeType).To(Equal("image/png"))
+		Expect(result.Content[2]).To(Equal(mcp.NewResourceLink("file:///reports/q1.pdf", "q1.pdf")))
+
+		b, err := json.Marshal(result)
+		Expect(err).ToNot(HaveOccurred())
+		var decoded mcp.CallToolResult
+		Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(result))
+	})
+
+	It("builds results without content", func() {
+		b, err := json.Marshal(mustBuild(mcp.NewResult()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"content":[]}`))
+	})
+
+	It("adds structured content", func() {
+		result := mustBuild(mcp.NewResult().Structured(map[string]int{"count": 2}))
+		Expect(result.StructuredContent).To(Equal(map[string]any{"count": 2.0}))
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.TextContent{Type: "text", Text: `{"count":2}`}}))
+	})
+
+	It("reports the first error", func() {
+		_, err := mcp.NewResult().Text("a").Structured("not an object").Build()
+		Expect(err).To(MatchError(ContainSubstring("structured content must be a JSON object")))
+	})
+})
+
+func mustBuild(b *mcp.ResultBuilder) mcp.CallToolResult {
+	result, err := b.Build()
+	Expect(err).ToNot(HaveOccurred())
+	return result
+}
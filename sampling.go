@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ErrSamplingUnsupported is returned by a Sampler whose client did not offer
+// the sampling capability.
+var ErrSamplingUnsupported = errors.New("client does not support sampling")
+
+// ErrMaxIterations is returned by SamplingLoop.Run when the model is still
+// calling tools after the last message it may sample.
+var ErrMaxIterations = errors.New("sampling loop reached its iteration limit")
+
+// ErrTokenBudget is returned by SamplingLoop.Run when the model is still
+// calling tools once the token budget of the loop is spent.
+var ErrTokenBudget = errors.New("sampling loop spent its token budget")
+
+// Sampler requests messages from the model of the client, with
+// sampling/createMessage.
+type Sampler interface {
+	CreateMessage(ctx context.Context, params CreateMessageRequestParams) (CreateMessageResult, error)
+}
+
+type samplerKey struct{}
+
+func contextWithSampler(ctx context.Context, s Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, s)
+}
+
+// SamplerFromContext returns a Sampler for the client calling a tool, from
+// the context passed to its Process function. Tool calls run concurrently
+// with the handling of other messages, so a tool may wait for the client to
+// reply.
+func SamplerFromContext(ctx context.Context) (Sampler, bool) {
+	s, ok := ctx.Value(samplerKey{}).(Sampler)
+	return s, ok
+}
+
+// connSampler sends sampling requests on conn, if the client offered the
+// sampling capability.
+type connSampler struct {
+	conn      *jsonrpc2.Conn
+	supported bool
+}
+
+func (s connSampler) CreateMessage(ctx context.Context, params CreateMessageRequestParams) (CreateMessageResult, error) {
+	if !s.supported {
+		return CreateMessageResult{}, ErrSamplingUnsupported
+	}
+	var result CreateMessageResult
+	if err := s.conn.Call(ctx, "sampling/createMessage", params, &result); err != nil {
+		return CreateMessageResult{}, fmt.Errorf("sampling: %w", err)
+	}
+	return result, nil
+}
+
+// DefaultSamplingMaxIterations is the number of messages SamplingLoop.Run
+// samples at most when MaxIterations is not set.
+const DefaultSamplingMaxIterations = 10
+
+// DefaultSamplingMaxTokens is the number of tokens SamplingLoop.Run asks
+// for in each message when MaxTokens is not set.
+const DefaultSamplingMaxTokens = 1024
+
+// SamplingLoop runs a conversation with the model of the client in which
+// the model may call tools: each message the model replies with that calls
+// a tool is answered with the result of the call, until the model replies
+// with its answer. For example, a tool can hand a task to the model:
+//
+//	sampler, _ := mcp.SamplerFromContext(ctx)
+//	loop := mcp.SamplingLoop{Tools: tools, MaxIterations: 5, TokenBudget: 4000}
+//	answer, err := loop.Run(ctx, sampler, []mcp.SamplingMessage{{
+//		Role:    mcp.RoleUser,
+//		Content: mcp.NewTextContent("Summarize the open incidents"),
+//	}}, n)
+//
+// Sampling requests do not describe tools, so the loop describes them in
+// the system prompt and asks the model to call one by replying with only a
+// JSON object such as {"tool": "search", "arguments": {"q": "x"}}.
+type SamplingLoop struct {
+	// Tools are the tools the model may call.
+	Tools []ToolDefinition
+	// SystemPrompt is sent ahead of the description of the tools.
+	SystemPrompt     string
+	ModelPreferences *ModelPreferences
+	// MaxTokens limits the tokens of each message sampled. It defaults to
+	// DefaultSamplingMaxTokens.
+	MaxTokens int
+	// MaxIterations limits the messages sampled. It defaults to
+	// DefaultSamplingMaxIterations.
+	MaxIterations int
+	// TokenBudget, if set, limits the tokens of all the messages sampled.
+	// Clients do not report the tokens used, so each message is charged the
+	// tokens it asks for, and the last may ask for fewer than MaxTokens.
+	TokenBudget int
+}
+
+// toolCall is how the model asks for a tool to be called.
+type toolCall struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// Run samples messages following messages until the model replies without
+// calling a tool, and returns that reply. The calls of tools are passed n.
+func (l *SamplingLoop) Run(ctx context.Context, s Sampler, messages []SamplingMessage, n Notifier) (CreateMessageResult, error) {
+	maxTokens := l.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultSamplingMaxTokens
+	}
+	maxIterations := l.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultSamplingMaxIterations
+	}
+	tools := make(map[string]ToolDefinition, len(l.Tools))
+	for _, t := range l.Tools {
+		tools[t.Metadata.Name] = t
+	}
+	system, err := l.systemPrompt()
+	if err != nil {
+		return CreateMessageResult{}, err
+	}
+	messages = append([]SamplingMessage(nil), messages...)
+	spent := 0
+	for range maxIterations {
+		tokens := maxTokens
+		if l.TokenBudget > 0 {
+			if spent >= l.TokenBudget {
+				return CreateMessageResult{}, ErrTokenBudget
+			}
+			tokens = min(tokens, l.TokenBudget-spent)
+		}
+		spent += tokens
+		result, err := s.CreateMessage(ctx, CreateMessageRequestParams{
+			Messages:         messages,
+			MaxTokens:        tokens,
+			ModelPreferences: l.ModelPreferences,
+			SystemPrompt:     system,
+		})
+		if err != nil {
+			return CreateMessageResult{}, err
+		}
+		call, ok := parseToolCall(result.Content)
+		if !ok || len(tools) == 0 {
+			return result, nil
+		}
+		messages = append(messages,
+			SamplingMessage{Role: RoleAssistant, Content: result.Content},
+			SamplingMessage{Role: RoleUser, Content: NewTextContent(l.callTool(ctx, tools, call, n))},
+		)
+	}
+	return CreateMessageResult{}, ErrMaxIterations
+}
+
+// systemPrompt returns the system prompt describing the tools.
+func (l *SamplingLoop) systemPrompt() (*string, error) {
+	if len(l.Tools) == 0 {
+		if l.SystemPrompt == "" {
+			return nil, nil
+		}
+		return &l.SystemPrompt, nil
+	}
+	var b strings.Builder
+	if l.SystemPrompt != "" {
+		b.WriteString(l.SystemPrompt)
+		b.WriteString("\n\n")
+	}
+	b.WriteString(`You may call the tools below. To call one, reply with only a JSON object naming the tool and its arguments, such as {"tool": "name", "arguments": {}}, and the result will be sent to you. Otherwise reply with your answer.` + "\n")
+	for _, t := range l.Tools {
+		schema, err := json.Marshal(t.Metadata.InputSchema)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "\n%s", t.Metadata.Name)
+		if t.Metadata.Description != nil {
+			fmt.Fprintf(&b, ": %s", *t.Metadata.Description)
+		}
+		fmt.Fprintf(&b, "\nArguments: %s\n", schema)
+	}
+	s := b.String()
+	return &s, nil
+}
+
+// parseToolCall returns the tool call c asks for, if it is text holding only
+// a tool call.
+func parseToolCall(c Content) (toolCall, bool) {
+	text, ok := c.(TextContent)
+	if !ok {
+		return toolCall{}, false
+	}
+	s := strings.TrimSpace(text.Text)
+	// models often fence JSON as code
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "```"), "```"))
+	var call toolCall
+	if !strings.HasPrefix(s, "{") || json.Unmarshal([]byte(s), &call) != nil || call.Tool == "" {
+		return toolCall{}, false
+	}
+	return call, true
+}
+
+// callTool calls a tool for the model, returning the text of its result,
+// or of the error, for the model to read.
+func (l *SamplingLoop) callTool(ctx context.Context, tools map[string]ToolDefinition, call toolCall, n Notifier) string {
+	t, ok := tools[call.Tool]
+	if !ok {
+		return fmt.Sprintf("Error: unknown tool %s", call.Tool)
+	}
+	if call.Arguments == nil {
+		call.Arguments = map[string]any{}
+	}
+	result, err := t.execute(ctx, CallToolRequestParams{Name: call.Tool, Arguments: call.Arguments}, n)
+	if err != nil {
+		return fmt.Sprintf("Error: %s", err)
+	}
+	var texts []string
+	for _, c := range result.Content {
+		if text, ok := c.(TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if result.IsError != nil && *result.IsError {
+		return "Error: " + text
+	}
+	return text
+}
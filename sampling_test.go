@@ -0,0 +1,201 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Sampler", func() {
+
+	var (
+		tools   []mcp.ToolDefinition
+		sampled chan mcp.CreateMessageRequestParams
+	)
+
+	BeforeEach(func() {
+		sampled = make(chan mcp.CreateMessageRequestParams, 1)
+		tools = []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "summarize", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+				s, ok := mcp.SamplerFromContext(ctx)
+				Expect(ok).To(BeTrue())
+				result, err := s.CreateMessage(ctx, mcp.CreateMessageRequestParams{
+					Messages:  []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent("summarize")}},
+					MaxTokens: 100,
+				})
+				if err != nil {
+					return mcp.CallToolResult{}, err
+				}
+				return textResult(result.Content.(mcp.TextContent).Text), nil
+			},
+		}}
+	})
+
+	// connect initializes a session with the client capabilities given,
+	// answering sampling requests.
+	connect := func(capabilities map[string]any) *jsonrpc2.Conn {
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools)
+		serverSide, clientSide := net.Pipe()
+		go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+		conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), jsonrpc2.HandlerWithError(
+			func(_ context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+				var params mcp.CreateMessageRequestParams
+				Expect(req.Method).To(Equal("sampling/createMessage"))
+				Expect(json.Unmarshal(*req.Params, &params)).To(Succeed())
+				sampled <- params
+				return mcp.CreateMessageResult{Role: mcp.RoleAssistant, Model: "test", Content: mcp.NewTextContent("a summary")}, nil
+			}))
+		DeferCleanup(conn.Close)
+		var result mcp.InitializeResult
+		// an empty sampling capability is omitted by ClientCapabilities
+		Expect(conn.Call(context.Background(), "initialize", map[string]any{
+			"protocolVersion": mcp.SupportedProtocolVersion,
+			"clientInfo":      mcp.Implementation{Name: "TestClient", Version: "1.0.0"},
+			"capabilities":    capabilities,
+		}, &result)).To(Succeed())
+		Expect(conn.Notify(context.Background(), "notifications/initialized", nil)).To(Succeed())
+		return conn
+	}
+
+	It("lets tools sample messages from the client", func() {
+		conn := connect(map[string]any{"sampling": map[string]any{}})
+		result, err := callTool(conn, "summarize", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(textResult("a summary")))
+
+		var params mcp.CreateMessageRequestParams
+		Eventually(sampled).Should(Receive(&params))
+		Expect(params.MaxTokens).To(Equal(100))
+		Expect(params.Messages[0].Content).To(Equal(mcp.NewTextContent("summarize")))
+	})
+
+	It("fails without sampling when the client does not support it", func() {
+		conn := connect(map[string]any{})
+		result, err := callTool(conn, "summarize", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent(mcp.ErrSamplingUnsupported.Error())}))
+		Expect(sampled).ToNot(Receive())
+	})
+})
+
+// fakeSampler replies with its replies in turn, recording the requests.
+type fakeSampler struct {
+	replies  []string
+	requests []mcp.CreateMessageRequestParams
+}
+
+func (s *fakeSampler) CreateMessage(_ context.Context, params mcp.CreateMessageRequestParams) (mcp.CreateMessageResult, error) {
+	s.requests = append(s.requests, params)
+	if len(s.replies) == 0 {
+		return mcp.CreateMessageResult{}, errors.New("no more replies")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return mcp.CreateMessageResult{Role: mcp.RoleAssistant, Model: "test", Content: mcp.NewTextContent(reply)}, nil
+}
+
+var _ = Describe("SamplingLoop", func() {
+
+	var (
+		loop    mcp.SamplingLoop
+		sampler *fakeSampler
+		calls   []map[string]any
+	)
+
+	BeforeEach(func() {
+		calls = nil
+		description := "Look up the weather"
+		loop = mcp.SamplingLoop{
+			SystemPrompt: "You are a forecaster.",
+			Tools: []mcp.ToolDefinition{{
+				Metadata: mcp.Tool{
+					Name:        "weather",
+					Description: &description,
+					InputSchema: mcp.ToolInputSchema{Type: "object", Required: []string{"city"}},
+				},
+				Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					calls = append(calls, params.Arguments)
+					if params.Arguments["city"] == "Atlantis" {
+						return mcp.NewResult().Text("no such city").Error(true).Build()
+					}
+					return textResult("sunny"), nil
+				},
+			}},
+		}
+		sampler = &fakeSampler{}
+	})
+
+	run := func() (mcp.CreateMessageResult, error) {
+		return loop.Run(context.Background(), sampler, []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent("Weather in Paris?")}}, nil)
+	}
+
+	It("calls the tools the model asks for until it answers", func() {
+		sampler.replies = []string{`{"tool": "weather", "arguments": {"city": "Paris"}}`, "It is sunny in Paris."}
+		result, err := run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.NewTextContent("It is sunny in Paris.")))
+		Expect(calls).To(Equal([]map[string]any{{"city": "Paris"}}))
+
+		Expect(sampler.requests).To(HaveLen(2))
+		Expect(*sampler.requests[0].SystemPrompt).To(HavePrefix("You are a forecaster.\n\n"))
+		Expect(*sampler.requests[0].SystemPrompt).To(ContainSubstring("\nweather: Look up the weather\nArguments: {\"required\":[\"city\"],\"type\":\"object\"}\n"))
+		Expect(sampler.requests[0].MaxTokens).To(Equal(mcp.DefaultSamplingMaxTokens))
+		Expect(sampler.requests[1].Messages).To(Equal([]mcp.SamplingMessage{
+			{Role: mcp.RoleUser, Content: mcp.NewTextContent("Weather in Paris?")},
+			{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(`{"tool": "weather", "arguments": {"city": "Paris"}}`)},
+			{Role: mcp.RoleUser, Content: mcp.NewTextContent("sunny")},
+		}))
+	})
+
+	It("accepts tool calls fenced as code", func() {
+		sampler.replies = []string{"```json\n{\"tool\": \"weather\", \"arguments\": {\"city\": \"Oslo\"}}\n```", "Done."}
+		_, err := run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal([]map[string]any{{"city": "Oslo"}}))
+	})
+
+	It("tells the model of unknown tools and tool errors", func() {
+		sampler.replies = []string{`{"tool": "forecast"}`, `{"tool": "weather", "arguments": {"city": "Atlantis"}}`, "Sorry."}
+		_, err := run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sampler.requests[1].Messages[2].Content).To(Equal(mcp.NewTextContent("Error: unknown tool forecast")))
+		Expect(sampler.requests[2].Messages[4].Content).To(Equal(mcp.NewTextContent("Error: no such city")))
+	})
+
+	It("stops once the model has called tools MaxIterations times", func() {
+		loop.MaxIterations = 2
+		sampler.replies = []string{`{"tool": "weather", "arguments": {"city": "Paris"}}`, `{"tool": "weather", "arguments": {"city": "Rome"}}`, "Done."}
+		_, err := run()
+		Expect(err).To(MatchError(mcp.ErrMaxIterations))
+		Expect(sampler.requests).To(HaveLen(2))
+	})
+
+	It("stops once the token budget is spent", func() {
+		loop.MaxTokens = 100
+		loop.TokenBudget = 250
+		call := `{"tool": "weather", "arguments": {"city": "Paris"}}`
+		sampler.replies = []string{call, call, call, "Done."}
+		_, err := run()
+		Expect(err).To(MatchError(mcp.ErrTokenBudget))
+		Expect(sampler.requests).To(HaveLen(3))
+		Expect(sampler.requests[2].MaxTokens).To(Equal(50))
+	})
+
+	It("sends the system prompt alone when there are no tools", func() {
+		loop.Tools = nil
+		sampler.replies = []string{`{"tool": "weather"}`}
+		result, err := run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.NewTextContent(`{"tool": "weather"}`)))
+		Expect(*sampler.requests[0].SystemPrompt).To(Equal("You are a forecaster."))
+	})
+})
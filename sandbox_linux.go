@@ -0,0 +1,139 @@
+//go:build linux
+// +build linux
+
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// sandboxExecEnv asks a server binary re-executed by a sandboxed command to
+// apply the limits it holds and then exec the command, so that the limits
+// are in place before the command runs. Go offers no way to set resource
+// limits between fork and exec.
+const sandboxExecEnv = "MCP_SANDBOX_EXEC"
+
+// sandboxExec is the work of a re-executed server binary.
+type sandboxExec struct {
+	CPUSeconds  uint64 `json:"cpuSeconds,omitempty"`
+	MemoryBytes uint64 `json:"memoryBytes,omitempty"`
+	Chroot      string `json:"chroot,omitempty"`
+	Path        string `json:"path"`
+}
+
+func init() {
+	if spec, ok := os.LookupEnv(sandboxExecEnv); ok {
+		if err := execSandboxed(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "sandboxing command: %s\n", err)
+		}
+		os.Exit(126)
+	}
+}
+
+func (s Sandbox) configure(c *exec.Cmd) error {
+	// the command runs in a process group of its own, so that the processes
+	// it starts can be killed with it
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	if s.Chroot && s.Dir == "" {
+		return fmt.Errorf("chroot requires a directory")
+	}
+	if s.CPUTime > 0 || s.MemoryBytes > 0 {
+		if err := s.reexec(c); err != nil {
+			return err
+		}
+	} else if s.Chroot {
+		attr.Chroot = s.Dir
+		c.Dir = "/"
+	}
+	if s.NoNetwork {
+		attr.Cloneflags = syscall.CLONE_NEWNET
+		if uid := os.Geteuid(); uid != 0 {
+			// unprivileged processes need a user namespace to create a
+			// network namespace
+			gid := os.Getegid()
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		}
+	}
+	c.SysProcAttr = attr
+	return nil
+}
+
+// killGroup kills the process group of c.
+func killGroup(c *exec.Cmd) error {
+	return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+}
+
+// reexec runs c through the server binary, which applies the resource
+// limits of s, and the chroot, before it execs the command. The command
+// keeps the process, so the namespaces and timeout of c still apply.
+func (s Sandbox) reexec(c *exec.Cmd) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding the server binary to apply limits: %w", err)
+	}
+	spec := sandboxExec{MemoryBytes: s.MemoryBytes, Path: c.Path}
+	if s.CPUTime > 0 {
+		spec.CPUSeconds = uint64((s.CPUTime + 999_999_999) / 1_000_000_000)
+	}
+	if s.Chroot {
+		spec.Chroot = s.Dir
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	env := c.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	c.Env = append(env[:len(env):len(env)], sandboxExecEnv+"="+string(b))
+	c.Path = self
+	return nil
+}
+
+// execSandboxed applies the limits of spec to this process and replaces it
+// with the command. It only returns on failure.
+func execSandboxed(spec string) error {
+	var s sandboxExec
+	if err := json.Unmarshal([]byte(spec), &s); err != nil {
+		return err
+	}
+	if s.CPUSeconds > 0 {
+		if err := setrlimit(syscall.RLIMIT_CPU, s.CPUSeconds); err != nil {
+			return fmt.Errorf("limiting cpu time: %w", err)
+		}
+	}
+	if s.MemoryBytes > 0 {
+		if err := setrlimit(syscall.RLIMIT_AS, s.MemoryBytes); err != nil {
+			return fmt.Errorf("limiting memory: %w", err)
+		}
+	}
+	if s.Chroot != "" {
+		if err := syscall.Chroot(s.Chroot); err != nil {
+			return fmt.Errorf("chroot: %w", err)
+		}
+		if err := os.Chdir("/"); err != nil {
+			return err
+		}
+	}
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, sandboxExecEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	args := os.Args
+	args[0] = s.Path
+	return syscall.Exec(s.Path, args, env)
+}
+
+func setrlimit(resource int, limit uint64) error {
+	return syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit, Max: limit})
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+func (s Sandbox) configure(c *exec.Cmd) error {
+	if s.Chroot || s.NoNetwork {
+		return fmt.Errorf("sandboxing commands: %w", errors.ErrUnsupported)
+	}
+	if s.CPUTime > 0 || s.MemoryBytes > 0 {
+		return fmt.Errorf("limiting command resources: %w", errors.ErrUnsupported)
+	}
+	return nil
+}
+
+// killGroup kills c, as processes cannot be grouped to be killed together.
+func killGroup(c *exec.Cmd) error {
+	return c.Process.Kill()
+}
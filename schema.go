@@ -15,6 +15,10 @@ type Annotations struct {
 	// audiences (e.g., `["user", "assistant"]`).
 	Audience []Role `json:"audience,omitempty" yaml:"audience,omitempty" mapstructure:"audience,omitempty"`
 
+	// When the annotated object was last modified, as an ISO 8601 formatted
+	// string (e.g., "2025-01-12T15:00:58Z").
+	LastModified *string `json:"lastModified,omitempty" yaml:"lastModified,omitempty" mapstructure:"lastModified,omitempty"`
+
 	// Describes how important this data is for operating the server.
 	//
 	// A value of 1 means "most important," and indicates that the data is
@@ -186,12 +190,16 @@ type CallToolResult struct {
 	Meta CallToolResultMeta `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
 
 	// Content corresponds to the JSON schema field "content".
-	Content []interface{} `json:"content" yaml:"content" mapstructure:"content"`
+	Content Contents `json:"content" yaml:"content" mapstructure:"content"`
 
 	// Whether the tool call ended in an error.
 	//
 	// If not set, this is assumed to be false (the call was successful).
 	IsError *bool `json:"isError,omitempty" yaml:"isError,omitempty" mapstructure:"isError,omitempty"`
+
+	// An optional JSON object that represents the structured result of the tool
+	// call.
+	StructuredContent map[string]interface{} `json:"structuredContent,omitempty" yaml:"structuredContent,omitempty" mapstructure:"structuredContent,omitempty"`
 }
 
 // This result property is reserved by the protocol to allow clients and servers to
@@ -601,7 +609,7 @@ type CreateMessageResult struct {
 	Meta CreateMessageResultMeta `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
 
 	// Content corresponds to the JSON schema field "content".
-	Content interface{} `json:"content" yaml:"content" mapstructure:"content"`
+	Content Content `json:"content" yaml:"content" mapstructure:"content"`
 
 	// The name of the model that generated the message.
 	Model string `json:"model" yaml:"model" mapstructure:"model"`
@@ -617,30 +625,6 @@ type CreateMessageResult struct {
 // attach additional metadata to their responses.
 type CreateMessageResultMeta map[string]interface{}
 
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *CreateMessageResult) UnmarshalJSON(b []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
-	}
-	if _, ok := raw["content"]; raw != nil && !ok {
-		return fmt.Errorf("field content in CreateMessageResult: required")
-	}
-	if _, ok := raw["model"]; raw != nil && !ok {
-		return fmt.Errorf("field model in CreateMessageResult: required")
-	}
-	if _, ok := raw["role"]; raw != nil && !ok {
-		return fmt.Errorf("field role in CreateMessageResult: required")
-	}
-	type Plain CreateMessageResult
-	var plain Plain
-	if err := json.Unmarshal(b, &plain); err != nil {
-		return err
-	}
-	*j = CreateMessageResult(plain)
-	return nil
-}
-
 // An opaque token used to represent a cursor for pagination.
 type Cursor string
 
@@ -653,33 +637,12 @@ type EmbeddedResource struct {
 	Annotations *Annotations `json:"annotations,omitempty" yaml:"annotations,omitempty" mapstructure:"annotations,omitempty"`
 
 	// Resource corresponds to the JSON schema field "resource".
-	Resource interface{} `json:"resource" yaml:"resource" mapstructure:"resource"`
+	Resource EmbeddedResourceContents `json:"resource" yaml:"resource" mapstructure:"resource"`
 
 	// Type corresponds to the JSON schema field "type".
 	Type string `json:"type" yaml:"type" mapstructure:"type"`
 }
 
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *EmbeddedResource) UnmarshalJSON(b []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
-	}
-	if _, ok := raw["resource"]; raw != nil && !ok {
-		return fmt.Errorf("field resource in EmbeddedResource: required")
-	}
-	if _, ok := raw["type"]; raw != nil && !ok {
-		return fmt.Errorf("field type in EmbeddedResource: required")
-	}
-	type Plain EmbeddedResource
-	var plain Plain
-	if err := json.Unmarshal(b, &plain); err != nil {
-		return err
-	}
-	*j = EmbeddedResource(plain)
-	return nil
-}
-
 // Used by the client to get a prompt provided by the server.
 type GetPromptRequest struct {
 	// Method corresponds to the JSON schema field "method".
@@ -2071,33 +2034,12 @@ func (j *PromptListChangedNotification) UnmarshalJSON(b []byte) error {
 // resources from the MCP server.
 type PromptMessage struct {
 	// Content corresponds to the JSON schema field "content".
-	Content interface{} `json:"content" yaml:"content" mapstructure:"content"`
+	Content Content `json:"content" yaml:"content" mapstructure:"content"`
 
 	// Role corresponds to the JSON schema field "role".
 	Role Role `json:"role" yaml:"role" mapstructure:"role"`
 }
 
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *PromptMessage) UnmarshalJSON(b []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
-	}
-	if _, ok := raw["content"]; raw != nil && !ok {
-		return fmt.Errorf("field content in PromptMessage: required")
-	}
-	if _, ok := raw["role"]; raw != nil && !ok {
-		return fmt.Errorf("field role in PromptMessage: required")
-	}
-	type Plain PromptMessage
-	var plain Plain
-	if err := json.Unmarshal(b, &plain); err != nil {
-		return err
-	}
-	*j = PromptMessage(plain)
-	return nil
-}
-
 // Identifies a prompt.
 type PromptReference struct {
 	// The name of the prompt or prompt template
@@ -2647,33 +2589,12 @@ func (j *RootsListChangedNotification) UnmarshalJSON(b []byte) error {
 // Describes a message issued to or received from an LLM API.
 type SamplingMessage struct {
 	// Content corresponds to the JSON schema field "content".
-	Content interface{} `json:"content" yaml:"content" mapstructure:"content"`
+	Content Content `json:"content" yaml:"content" mapstructure:"content"`
 
 	// Role corresponds to the JSON schema field "role".
 	Role Role `json:"role" yaml:"role" mapstructure:"role"`
 }
 
-// UnmarshalJSON implements json.Unmarshaler.
-func (j *SamplingMessage) UnmarshalJSON(b []byte) error {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
-	}
-	if _, ok := raw["content"]; raw != nil && !ok {
-		return fmt.Errorf("field content in SamplingMessage: required")
-	}
-	if _, ok := raw["role"]; raw != nil && !ok {
-		return fmt.Errorf("field role in SamplingMessage: required")
-	}
-	type Plain SamplingMessage
-	var plain Plain
-	if err := json.Unmarshal(b, &plain); err != nil {
-		return err
-	}
-	*j = SamplingMessage(plain)
-	return nil
-}
-
 // Capabilities that a server may support. Known capabilities are defined here, in
 // this schema, but this is not a closed set: any server can define its own,
 // additional capabilities.
@@ -0,0 +1,353 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+)
+
+// ErrContentBlocked is returned by a ContentScreener to prevent content from
+// reaching its destination.
+var ErrContentBlocked = errors.New("content blocked")
+
+// ContentScreener inspects content passing between clients and tools or
+// prompts. Each method returns the content to use in place of its input,
+// which may be unchanged, annotated or rewritten. An error blocks the call
+// and is reported to the client as a tool error, or as a protocol error for
+// prompts.
+type ContentScreener interface {
+	// ScreenArguments screens the arguments of a call before the tool is
+	// executed.
+	ScreenArguments(ctx context.Context, tool string, args map[string]any) (map[string]any, error)
+	// ScreenResult screens a tool result, once its content has been
+	// transformed, before it is sent to the client. Both its content and
+	// its structured content are screened.
+	ScreenResult(ctx context.Context, tool string, result CallToolResult) (CallToolResult, error)
+	// ScreenPrompt screens a rendered prompt before it is sent to the
+	// client.
+	ScreenPrompt(ctx context.Context, prompt string, result GetPromptResult) (GetPromptResult, error)
+}
+
+// WithContentScreeners screens tool arguments and results, and rendered
+// prompts, with screeners, applied in order.
+func WithContentScreeners(screeners ...ContentScreener) ServerOption {
+	return func(h *handler) {
+		h.screeners = append(h.screeners, screeners...)
+	}
+}
+
+// InjectionPatterns match phrases commonly used to inject instructions into
+// content read by a model.
+var InjectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+)?(the\s+)?(previous|prior|above|earlier)\s+(instructions|prompts|directions)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(in\s+)?(developer|dan|jailbreak)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show)\s+(your|the)\s+system\s+prompt`),
+	regexp.MustCompile(`<\|(im_start|im_end|system|endoftext)\|>`),
+	regexp.MustCompile(`(?i)</?\s*(system|instructions)\s*>`),
+}
+
+// ScreenAction is what a PatternScreener does with matching content.
+type ScreenAction int
+
+const (
+	// ScreenBlock fails the call.
+	ScreenBlock ScreenAction = iota
+	// ScreenRewrite replaces the matching text.
+	ScreenRewrite
+	// ScreenAnnotate passes results and prompts through with a warning
+	// appended for the user. Matching arguments are logged and passed
+	// through.
+	ScreenAnnotate
+)
+
+const (
+	defaultScreenReplacement = "[REMOVED]"
+	defaultScreenWarning     = "Warning: this content may contain instructions intended to manipulate the model and should not be trusted."
+)
+
+// PatternScreener is a ContentScreener that looks for text matching
+// Patterns, which default to InjectionPatterns.
+type PatternScreener struct {
+	Patterns []*regexp.Regexp
+	Action   ScreenAction
+	// Replacement is substituted for matching text by ScreenRewrite. It
+	// defaults to "[REMOVED]".
+	Replacement string
+	// Warning is the text appended by ScreenAnnotate.
+	Warning string
+	// Arguments enables screening of tool arguments as well as results.
+	Arguments bool
+}
+
+func (s *PatternScreener) ScreenArguments(ctx context.Context, tool string, args map[string]any) (map[string]any, error) {
+	if !s.Arguments || args == nil {
+		return args, nil
+	}
+	screened, err := s.screenValue(tool, args)
+	if err != nil {
+		return nil, err
+	}
+	return screened.(map[string]any), nil
+}
+
+func (s *PatternScreener) screenValue(tool string, v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return s.screenArgument(tool, v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			screened, err := s.screenValue(tool, e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = screened
+		}
+		return out, nil
+	case CallToolRequestParamsArguments:
+		return s.screenValue(tool, map[string]any(v))
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			screened, err := s.screenValue(tool, e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = screened
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (s *PatternScreener) screenArgument(tool, text string) (string, error) {
+	p := s.match(text)
+	if p == nil {
+		return text, nil
+	}
+	switch s.Action {
+	case ScreenRewrite:
+		return s.rewrite(text), nil
+	case ScreenAnnotate:
+		slog.Warn("screened content matched", "tool", tool, "pattern", p.String())
+		return text, nil
+	default:
+		return "", fmt.Errorf("%w: arguments matched %q", ErrContentBlocked, p.String())
+	}
+}
+
+func (s *PatternScreener) ScreenResult(ctx context.Context, tool string, result CallToolResult) (CallToolResult, error) {
+	content, matched := s.screenContents(result.Content)
+	var structured any
+	if result.StructuredContent != nil {
+		v, err := jsonValue(result.StructuredContent)
+		if err != nil {
+			return CallToolResult{}, fmt.Errorf("screening structured content: %w", err)
+		}
+		var p *regexp.Regexp
+		if structured, p = s.screenStructured(v); p != nil {
+			matched = p
+		}
+	}
+	if matched == nil {
+		return result, nil
+	}
+
+	switch s.Action {
+	case ScreenRewrite:
+		result.Content = content
+		if result.StructuredContent != nil {
+			result.StructuredContent = structured.(map[string]any)
+		}
+		return result, nil
+	case ScreenAnnotate:
+		result.Content = append(slices.Clip(result.Content), s.warning())
+		return result, nil
+	default:
+		return CallToolResult{}, fmt.Errorf("%w: result matched %q", ErrContentBlocked, matched.String())
+	}
+}
+
+// ScreenContent screens content as ScreenResult screens the content of a
+// tool result.
+func (s *PatternScreener) ScreenContent(ctx context.Context, tool string, content []Content) ([]Content, error) {
+	result, err := s.ScreenResult(ctx, tool, CallToolResult{Content: content})
+	return result.Content, err
+}
+
+func (s *PatternScreener) ScreenPrompt(ctx context.Context, prompt string, result GetPromptResult) (GetPromptResult, error) {
+	var matched *regexp.Regexp
+	messages := make([]PromptMessage, len(result.Messages))
+	for i, m := range result.Messages {
+		screened, p := s.screenContent(m.Content)
+		messages[i] = PromptMessage{Role: m.Role, Content: screened}
+		if p != nil {
+			matched = p
+		}
+	}
+	var description *string
+	if result.Description != nil {
+		d := *result.Description
+		if p := s.screenText(&d); p != nil {
+			matched = p
+		}
+		description = &d
+	}
+	if matched == nil {
+		return result, nil
+	}
+
+	switch s.Action {
+	case ScreenRewrite:
+		result.Messages = messages
+		result.Description = description
+		return result, nil
+	case ScreenAnnotate:
+		result.Messages = append(slices.Clip(result.Messages), PromptMessage{Role: RoleUser, Content: s.warning()})
+		return result, nil
+	default:
+		return GetPromptResult{}, fmt.Errorf("%w: prompt matched %q", ErrContentBlocked, matched.String())
+	}
+}
+
+// screenContents screens each item of content, returning the last pattern
+// matched and content rewritten as for ScreenRewrite.
+func (s *PatternScreener) screenContents(content []Content) ([]Content, *regexp.Regexp) {
+	var matched *regexp.Regexp
+	out := make([]Content, len(content))
+	for i, c := range content {
+		screened, p := s.screenContent(c)
+		out[i] = screened
+		if p != nil {
+			matched = p
+		}
+	}
+	return out, matched
+}
+
+// screenStructured screens the strings of structured content, returning
+// the last pattern matched and a copy of v rewritten as for ScreenRewrite.
+func (s *PatternScreener) screenStructured(v any) (any, *regexp.Regexp) {
+	var matched *regexp.Regexp
+	switch v := v.(type) {
+	case string:
+		p := s.screenText(&v)
+		return v, p
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			screened, p := s.screenStructured(e)
+			out[k] = screened
+			if p != nil {
+				matched = p
+			}
+		}
+		return out, matched
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			screened, p := s.screenStructured(e)
+			out[i] = screened
+			if p != nil {
+				matched = p
+			}
+		}
+		return out, matched
+	default:
+		return v, nil
+	}
+}
+
+func (s *PatternScreener) warning() TextContent {
+	warning := s.Warning
+	if warning == "" {
+		warning = defaultScreenWarning
+	}
+	return TextContent{
+		Type:        "text",
+		Text:        warning,
+		Annotations: &Annotations{Audience: []Role{RoleUser}},
+	}
+}
+
+// screenContent screens the text of c, which is the text of text content
+// and embedded text resources, and the name and description of resource
+// links. It returns the pattern matched, and c rewritten if the action is
+// ScreenRewrite. Binary content is not screened.
+func (s *PatternScreener) screenContent(c Content) (Content, *regexp.Regexp) {
+	switch c := c.(type) {
+	case *TextContent:
+		return s.screenContent(*c)
+	case *EmbeddedResource:
+		return s.screenContent(*c)
+	case *ResourceLink:
+		return s.screenContent(*c)
+	case TextContent:
+		p := s.screenText(&c.Text)
+		return c, p
+	case EmbeddedResource:
+		switch r := c.Resource.(type) {
+		case *TextResourceContents:
+			tr := *r
+			p := s.screenText(&tr.Text)
+			c.Resource = tr
+			return c, p
+		case TextResourceContents:
+			p := s.screenText(&r.Text)
+			c.Resource = r
+			return c, p
+		}
+	case ResourceLink:
+		p := s.screenText(&c.Name)
+		if c.Description != nil {
+			d := *c.Description
+			if dp := s.screenText(&d); dp != nil {
+				p = dp
+			}
+			c.Description = &d
+		}
+		return c, p
+	}
+	return c, nil
+}
+
+// screenText returns the pattern text matches, rewriting it in place if the
+// action is ScreenRewrite.
+func (s *PatternScreener) screenText(text *string) *regexp.Regexp {
+	p := s.match(*text)
+	if p != nil && s.Action == ScreenRewrite {
+		*text = s.rewrite(*text)
+	}
+	return p
+}
+
+func (s *PatternScreener) patterns() []*regexp.Regexp {
+	if s.Patterns == nil {
+		return InjectionPatterns
+	}
+	return s.Patterns
+}
+
+func (s *PatternScreener) match(text string) *regexp.Regexp {
+	for _, p := range s.patterns() {
+		if p.MatchString(text) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *PatternScreener) rewrite(text string) string {
+	replacement := s.Replacement
+	if replacement == "" {
+		replacement = defaultScreenReplacement
+	}
+	for _, p := range s.patterns() {
+		text = p.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
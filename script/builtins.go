@@ -0,0 +1,1486 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// failError is returned by fail, and reported to the client as a tool
+// error rather than a failure of the script.
+type failError struct {
+	msg string
+}
+
+func (e *failError) Error() string {
+	return e.msg
+}
+
+type builtinFunc = func(t *thread, b *builtin, args []value, kwargs []kwarg) (value, error)
+
+var builtins map[string]value
+
+func init() {
+	fns := map[string]builtinFunc{
+		"abs":       builtinAbs,
+		"all":       builtinAll,
+		"any":       builtinAny,
+		"bool":      builtinBool,
+		"dict":      builtinDict,
+		"enumerate": builtinEnumerate,
+		"fail":      builtinFail,
+		"float":     builtinFloat,
+		"getattr":   builtinGetattr,
+		"hasattr":   builtinHasattr,
+		"int":       builtinInt,
+		"len":       builtinLen,
+		"list":      builtinList,
+		"max":       builtinMax,
+		"min":       builtinMin,
+		"print":     builtinPrint,
+		"range":     builtinRange,
+		"repr":      builtinRepr,
+		"reversed":  builtinReversed,
+		"sorted":    builtinSorted,
+		"str":       builtinStr,
+		"tool":      builtinTool,
+		"tuple":     builtinTuple,
+		"type":      builtinType,
+		"zip":       builtinZip,
+	}
+	builtins = map[string]value{
+		"json": &module{name: "json", members: map[string]value{
+			"encode": &builtin{name: "json.encode", fn: builtinJSONEncode},
+			"decode": &builtin{name: "json.decode", fn: builtinJSONDecode},
+		}},
+	}
+	for name, fn := range fns {
+		builtins[name] = &builtin{name: name, fn: fn}
+	}
+}
+
+// unpackArgs stores args and kwargs in dst, in the order of the parameters
+// named by params. Parameters whose names end in ? are optional, and left
+// unchanged if not passed.
+func unpackArgs(args []value, kwargs []kwarg, params []string, dst ...*value) error {
+	names := make([]string, len(params))
+	set := make([]bool, len(params))
+	for i, p := range params {
+		names[i] = strings.TrimSuffix(p, "?")
+	}
+	if len(args) > len(params) {
+		return fmt.Errorf("got %d arguments, want at most %d", len(args), len(params))
+	}
+	for i, a := range args {
+		*dst[i] = a
+		set[i] = true
+	}
+	for _, kw := range kwargs {
+		i := slices.Index(names, kw.name)
+		if i < 0 {
+			return fmt.Errorf("unexpected keyword argument %s", kw.name)
+		}
+		if set[i] {
+			return fmt.Errorf("got multiple values for parameter %s", kw.name)
+		}
+		*dst[i] = kw.value
+		set[i] = true
+	}
+	for i, p := range params {
+		if !set[i] && !strings.HasSuffix(p, "?") {
+			return fmt.Errorf("missing argument for %s", p)
+		}
+	}
+	return nil
+}
+
+func noKwargs(kwargs []kwarg) error {
+	if len(kwargs) > 0 {
+		return fmt.Errorf("unexpected keyword argument %s", kwargs[0].name)
+	}
+	return nil
+}
+
+func asInt(v value, what string) (int64, error) {
+	i, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%s must be int, not %s", what, typeName(v))
+	}
+	return i, nil
+}
+
+func asString(v value, what string) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be string, not %s", what, typeName(v))
+	}
+	return s, nil
+}
+
+// elems returns the elements of the iterable x.
+func (t *thread) elems(x value) ([]value, error) {
+	var out []value
+	err := t.forEach(x, func(v value) (bool, error) {
+		out = append(out, v)
+		return true, nil
+	})
+	return out, err
+}
+
+func oneArg(args []value, kwargs []kwarg) (value, error) {
+	var x value
+	err := unpackArgs(args, kwargs, []string{"x"}, &x)
+	return x, err
+}
+
+func builtinAbs(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case int64:
+		if x < 0 {
+			return unary("-", x)
+		}
+		return x, nil
+	case float64:
+		return math.Abs(x), nil
+	}
+	return nil, fmt.Errorf("got %s, want int or float", typeName(x))
+}
+
+func builtinAll(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	result := true
+	err = t.forEach(x, func(v value) (bool, error) {
+		result = truth(v)
+		return result, nil
+	})
+	return result, err
+}
+
+func builtinAny(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	result := false
+	err = t.forEach(x, func(v value) (bool, error) {
+		result = truth(v)
+		return !result, nil
+	})
+	return result, err
+}
+
+func builtinBool(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x value = false
+	err := unpackArgs(args, kwargs, []string{"x?"}, &x)
+	return truth(x), err
+}
+
+func builtinDict(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("got %d arguments, want at most 1", len(args))
+	}
+	d := newDict()
+	if len(args) == 1 {
+		if err := t.update(d, args[0]); err != nil {
+			return nil, err
+		}
+	}
+	for _, kw := range kwargs {
+		d.set(kw.name, kw.value)
+	}
+	return d, nil
+}
+
+// update sets the entries of d from pairs, a dict or an iterable of pairs.
+func (t *thread) update(d *dict, pairs value) error {
+	if src, ok := pairs.(*dict); ok {
+		for i, k := range src.keys {
+			if err := d.set(k, src.values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	items, err := t.elems(pairs)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		kv, err := t.elems(item)
+		if err != nil || len(kv) != 2 {
+			return fmt.Errorf("dictionary update sequence element #%d is not a pair", i)
+		}
+		if err := d.set(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func builtinEnumerate(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	var start value = int64(0)
+	if err := unpackArgs(args, kwargs, []string{"x", "start?"}, &x, &start); err != nil {
+		return nil, err
+	}
+	n, err := asInt(start, "start")
+	if err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	if err != nil {
+		return nil, err
+	}
+	out := &list{elems: make([]value, len(elems))}
+	for i, e := range elems {
+		out.elems[i] = tuple{n + int64(i), e}
+	}
+	return out, nil
+}
+
+func builtinFail(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	sep := " "
+	for _, kw := range kwargs {
+		if kw.name != "sep" {
+			return nil, fmt.Errorf("unexpected keyword argument %s", kw.name)
+		}
+		s, err := asString(kw.value, "sep")
+		if err != nil {
+			return nil, err
+		}
+		sep = s
+	}
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = str(a)
+	}
+	return nil, &failError{msg: strings.Join(parts, sep)}
+}
+
+func builtinFloat(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x value = 0.0
+	if err := unpackArgs(args, kwargs, []string{"x?"}, &x); err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case bool:
+		return float64(boolInt(x)), nil
+	case int64:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float literal: %s", x)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to float", typeName(x))
+}
+
+func builtinGetattr(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x, name, def value
+	if err := unpackArgs(args, kwargs, []string{"x", "name", "default?"}, &x, &name, &def); err != nil {
+		return nil, err
+	}
+	s, err := asString(name, "name")
+	if err != nil {
+		return nil, err
+	}
+	v, err := attr(x, s)
+	if err != nil && len(args)+len(kwargs) == 3 {
+		return def, nil
+	}
+	return v, err
+}
+
+func builtinHasattr(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x, name value
+	if err := unpackArgs(args, kwargs, []string{"x", "name"}, &x, &name); err != nil {
+		return nil, err
+	}
+	s, err := asString(name, "name")
+	if err != nil {
+		return nil, err
+	}
+	_, err = attr(x, s)
+	return err == nil, nil
+}
+
+func builtinInt(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x value = int64(0)
+	var base value
+	if err := unpackArgs(args, kwargs, []string{"x?", "base?"}, &x, &base); err != nil {
+		return nil, err
+	}
+	if s, ok := x.(string); ok {
+		b := int64(10)
+		if base != nil {
+			var err error
+			if b, err = asInt(base, "base"); err != nil {
+				return nil, err
+			}
+		}
+		digits := strings.TrimSpace(s)
+		sign := ""
+		if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+			sign, digits = digits[:1], digits[1:]
+		}
+		// the prefix of the base is optional, as in Python
+		if prefix := map[int64]string{16: "0x", 8: "0o", 2: "0b"}[b]; prefix != "" && len(digits) > 2 && strings.EqualFold(digits[:2], prefix) {
+			digits = digits[2:]
+		}
+		n, err := strconv.ParseInt(sign+digits, int(b), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid literal for int() with base %d: %s", b, strconv.Quote(s))
+		}
+		return n, nil
+	}
+	if base != nil {
+		return nil, errors.New("can't convert non-string with explicit base")
+	}
+	switch x := x.(type) {
+	case bool:
+		return boolInt(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) || math.Abs(x) >= 1<<63 {
+			return nil, fmt.Errorf("cannot convert float %s to integer", formatFloat(x))
+		}
+		return int64(x), nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to int", typeName(x))
+}
+
+func builtinLen(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := length(x)
+	if !ok {
+		return nil, fmt.Errorf("value of type %s has no len", typeName(x))
+	}
+	return n, nil
+}
+
+func builtinList(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x := value(tuple{})
+	if err := unpackArgs(args, kwargs, []string{"x?"}, &x); err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	return &list{elems: elems}, err
+}
+
+func builtinMax(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	return t.extreme(args, kwargs, 1)
+}
+
+func builtinMin(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	return t.extreme(args, kwargs, -1)
+}
+
+// extreme returns the greatest of its arguments if sign is 1, or the least
+// if it is -1.
+func (t *thread) extreme(args []value, kwargs []kwarg, sign int) (value, error) {
+	var key value
+	for _, kw := range kwargs {
+		if kw.name != "key" {
+			return nil, fmt.Errorf("unexpected keyword argument %s", kw.name)
+		}
+		key = kw.value
+	}
+	elems := args
+	if len(args) == 1 {
+		var err error
+		if elems, err = t.elems(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(elems) == 0 {
+		return nil, errors.New("expected at least one item")
+	}
+	var best, bestKey value
+	for i, e := range elems {
+		k := e
+		if key != nil {
+			var err error
+			if k, err = t.call(key, []value{e}, nil); err != nil {
+				return nil, err
+			}
+		}
+		if i == 0 {
+			best, bestKey = e, k
+			continue
+		}
+		c, err := compare("<", k, bestKey)
+		if err != nil && err != errNaN {
+			return nil, err
+		}
+		if c*sign > 0 {
+			best, bestKey = e, k
+		}
+	}
+	return best, nil
+}
+
+func builtinPrint(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	sep := " "
+	for _, kw := range kwargs {
+		if kw.name != "sep" {
+			return nil, fmt.Errorf("unexpected keyword argument %s", kw.name)
+		}
+		s, err := asString(kw.value, "sep")
+		if err != nil {
+			return nil, err
+		}
+		sep = s
+	}
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = str(a)
+	}
+	t.print(strings.Join(parts, sep))
+	return nil, nil
+}
+
+func builtinRange(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	if err := noKwargs(kwargs); err != nil {
+		return nil, err
+	}
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("got %d arguments, want 1 to 3", len(args))
+	}
+	n := make([]int64, len(args))
+	for i, a := range args {
+		var err error
+		if n[i], err = asInt(a, "argument"); err != nil {
+			return nil, err
+		}
+	}
+	r := rangeValue{step: 1}
+	switch len(n) {
+	case 1:
+		r.stop = n[0]
+	case 2:
+		r.start, r.stop = n[0], n[1]
+	case 3:
+		r.start, r.stop, r.step = n[0], n[1], n[2]
+	}
+	if r.step == 0 {
+		return nil, errors.New("step argument must not be zero")
+	}
+	return r, nil
+}
+
+func builtinRepr(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	return repr(x), err
+}
+
+func builtinReversed(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	slices.Reverse(elems)
+	return &list{elems: elems}, err
+}
+
+func builtinSorted(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	var x, key value
+	var reverse value = false
+	if err := unpackArgs(args, kwargs, []string{"x", "key?", "reverse?"}, &x, &key, &reverse); err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	if err != nil {
+		return nil, err
+	}
+	keys := elems
+	if key != nil {
+		keys = make([]value, len(elems))
+		for i, e := range elems {
+			if keys[i], err = t.call(key, []value{e}, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	order := make([]int, len(elems))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		c, cerr := compare("<", keys[a], keys[b])
+		if cerr != nil && cerr != errNaN && err == nil {
+			err = cerr
+		}
+		if truth(reverse) {
+			return -c
+		}
+		return c
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := &list{elems: make([]value, len(elems))}
+	for i, k := range order {
+		out.elems[i] = elems[k]
+	}
+	return out, nil
+}
+
+func builtinStr(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	return str(x), err
+}
+
+func builtinTuple(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x := value(tuple{})
+	if err := unpackArgs(args, kwargs, []string{"x?"}, &x); err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	return tuple(elems), err
+}
+
+func builtinType(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	return typeName(x), err
+}
+
+func builtinZip(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	if err := noKwargs(kwargs); err != nil {
+		return nil, err
+	}
+	out := &list{}
+	var columns [][]value
+	for _, a := range args {
+		elems, err := t.elems(a)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, elems)
+	}
+	for i := 0; len(columns) > 0; i++ {
+		row := make(tuple, len(columns))
+		for j, c := range columns {
+			if i >= len(c) {
+				return out, nil
+			}
+			row[j] = c[i]
+		}
+		out.elems = append(out.elems, row)
+	}
+	return out, nil
+}
+
+// toolDecl is a tool declared by a script.
+type toolDecl struct {
+	name, description string
+	schema            value
+	handler           *function
+}
+
+func builtinTool(t *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	if t.tools == nil {
+		return nil, errors.New("tools may only be declared while the script is loaded")
+	}
+	var name, handler, description, schema value
+	if err := unpackArgs(args, kwargs, []string{"name", "handler", "description?", "input_schema?"}, &name, &handler, &description, &schema); err != nil {
+		return nil, err
+	}
+	d := toolDecl{schema: schema}
+	var err error
+	if d.name, err = asString(name, "name"); err != nil {
+		return nil, err
+	}
+	if description != nil {
+		if d.description, err = asString(description, "description"); err != nil {
+			return nil, err
+		}
+	}
+	if schema != nil {
+		if _, ok := schema.(*dict); !ok {
+			return nil, fmt.Errorf("input_schema must be dict, not %s", typeName(schema))
+		}
+	}
+	fn, ok := handler.(*function)
+	if !ok {
+		return nil, fmt.Errorf("handler must be a function, not %s", typeName(handler))
+	}
+	d.handler = fn
+	*t.tools = append(*t.tools, d)
+	return nil, nil
+}
+
+func builtinJSONEncode(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	b, err := encodeJSON(x)
+	return string(b), err
+}
+
+func builtinJSONDecode(_ *thread, _ *builtin, args []value, kwargs []kwarg) (value, error) {
+	x, err := oneArg(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	s, err := asString(x, "x")
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON([]byte(s))
+}
+
+// encodeJSON returns the JSON encoding of v, keeping the order of dict
+// keys.
+func encodeJSON(v value) ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeJSON(&b, v, 0); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeJSON(b *bytes.Buffer, v value, depth int) error {
+	if depth > 100 {
+		return errors.New("json.encode: value is nested too deeply")
+	}
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("cannot encode %s as JSON", formatFloat(v))
+		}
+		enc, _ := json.Marshal(v)
+		b.Write(enc)
+	case string:
+		enc, _ := json.Marshal(v)
+		b.Write(enc)
+	case *list, tuple:
+		elems := v
+		if l, ok := v.(*list); ok {
+			elems = tuple(l.elems)
+		}
+		b.WriteByte('[')
+		for i, e := range elems.(tuple) {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, e, depth+1); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case *dict:
+		b.WriteByte('{')
+		for i, k := range v.keys {
+			s, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("cannot encode dict with %s key as JSON", typeName(k))
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			enc, _ := json.Marshal(s)
+			b.Write(enc)
+			b.WriteByte(':')
+			if err := writeJSON(b, v.values[i], depth+1); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot encode %s as JSON", typeName(v))
+	}
+	return nil
+}
+
+// decodeJSON returns the value of the JSON document b, keeping the order of
+// object keys. Numbers without a fraction or exponent are ints.
+func decodeJSON(b []byte) (value, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := readJSON(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after the value")
+	}
+	return v, nil
+}
+
+func readJSON(dec *json.Decoder) (value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			l := &list{}
+			for dec.More() {
+				v, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				l.elems = append(l.elems, v)
+			}
+			_, err := dec.Token()
+			return l, err
+		}
+		d := newDict()
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			d.set(k, v)
+		}
+		_, err := dec.Token()
+		return d, err
+	case json.Number:
+		if n, err := tok.Int64(); err == nil && !strings.ContainsAny(tok.String(), ".eE") {
+			return n, nil
+		}
+		return tok.Float64()
+	}
+	return tok, nil
+}
+
+type method = func(t *thread, recv value, args []value, kwargs []kwarg) (value, error)
+
+var (
+	stringMethods map[string]method
+	listMethods   map[string]method
+	dictMethods   map[string]method
+)
+
+func init() {
+	stringMethods = map[string]method{
+		"capitalize": stringCapitalize,
+		"count":      stringCount,
+		"endswith":   stringEndswith,
+		"find":       stringFind,
+		"format":     stringFormat,
+		"index":      stringIndex,
+		"isalnum":    stringIs(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }),
+		"isalpha":    stringIs(unicode.IsLetter),
+		"isdigit":    stringIs(unicode.IsDigit),
+		"isspace":    stringIs(unicode.IsSpace),
+		"join":       stringJoin,
+		"lower":      stringMap(strings.ToLower),
+		"lstrip":     stringStrip(strings.TrimLeft, strings.TrimLeftFunc),
+		"partition":  stringPartition,
+		"replace":    stringReplace,
+		"rfind":      stringRfind,
+		"rstrip":     stringStrip(strings.TrimRight, strings.TrimRightFunc),
+		"split":      stringSplit,
+		"splitlines": stringSplitlines,
+		"startswith": stringStartswith,
+		"strip":      stringStrip(strings.Trim, strings.TrimFunc),
+		"title":      stringTitle,
+		"upper":      stringMap(strings.ToUpper),
+	}
+	listMethods = map[string]method{
+		"append": listAppend,
+		"clear":  listClear,
+		"extend": listExtend,
+		"index":  listIndex,
+		"insert": listInsert,
+		"pop":    listPop,
+		"remove": listRemove,
+	}
+	dictMethods = map[string]method{
+		"clear":      dictClear,
+		"get":        dictGet,
+		"items":      dictItems,
+		"keys":       dictKeys,
+		"pop":        dictPop,
+		"setdefault": dictSetdefault,
+		"update":     dictUpdate,
+		"values":     dictValues,
+	}
+}
+
+// attr returns the attribute name of v, which is a method bound to v or a
+// member of a module.
+func attr(v value, name string) (value, error) {
+	var methods map[string]method
+	switch v := v.(type) {
+	case string:
+		methods = stringMethods
+	case *list:
+		methods = listMethods
+	case *dict:
+		methods = dictMethods
+	case *module:
+		if m, ok := v.members[name]; ok {
+			return m, nil
+		}
+	}
+	if m, ok := methods[name]; ok {
+		return &builtin{name: typeName(v) + "." + name, recv: v, fn: func(t *thread, b *builtin, args []value, kwargs []kwarg) (value, error) {
+			return m(t, b.recv, args, kwargs)
+		}}, nil
+	}
+	return nil, fmt.Errorf("%s has no .%s field or method", typeName(v), name)
+}
+
+func stringCapitalize(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	s := recv.(string)
+	if s == "" {
+		return s, nil
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:]), nil
+}
+
+func stringCount(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var sub value
+	if err := unpackArgs(args, kwargs, []string{"sub"}, &sub); err != nil {
+		return nil, err
+	}
+	s, err := asString(sub, "sub")
+	if err != nil {
+		return nil, err
+	}
+	return int64(strings.Count(recv.(string), s)), nil
+}
+
+// affixes returns the strings x names, which is a string or a tuple of
+// strings.
+func affixes(x value) ([]string, error) {
+	if s, ok := x.(string); ok {
+		return []string{s}, nil
+	}
+	t, ok := x.(tuple)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want string or tuple of strings", typeName(x))
+	}
+	out := make([]string, len(t))
+	for i, e := range t {
+		s, err := asString(e, "element")
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func stringEndswith(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"suffix"}, &x); err != nil {
+		return nil, err
+	}
+	suffixes, err := affixes(x)
+	if err != nil {
+		return nil, err
+	}
+	return slices.ContainsFunc(suffixes, func(s string) bool { return strings.HasSuffix(recv.(string), s) }), nil
+}
+
+func stringStartswith(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"prefix"}, &x); err != nil {
+		return nil, err
+	}
+	prefixes, err := affixes(x)
+	if err != nil {
+		return nil, err
+	}
+	return slices.ContainsFunc(prefixes, func(s string) bool { return strings.HasPrefix(recv.(string), s) }), nil
+}
+
+func stringFind(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var sub value
+	if err := unpackArgs(args, kwargs, []string{"sub"}, &sub); err != nil {
+		return nil, err
+	}
+	s, err := asString(sub, "sub")
+	if err != nil {
+		return nil, err
+	}
+	return int64(strings.Index(recv.(string), s)), nil
+}
+
+func stringRfind(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var sub value
+	if err := unpackArgs(args, kwargs, []string{"sub"}, &sub); err != nil {
+		return nil, err
+	}
+	s, err := asString(sub, "sub")
+	if err != nil {
+		return nil, err
+	}
+	return int64(strings.LastIndex(recv.(string), s)), nil
+}
+
+func stringIndex(t *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	i, err := stringFind(t, recv, args, kwargs)
+	if err == nil && i.(int64) < 0 {
+		return nil, errors.New("substring not found")
+	}
+	return i, err
+}
+
+func stringIs(f func(rune) bool) method {
+	return func(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+		if err := unpackArgs(args, kwargs, nil); err != nil {
+			return nil, err
+		}
+		s := recv.(string)
+		return s != "" && strings.IndexFunc(s, func(r rune) bool { return !f(r) }) < 0, nil
+	}
+}
+
+func stringMap(f func(string) string) method {
+	return func(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+		if err := unpackArgs(args, kwargs, nil); err != nil {
+			return nil, err
+		}
+		return f(recv.(string)), nil
+	}
+}
+
+func stringTitle(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	prevLetter := false
+	for _, r := range recv.(string) {
+		if prevLetter {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToTitle(r))
+		}
+		prevLetter = unicode.IsLetter(r)
+	}
+	return b.String(), nil
+}
+
+func stringStrip(cut func(string, string) string, cutSpace func(string, func(rune) bool) string) method {
+	return func(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+		var chars value
+		if err := unpackArgs(args, kwargs, []string{"chars?"}, &chars); err != nil {
+			return nil, err
+		}
+		if chars == nil {
+			return cutSpace(recv.(string), unicode.IsSpace), nil
+		}
+		cs, err := asString(chars, "chars")
+		if err != nil {
+			return nil, err
+		}
+		return cut(recv.(string), cs), nil
+	}
+}
+
+func stringJoin(t *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"x"}, &x); err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(elems))
+	for i, e := range elems {
+		if parts[i], err = asString(e, "join element"); err != nil {
+			return nil, err
+		}
+	}
+	return strings.Join(parts, recv.(string)), nil
+}
+
+func stringPartition(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var sep value
+	if err := unpackArgs(args, kwargs, []string{"sep"}, &sep); err != nil {
+		return nil, err
+	}
+	s, err := asString(sep, "sep")
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return nil, errors.New("empty separator")
+	}
+	before, after, found := strings.Cut(recv.(string), s)
+	if !found {
+		return tuple{before, "", ""}, nil
+	}
+	return tuple{before, s, after}, nil
+}
+
+func stringReplace(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var old, repl value
+	var count value = int64(-1)
+	if err := unpackArgs(args, kwargs, []string{"old", "new", "count?"}, &old, &repl, &count); err != nil {
+		return nil, err
+	}
+	o, err := asString(old, "old")
+	if err != nil {
+		return nil, err
+	}
+	r, err := asString(repl, "new")
+	if err != nil {
+		return nil, err
+	}
+	n, err := asInt(count, "count")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Replace(recv.(string), o, r, int(max(n, -1))), nil
+}
+
+func stringSplit(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var sep value
+	var maxSplit value = int64(-1)
+	if err := unpackArgs(args, kwargs, []string{"sep?", "maxsplit?"}, &sep, &maxSplit); err != nil {
+		return nil, err
+	}
+	n, err := asInt(maxSplit, "maxsplit")
+	if err != nil {
+		return nil, err
+	}
+	s := recv.(string)
+	var parts []string
+	if sep == nil {
+		parts = strings.Fields(s)
+		if n >= 0 && int64(len(parts)) > n+1 {
+			// keep the rest of the string after n splits
+			rest := s
+			parts = parts[:0]
+			for range n {
+				rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+				i := strings.IndexFunc(rest, unicode.IsSpace)
+				parts = append(parts, rest[:i])
+				rest = rest[i:]
+			}
+			parts = append(parts, strings.TrimLeftFunc(rest, unicode.IsSpace))
+		}
+	} else {
+		sp, err := asString(sep, "sep")
+		if err != nil {
+			return nil, err
+		}
+		if sp == "" {
+			return nil, errors.New("empty separator")
+		}
+		k := -1
+		if n >= 0 {
+			k = int(n) + 1
+		}
+		parts = strings.SplitN(s, sp, k)
+	}
+	out := &list{elems: make([]value, len(parts))}
+	for i, p := range parts {
+		out.elems[i] = p
+	}
+	return out, nil
+}
+
+func stringSplitlines(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	s := strings.TrimSuffix(strings.ReplaceAll(recv.(string), "\r\n", "\n"), "\n")
+	out := &list{}
+	if s == "" {
+		return out, nil
+	}
+	for _, line := range strings.Split(s, "\n") {
+		out.elems = append(out.elems, line)
+	}
+	return out, nil
+}
+
+func stringFormat(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	s := recv.(string)
+	var b strings.Builder
+	auto, manual := 0, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '}' {
+			if i+1 < len(s) && s[i+1] == '}' {
+				b.WriteByte('}')
+				i++
+				continue
+			}
+			return nil, errors.New("single '}' in format")
+		}
+		if c != '{' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '{' {
+			b.WriteByte('{')
+			i++
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, errors.New("unmatched '{' in format")
+		}
+		field := s[i+1 : i+end]
+		i += end
+		conv := "s"
+		if name, c, found := strings.Cut(field, "!"); found {
+			field, conv = name, c
+		}
+		if strings.Contains(field, ":") {
+			return nil, errors.New("format specifications are not supported")
+		}
+		var v value
+		switch n, err := strconv.Atoi(field); {
+		case field == "":
+			if manual {
+				return nil, errors.New("cannot switch from manual field numbering to automatic")
+			}
+			if auto >= len(args) {
+				return nil, errors.New("not enough arguments for format string")
+			}
+			v = args[auto]
+			auto++
+		case err == nil:
+			if auto > 0 {
+				return nil, errors.New("cannot switch from automatic field numbering to manual")
+			}
+			manual = true
+			if n < 0 || n >= len(args) {
+				return nil, fmt.Errorf("tuple index out of range: %d", n)
+			}
+			v = args[n]
+		default:
+			i := slices.IndexFunc(kwargs, func(kw kwarg) bool { return kw.name == field })
+			if i < 0 {
+				return nil, fmt.Errorf("keyword %s not found", field)
+			}
+			v = kwargs[i].value
+		}
+		switch conv {
+		case "s":
+			b.WriteString(str(v))
+		case "r":
+			b.WriteString(repr(v))
+		default:
+			return nil, fmt.Errorf("unknown conversion %q", conv)
+		}
+	}
+	return b.String(), nil
+}
+
+// format implements the % operator of strings, as in "%s=%d" % (k, v).
+func format(f string, arg value) (value, error) {
+	args := []value{arg}
+	if t, ok := arg.(tuple); ok {
+		args = t
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			b.WriteByte(f[i])
+			continue
+		}
+		j := i + 1
+		for j < len(f) && strings.IndexByte("-+ 0#.0123456789", f[j]) >= 0 {
+			j++
+		}
+		if j == len(f) {
+			return nil, errors.New("incomplete format")
+		}
+		flags, verb := f[i+1:j], f[j]
+		i = j
+		if verb == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if n >= len(args) {
+			return nil, errors.New("not enough arguments for format string")
+		}
+		v := args[n]
+		n++
+		switch verb {
+		case 's':
+			fmt.Fprintf(&b, "%"+flags+"s", str(v))
+		case 'r':
+			fmt.Fprintf(&b, "%"+flags+"s", repr(v))
+		case 'd', 'i', 'x', 'X', 'o':
+			var k int64
+			switch v := v.(type) {
+			case int64:
+				k = v
+			case float64:
+				if verb != 'd' && verb != 'i' {
+					return nil, fmt.Errorf("%%%c format requires integer: %s", verb, typeName(v))
+				}
+				k = int64(v)
+			default:
+				return nil, fmt.Errorf("%%%c format requires integer: %s", verb, typeName(v))
+			}
+			if verb == 'i' {
+				verb = 'd'
+			}
+			fmt.Fprintf(&b, "%"+flags+string(verb), k)
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			var x float64
+			switch v := v.(type) {
+			case int64:
+				x = float64(v)
+			case float64:
+				x = v
+			default:
+				return nil, fmt.Errorf("%%%c format requires float: %s", verb, typeName(v))
+			}
+			fmt.Fprintf(&b, "%"+flags+string(verb), x)
+		default:
+			return nil, fmt.Errorf("unsupported format character %q", verb)
+		}
+	}
+	if n < len(args) {
+		return nil, errors.New("too many arguments for format string")
+	}
+	return b.String(), nil
+}
+
+func listAppend(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"x"}, &x); err != nil {
+		return nil, err
+	}
+	l := recv.(*list)
+	if err := l.checkMutable("append to"); err != nil {
+		return nil, err
+	}
+	l.elems = append(l.elems, x)
+	return nil, nil
+}
+
+func listClear(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	l := recv.(*list)
+	if err := l.checkMutable("clear"); err != nil {
+		return nil, err
+	}
+	l.elems = nil
+	return nil, nil
+}
+
+func listExtend(t *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"x"}, &x); err != nil {
+		return nil, err
+	}
+	l := recv.(*list)
+	if err := l.checkMutable("extend"); err != nil {
+		return nil, err
+	}
+	elems, err := t.elems(x)
+	l.elems = append(l.elems, elems...)
+	return nil, err
+}
+
+func listIndex(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"x"}, &x); err != nil {
+		return nil, err
+	}
+	for i, e := range recv.(*list).elems {
+		if eq, err := equal(e, x); err != nil || eq {
+			return int64(i), err
+		}
+	}
+	return nil, fmt.Errorf("value %s not in list", repr(x))
+}
+
+func listInsert(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var i, x value
+	if err := unpackArgs(args, kwargs, []string{"index", "x"}, &i, &x); err != nil {
+		return nil, err
+	}
+	l := recv.(*list)
+	if err := l.checkMutable("insert into"); err != nil {
+		return nil, err
+	}
+	k, err := asInt(i, "index")
+	if err != nil {
+		return nil, err
+	}
+	n := int64(len(l.elems))
+	if k < 0 {
+		k += n
+	}
+	l.elems = slices.Insert(l.elems, int(min(max(k, 0), n)), x)
+	return nil, nil
+}
+
+func listPop(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	l := recv.(*list)
+	var i value = int64(-1)
+	if err := unpackArgs(args, kwargs, []string{"index?"}, &i); err != nil {
+		return nil, err
+	}
+	if err := l.checkMutable("pop from"); err != nil {
+		return nil, err
+	}
+	k, err := seqIndex(i, int64(len(l.elems)), "pop")
+	if err != nil {
+		return nil, err
+	}
+	v := l.elems[k]
+	l.elems = slices.Delete(l.elems, int(k), int(k)+1)
+	return v, nil
+}
+
+func listRemove(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var x value
+	if err := unpackArgs(args, kwargs, []string{"x"}, &x); err != nil {
+		return nil, err
+	}
+	l := recv.(*list)
+	if err := l.checkMutable("remove from"); err != nil {
+		return nil, err
+	}
+	for i, e := range l.elems {
+		if eq, err := equal(e, x); err != nil {
+			return nil, err
+		} else if eq {
+			l.elems = slices.Delete(l.elems, i, i+1)
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("value %s not in list", repr(x))
+}
+
+func dictClear(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	return nil, recv.(*dict).clear()
+}
+
+func dictGet(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var k, def value
+	if err := unpackArgs(args, kwargs, []string{"key", "default?"}, &k, &def); err != nil {
+		return nil, err
+	}
+	v, found, err := recv.(*dict).get(k)
+	if err != nil || !found {
+		return def, err
+	}
+	return v, nil
+}
+
+func dictItems(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	d := recv.(*dict)
+	out := &list{elems: make([]value, len(d.keys))}
+	for i, k := range d.keys {
+		out.elems[i] = tuple{k, d.values[i]}
+	}
+	return out, nil
+}
+
+func dictKeys(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	return &list{elems: slices.Clone(recv.(*dict).keys)}, nil
+}
+
+func dictValues(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if err := unpackArgs(args, kwargs, nil); err != nil {
+		return nil, err
+	}
+	return &list{elems: slices.Clone(recv.(*dict).values)}, nil
+}
+
+func dictPop(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var k, def value
+	if err := unpackArgs(args, kwargs, []string{"key", "default?"}, &k, &def); err != nil {
+		return nil, err
+	}
+	v, found, err := recv.(*dict).delete(k)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if len(args)+len(kwargs) == 2 {
+			return def, nil
+		}
+		return nil, fmt.Errorf("key %s not in dict", repr(k))
+	}
+	return v, nil
+}
+
+func dictSetdefault(_ *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	var k, def value
+	if err := unpackArgs(args, kwargs, []string{"key", "default?"}, &k, &def); err != nil {
+		return nil, err
+	}
+	d := recv.(*dict)
+	v, found, err := d.get(k)
+	if err != nil || found {
+		return v, err
+	}
+	return def, d.set(k, def)
+}
+
+func dictUpdate(t *thread, recv value, args []value, kwargs []kwarg) (value, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("got %d arguments, want at most 1", len(args))
+	}
+	d := recv.(*dict)
+	if len(args) == 1 {
+		if err := t.update(d, args[0]); err != nil {
+			return nil, err
+		}
+	}
+	for _, kw := range kwargs {
+		if err := d.set(kw.name, kw.value); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
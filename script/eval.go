@@ -0,0 +1,685 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// thread is the state of a single execution of a script: loading it, or
+// a call to one of its tools.
+type thread struct {
+	ctx   context.Context
+	steps uint64
+	// globals are the variables of the script, read only after loading.
+	globals map[string]value
+	// active are the functions being called, which may not be called
+	// again until they return, as Starlark forbids recursion.
+	active map[*funcDecl]bool
+	// print is called with the text passed to print.
+	print func(text string)
+	// tools receives the tools declared by the script, and is nil after
+	// loading.
+	tools *[]toolDecl
+}
+
+// frame holds the local variables of a call to a function, or of a
+// comprehension.
+type frame struct {
+	locals map[string]bool
+	vars   map[string]value
+	parent *frame
+}
+
+// tick counts a step against the limit of the thread, checking for
+// cancellation every few thousand.
+func (t *thread) tick() error {
+	if t.steps == 0 {
+		return ErrStepLimit
+	}
+	t.steps--
+	if t.steps%4096 == 0 && t.ctx.Err() != nil {
+		return t.ctx.Err()
+	}
+	return nil
+}
+
+type control int
+
+const (
+	ctlNone control = iota
+	ctlBreak
+	ctlContinue
+	ctlReturn
+)
+
+// errorAt returns err located at p, unless it is already located or should
+// be reported as is.
+func errorAt(p position, err error) error {
+	var located *Error
+	var failed *failError
+	if errors.As(err, &located) || errors.As(err, &failed) {
+		return err
+	}
+	return &Error{Line: p.line, Col: p.col, Msg: err.Error(), err: err}
+}
+
+func (t *thread) exec(fr *frame, stmts []stmt) (control, value, error) {
+	for _, s := range stmts {
+		if err := t.tick(); err != nil {
+			return 0, nil, errorAt(s.position(), err)
+		}
+		ctl, v, err := t.execStmt(fr, s)
+		if err != nil {
+			return 0, nil, errorAt(s.position(), err)
+		}
+		if ctl != ctlNone {
+			return ctl, v, nil
+		}
+	}
+	return ctlNone, nil, nil
+}
+
+func (t *thread) execStmt(fr *frame, s stmt) (control, value, error) {
+	switch s := s.(type) {
+	case *exprStmt:
+		_, err := t.eval(fr, s.x)
+		return ctlNone, nil, err
+	case *assignStmt:
+		return ctlNone, nil, t.assign(fr, s)
+	case *returnStmt:
+		if s.x == nil {
+			return ctlReturn, nil, nil
+		}
+		v, err := t.eval(fr, s.x)
+		return ctlReturn, v, err
+	case *branchStmt:
+		switch s.kind {
+		case "break":
+			return ctlBreak, nil, nil
+		case "continue":
+			return ctlContinue, nil, nil
+		}
+		return ctlNone, nil, nil
+	case *ifStmt:
+		cond, err := t.eval(fr, s.cond)
+		if err != nil {
+			return 0, nil, err
+		}
+		if truth(cond) {
+			return t.exec(fr, s.then)
+		}
+		return t.exec(fr, s.els)
+	case *forStmt:
+		iter, err := t.eval(fr, s.iter)
+		if err != nil {
+			return 0, nil, err
+		}
+		var ctl control
+		var result value
+		err = t.forEach(iter, func(v value) (bool, error) {
+			if err := t.bindTarget(fr, s.vars, v); err != nil {
+				return false, err
+			}
+			c, r, err := t.exec(fr, s.body)
+			if err != nil {
+				return false, err
+			}
+			switch c {
+			case ctlBreak:
+				return false, nil
+			case ctlReturn:
+				ctl, result = c, r
+				return false, nil
+			}
+			return true, nil
+		})
+		return ctl, result, err
+	case *defStmt:
+		fn, err := t.makeFunction(fr, s.fn)
+		if err != nil {
+			return 0, nil, err
+		}
+		return ctlNone, nil, t.setVar(fr, s.fn.name, fn)
+	}
+	return 0, nil, fmt.Errorf("unexpected statement %T", s)
+}
+
+func (t *thread) makeFunction(fr *frame, decl *funcDecl) (*function, error) {
+	fn := &function{decl: decl, parent: fr}
+	for _, p := range decl.params {
+		var def value
+		if p.def != nil {
+			var err error
+			if def, err = t.eval(fr, p.def); err != nil {
+				return nil, err
+			}
+		}
+		fn.defaults = append(fn.defaults, def)
+	}
+	return fn, nil
+}
+
+// setVar binds name in fr, or in the globals at the top level.
+func (t *thread) setVar(fr *frame, name string, v value) error {
+	for f := fr; f != nil; f = f.parent {
+		if f.locals[name] {
+			f.vars[name] = v
+			return nil
+		}
+	}
+	if t.tools == nil {
+		return fmt.Errorf("cannot reassign global %s after the script is loaded", name)
+	}
+	t.globals[name] = v
+	return nil
+}
+
+func (t *thread) lookup(fr *frame, name string) (value, error) {
+	for f := fr; f != nil; f = f.parent {
+		if f.locals[name] {
+			v, ok := f.vars[name]
+			if !ok {
+				return nil, fmt.Errorf("local variable %s referenced before assignment", name)
+			}
+			return v, nil
+		}
+	}
+	if v, ok := t.globals[name]; ok {
+		return v, nil
+	}
+	if v, ok := builtins[name]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("undefined: %s", name)
+}
+
+func (t *thread) assign(fr *frame, s *assignStmt) error {
+	if s.op == "=" {
+		v, err := t.eval(fr, s.rhs)
+		if err != nil {
+			return err
+		}
+		return t.bindTarget(fr, s.lhs, v)
+	}
+	// the operands of an augmented assignment are evaluated once
+	op := strings.TrimSuffix(s.op, "=")
+	switch lhs := s.lhs.(type) {
+	case *identExpr:
+		x, err := t.lookup(fr, lhs.name)
+		if err != nil {
+			return err
+		}
+		v, err := t.augment(fr, op, x, s.rhs)
+		if err != nil {
+			return err
+		}
+		return t.setVar(fr, lhs.name, v)
+	case *indexExpr:
+		c, err := t.eval(fr, lhs.x)
+		if err != nil {
+			return err
+		}
+		i, err := t.eval(fr, lhs.index)
+		if err != nil {
+			return err
+		}
+		x, err := index(c, i)
+		if err != nil {
+			return err
+		}
+		v, err := t.augment(fr, op, x, s.rhs)
+		if err != nil {
+			return err
+		}
+		return setIndex(c, i, v)
+	}
+	return errors.New("cannot use augmented assignment here")
+}
+
+// augment returns x op rhs, extending x in place if it is a list and op
+// is +, as Starlark does.
+func (t *thread) augment(fr *frame, op string, x value, rhs expr) (value, error) {
+	y, err := t.eval(fr, rhs)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := x.(*list); ok && op == "+" {
+		if err := l.checkMutable("append to"); err != nil {
+			return nil, err
+		}
+		if err := t.forEach(y, func(v value) (bool, error) {
+			l.elems = append(l.elems, v)
+			return true, nil
+		}); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	return binary(op, x, y)
+}
+
+func (t *thread) bindTarget(fr *frame, target expr, v value) error {
+	switch target := target.(type) {
+	case *identExpr:
+		return t.setVar(fr, target.name, v)
+	case *tupleExpr:
+		return t.unpack(fr, target.elems, v)
+	case *listExpr:
+		return t.unpack(fr, target.elems, v)
+	case *indexExpr:
+		c, err := t.eval(fr, target.x)
+		if err != nil {
+			return err
+		}
+		i, err := t.eval(fr, target.index)
+		if err != nil {
+			return err
+		}
+		return setIndex(c, i, v)
+	case *dotExpr:
+		return fmt.Errorf("cannot set attribute %s", target.name)
+	}
+	return errors.New("cannot assign to this expression")
+}
+
+func (t *thread) unpack(fr *frame, targets []expr, v value) error {
+	var elems []value
+	if err := t.forEach(v, func(e value) (bool, error) {
+		elems = append(elems, e)
+		return len(elems) <= len(targets), nil
+	}); err != nil {
+		return err
+	}
+	if len(elems) > len(targets) {
+		return fmt.Errorf("too many values to unpack (want %d)", len(targets))
+	}
+	if len(elems) < len(targets) {
+		return fmt.Errorf("too few values to unpack (got %d, want %d)", len(elems), len(targets))
+	}
+	for i, target := range targets {
+		if err := t.bindTarget(fr, target, elems[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setIndex(c, i, v value) error {
+	switch c := c.(type) {
+	case *list:
+		if err := c.checkMutable("assign to an element of"); err != nil {
+			return err
+		}
+		k, err := seqIndex(i, int64(len(c.elems)), "list")
+		if err != nil {
+			return err
+		}
+		c.elems[k] = v
+		return nil
+	case *dict:
+		return c.set(i, v)
+	}
+	return fmt.Errorf("%s value does not support item assignment", typeName(c))
+}
+
+// forEach calls f with each element of the iterable x until it returns
+// false, counting a step for each.
+func (t *thread) forEach(x value, f func(value) (bool, error)) error {
+	each := func(v value) (bool, error) {
+		if err := t.tick(); err != nil {
+			return false, err
+		}
+		return f(v)
+	}
+	switch x := x.(type) {
+	case *list:
+		// elements appended while iterating are not visited
+		for _, v := range x.elems[:len(x.elems):len(x.elems)] {
+			if more, err := each(v); err != nil || !more {
+				return err
+			}
+		}
+	case tuple:
+		for _, v := range x {
+			if more, err := each(v); err != nil || !more {
+				return err
+			}
+		}
+	case *dict:
+		for _, k := range append([]value(nil), x.keys...) {
+			if more, err := each(k); err != nil || !more {
+				return err
+			}
+		}
+	case rangeValue:
+		for i := range x.len() {
+			if more, err := each(x.at(i)); err != nil || !more {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s value is not iterable", typeName(x))
+	}
+	return nil
+}
+
+func (t *thread) eval(fr *frame, x expr) (value, error) {
+	v, err := t.evalExpr(fr, x)
+	if err != nil {
+		return nil, errorAt(x.position(), err)
+	}
+	return v, nil
+}
+
+func (t *thread) evalExpr(fr *frame, x expr) (value, error) {
+	switch x := x.(type) {
+	case *literalExpr:
+		return x.value, nil
+	case *identExpr:
+		return t.lookup(fr, x.name)
+	case *listExpr:
+		elems, err := t.evalAll(fr, x.elems)
+		return &list{elems: elems}, err
+	case *tupleExpr:
+		elems, err := t.evalAll(fr, x.elems)
+		return tuple(elems), err
+	case *dictExpr:
+		d := newDict()
+		for i := range x.keys {
+			k, err := t.eval(fr, x.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			v, err := t.eval(fr, x.values[i])
+			if err != nil {
+				return nil, err
+			}
+			if _, found, _ := d.get(k); found {
+				return nil, errorAt(x.keys[i].position(), fmt.Errorf("duplicate key %s in dict literal", repr(k)))
+			}
+			if err := d.set(k, v); err != nil {
+				return nil, errorAt(x.keys[i].position(), err)
+			}
+		}
+		return d, nil
+	case *compExpr:
+		return t.comprehension(fr, x)
+	case *unaryExpr:
+		v, err := t.eval(fr, x.x)
+		if err != nil {
+			return nil, err
+		}
+		return unary(x.op, v)
+	case *binaryExpr:
+		l, err := t.eval(fr, x.x)
+		if err != nil {
+			return nil, err
+		}
+		switch x.op {
+		case "and":
+			if !truth(l) {
+				return l, nil
+			}
+			return t.eval(fr, x.y)
+		case "or":
+			if truth(l) {
+				return l, nil
+			}
+			return t.eval(fr, x.y)
+		}
+		r, err := t.eval(fr, x.y)
+		if err != nil {
+			return nil, err
+		}
+		return binary(x.op, l, r)
+	case *condExpr:
+		cond, err := t.eval(fr, x.cond)
+		if err != nil {
+			return nil, err
+		}
+		if truth(cond) {
+			return t.eval(fr, x.then)
+		}
+		return t.eval(fr, x.els)
+	case *indexExpr:
+		c, err := t.eval(fr, x.x)
+		if err != nil {
+			return nil, err
+		}
+		i, err := t.eval(fr, x.index)
+		if err != nil {
+			return nil, err
+		}
+		return index(c, i)
+	case *sliceExpr:
+		c, err := t.eval(fr, x.x)
+		if err != nil {
+			return nil, err
+		}
+		bounds := make([]value, 3)
+		for i, b := range []expr{x.lo, x.hi, x.step} {
+			if b != nil {
+				if bounds[i], err = t.eval(fr, b); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return slice(c, bounds[0], bounds[1], bounds[2])
+	case *dotExpr:
+		v, err := t.eval(fr, x.x)
+		if err != nil {
+			return nil, err
+		}
+		return attr(v, x.name)
+	case *lambdaExpr:
+		return t.makeFunction(fr, x.fn)
+	case *callExpr:
+		return t.evalCall(fr, x)
+	}
+	return nil, fmt.Errorf("unexpected expression %T", x)
+}
+
+func (t *thread) evalAll(fr *frame, xs []expr) ([]value, error) {
+	vs := make([]value, len(xs))
+	for i, x := range xs {
+		v, err := t.eval(fr, x)
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+func (t *thread) comprehension(fr *frame, c *compExpr) (value, error) {
+	cf := &frame{locals: c.locals, vars: map[string]value{}, parent: fr}
+	var out *list
+	var outDict *dict
+	if c.key != nil {
+		outDict = newDict()
+	} else {
+		out = &list{}
+	}
+	var clause func(i int) error
+	clause = func(i int) error {
+		if i == len(c.clauses) {
+			if outDict != nil {
+				k, err := t.eval(cf, c.key)
+				if err != nil {
+					return err
+				}
+				v, err := t.eval(cf, c.body)
+				if err != nil {
+					return err
+				}
+				if err := outDict.set(k, v); err != nil {
+					return errorAt(c.key.position(), err)
+				}
+				return nil
+			}
+			v, err := t.eval(cf, c.body)
+			out.elems = append(out.elems, v)
+			return err
+		}
+		cl := c.clauses[i]
+		if cl.iter == nil {
+			cond, err := t.eval(cf, cl.cond)
+			if err != nil || !truth(cond) {
+				return err
+			}
+			return clause(i + 1)
+		}
+		iter, err := t.eval(cf, cl.iter)
+		if err != nil {
+			return err
+		}
+		return t.forEach(iter, func(v value) (bool, error) {
+			if err := t.bindTarget(cf, cl.vars, v); err != nil {
+				return false, err
+			}
+			return true, clause(i + 1)
+		})
+	}
+	if err := clause(0); err != nil {
+		return nil, err
+	}
+	if outDict != nil {
+		return outDict, nil
+	}
+	return out, nil
+}
+
+func (t *thread) evalCall(fr *frame, c *callExpr) (value, error) {
+	fn, err := t.eval(fr, c.fn)
+	if err != nil {
+		return nil, err
+	}
+	var args []value
+	var kwargs []kwarg
+	for _, a := range c.args {
+		v, err := t.eval(fr, a.value)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case a.star == 1:
+			if err := t.forEach(v, func(e value) (bool, error) {
+				args = append(args, e)
+				return true, nil
+			}); err != nil {
+				return nil, errorAt(a.value.position(), err)
+			}
+		case a.star == 2:
+			d, ok := v.(*dict)
+			if !ok {
+				return nil, errorAt(a.value.position(), fmt.Errorf("argument after ** must be a dict, not %s", typeName(v)))
+			}
+			for i, k := range d.keys {
+				name, ok := k.(string)
+				if !ok {
+					return nil, errorAt(a.value.position(), fmt.Errorf("keywords must be strings, not %s", typeName(k)))
+				}
+				kwargs = append(kwargs, kwarg{name, d.values[i]})
+			}
+		case a.name != "":
+			kwargs = append(kwargs, kwarg{a.name, v})
+		default:
+			args = append(args, v)
+		}
+	}
+	return t.call(fn, args, kwargs)
+}
+
+// call calls fn with args and kwargs.
+func (t *thread) call(fn value, args []value, kwargs []kwarg) (value, error) {
+	if err := t.tick(); err != nil {
+		return nil, err
+	}
+	switch fn := fn.(type) {
+	case *builtin:
+		v, err := fn.fn(t, fn, args, kwargs)
+		var failed *failError
+		var located *Error
+		if err != nil && !errors.As(err, &failed) && !errors.As(err, &located) && !errors.Is(err, ErrStepLimit) && !errors.Is(err, t.ctx.Err()) {
+			err = fmt.Errorf("%s: %w", fn.name, err)
+		}
+		return v, err
+	case *function:
+		return t.callFunction(fn, args, kwargs)
+	}
+	return nil, fmt.Errorf("invalid call of non-function (%s)", typeName(fn))
+}
+
+func (t *thread) callFunction(fn *function, args []value, kwargs []kwarg) (value, error) {
+	decl := fn.decl
+	if t.active[decl] {
+		return nil, fmt.Errorf("function %s called recursively", decl.name)
+	}
+	t.active[decl] = true
+	defer delete(t.active, decl)
+
+	fr := &frame{locals: decl.locals, vars: map[string]value{}, parent: fn.parent}
+	vars := fr.vars
+	// parameters after *args may only be passed by keyword
+	n, variadic := 0, false
+	var extraKw *dict
+	for _, p := range decl.params {
+		switch p.star {
+		case 1:
+			variadic = true
+		case 2:
+			extraKw = newDict()
+		default:
+			if !variadic && n < len(args) {
+				vars[p.name] = args[n]
+				n++
+			}
+		}
+	}
+	extra := tuple{}
+	if n < len(args) {
+		if !variadic {
+			return nil, fmt.Errorf("function %s accepts at most %d positional arguments (%d given)", decl.name, n, len(args))
+		}
+		extra = append(extra, args[n:]...)
+	}
+	for _, kw := range kwargs {
+		named := slices.ContainsFunc(decl.params, func(p param) bool { return p.star == 0 && p.name == kw.name })
+		switch {
+		case named:
+			if _, ok := vars[kw.name]; ok {
+				return nil, fmt.Errorf("function %s got multiple values for parameter %s", decl.name, kw.name)
+			}
+			vars[kw.name] = kw.value
+		case extraKw != nil:
+			if _, found, _ := extraKw.get(kw.name); found {
+				return nil, fmt.Errorf("function %s got multiple values for keyword argument %s", decl.name, kw.name)
+			}
+			extraKw.set(kw.name, kw.value)
+		default:
+			return nil, fmt.Errorf("function %s got an unexpected keyword argument %s", decl.name, kw.name)
+		}
+	}
+	for i, p := range decl.params {
+		switch {
+		case p.star == 1:
+			vars[p.name] = extra
+		case p.star == 2:
+			vars[p.name] = extraKw
+		default:
+			if _, ok := vars[p.name]; ok {
+				continue
+			}
+			if p.def == nil {
+				return nil, fmt.Errorf("function %s missing argument for %s", decl.name, p.name)
+			}
+			vars[p.name] = fn.defaults[i]
+		}
+	}
+
+	_, v, err := t.exec(fr, decl.body)
+	return v, err
+}
@@ -0,0 +1,414 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// position is a line and column in a script, both counted from 1.
+type position struct {
+	line, col int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%d:%d", p.line, p.col)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIndent
+	tokOutdent
+	tokName
+	tokInt
+	tokFloat
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	// text is the name or operator, or the source of a literal.
+	text  string
+	value value
+	pos   position
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "newline"
+	case tokIndent:
+		return "indent"
+	case tokOutdent:
+		return "outdent"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators are the operators and punctuation, longest first so that the
+// scanner takes the longest match.
+var operators = []string{
+	"//=", "<<=", ">>=",
+	"//", "<<", ">>", "==", "!=", "<=", ">=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**",
+	"+", "-", "*", "/", "%", "&", "|", "^", "~", "<", ">", "=", "(", ")", "[", "]", "{", "}", ",", ":", ".", ";",
+}
+
+// scanner splits a script into tokens, turning indentation into indent and
+// outdent tokens as Python does.
+type scanner struct {
+	src     string
+	off     int
+	line    int
+	lineOff int
+	// depth counts the open brackets, inside which newlines and
+	// indentation are insignificant.
+	depth   int
+	indents []int
+	tokens  []token
+}
+
+// scan returns the tokens of src.
+func scan(src string) ([]token, error) {
+	s := &scanner{src: src, line: 1, indents: []int{0}}
+	if err := s.run(); err != nil {
+		return nil, err
+	}
+	return s.tokens, nil
+}
+
+func (s *scanner) pos() position {
+	return position{line: s.line, col: s.off - s.lineOff + 1}
+}
+
+func (s *scanner) errorf(p position, format string, args ...any) error {
+	return errorAt(p, fmt.Errorf(format, args...))
+}
+
+func (s *scanner) emit(kind tokenKind, text string, v value, p position) {
+	s.tokens = append(s.tokens, token{kind: kind, text: text, value: v, pos: p})
+}
+
+func (s *scanner) newline() {
+	s.line++
+	s.lineOff = s.off
+}
+
+func (s *scanner) run() error {
+	atLineStart := true
+	for {
+		if atLineStart && s.depth == 0 {
+			blank, err := s.indentation()
+			if err != nil {
+				return err
+			}
+			if !blank {
+				atLineStart = false
+			}
+			if s.off >= len(s.src) {
+				break
+			}
+			if blank {
+				continue
+			}
+		}
+		if s.off >= len(s.src) {
+			break
+		}
+		c := s.src[s.off]
+		p := s.pos()
+		switch {
+		case c == '\n':
+			s.off++
+			if s.depth == 0 {
+				s.emit(tokNewline, "", nil, p)
+				atLineStart = true
+			}
+			s.newline()
+		case c == ' ' || c == '\t' || c == '\r':
+			s.off++
+		case c == '#':
+			for s.off < len(s.src) && s.src[s.off] != '\n' {
+				s.off++
+			}
+		case c == '\\' && s.off+1 < len(s.src) && s.src[s.off+1] == '\n':
+			s.off += 2
+			s.newline()
+		case isDigit(c) || c == '.' && s.off+1 < len(s.src) && isDigit(s.src[s.off+1]):
+			if err := s.number(); err != nil {
+				return err
+			}
+		case c == '\'' || c == '"':
+			if err := s.string(false); err != nil {
+				return err
+			}
+		case (c == 'r' || c == 'R') && s.off+1 < len(s.src) && (s.src[s.off+1] == '\'' || s.src[s.off+1] == '"'):
+			s.off++
+			if err := s.string(true); err != nil {
+				return err
+			}
+		case isNameStart(c):
+			start := s.off
+			for s.off < len(s.src) && (isNameStart(s.src[s.off]) || isDigit(s.src[s.off])) {
+				s.off++
+			}
+			s.emit(tokName, s.src[start:s.off], nil, p)
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s.src[s.off:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(s.src[s.off:])
+				return s.errorf(p, "unexpected character %q", r)
+			}
+			switch op {
+			case "(", "[", "{":
+				s.depth++
+			case ")", "]", "}":
+				if s.depth > 0 {
+					s.depth--
+				}
+			}
+			s.off += len(op)
+			s.emit(tokOp, op, nil, p)
+		}
+	}
+	if n := len(s.tokens); n > 0 && s.tokens[n-1].kind != tokNewline {
+		s.emit(tokNewline, "", nil, s.pos())
+	}
+	for len(s.indents) > 1 {
+		s.indents = s.indents[:len(s.indents)-1]
+		s.emit(tokOutdent, "", nil, s.pos())
+	}
+	s.emit(tokEOF, "", nil, s.pos())
+	return nil
+}
+
+// indentation reads the indentation of a line, emitting indent and outdent
+// tokens, and reports whether the line is blank or only a comment.
+func (s *scanner) indentation() (bool, error) {
+	width := 0
+	for s.off < len(s.src) {
+		switch s.src[s.off] {
+		case ' ':
+			width++
+		case '\t':
+			width += 8 - width%8
+		case '\r':
+		default:
+			goto measured
+		}
+		s.off++
+	}
+measured:
+	if s.off >= len(s.src) || s.src[s.off] == '\n' || s.src[s.off] == '#' {
+		for s.off < len(s.src) && s.src[s.off] != '\n' {
+			s.off++
+		}
+		if s.off < len(s.src) {
+			s.off++
+			s.newline()
+		}
+		return true, nil
+	}
+	p := s.pos()
+	switch top := s.indents[len(s.indents)-1]; {
+	case width > top:
+		s.indents = append(s.indents, width)
+		s.emit(tokIndent, "", nil, p)
+	case width < top:
+		for width < s.indents[len(s.indents)-1] {
+			s.indents = s.indents[:len(s.indents)-1]
+			s.emit(tokOutdent, "", nil, p)
+		}
+		if width != s.indents[len(s.indents)-1] {
+			return false, s.errorf(p, "unindent does not match any outer indentation level")
+		}
+	}
+	return false, nil
+}
+
+func (s *scanner) number() error {
+	p := s.pos()
+	start := s.off
+	if s.src[s.off] == '0' && s.off+1 < len(s.src) && strings.ContainsRune("xXoObB", rune(s.src[s.off+1])) {
+		s.off += 2
+		for s.off < len(s.src) && (isNameStart(s.src[s.off]) || isDigit(s.src[s.off])) {
+			s.off++
+		}
+		text := s.src[start:s.off]
+		n, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return s.errorf(p, "invalid integer %s", text)
+		}
+		s.emit(tokInt, text, n, p)
+		return nil
+	}
+	float := false
+	for s.off < len(s.src) {
+		c := s.src[s.off]
+		switch {
+		case isDigit(c):
+		case c == '.':
+			float = true
+		case c == 'e' || c == 'E':
+			float = true
+			if s.off+1 < len(s.src) && (s.src[s.off+1] == '+' || s.src[s.off+1] == '-') {
+				s.off++
+			}
+		default:
+			goto done
+		}
+		s.off++
+	}
+done:
+	text := s.src[start:s.off]
+	if float {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return s.errorf(p, "invalid float %s", text)
+		}
+		s.emit(tokFloat, text, f, p)
+		return nil
+	}
+	if len(text) > 1 && text[0] == '0' {
+		return s.errorf(p, "invalid integer %s: leading zeros are not allowed", text)
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return s.errorf(p, "integer %s is out of range", text)
+	}
+	s.emit(tokInt, text, n, p)
+	return nil
+}
+
+// string reads a string literal, the r prefix of a raw string having been
+// read.
+func (s *scanner) string(raw bool) error {
+	p := s.pos()
+	if raw {
+		p.col--
+	}
+	start := s.off
+	quote := s.src[s.off : s.off+1]
+	if strings.HasPrefix(s.src[s.off:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	s.off += len(quote)
+	var b strings.Builder
+	for {
+		if s.off >= len(s.src) {
+			return s.errorf(p, "unterminated string")
+		}
+		if strings.HasPrefix(s.src[s.off:], quote) {
+			s.off += len(quote)
+			break
+		}
+		c := s.src[s.off]
+		switch {
+		case c == '\n':
+			if len(quote) == 1 {
+				return s.errorf(p, "unterminated string")
+			}
+			b.WriteByte(c)
+			s.off++
+			s.newline()
+		case c == '\\' && raw:
+			// raw strings keep the backslash, but it still escapes a quote
+			b.WriteByte(c)
+			s.off++
+			if s.off < len(s.src) {
+				if s.src[s.off] == '\n' {
+					s.newline()
+				}
+				b.WriteByte(s.src[s.off])
+				s.off++
+			}
+		case c == '\\':
+			if err := s.escape(&b); err != nil {
+				return err
+			}
+		default:
+			b.WriteByte(c)
+			s.off++
+		}
+	}
+	s.emit(tokString, s.src[start:s.off], b.String(), p)
+	return nil
+}
+
+func (s *scanner) escape(b *strings.Builder) error {
+	p := s.pos()
+	s.off++
+	if s.off >= len(s.src) {
+		return s.errorf(p, "unterminated string")
+	}
+	c := s.src[s.off]
+	s.off++
+	switch c {
+	case '\n':
+		s.newline()
+	case '\\', '\'', '"':
+		b.WriteByte(c)
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'a':
+		b.WriteByte('\a')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		end := s.off - 1
+		for end < len(s.src) && end < s.off+2 && s.src[end] >= '0' && s.src[end] <= '7' {
+			end++
+		}
+		n, _ := strconv.ParseUint(s.src[s.off-1:end], 8, 8)
+		b.WriteByte(byte(n))
+		s.off = end
+	case 'x', 'u', 'U':
+		digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		if s.off+digits > len(s.src) {
+			return s.errorf(p, "invalid escape sequence \\%c", c)
+		}
+		n, err := strconv.ParseUint(s.src[s.off:s.off+digits], 16, 32)
+		if err != nil {
+			return s.errorf(p, "invalid escape sequence \\%c%s", c, s.src[s.off:s.off+digits])
+		}
+		s.off += digits
+		if c == 'x' {
+			b.WriteByte(byte(n))
+		} else if !utf8.ValidRune(rune(n)) {
+			return s.errorf(p, "invalid Unicode code point U+%04X", n)
+		} else {
+			b.WriteRune(rune(n))
+		}
+	default:
+		return s.errorf(p, "invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,767 @@
+package script
+
+import (
+	"fmt"
+	"slices"
+)
+
+type (
+	expr interface{ position() position }
+	stmt interface{ position() position }
+)
+
+type node struct {
+	pos position
+}
+
+func (n node) position() position { return n.pos }
+
+type (
+	identExpr struct {
+		node
+		name string
+	}
+	literalExpr struct {
+		node
+		value value
+	}
+	listExpr struct {
+		node
+		elems []expr
+	}
+	tupleExpr struct {
+		node
+		elems []expr
+	}
+	dictExpr struct {
+		node
+		keys, values []expr
+	}
+	// compExpr is a list comprehension, or a dict comprehension if key is
+	// set.
+	compExpr struct {
+		node
+		key, body expr
+		clauses   []compClause
+		// locals are the variables of the for clauses.
+		locals map[string]bool
+	}
+	unaryExpr struct {
+		node
+		op string
+		x  expr
+	}
+	binaryExpr struct {
+		node
+		op   string
+		x, y expr
+	}
+	condExpr struct {
+		node
+		cond, then, els expr
+	}
+	callExpr struct {
+		node
+		fn   expr
+		args []argument
+	}
+	indexExpr struct {
+		node
+		x, index expr
+	}
+	sliceExpr struct {
+		node
+		x, lo, hi, step expr
+	}
+	dotExpr struct {
+		node
+		x    expr
+		name string
+	}
+	lambdaExpr struct {
+		node
+		fn *funcDecl
+	}
+)
+
+// compClause is a for clause, or an if clause if iter is nil.
+type compClause struct {
+	vars, iter, cond expr
+}
+
+type argument struct {
+	// name is set for keyword arguments.
+	name string
+	// star is 1 for *args and 2 for **kwargs.
+	star  int
+	value expr
+}
+
+type param struct {
+	name string
+	def  expr
+	// star is 1 for *args and 2 for **kwargs.
+	star int
+}
+
+type funcDecl struct {
+	pos    position
+	name   string
+	params []param
+	body   []stmt
+	// locals are the names bound in the function, which are local to it.
+	locals map[string]bool
+}
+
+type (
+	defStmt struct {
+		node
+		fn *funcDecl
+	}
+	ifStmt struct {
+		node
+		cond      expr
+		then, els []stmt
+	}
+	forStmt struct {
+		node
+		vars, iter expr
+		body       []stmt
+	}
+	returnStmt struct {
+		node
+		x expr
+	}
+	// branchStmt is break, continue or pass.
+	branchStmt struct {
+		node
+		kind string
+	}
+	assignStmt struct {
+		node
+		// op is "=" or an augmented assignment such as "+=".
+		op       string
+		lhs, rhs expr
+	}
+	exprStmt struct {
+		node
+		x expr
+	}
+)
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "continue": true, "def": true, "elif": true, "else": true,
+	"for": true, "if": true, "in": true, "lambda": true, "not": true, "or": true,
+	"pass": true, "return": true, "None": true, "True": true, "False": true,
+	// reserved by Starlark
+	"as": true, "assert": true, "async": true, "await": true, "class": true, "del": true,
+	"except": true, "finally": true, "from": true, "global": true, "import": true,
+	"is": true, "nonlocal": true, "raise": true, "try": true, "while": true, "with": true,
+	"yield": true, "load": true,
+}
+
+type parser struct {
+	tokens []token
+	off    int
+	// funcs are the functions being parsed, innermost last, whose locals
+	// the names bound are added to.
+	funcs []map[string]bool
+}
+
+// parse returns the statements of src.
+func parse(src string) ([]stmt, error) {
+	tokens, err := scan(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var stmts []stmt
+	err = p.catch(func() {
+		for p.peek().kind != tokEOF {
+			stmts = append(stmts, p.statement()...)
+		}
+	})
+	return stmts, err
+}
+
+// parseError is panicked by the parser to abandon parsing.
+type parseError struct {
+	err error
+}
+
+func (p *parser) catch(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = e.err
+		}
+	}()
+	f()
+	return nil
+}
+
+func (p *parser) fail(pos position, format string, args ...any) {
+	panic(parseError{errorAt(pos, fmt.Errorf(format, args...))})
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.off]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.off]
+	if t.kind != tokEOF {
+		p.off++
+	}
+	return t
+}
+
+// is reports whether the next token is the operator or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokOp || t.kind == tokName) && t.text == text
+}
+
+// accept consumes the next token if it is the operator or keyword text.
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	if !p.is(text) {
+		t := p.peek()
+		p.fail(t.pos, "got %s, want %q", t, text)
+	}
+	return p.next()
+}
+
+func (p *parser) expectKind(kind tokenKind, what string) token {
+	t := p.peek()
+	if t.kind != kind {
+		p.fail(t.pos, "got %s, want %s", t, what)
+	}
+	return p.next()
+}
+
+func (p *parser) name() token {
+	t := p.expectKind(tokName, "name")
+	if keywords[t.text] {
+		p.fail(t.pos, "%s is a reserved word", t.text)
+	}
+	return t
+}
+
+// bind records that name is bound in the innermost function being parsed.
+func (p *parser) bind(name string) {
+	if len(p.funcs) > 0 {
+		p.funcs[len(p.funcs)-1][name] = true
+	}
+}
+
+// bindTarget binds the names assigned to by the target x.
+func (p *parser) bindTarget(x expr) {
+	switch x := x.(type) {
+	case *identExpr:
+		p.bind(x.name)
+	case *tupleExpr:
+		for _, e := range x.elems {
+			p.bindTarget(e)
+		}
+	case *listExpr:
+		for _, e := range x.elems {
+			p.bindTarget(e)
+		}
+	case *indexExpr, *dotExpr:
+	default:
+		p.fail(x.position(), "cannot assign to this expression")
+	}
+}
+
+func (p *parser) statement() []stmt {
+	t := p.peek()
+	if t.kind == tokIndent {
+		p.fail(t.pos, "unexpected indent")
+	}
+	if t.kind == tokName {
+		switch t.text {
+		case "def":
+			return []stmt{p.def()}
+		case "if":
+			return []stmt{p.ifStatement()}
+		case "for":
+			return []stmt{p.forStatement()}
+		case "while", "class", "import", "from", "try", "with", "load":
+			p.fail(t.pos, "%s statements are not supported", t.text)
+		}
+	}
+	var stmts []stmt
+	for {
+		stmts = append(stmts, p.simpleStatement())
+		if !p.accept(";") || p.peek().kind == tokNewline {
+			break
+		}
+	}
+	p.expectKind(tokNewline, "newline")
+	return stmts
+}
+
+func (p *parser) simpleStatement() stmt {
+	t := p.peek()
+	if t.kind == tokName {
+		switch t.text {
+		case "return":
+			p.next()
+			var x expr
+			if k := p.peek(); k.kind != tokNewline && !p.is(";") {
+				x = p.expressionList()
+			}
+			return &returnStmt{node{t.pos}, x}
+		case "break", "continue", "pass":
+			p.next()
+			return &branchStmt{node{t.pos}, t.text}
+		}
+	}
+	x := p.expressionList()
+	op := p.peek()
+	if op.kind == tokOp {
+		switch op.text {
+		case "=", "+=", "-=", "*=", "/=", "//=", "%=", "&=", "|=", "^=", "<<=", ">>=":
+			p.next()
+			if op.text != "=" {
+				if _, ok := x.(*tupleExpr); ok {
+					p.fail(op.pos, "cannot use %s with a tuple", op.text)
+				}
+			}
+			p.bindTarget(x)
+			return &assignStmt{node{op.pos}, op.text, x, p.expressionList()}
+		}
+	}
+	return &exprStmt{node{t.pos}, x}
+}
+
+func (p *parser) block() []stmt {
+	p.expect(":")
+	if p.peek().kind != tokNewline {
+		// a simple statement on the same line
+		var stmts []stmt
+		for {
+			stmts = append(stmts, p.simpleStatement())
+			if !p.accept(";") || p.peek().kind == tokNewline {
+				break
+			}
+		}
+		p.expectKind(tokNewline, "newline")
+		return stmts
+	}
+	p.next()
+	p.expectKind(tokIndent, "indented block")
+	var stmts []stmt
+	for p.peek().kind != tokOutdent && p.peek().kind != tokEOF {
+		stmts = append(stmts, p.statement()...)
+	}
+	p.next()
+	return stmts
+}
+
+func (p *parser) def() stmt {
+	pos := p.expect("def").pos
+	name := p.name()
+	p.bind(name.text)
+	p.expect("(")
+	fn := p.function(pos, name.text, ")", func() []stmt { return p.block() })
+	return &defStmt{node{pos}, fn}
+}
+
+// function parses the parameters of a function up to the token end, and
+// then its body.
+func (p *parser) function(pos position, name, end string, body func() []stmt) *funcDecl {
+	fn := &funcDecl{pos: pos, name: name, locals: map[string]bool{}}
+	seen := map[string]bool{}
+	for !p.is(end) {
+		star := 0
+		if p.accept("*") {
+			star = 1
+		} else if p.accept("**") {
+			star = 2
+		}
+		t := p.name()
+		if seen[t.text] {
+			p.fail(t.pos, "duplicate parameter %s", t.text)
+		}
+		seen[t.text] = true
+		prm := param{name: t.text, star: star}
+		if star == 0 && p.accept("=") {
+			prm.def = p.test()
+		}
+		fn.params = append(fn.params, prm)
+		fn.locals[t.text] = true
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(end)
+	p.funcs = append(p.funcs, fn.locals)
+	fn.body = body()
+	p.funcs = p.funcs[:len(p.funcs)-1]
+	return fn
+}
+
+func (p *parser) ifStatement() stmt {
+	pos := p.next().pos
+	s := &ifStmt{node: node{pos}, cond: p.test()}
+	s.then = p.block()
+	switch {
+	case p.is("elif"):
+		s.els = []stmt{p.ifStatement()}
+	case p.accept("else"):
+		s.els = p.block()
+	}
+	return s
+}
+
+func (p *parser) forStatement() stmt {
+	pos := p.expect("for").pos
+	vars := p.targets()
+	p.bindTarget(vars)
+	p.expect("in")
+	iter := p.expressionList()
+	return &forStmt{node{pos}, vars, iter, p.block()}
+}
+
+// targets parses the variables of a for loop or clause.
+func (p *parser) targets() expr {
+	pos := p.peek().pos
+	var elems []expr
+	for {
+		elems = append(elems, p.primary())
+		if !p.accept(",") || p.is("in") {
+			break
+		}
+	}
+	if len(elems) == 1 && !p.tokens[p.off-1].isOp(",") {
+		return elems[0]
+	}
+	return &tupleExpr{node{pos}, elems}
+}
+
+func (t token) isOp(text string) bool {
+	return t.kind == tokOp && t.text == text
+}
+
+// expressionList parses expressions separated by commas, which form a
+// tuple if there is more than one or a trailing comma.
+func (p *parser) expressionList() expr {
+	pos := p.peek().pos
+	x := p.test()
+	if !p.is(",") {
+		return x
+	}
+	elems := []expr{x}
+	for p.accept(",") {
+		if p.endsList() {
+			break
+		}
+		elems = append(elems, p.test())
+	}
+	return &tupleExpr{node{pos}, elems}
+}
+
+// endsList reports whether the next token ends a list of expressions.
+func (p *parser) endsList() bool {
+	t := p.peek()
+	if t.kind == tokNewline || t.kind == tokEOF {
+		return true
+	}
+	return t.kind == tokOp && slices.Contains([]string{")", "]", "}", "=", ";", ":"}, t.text)
+}
+
+// test parses a conditional expression or a lambda.
+func (p *parser) test() expr {
+	if p.is("lambda") {
+		pos := p.next().pos
+		fn := p.function(pos, "lambda", ":", func() []stmt {
+			x := p.test()
+			return []stmt{&returnStmt{node{x.position()}, x}}
+		})
+		return &lambdaExpr{node{pos}, fn}
+	}
+	x := p.or()
+	if p.is("if") {
+		pos := p.next().pos
+		cond := p.or()
+		p.expect("else")
+		return &condExpr{node{pos}, cond, x, p.test()}
+	}
+	return x
+}
+
+func (p *parser) or() expr {
+	x := p.and()
+	for p.is("or") {
+		pos := p.next().pos
+		x = &binaryExpr{node{pos}, "or", x, p.and()}
+	}
+	return x
+}
+
+func (p *parser) and() expr {
+	x := p.not()
+	for p.is("and") {
+		pos := p.next().pos
+		x = &binaryExpr{node{pos}, "and", x, p.not()}
+	}
+	return x
+}
+
+func (p *parser) not() expr {
+	if p.is("not") {
+		pos := p.next().pos
+		return &unaryExpr{node{pos}, "not", p.not()}
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() expr {
+	x := p.binary(0)
+	t, op := p.comparisonOp()
+	if op == "" {
+		return x
+	}
+	x = &binaryExpr{node{t.pos}, op, x, p.binary(0)}
+	// comparisons do not chain, as they do in Python
+	if next, op2 := p.comparisonOp(); op2 != "" {
+		p.fail(next.pos, "%s does not associate with %s (use parens)", op, op2)
+	}
+	return x
+}
+
+// comparisonOp consumes a comparison operator, returning its first token and
+// the operator, or returns an empty operator if there is none.
+func (p *parser) comparisonOp() (token, string) {
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && slices.Contains([]string{"==", "!=", "<", ">", "<=", ">="}, t.text):
+		p.next()
+		return t, t.text
+	case p.is("in"):
+		p.next()
+		return t, "in"
+	case p.is("not") && p.tokens[p.off+1].kind == tokName && p.tokens[p.off+1].text == "in":
+		p.next()
+		p.next()
+		return t, "not in"
+	}
+	return t, ""
+}
+
+// precedence lists the binary operators below comparisons, loosest first.
+var precedence = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "//", "%"},
+}
+
+func (p *parser) binary(level int) expr {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x := p.binary(level + 1)
+	for {
+		t := p.peek()
+		if t.kind != tokOp || !slices.Contains(precedence[level], t.text) {
+			return x
+		}
+		p.next()
+		x = &binaryExpr{node{t.pos}, t.text, x, p.binary(level + 1)}
+	}
+}
+
+func (p *parser) unary() expr {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "-" || t.text == "+" || t.text == "~") {
+		p.next()
+		return &unaryExpr{node{t.pos}, t.text, p.unary()}
+	}
+	return p.primary()
+}
+
+// primary parses an operand followed by any calls, indexes, slices and
+// attributes.
+func (p *parser) primary() expr {
+	x := p.operand()
+	for {
+		t := p.peek()
+		switch {
+		case t.isOp("("):
+			p.next()
+			x = &callExpr{node{t.pos}, x, p.arguments()}
+		case t.isOp("["):
+			p.next()
+			x = p.subscript(t.pos, x)
+		case t.isOp("."):
+			p.next()
+			x = &dotExpr{node{t.pos}, x, p.expectKind(tokName, "name").text}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) arguments() []argument {
+	var args []argument
+	seen := map[string]bool{}
+	for !p.is(")") {
+		t := p.peek()
+		var a argument
+		switch {
+		case p.accept("*"):
+			a.star = 1
+		case p.accept("**"):
+			a.star = 2
+		case t.kind == tokName && p.tokens[p.off+1].isOp("="):
+			a.name = p.name().text
+			p.next()
+			if seen[a.name] {
+				p.fail(t.pos, "keyword argument %s is repeated", a.name)
+			}
+			seen[a.name] = true
+		}
+		a.value = p.test()
+		args = append(args, a)
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(")")
+	return args
+}
+
+func (p *parser) subscript(pos position, x expr) expr {
+	var lo, hi, step expr
+	if !p.is(":") {
+		lo = p.expressionList()
+		if p.accept("]") {
+			return &indexExpr{node{pos}, x, lo}
+		}
+	}
+	p.expect(":")
+	if !p.is(":") && !p.is("]") {
+		hi = p.test()
+	}
+	if p.accept(":") && !p.is("]") {
+		step = p.test()
+	}
+	p.expect("]")
+	return &sliceExpr{node{pos}, x, lo, hi, step}
+}
+
+func (p *parser) operand() expr {
+	t := p.next()
+	switch t.kind {
+	case tokInt, tokFloat:
+		return &literalExpr{node{t.pos}, t.value}
+	case tokString:
+		s := t.value.(string)
+		// adjacent string literals are not concatenated, as in Starlark
+		return &literalExpr{node{t.pos}, s}
+	case tokName:
+		switch t.text {
+		case "None":
+			return &literalExpr{node{t.pos}, nil}
+		case "True":
+			return &literalExpr{node{t.pos}, true}
+		case "False":
+			return &literalExpr{node{t.pos}, false}
+		}
+		if keywords[t.text] {
+			p.fail(t.pos, "unexpected %s", t.text)
+		}
+		return &identExpr{node{t.pos}, t.text}
+	case tokOp:
+		switch t.text {
+		case "(":
+			if p.accept(")") {
+				return &tupleExpr{node{t.pos}, nil}
+			}
+			x := p.expressionList()
+			p.expect(")")
+			return x
+		case "[":
+			return p.list(t.pos)
+		case "{":
+			return p.dict(t.pos)
+		}
+	}
+	p.fail(t.pos, "unexpected %s", t)
+	return nil
+}
+
+func (p *parser) list(pos position) expr {
+	if p.accept("]") {
+		return &listExpr{node{pos}, nil}
+	}
+	x := p.test()
+	if p.is("for") {
+		c := p.comprehension(pos, nil, x, "]")
+		return c
+	}
+	elems := []expr{x}
+	for p.accept(",") && !p.is("]") {
+		elems = append(elems, p.test())
+	}
+	p.expect("]")
+	return &listExpr{node{pos}, elems}
+}
+
+func (p *parser) dict(pos position) expr {
+	if p.accept("}") {
+		return &dictExpr{node: node{pos}}
+	}
+	k := p.test()
+	p.expect(":")
+	v := p.test()
+	if p.is("for") {
+		return p.comprehension(pos, k, v, "}")
+	}
+	d := &dictExpr{node{pos}, []expr{k}, []expr{v}}
+	for p.accept(",") && !p.is("}") {
+		d.keys = append(d.keys, p.test())
+		p.expect(":")
+		d.values = append(d.values, p.test())
+	}
+	p.expect("}")
+	return d
+}
+
+// comprehension parses the clauses of a comprehension whose key and body
+// have been parsed. The variables of its for clauses are bound in the
+// comprehension, which the body has been parsed outside of, so they are
+// only looked up in it at run time.
+func (p *parser) comprehension(pos position, key, body expr, end string) expr {
+	c := &compExpr{node: node{pos}, key: key, body: body, locals: map[string]bool{}}
+	p.funcs = append(p.funcs, c.locals)
+	for !p.is(end) {
+		if p.accept("if") {
+			c.clauses = append(c.clauses, compClause{cond: p.or()})
+			continue
+		}
+		p.expect("for")
+		vars := p.targets()
+		p.bindTarget(vars)
+		p.expect("in")
+		c.clauses = append(c.clauses, compClause{vars: vars, iter: p.or()})
+	}
+	p.funcs = p.funcs[:len(p.funcs)-1]
+	p.expect(end)
+	return c
+}
@@ -0,0 +1,273 @@
+// Package script offers tools written in Starlark, a small dialect of
+// Python, so that simple tools can be added by dropping a file next to the
+// server rather than building Go. A script declares its tools by calling
+// tool with a handler, which is called with the arguments of each call:
+//
+//	def add(args):
+//	    return {"sum": args["a"] + args["b"]}
+//
+//	tool(
+//	    name = "add",
+//	    description = "Add two numbers",
+//	    input_schema = {
+//	        "type": "object",
+//	        "properties": {"a": {"type": "number"}, "b": {"type": "number"}},
+//	        "required": ["a", "b"],
+//	    },
+//	    handler = add,
+//	)
+//
+// A handler returns a string, sent as text content, a dict, sent as
+// structured content, or None for an empty result. Calling fail reports a
+// tool error to the client, and print sends a log message. The json module
+// encodes and decodes JSON.
+//
+// Scripts have no access to the host: there is no load statement, and no
+// builtins for files, the network or the clock. The globals of a script are
+// frozen once it is loaded, so calls, which may run concurrently, cannot
+// affect each other. Each call is limited in the steps it may execute.
+//
+// The interpreter implements the core of the Starlark language: while
+// loops, load, set and the struct type are not supported.
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/acrmp/mcp"
+)
+
+// DefaultMaxSteps is the number of steps, roughly statements, calls and loop
+// iterations, that loading a script or a call may execute when
+// Config.MaxSteps is not set.
+const DefaultMaxSteps = 10_000_000
+
+// ErrStepLimit is returned when a script exceeds Config.MaxSteps.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// Config limits the execution of scripts.
+type Config struct {
+	// MaxSteps limits the steps executed by loading a script and by each
+	// call to one of its tools. It defaults to DefaultMaxSteps.
+	MaxSteps uint64
+}
+
+// Error is an error in a script, located at a line and column.
+type Error struct {
+	Filename  string
+	Line, Col int
+	Msg       string
+	err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Col, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Load returns the tools declared by the script at filename.
+func Load(filename string, cfg Config) ([]mcp.ToolDefinition, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filename, b, cfg)
+}
+
+// Parse runs the script src, named filename in errors, and returns the tools
+// it declares.
+func Parse(filename string, src []byte, cfg Config) ([]mcp.ToolDefinition, error) {
+	if cfg.MaxSteps == 0 {
+		cfg.MaxSteps = DefaultMaxSteps
+	}
+	stmts, err := parse(string(src))
+	if err != nil {
+		return nil, locate(filename, err)
+	}
+	var decls []toolDecl
+	t := &thread{
+		ctx:     context.Background(),
+		steps:   cfg.MaxSteps,
+		globals: map[string]value{},
+		active:  map[*funcDecl]bool{},
+		print: func(text string) {
+			slog.Info(text, "script", filename)
+		},
+		tools: &decls,
+	}
+	if _, _, err := t.exec(nil, stmts); err != nil {
+		return nil, locate(filename, err)
+	}
+	seen := map[any]bool{}
+	for _, v := range t.globals {
+		freeze(v, seen)
+	}
+
+	s := &loaded{filename: filename, cfg: cfg, globals: t.globals}
+	tools := make([]mcp.ToolDefinition, 0, len(decls))
+	names := map[string]bool{}
+	for _, d := range decls {
+		if names[d.name] {
+			return nil, fmt.Errorf("%s: tool %s is declared more than once", filename, d.name)
+		}
+		names[d.name] = true
+		def, err := s.tool(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: tool %s: %w", filename, d.name, err)
+		}
+		tools = append(tools, def)
+	}
+	return tools, nil
+}
+
+// locate sets the filename of err if it is an Error, and otherwise prefixes
+// err with it.
+func locate(filename string, err error) error {
+	var located *Error
+	if errors.As(err, &located) {
+		located.Filename = filename
+		return err
+	}
+	return fmt.Errorf("%s: %w", filename, err)
+}
+
+// loaded is a script whose globals have been frozen.
+type loaded struct {
+	filename string
+	cfg      Config
+	globals  map[string]value
+}
+
+func (s *loaded) tool(d toolDecl) (mcp.ToolDefinition, error) {
+	metadata := mcp.Tool{Name: d.name, InputSchema: mcp.ToolInputSchema{Type: "object"}}
+	if d.description != "" {
+		metadata.Description = &d.description
+	}
+	if d.schema != nil {
+		b, err := encodeJSON(d.schema)
+		if err != nil {
+			return mcp.ToolDefinition{}, err
+		}
+		if err := json.Unmarshal(b, &metadata.InputSchema); err != nil {
+			return mcp.ToolDefinition{}, fmt.Errorf("invalid input_schema: %w", err)
+		}
+	}
+	return mcp.ToolDefinition{
+		Metadata: metadata,
+		Process: func(ctx context.Context, params mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+			return s.call(ctx, d, params, n)
+		},
+	}, nil
+}
+
+func (s *loaded) call(ctx context.Context, d toolDecl, params mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+	args := params.Arguments
+	if args == nil {
+		args = map[string]any{}
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	v, err := decodeJSON(b)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+
+	t := &thread{
+		ctx:     ctx,
+		steps:   s.cfg.MaxSteps,
+		globals: s.globals,
+		active:  map[*funcDecl]bool{},
+		print: func(text string) {
+			if n != nil {
+				n.Notify(ctx, "notifications/message", mcp.LoggingMessageNotificationParams{
+					Level:  mcp.LoggingLevelInfo,
+					Logger: &d.name,
+					Data:   text,
+				})
+			}
+		},
+	}
+	result, err := t.call(d.handler, []value{v}, nil)
+	var failed *failError
+	if errors.As(err, &failed) {
+		return mcp.NewResult().Text(failed.msg).Error(true).Build()
+	}
+	if err != nil {
+		return mcp.CallToolResult{}, locate(s.filename, err)
+	}
+
+	switch result := result.(type) {
+	case nil:
+		return mcp.NewResult().Build()
+	case string:
+		return mcp.NewResult().Text(result).Build()
+	case *dict:
+		b, err := encodeJSON(result)
+		if err != nil {
+			return mcp.CallToolResult{}, fmt.Errorf("encoding result of %s: %w", d.name, err)
+		}
+		return mcp.NewResult().Structured(json.RawMessage(b)).Build()
+	}
+	return mcp.CallToolResult{}, fmt.Errorf("tool %s returned %s, want string, dict or None", d.name, typeName(result))
+}
+
+// Dir is a directory of scripts, whose tools may be reloaded while the
+// server runs.
+type Dir struct {
+	path string
+	cfg  Config
+	set  *mcp.ToolSet
+}
+
+// LoadDir loads the tools declared by the scripts named *.star in the
+// directory at path.
+func LoadDir(path string, cfg Config) (*Dir, error) {
+	d := &Dir{path: path, cfg: cfg, set: mcp.NewToolSet(nil)}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ToolSet returns the tools of the scripts, for serving with
+// mcp.WithToolSet.
+func (d *Dir) ToolSet() *mcp.ToolSet {
+	return d.set
+}
+
+// Reload loads the scripts again, replacing the tools of the set. If a
+// script fails to load, the tools are left unchanged.
+func (d *Dir) Reload() error {
+	files, err := filepath.Glob(filepath.Join(d.path, "*.star"))
+	if err != nil {
+		return err
+	}
+	var tools []mcp.ToolDefinition
+	declared := map[string]string{}
+	for _, f := range files {
+		defs, err := Load(f, d.cfg)
+		if err != nil {
+			return err
+		}
+		for _, t := range defs {
+			if other, ok := declared[t.Metadata.Name]; ok {
+				return fmt.Errorf("tool %s is declared in both %s and %s", t.Metadata.Name, other, f)
+			}
+			declared[t.Metadata.Name] = f
+		}
+		tools = append(tools, defs...)
+	}
+	d.set.Set(tools)
+	return nil
+}
@@ -0,0 +1,278 @@
+package script_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+	"github.com/acrmp/mcp/script"
+)
+
+var _ = Describe("Load", func() {
+
+	var cfg script.Config
+
+	BeforeEach(func() {
+		cfg = script.Config{}
+	})
+
+	tool := func(name string) mcp.ToolDefinition {
+		tools, err := script.Load("testdata/tools.star", cfg)
+		Expect(err).ToNot(HaveOccurred())
+		for _, t := range tools {
+			if t.Metadata.Name == name {
+				return t
+			}
+		}
+		Fail("no tool " + name)
+		return mcp.ToolDefinition{}
+	}
+
+	call := func(t mcp.ToolDefinition, args map[string]any, n mcp.Notifier) (mcp.CallToolResult, error) {
+		return t.Process(context.Background(), mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: args}, n)
+	}
+
+	It("declares the tools of the script", func() {
+		tools, err := script.Load("testdata/tools.star", cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(HaveLen(6))
+
+		convert := tools[0].Metadata
+		Expect(convert.Name).To(Equal("convert"))
+		Expect(*convert.Description).To(Equal("Convert a temperature"))
+		Expect(convert.InputSchema.Required).To(Equal([]string{"temperature", "to"}))
+		Expect(convert.InputSchema.Properties["to"]).To(Equal(map[string]any{"type": "string", "enum": []any{"c", "f"}}))
+		Expect(tools[1].Metadata.InputSchema).To(Equal(mcp.ToolInputSchema{Type: "object"}))
+
+		metadata := make([]mcp.Tool, len(tools))
+		for i, t := range tools {
+			metadata[i] = t.Metadata
+		}
+		mcptest.ValidateToolSchemas(metadata...)
+	})
+
+	It("returns a dict as structured content", func() {
+		result, err := call(tool("convert"), map[string]any{"temperature": 100, "to": "f"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsError).To(BeNil())
+		Expect(result.StructuredContent).To(Equal(map[string]any{"value": 212.0, "unit": "Fahrenheit"}))
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent(`{"value":212,"unit":"Fahrenheit"}`)}))
+	})
+
+	It("returns a string as text content", func() {
+		result, err := call(tool("words"), map[string]any{"text": "b a b c a b d", "limit": 2}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("b 3\na 2")}))
+	})
+
+	It("returns None as an empty result", func() {
+		result, err := call(tool("nothing"), nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(BeEmpty())
+		Expect(result.IsError).To(BeNil())
+	})
+
+	It("reports fail as a tool error", func() {
+		result, err := call(tool("convert"), map[string]any{"temperature": 1, "to": "k"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("unknown unit: k")}))
+	})
+
+	It("sends what the script prints as log messages", func() {
+		n := &mcptest.FakeNotifier{}
+		_, err := call(tool("words"), map[string]any{"text": "a b"}, n)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(n.Methods()).To(Equal([]string{"notifications/message"}))
+		var params mcp.LoggingMessageNotificationParams
+		Expect(json.Unmarshal(n.Notifications()[0].Params, &params)).To(Succeed())
+		Expect(params.Level).To(Equal(mcp.LoggingLevelInfo))
+		Expect(*params.Logger).To(Equal("words"))
+		Expect(params.Data).To(Equal("counted 2 words"))
+	})
+
+	It("locates runtime errors", func() {
+		_, err := call(tool("convert"), map[string]any{"to": "f"}, nil)
+		var located *script.Error
+		Expect(errors.As(err, &located)).To(BeTrue())
+		Expect(located.Filename).To(Equal("testdata/tools.star"))
+		Expect(located.Line).To(Equal(6))
+		Expect(err).To(MatchError(`testdata/tools.star:6:13: key "temperature" not in dict`))
+	})
+
+	It("freezes the globals once the script is loaded", func() {
+		_, err := call(tool("mutate"), nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("cannot insert into a frozen dict")))
+	})
+
+	It("rejects results that are not strings, dicts or None", func() {
+		_, err := call(tool("broken"), nil, nil)
+		Expect(err).To(MatchError("tool broken returned list, want string, dict or None"))
+	})
+
+	It("runs calls concurrently", func() {
+		t := tool("words")
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				result, err := call(t, map[string]any{"text": "a b a"}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("a 2\nb 1")}))
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("limits the steps of a call", func() {
+		cfg.MaxSteps = 1000
+		result, err := call(tool("spin"), map[string]any{"n": 10}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("45")}))
+
+		_, err = call(tool("spin"), map[string]any{"n": 1000}, nil)
+		Expect(err).To(MatchError(script.ErrStepLimit))
+	})
+
+	It("stops when the call is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		t := tool("spin")
+		_, err := t.Process(ctx, mcp.CallToolRequestParams{Name: "spin", Arguments: map[string]any{"n": 1_000_000}}, nil)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	DescribeTable("rejects invalid scripts",
+		func(src, message string) {
+			_, err := script.Parse("bad.star", []byte(src), cfg)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("syntax errors", "def f(:\n    pass\n", `bad.star:1:7: got ":", want name`),
+		Entry("bad indentation", "if True:\n    x = 1\n  y = 2\n", "bad.star:3:3: unindent does not match any outer indentation level"),
+		Entry("unsupported statements", "while True:\n    pass\n", "bad.star:1:1: while statements are not supported"),
+		Entry("undefined names", "x = y\n", "bad.star:1:5: undefined: y"),
+		Entry("duplicate tools", "def f(args):\n    pass\ntool(name='a', handler=f)\ntool(name='a', handler=f)\n", "bad.star: tool a is declared more than once"),
+		Entry("non-function handlers", "tool(name='a', handler=1)\n", "bad.star:1:5: tool: handler must be a function, not int"),
+		Entry("failing scripts", "fail('no config')\n", "bad.star: no config"),
+		Entry("recursion", "def f(n):\n    return f(n)\nf(1)\n", "bad.star:2:13: function f called recursively"),
+	)
+})
+
+var _ = Describe("Language", func() {
+
+	// run calls a tool whose handler has the given body, returning its text.
+	run := func(body string) (string, error) {
+		src := "def main(args):\n    " + strings.ReplaceAll(strings.TrimSpace(body), "\n", "\n    ") + "\ntool(name='main', handler=main)\n"
+		tools, err := script.Parse("main.star", []byte(src), script.Config{})
+		if err != nil {
+			return "", err
+		}
+		result, err := tools[0].Process(context.Background(), mcp.CallToolRequestParams{Name: "main"}, nil)
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	DescribeTable("evaluates",
+		func(body, want string) {
+			got, err := run(body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal(want))
+		},
+		Entry("arithmetic", "return repr([1 + 2 * 3, 7 // 2, -7 // 2, 7 % -3, 2 * -3, 1 / 2, 0x10 | 1, 1 << 4])", "[7, 3, -4, -2, -6, 0.5, 17, 16]"),
+		Entry("floats", "return repr([1.5 * 2, 3.0, 1e100, float('inf'), 7.5 // 2])", "[3.0, 3.0, 1e+100, +inf, 3.0]"),
+		Entry("comparisons", "return repr([1 < 2, 2 <= 2, 'a' < 'b', [1, 2] < [1, 3], 1 == 1.0, None == False])", "[True, True, True, True, True, False]"),
+		Entry("boolean operators", "return repr([1 and 2, 0 or 'x', not [], 1 if '' else 2])", "[2, \"x\", True, 2]"),
+		Entry("strings", `return repr(["a,b".split(","), "x".join(["1", "2"]), " s ".strip(), "ab"[::-1], "%s=%r %05.1f" % ("k", "v", 2.5), "{}-{name}".format(1, name="n")])`, `[["a", "b"], "1x2", "s", "ba", "k=\"v\" 002.5", "1-n"]`),
+		Entry("string methods", `return repr(["Hello".upper(), "a b".title(), "abc".startswith(("x", "a")), "aaa".replace("a", "b", 2), "a=b=c".partition("="), "a b  c".split(None, 1)])`, `["HELLO", "A B", True, "bba", ("a", "=", "b=c"), ["a", "b  c"]]`),
+		Entry("lists", "l = [3, 1]\nl.append(2)\nl += [0]\nl.insert(0, 9)\nl.remove(1)\nreturn repr([l, l.pop(), sorted(l), l[1:3], len(l), 3 in l])", "[[9, 3, 2], 0, [2, 3, 9], [3, 2], 3, True]"),
+		Entry("dicts", "d = {'b': 1}\nd['a'] = 2\nd.update(c=3)\nreturn repr([d, d.keys(), d.pop('b'), d.get('x', 0), d.setdefault('z', [])])", `[{"a": 2, "c": 3, "z": []}, ["b", "a", "c"], 1, 0, []]`),
+		Entry("appending while iterating", "l = [1, 2]\nfor x in l:\n    l.append(x)\nreturn repr(l)", "[1, 2, 1, 2]"),
+		Entry("comprehensions", "return repr([[x * y for x in range(3) for y in range(3) if x < y], {k: v for k, v in zip(['a', 'b'], [1, 2])}])", `[[0, 0, 2], {"a": 1, "b": 2}]`),
+		Entry("unpacking", "a, (b, c) = 1, [2, 3]\nfor i, x in enumerate(['x', 'y']):\n    a += i\nreturn repr((a, b, c))", "(2, 2, 3)"),
+		Entry("loops", "n = 0\nfor i in range(10):\n    if i % 2:\n        continue\n    if i > 6:\n        break\n    n += i\nreturn str(n)", "12"),
+		Entry("functions", "def f(a, b=2, *args, c, **kwargs):\n    return [a, b, args, c, kwargs]\nreturn repr([f(1, c=3), f(1, 2, 3, 4, c=5, d=6), f(*[1], **{'c': 3})])", `[[1, 2, (), 3, {}], [1, 2, (3, 4), 5, {"d": 6}], [1, 2, (), 3, {}]]`),
+		Entry("closures", "def adder(n):\n    return lambda x: x + n\nreturn repr([adder(2)(1), adder(3)(1)])", "[3, 4]"),
+		Entry("builtins", "return repr([min(3, 1, 2), max([1, 5], key=lambda x: -x), abs(-2), int('0x1f', 16), int(2.9), any([0, 1]), all([]), type({}), reversed([1, 2]), str(None), bool([])])", `[1, 1, 2, 31, 2, True, True, "dict", [2, 1], "None", False]`),
+		Entry("json", `v = json.decode('{"a": [1, 2.5, true, null]}')`+"\nreturn repr([v, json.encode({'x': (1, 'y')})])", `[{"a": [1, 2.5, True, None]}, "{\"x\":[1,\"y\"]}"]`),
+	)
+
+	DescribeTable("reports errors",
+		func(body, message string) {
+			_, err := run(body)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("division by zero", "return str(1 // 0)", "main.star:2:18: integer division or modulo by zero"),
+		Entry("overflow", "return str(9223372036854775807 + 1)", "integer overflow"),
+		Entry("bad operands", "return 1 + 'a'", "unknown binary op: int + string"),
+		Entry("out of range indexes", "return [1][2]", "index 2 out of range"),
+		Entry("unhashable keys", "return {[]: 1}", "unhashable type: list"),
+		Entry("bad arguments", "return len(1, 2)", "len: got 2 arguments, want at most 1"),
+		Entry("chained comparisons", "return 1 < 2 < 3", "< does not associate with <"),
+		Entry("unbound locals", "if False:\n    x = 1\nreturn x", "local variable x referenced before assignment"),
+	)
+})
+
+var _ = Describe("Dir", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		b, err := os.ReadFile("testdata/dir/a.star")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "a.star"), b, 0o644)).To(Succeed())
+	})
+
+	names := func(s *mcp.ToolSet) []string {
+		var names []string
+		for _, t := range s.Tools() {
+			names = append(names, t.Name)
+		}
+		return names
+	}
+
+	It("offers the tools of the scripts in the directory", func() {
+		d, err := script.LoadDir(dir, script.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(d.ToolSet())).To(Equal([]string{"hello"}))
+	})
+
+	It("replaces the tools when reloaded", func() {
+		d, err := script.LoadDir(dir, script.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "b.star"), []byte("def f(args):\n    return 'b'\ntool(name='b', handler=f)\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a script"), 0o644)).To(Succeed())
+
+		Expect(d.Reload()).To(Succeed())
+		Expect(names(d.ToolSet())).To(Equal([]string{"hello", "b"}))
+	})
+
+	It("keeps the tools when a script fails to load", func() {
+		d, err := script.LoadDir(dir, script.Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "b.star"), []byte("tool(\n"), 0o644)).To(Succeed())
+
+		Expect(d.Reload()).To(MatchError(ContainSubstring("b.star:2:1")))
+		Expect(names(d.ToolSet())).To(Equal([]string{"hello"}))
+	})
+
+	It("rejects tools declared by more than one script", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "b.star"), []byte("def f(args):\n    pass\ntool(name='hello', handler=f)\n"), 0o644)).To(Succeed())
+		_, err := script.LoadDir(dir, script.Config{})
+		Expect(err).To(MatchError(ContainSubstring("tool hello is declared in both")))
+	})
+})
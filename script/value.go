@@ -0,0 +1,982 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// value is a script value: nil for None, a bool, int64, float64 or string,
+// or one of the types below.
+type value = any
+
+type list struct {
+	elems  []value
+	frozen bool
+}
+
+type tuple []value
+
+// dict is a dictionary that keeps its keys in the order they were added.
+type dict struct {
+	keys, values []value
+	// index maps the hash keys of the keys to their positions.
+	index  map[string]int
+	frozen bool
+}
+
+// rangeValue is the sequence of integers returned by range.
+type rangeValue struct {
+	start, stop, step int64
+}
+
+// function is a function defined by a script.
+type function struct {
+	decl     *funcDecl
+	defaults []value
+	// parent is the frame the function was defined in, nil for functions
+	// defined at the top level.
+	parent *frame
+}
+
+// builtin is a function implemented in Go, bound to recv if it is a method.
+type builtin struct {
+	name string
+	recv value
+	fn   func(t *thread, b *builtin, args []value, kwargs []kwarg) (value, error)
+}
+
+// module is a namespace of builtins, such as json.
+type module struct {
+	name    string
+	members map[string]value
+}
+
+type kwarg struct {
+	name  string
+	value value
+}
+
+func newDict() *dict {
+	return &dict{index: map[string]int{}}
+}
+
+func (d *dict) get(k value) (value, bool, error) {
+	h, err := hashKey(k)
+	if err != nil {
+		return nil, false, err
+	}
+	i, ok := d.index[h]
+	if !ok {
+		return nil, false, nil
+	}
+	return d.values[i], true, nil
+}
+
+func (d *dict) set(k, v value) error {
+	if d.frozen {
+		return errors.New("cannot insert into a frozen dict")
+	}
+	h, err := hashKey(k)
+	if err != nil {
+		return err
+	}
+	if i, ok := d.index[h]; ok {
+		d.values[i] = v
+		return nil
+	}
+	d.index[h] = len(d.keys)
+	d.keys = append(d.keys, k)
+	d.values = append(d.values, v)
+	return nil
+}
+
+func (d *dict) delete(k value) (value, bool, error) {
+	if d.frozen {
+		return nil, false, errors.New("cannot delete from a frozen dict")
+	}
+	h, err := hashKey(k)
+	if err != nil {
+		return nil, false, err
+	}
+	i, ok := d.index[h]
+	if !ok {
+		return nil, false, nil
+	}
+	v := d.values[i]
+	d.keys = slices.Delete(d.keys, i, i+1)
+	d.values = slices.Delete(d.values, i, i+1)
+	delete(d.index, h)
+	for h, j := range d.index {
+		if j > i {
+			d.index[h] = j - 1
+		}
+	}
+	return v, true, nil
+}
+
+func (d *dict) clear() error {
+	if d.frozen {
+		return errors.New("cannot clear a frozen dict")
+	}
+	d.keys, d.values, d.index = nil, nil, map[string]int{}
+	return nil
+}
+
+func (l *list) checkMutable(verb string) error {
+	if l.frozen {
+		return fmt.Errorf("cannot %s a frozen list", verb)
+	}
+	return nil
+}
+
+func (r rangeValue) len() int64 {
+	switch {
+	case r.step > 0 && r.start < r.stop:
+		return (r.stop-r.start-1)/r.step + 1
+	case r.step < 0 && r.start > r.stop:
+		return (r.start-r.stop-1)/(-r.step) + 1
+	}
+	return 0
+}
+
+func (r rangeValue) at(i int64) int64 {
+	return r.start + i*r.step
+}
+
+// hashKey returns the key a value is stored under in a dict, failing for
+// values that may not be keys. Ints and floats of equal value have the
+// same key, as they are equal.
+func hashKey(v value) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "N", nil
+	case bool:
+		if v {
+			return "T", nil
+		}
+		return "F", nil
+	case int64:
+		return "i" + strconv.FormatInt(v, 10), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return "i" + strconv.FormatInt(int64(v), 10), nil
+		}
+		return "f" + strconv.FormatUint(math.Float64bits(v), 16), nil
+	case string:
+		return "s" + v, nil
+	case tuple:
+		var b strings.Builder
+		b.WriteString("t")
+		for _, e := range v {
+			k, err := hashKey(e)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(strconv.Itoa(len(k)))
+			b.WriteString(":")
+			b.WriteString(k)
+		}
+		return b.String(), nil
+	case *function, *builtin:
+		return fmt.Sprintf("p%p", v), nil
+	}
+	return "", fmt.Errorf("unhashable type: %s", typeName(v))
+}
+
+func typeName(v value) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case *list:
+		return "list"
+	case tuple:
+		return "tuple"
+	case *dict:
+		return "dict"
+	case rangeValue:
+		return "range"
+	case *function:
+		return "function"
+	case *builtin:
+		return "builtin_function_or_method"
+	case *module:
+		return "module"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func truth(v value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case *list:
+		return len(v.elems) > 0
+	case tuple:
+		return len(v) > 0
+	case *dict:
+		return len(v.keys) > 0
+	case rangeValue:
+		return v.len() > 0
+	}
+	return true
+}
+
+// str returns v as str does, which is repr except for strings.
+func str(v value) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return repr(v)
+}
+
+func repr(v value) string {
+	var b strings.Builder
+	writeRepr(&b, v, nil)
+	return b.String()
+}
+
+func writeRepr(b *strings.Builder, v value, path []value) {
+	// containers holding themselves are written as ...
+	for _, p := range path {
+		if p == v {
+			b.WriteString("...")
+			return
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if v {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		b.WriteString(formatFloat(v))
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case *list:
+		b.WriteString("[")
+		for i, e := range v.elems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, e, append(path, v))
+		}
+		b.WriteString("]")
+	case tuple:
+		b.WriteString("(")
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, e, path)
+		}
+		if len(v) == 1 {
+			b.WriteString(",")
+		}
+		b.WriteString(")")
+	case *dict:
+		b.WriteString("{")
+		for i, k := range v.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, k, path)
+			b.WriteString(": ")
+			writeRepr(b, v.values[i], append(path, v))
+		}
+		b.WriteString("}")
+	case rangeValue:
+		fmt.Fprintf(b, "range(%d, %d", v.start, v.stop)
+		if v.step != 1 {
+			fmt.Fprintf(b, ", %d", v.step)
+		}
+		b.WriteString(")")
+	case *function:
+		fmt.Fprintf(b, "<function %s>", v.decl.name)
+	case *builtin:
+		fmt.Fprintf(b, "<built-in function %s>", v.name)
+	case *module:
+		fmt.Fprintf(b, "<module %s>", v.name)
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// equal reports whether x and y are equal, comparing containers by value.
+func equal(x, y value) (bool, error) {
+	return equalDepth(x, y, 0)
+}
+
+func equalDepth(x, y value, depth int) (bool, error) {
+	if depth > 100 {
+		return false, errors.New("comparison exceeds maximum recursion depth")
+	}
+	if xf, yf, ok := numbers(x, y); ok {
+		return xf == yf, nil
+	}
+	switch x := x.(type) {
+	case *list:
+		y, ok := y.(*list)
+		if !ok {
+			return false, nil
+		}
+		return equalElems(x.elems, y.elems, depth)
+	case tuple:
+		y, ok := y.(tuple)
+		if !ok {
+			return false, nil
+		}
+		return equalElems(x, y, depth)
+	case *dict:
+		y, ok := y.(*dict)
+		if !ok || len(x.keys) != len(y.keys) {
+			return false, nil
+		}
+		for i, k := range x.keys {
+			yv, found, err := y.get(k)
+			if err != nil || !found {
+				return false, err
+			}
+			if eq, err := equalDepth(x.values[i], yv, depth+1); err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	case rangeValue:
+		y, ok := y.(rangeValue)
+		if !ok {
+			return false, nil
+		}
+		n := x.len()
+		return n == y.len() && (n == 0 || x.start == y.start && (n == 1 || x.step == y.step)), nil
+	}
+	return x == y, nil
+}
+
+func equalElems(x, y []value, depth int) (bool, error) {
+	if len(x) != len(y) {
+		return false, nil
+	}
+	for i := range x {
+		if eq, err := equalDepth(x[i], y[i], depth+1); err != nil || !eq {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// numbers returns x and y as float64 if both are numbers and either is a
+// float. Two ints compare exactly, so they are not converted.
+func numbers(x, y value) (float64, float64, bool) {
+	xf, xok := x.(float64)
+	yf, yok := y.(float64)
+	if !xok && !yok {
+		return 0, 0, false
+	}
+	if xi, ok := x.(int64); ok {
+		xf, xok = float64(xi), true
+	}
+	if yi, ok := y.(int64); ok {
+		yf, yok = float64(yi), true
+	}
+	return xf, yf, xok && yok
+}
+
+// compare returns the order of x and y, which must be of types that are
+// ordered.
+func compare(op string, x, y value) (int, error) {
+	if xf, yf, ok := numbers(x, y); ok {
+		switch {
+		case xf < yf:
+			return -1, nil
+		case xf > yf:
+			return 1, nil
+		case xf == yf:
+			return 0, nil
+		}
+		// comparisons with NaN are false, which no order gives for every
+		// operator, so NaN is reported as equal and handled by the caller
+		return 0, errNaN
+	}
+	switch x := x.(type) {
+	case int64:
+		if y, ok := y.(int64); ok {
+			return cmpInt(x, y), nil
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			return cmpInt(boolInt(x), boolInt(y)), nil
+		}
+	case *list:
+		if y, ok := y.(*list); ok {
+			return compareElems(op, x.elems, y.elems)
+		}
+	case tuple:
+		if y, ok := y.(tuple); ok {
+			return compareElems(op, x, y)
+		}
+	}
+	return 0, fmt.Errorf("unsupported comparison: %s %s %s", typeName(x), op, typeName(y))
+}
+
+var errNaN = errors.New("NaN")
+
+func compareElems(op string, x, y []value) (int, error) {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if eq, err := equal(x[i], y[i]); err != nil {
+			return 0, err
+		} else if !eq {
+			return compare(op, x[i], y[i])
+		}
+	}
+	return cmpInt(int64(len(x)), int64(len(y))), nil
+}
+
+func cmpInt(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// maxRepeat is the longest string, list or tuple repetition may make, which
+// keeps scripts from exhausting memory in a single step.
+const maxRepeat = 1 << 24
+
+// binary applies the binary operator op, other than and and or, to x and y.
+func binary(op string, x, y value) (value, error) {
+	switch op {
+	case "==":
+		return equal(x, y)
+	case "!=":
+		eq, err := equal(x, y)
+		return !eq, err
+	case "<", ">", "<=", ">=":
+		c, err := compare(op, x, y)
+		if err == errNaN {
+			return false, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case ">":
+			return c > 0, nil
+		case "<=":
+			return c <= 0, nil
+		}
+		return c >= 0, nil
+	case "in", "not in":
+		in, err := contains(y, x)
+		if op == "not in" {
+			in = !in
+		}
+		return in, err
+	}
+
+	if xi, ok := x.(int64); ok {
+		if yi, ok := y.(int64); ok {
+			return intBinary(op, xi, yi)
+		}
+	}
+	if xf, yf, ok := numbers(x, y); ok {
+		return floatBinary(op, xf, yf)
+	}
+	switch x := x.(type) {
+	case string:
+		switch y := y.(type) {
+		case string:
+			if op == "+" {
+				return x + y, nil
+			}
+		case int64:
+			if op == "*" {
+				return repeatString(x, y)
+			}
+		}
+		if op == "%" {
+			return format(x, y)
+		}
+	case int64:
+		if op == "*" {
+			switch y.(type) {
+			case string, *list, tuple:
+				return binary(op, y, x)
+			}
+		}
+	case *list:
+		switch y := y.(type) {
+		case *list:
+			if op == "+" {
+				return &list{elems: append(slices.Clip(x.elems), y.elems...)}, nil
+			}
+		case int64:
+			if op == "*" {
+				elems, err := repeat(x.elems, y)
+				return &list{elems: elems}, err
+			}
+		}
+	case tuple:
+		switch y := y.(type) {
+		case tuple:
+			if op == "+" {
+				return append(slices.Clip(x), y...), nil
+			}
+		case int64:
+			if op == "*" {
+				elems, err := repeat(x, y)
+				return tuple(elems), err
+			}
+		}
+	case *dict:
+		if y, ok := y.(*dict); ok && op == "|" {
+			d := newDict()
+			for _, src := range []*dict{x, y} {
+				for i, k := range src.keys {
+					d.set(k, src.values[i])
+				}
+			}
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown binary op: %s %s %s", typeName(x), op, typeName(y))
+}
+
+func repeatString(s string, n int64) (value, error) {
+	if n <= 0 {
+		return "", nil
+	}
+	if int64(len(s)) > maxRepeat/n {
+		return nil, errors.New("repeated string is too long")
+	}
+	return strings.Repeat(s, int(n)), nil
+}
+
+func repeat(elems []value, n int64) ([]value, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if int64(len(elems)) > maxRepeat/n {
+		return nil, errors.New("repeated sequence is too long")
+	}
+	out := make([]value, 0, len(elems)*int(n))
+	for range n {
+		out = append(out, elems...)
+	}
+	return out, nil
+}
+
+var errOverflow = errors.New("integer overflow")
+
+func intBinary(op string, x, y int64) (value, error) {
+	switch op {
+	case "+":
+		z := x + y
+		if (z > x) != (y > 0) {
+			return nil, errOverflow
+		}
+		return z, nil
+	case "-":
+		z := x - y
+		if (z < x) != (y > 0) {
+			return nil, errOverflow
+		}
+		return z, nil
+	case "*":
+		if x == 0 || y == 0 {
+			return int64(0), nil
+		}
+		z := x * y
+		if z/y != x || x == -1 && y == math.MinInt64 || y == -1 && x == math.MinInt64 {
+			return nil, errOverflow
+		}
+		return z, nil
+	case "/":
+		if y == 0 {
+			return nil, errors.New("floating-point division by zero")
+		}
+		return float64(x) / float64(y), nil
+	case "//", "%":
+		if y == 0 {
+			return nil, errors.New("integer division or modulo by zero")
+		}
+		if x == math.MinInt64 && y == -1 {
+			if op == "%" {
+				return int64(0), nil
+			}
+			return nil, errOverflow
+		}
+		q, r := x/y, x%y
+		// the quotient is floored and the remainder has the sign of y
+		if r != 0 && (r < 0) != (y < 0) {
+			q--
+			r += y
+		}
+		if op == "//" {
+			return q, nil
+		}
+		return r, nil
+	case "&":
+		return x & y, nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "<<", ">>":
+		if y < 0 {
+			return nil, errors.New("negative shift count")
+		}
+		if op == ">>" {
+			return x >> min(y, 63), nil
+		}
+		if y >= 64 || x<<y>>y != x {
+			return nil, errOverflow
+		}
+		return x << y, nil
+	}
+	return nil, fmt.Errorf("unknown binary op: int %s int", op)
+}
+
+func floatBinary(op string, x, y float64) (value, error) {
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, errors.New("floating-point division by zero")
+		}
+		return x / y, nil
+	case "//":
+		if y == 0 {
+			return nil, errors.New("floating-point division by zero")
+		}
+		return math.Floor(x / y), nil
+	case "%":
+		if y == 0 {
+			return nil, errors.New("floating-point modulo by zero")
+		}
+		r := math.Mod(x, y)
+		if r != 0 && (r < 0) != (y < 0) {
+			r += y
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown binary op: float %s float", op)
+}
+
+// contains reports whether the container c holds x.
+func contains(c, x value) (bool, error) {
+	switch c := c.(type) {
+	case *list:
+		return containsElem(c.elems, x)
+	case tuple:
+		return containsElem(c, x)
+	case *dict:
+		_, found, err := c.get(x)
+		return found, err
+	case string:
+		s, ok := x.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires string as left operand, not %s", typeName(x))
+		}
+		return strings.Contains(c, s), nil
+	case rangeValue:
+		i, ok := x.(int64)
+		if !ok || c.len() == 0 {
+			return false, nil
+		}
+		if c.step > 0 && (i < c.start || i >= c.stop) || c.step < 0 && (i > c.start || i <= c.stop) {
+			return false, nil
+		}
+		return (i-c.start)%c.step == 0, nil
+	}
+	return false, fmt.Errorf("unknown binary op: %s in %s", typeName(x), typeName(c))
+}
+
+func containsElem(elems []value, x value) (bool, error) {
+	for _, e := range elems {
+		if eq, err := equal(e, x); err != nil || eq {
+			return eq, err
+		}
+	}
+	return false, nil
+}
+
+func unary(op string, x value) (value, error) {
+	switch op {
+	case "not":
+		return !truth(x), nil
+	case "-":
+		switch x := x.(type) {
+		case int64:
+			if x == math.MinInt64 {
+				return nil, errOverflow
+			}
+			return -x, nil
+		case float64:
+			return -x, nil
+		}
+	case "+":
+		switch x.(type) {
+		case int64, float64:
+			return x, nil
+		}
+	case "~":
+		if x, ok := x.(int64); ok {
+			return ^x, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown unary op: %s%s", op, typeName(x))
+}
+
+// index returns x[i].
+func index(x, i value) (value, error) {
+	if d, ok := x.(*dict); ok {
+		v, found, err := d.get(i)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("key %s not in dict", repr(i))
+		}
+		return v, nil
+	}
+	n, ok := length(x)
+	if !ok {
+		return nil, fmt.Errorf("unhandled index operation %s[%s]", typeName(x), typeName(i))
+	}
+	k, err := seqIndex(i, n, typeName(x))
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case *list:
+		return x.elems[k], nil
+	case tuple:
+		return x[k], nil
+	case string:
+		return x[k : k+1], nil
+	case rangeValue:
+		return x.at(k), nil
+	}
+	return nil, fmt.Errorf("unhandled index operation %s[%s]", typeName(x), typeName(i))
+}
+
+// seqIndex returns the index i of a sequence of length n, which may count
+// from the end if negative.
+func seqIndex(i value, n int64, what string) (int64, error) {
+	k, ok := i.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%s index must be int, not %s", what, typeName(i))
+	}
+	if k < 0 {
+		k += n
+	}
+	if k < 0 || k >= n {
+		return 0, fmt.Errorf("%s index %d out of range [%d:%d]", what, i, -n, n)
+	}
+	return k, nil
+}
+
+// length returns the number of elements of x, if it is a sequence or
+// mapping.
+func length(x value) (int64, bool) {
+	switch x := x.(type) {
+	case string:
+		return int64(len(x)), true
+	case *list:
+		return int64(len(x.elems)), true
+	case tuple:
+		return int64(len(x)), true
+	case *dict:
+		return int64(len(x.keys)), true
+	case rangeValue:
+		return x.len(), true
+	}
+	return 0, false
+}
+
+// slice returns x[lo:hi:step], where the bounds are None if omitted.
+func slice(x, lo, hi, step value) (value, error) {
+	n, ok := length(x)
+	if _, isDict := x.(*dict); !ok || isDict {
+		return nil, fmt.Errorf("invalid slice operand %s", typeName(x))
+	}
+	var s int64 = 1
+	if step != nil {
+		var ok bool
+		if s, ok = step.(int64); !ok {
+			return nil, fmt.Errorf("slice step must be int, not %s", typeName(step))
+		}
+		if s == 0 {
+			return nil, errors.New("slice step cannot be zero")
+		}
+	}
+	var start, end int64
+	if s > 0 {
+		start, end = 0, n
+	} else {
+		start, end = n-1, -1
+	}
+	bound := func(v value, def int64) (int64, error) {
+		if v == nil {
+			return def, nil
+		}
+		k, ok := v.(int64)
+		if !ok {
+			return 0, fmt.Errorf("slice bound must be int, not %s", typeName(v))
+		}
+		if k < 0 {
+			k += n
+		}
+		if s > 0 {
+			return min(max(k, 0), n), nil
+		}
+		return min(max(k, -1), n-1), nil
+	}
+	var err error
+	if start, err = bound(lo, start); err != nil {
+		return nil, err
+	}
+	if end, err = bound(hi, end); err != nil {
+		return nil, err
+	}
+
+	var indexes []int64
+	for i := start; s > 0 && i < end || s < 0 && i > end; i += s {
+		indexes = append(indexes, i)
+	}
+	switch x := x.(type) {
+	case string:
+		var b strings.Builder
+		for _, i := range indexes {
+			b.WriteByte(x[i])
+		}
+		return b.String(), nil
+	case *list:
+		out := &list{}
+		for _, i := range indexes {
+			out.elems = append(out.elems, x.elems[i])
+		}
+		return out, nil
+	case tuple:
+		out := tuple{}
+		for _, i := range indexes {
+			out = append(out, x[i])
+		}
+		return out, nil
+	case rangeValue:
+		if len(indexes) == 0 {
+			return rangeValue{0, 0, 1}, nil
+		}
+		first := x.at(indexes[0])
+		return rangeValue{first, first + int64(len(indexes))*x.step*s, x.step * s}, nil
+	}
+	return nil, fmt.Errorf("invalid slice operand %s", typeName(x))
+}
+
+// freeze makes v and the values it refers to immutable, so that values
+// made while loading a script may be shared by concurrent calls.
+func freeze(v value, seen map[any]bool) {
+	switch v := v.(type) {
+	case *list:
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		v.frozen = true
+		for _, e := range v.elems {
+			freeze(e, seen)
+		}
+	case tuple:
+		for _, e := range v {
+			freeze(e, seen)
+		}
+	case *dict:
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		v.frozen = true
+		for i := range v.keys {
+			freeze(v.keys[i], seen)
+			freeze(v.values[i], seen)
+		}
+	case *function:
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		for _, d := range v.defaults {
+			freeze(d, seen)
+		}
+		for f := v.parent; f != nil; f = f.parent {
+			for _, fv := range f.vars {
+				freeze(fv, seen)
+			}
+		}
+	case *builtin:
+		freeze(v.recv, seen)
+	}
+}
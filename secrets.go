@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Secrets is a set of values that are masked from tool error messages and
+// notifications before they are sent to clients or logged. Tools may add
+// values they obtain while executing, such as credentials fetched from a
+// vault.
+type Secrets struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// NewSecrets returns a set containing values.
+func NewSecrets(values ...string) *Secrets {
+	s := &Secrets{}
+	s.Add(values...)
+	return s
+}
+
+// WithSecrets sets the values masked from messages sent by the server.
+func WithSecrets(s *Secrets) ServerOption {
+	return func(h *handler) {
+		h.secrets = s
+	}
+}
+
+// Add marks values as secret. Empty values are ignored.
+func (s *Secrets) Add(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range values {
+		if v != "" && !slices.Contains(s.values, v) {
+			s.values = append(s.values, v)
+		}
+	}
+}
+
+// Mask replaces each secret in str, and each of extra, with "[REDACTED]".
+func (s *Secrets) Mask(str string, extra ...string) string {
+	var values []string
+	if s != nil {
+		s.mu.RLock()
+		values = append(values, s.values...)
+		s.mu.RUnlock()
+	}
+	for _, v := range extra {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+
+	// mask longer values first so a secret containing another is not
+	// partially revealed
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	for _, v := range values {
+		str = strings.ReplaceAll(str, v, defaultRedactionReplacement)
+	}
+	return str
+}
+
+// secretArguments returns the values of the arguments t declares as secret.
+func secretArguments(t ToolDefinition, params CallToolRequestParams) []string {
+	var values []string
+	for _, name := range t.SecretArguments {
+		if v, ok := params.Arguments[name].(string); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// maskResult returns a copy of result with secrets masked from its text and
+// structured content.
+func (s *Secrets) maskResult(result CallToolResult, extra ...string) (CallToolResult, error) {
+	if result.StructuredContent != nil {
+		v, err := jsonValue(result.StructuredContent)
+		if err != nil {
+			return CallToolResult{}, err
+		}
+		if m, ok := s.maskValue(v, extra...).(map[string]any); ok {
+			result.StructuredContent = m
+		}
+	}
+	if len(result.Content) == 0 {
+		return result, nil
+	}
+	content := make(Contents, len(result.Content))
+	for i, c := range result.Content {
+		switch c := c.(type) {
+		case TextContent:
+			c.Text = s.Mask(c.Text, extra...)
+			content[i] = c
+		case *TextContent:
+			masked := *c
+			masked.Text = s.Mask(c.Text, extra...)
+			content[i] = &masked
+		default:
+			content[i] = c
+		}
+	}
+	result.Content = content
+	return result, nil
+}
+
+// maskJSON returns the JSON encoding of v with secrets masked from the
+// strings it holds, whatever the type of v.
+func (s *Secrets) maskJSON(v any, extra ...string) (json.RawMessage, error) {
+	decoded, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.maskValue(decoded, extra...))
+}
+
+// jsonValue returns v as decoded from its JSON encoding, so that the strings
+// it holds can be found whatever its type. Numbers are decoded as
+// json.Number to keep their precision.
+func jsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var decoded any
+	if err := d.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// maskValue masks secrets in the strings held by v, descending into maps and
+// slices.
+func (s *Secrets) maskValue(v any, extra ...string) any {
+	switch v := v.(type) {
+	case string:
+		return s.Mask(v, extra...)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = s.maskValue(e, extra...)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = s.maskValue(e, extra...)
+		}
+		return out
+	default:
+		return v
+	}
+}
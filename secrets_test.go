@@ -0,0 +1,95 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Secrets", func() {
+
+	It("masks each secret value", func() {
+		s := mcp.NewSecrets("hunter2", "s3cr3t")
+		Expect(s.Mask("login with hunter2 or s3cr3t")).To(Equal("login with [REDACTED] or [REDACTED]"))
+	})
+
+	It("masks longer secrets before the secrets they contain", func() {
+		s := mcp.NewSecrets("abc", "abcdef")
+		Expect(s.Mask("key abcdef")).To(Equal("key [REDACTED]"))
+	})
+
+	It("ignores empty values", func() {
+		s := mcp.NewSecrets("")
+		Expect(s.Mask("nothing to hide")).To(Equal("nothing to hide"))
+	})
+
+	Context("when configured on a server", func() {
+		var (
+			secrets *mcp.Secrets
+			logs    *bytes.Buffer
+			tools   []mcp.ToolDefinition
+		)
+
+		BeforeEach(func() {
+			logs = &bytes.Buffer{}
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			DeferCleanup(slog.SetDefault, previous)
+
+			secrets = mcp.NewSecrets("global-api-key")
+			tools = []mcp.ToolDefinition{{
+				Metadata: mcp.Tool{Name: "deploy", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(params mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					fetched := "fetched-db-password"
+					secrets.Add(fetched)
+					return mcp.CallToolResult{}, fmt.Errorf("connecting with token %s, api key global-api-key and password %s: %w",
+						params.Arguments["token"], fetched, errors.New("refused"))
+				},
+				SecretArguments: []string{"token"},
+			}}
+		})
+
+		It("masks secrets from tool errors and logs", func() {
+			conn := startServer(context.Background(), tools, mcp.WithSecrets(secrets))
+			result, err := callTool(conn, "deploy", map[string]any{"token": "tok-123"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*result.IsError).To(BeTrue())
+			Expect(result.Content).To(Equal(mcp.Contents{mcp.TextContent{
+				Type: "text",
+				Text: "connecting with token [REDACTED], api key [REDACTED] and password [REDACTED]: refused",
+			}}))
+			Expect(logs.String()).To(ContainSubstring("calling tool"))
+			Expect(logs.String()).ToNot(ContainSubstring("tok-123"))
+		})
+
+		It("masks secrets from notifications and the data of mapped errors", func() {
+			tools[0].Process = func(ctx context.Context, params mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+				message := fmt.Sprintf("deploying with token %s", params.Arguments["token"])
+				Expect(n.Notify(ctx, "notifications/message", mcp.LoggingMessageNotificationParams{Level: "info", Data: message})).To(Succeed())
+				return tools[0].Execute(params)
+			}
+			mapper := mcp.WithErrorMapper(func(err error) (*jsonrpc2.Error, *mcp.CallToolResult) {
+				data := json.RawMessage(fmt.Sprintf("{\"cause\":%q}", err.Error()))
+				return &jsonrpc2.Error{Code: -32001, Message: "Deploy failed", Data: &data}, nil
+			})
+			s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools, mcp.WithSecrets(secrets), mapper)
+			pair := mcptest.NewPair(GinkgoT(), s)
+
+			_, err := pair.CallTool(context.Background(), "deploy", map[string]any{"token": "tok-123"})
+			data := json.RawMessage(`{"cause":"connecting with token [REDACTED], api key [REDACTED] and password [REDACTED]: refused"}`)
+			Expect(err).To(MatchError(&jsonrpc2.Error{Code: -32001, Message: "Deploy failed", Data: &data}))
+			Eventually(pair.Notifications).Should(HaveLen(1))
+			Expect(pair.Notifications()[0].Params).To(MatchJSON(`{"level":"info","data":"deploying with token [REDACTED]"}`))
+		})
+	})
+})
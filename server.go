@@ -4,70 +4,296 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/sourcegraph/jsonrpc2"
 	"golang.org/x/time/rate"
+
+	"github.com/acrmp/mcp/spec20250618"
 )
 
-const SupportedProtocolVersion = "2024-11-05"
+// SupportedProtocolVersion is the latest protocol version supported, which
+// clients ask for and servers answer clients asking for an unsupported one.
+const SupportedProtocolVersion = spec20250618.ProtocolVersion
 
 type ToolDefinition struct {
-	Metadata  Tool
-	Execute   func(CallToolRequestParams) (CallToolResult, error)
+	Metadata Tool
+	// Execute performs the call. An error it returns is reported as set by
+	// WithErrorMapper, or otherwise as a tool result with IsError set, or as
+	// a protocol error if it is an InvalidParamsError or InternalError.
+	Execute func(CallToolRequestParams) (CallToolResult, error)
+	// Process, if set, is called instead of Execute. It receives the request
+	// context and a Notifier for sending notifications, such as progress,
+	// to the client while the call is in progress.
+	Process   func(ctx context.Context, params CallToolRequestParams, n Notifier) (CallToolResult, error)
 	RateLimit *rate.Limiter
+	// Authorize, if set, is called before Execute. A non-nil error denies the
+	// call and is reported to the client as a permission denied tool error.
+	// The principal is nil if the transport did not authenticate the caller.
+	Authorize func(ctx context.Context, principal *Principal, params CallToolRequestParams) error
+	// RequiredScopes lists the scopes a principal must hold for the tool to be
+	// listed or called.
+	RequiredScopes []string
+	// SecretArguments names arguments whose values are masked from error
+	// messages and logs for the call.
+	SecretArguments []string
+	// ContentLimit, if set, splits or truncates oversized text content.
+	ContentLimit *ContentLimit
+	// Transformers rewrite the content returned by the tool, before any
+	// configured with WithContentTransformers.
+	Transformers []ContentTransformer
+	// Descriptions translates the description of the tool, keyed by BCP 47
+	// language tag, for clients that ask for a locale.
+	Descriptions map[string]string
 }
 
 type handler struct {
-	serverInfo   Implementation
-	toolMetadata []Tool
-	tools        map[string]ToolDefinition
+	serverInfo        Implementation
+	toolMetadata      []Tool
+	tools             map[string]ToolDefinition
+	promptMetadata    []Prompt
+	prompts           map[string]PromptDefinition
+	resourceMetadata  []Resource
+	resources         map[string]ResourceDefinition
+	resourceTemplates []ResourceTemplateDefinition
+	scopeMapper       ScopeMapper
+	redactor          *Redactor
+	secrets           *Secrets
+	policy            *Policy
+	quota             *quota
+	auditLog          *AuditLog
+	screeners         []ContentScreener
+	transformers      []ContentTransformer
+	upstream          *Client
+	rateLimit         *rate.Limiter
+	toolSources       []toolSource
+	capabilities      []func(*ServerCapabilities)
+	sessionCleanups   []func(context.Context)
+
+	promptErrorMessages bool
+	errorMapper         ErrorMapper
+	stdinEOF            EOFBehavior
+	journal             JournalStore
 }
 
 type Server struct {
 	handler *handler
 }
 
-func NewServer(serverInfo Implementation, tools []ToolDefinition) *Server {
+// ServerOption configures optional behaviour of a Server.
+type ServerOption func(*handler)
+
+// ScopeMapper returns the scopes held by principal, which is nil if the
+// caller was not authenticated.
+type ScopeMapper func(principal *Principal) []string
+
+// WithScopeMapper sets how principals are mapped to the scopes checked against
+// the RequiredScopes of tools and prompts. By default a principal holds the
+// scopes granted by its access token.
+func WithScopeMapper(m ScopeMapper) ServerOption {
+	return func(h *handler) {
+		h.scopeMapper = m
+	}
+}
+
+// WithRateLimit limits the rate of tool calls, prompt requests and requests
+// forwarded by a proxy made by all clients of the server together. Each tool
+// may be limited further by its RateLimit.
+func WithRateLimit(l *rate.Limiter) ServerOption {
+	return func(h *handler) {
+		h.rateLimit = l
+	}
+}
+
+// errRateLimited is the error returned to requests refused by the rate limit
+// set by WithRateLimit, other than tool calls, which fail with a tool error.
+var errRateLimited = &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: "rate limit exceeded"}
+
+// WithCapabilities lets f change the capabilities advertised to clients, for
+// example to advertise logging or an experimental capability. f is passed the
+// capabilities of the tools, prompts and resources the server offers, which
+// are advertised by default.
+func WithCapabilities(f func(c *ServerCapabilities)) ServerOption {
+	return func(h *handler) {
+		h.capabilities = append(h.capabilities, f)
+	}
+}
+
+// MarshalJSON implements json.Marshaler, advertising Completions and Logging
+// whenever they are non-nil, as these capabilities have no settings and are
+// empty when supported.
+func (c ServerCapabilities) MarshalJSON() ([]byte, error) {
+	type Plain ServerCapabilities
+	out := struct {
+		Plain
+		Completions *ServerCapabilitiesCompletions `json:"completions,omitempty"`
+		Logging     *ServerCapabilitiesLogging     `json:"logging,omitempty"`
+	}{Plain: Plain(c)}
+	if c.Completions != nil {
+		out.Completions = &c.Completions
+	}
+	if c.Logging != nil {
+		out.Logging = &c.Logging
+	}
+	return json.Marshal(out)
+}
+
+func NewServer(serverInfo Implementation, tools []ToolDefinition, opts ...ServerOption) *Server {
 	toolMetadata := make([]Tool, 0, len(tools))
 	toolFuncs := make(map[string]ToolDefinition, len(tools))
 	for _, t := range tools {
 		toolMetadata = append(toolMetadata, t.Metadata)
 		toolFuncs[t.Metadata.Name] = t
 	}
-	return &Server{handler: &handler{serverInfo: serverInfo, toolMetadata: toolMetadata, tools: toolFuncs}}
+	h := &handler{serverInfo: serverInfo, toolMetadata: toolMetadata, tools: toolFuncs, scopeMapper: tokenScopes}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return &Server{handler: h}
 }
 
+func tokenScopes(p *Principal) []string {
+	if p == nil {
+		return nil
+	}
+	return p.Scopes
+}
+
+// Serve serves newline delimited messages read from stdin, replying on
+// stdout. What it does once stdin is closed is set by WithStdinEOF.
 func (s *Server) Serve() {
-	stream := jsonrpc2.NewPlainObjectStream(&stdinStdoutReadWriter{})
-	conn := jsonrpc2.NewConn(context.Background(), stream, s.handler)
+	s.serveStdio(os.Stdin, os.Stdout)
+}
+
+// StdioStream returns a stream reading newline delimited messages from stdin
+// and writing them to stdout.
+func StdioStream() jsonrpc2.ObjectStream {
+	return jsonrpc2.NewPlainObjectStream(&stdinStdoutReadWriter{})
+}
+
+// ServeStream serves requests read from stream until it is closed. Values
+// carried by ctx, such as the Principal established by a ResourceServer, are
+// visible to the handlers of those requests. Once the stream is closed the
+// contexts of the handlers in progress are cancelled, and the session is
+// cleaned up when they return.
+func (s *Server) ServeStream(ctx context.Context, stream jsonrpc2.ObjectStream) {
+	s.serveStream(ctx, stream, true)
+}
+
+// serveStream serves stream, cancelling the handler in progress as soon as
+// reading from stream fails if cancelOnEOF is set.
+func (s *Server) serveStream(ctx context.Context, stream jsonrpc2.ObjectStream, cancelOnEOF bool) {
+	if _, ok := SessionIDFromContext(ctx); !ok {
+		ctx = ContextWithSessionID(ctx, newID())
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if cancelOnEOF {
+		stream = newDisconnectStream(stream, cancel)
+	}
+	sess := &session{handler: s.handler}
+	conn := jsonrpc2.NewConn(ctx, drainStream{stream, &sess.calls}, sess)
 	<-conn.DisconnectNotify()
+	cancel()
+	s.handler.endSession(ctx)
+}
+
+// HandleRaw serves the encoded messages in msg and returns the messages
+// written in reply, each followed by a newline. It needs no transport, which
+// makes it suitable for fuzzing request parsing and dispatch. As with a
+// stream, a message that cannot be decoded ends processing.
+func (s *Server) HandleRaw(ctx context.Context, msg []byte) []byte {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		inW.Write(msg)
+		inW.Close()
+	}()
+	go func() {
+		s.ServeStream(ctx, jsonrpc2.NewPlainObjectStream(rawStream{inR, outW}))
+		outW.Close()
+	}()
+	out, _ := io.ReadAll(outR)
+	return out
+}
+
+type rawStream struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (s rawStream) Close() error {
+	s.PipeReader.Close()
+	return s.PipeWriter.Close()
 }
 
 func (h *handler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
 	switch req.Method {
-	case "initialize":
-		h.handleInitialize(ctx, conn, req)
-	case "notifications/initialized":
 	case "ping":
 		h.replyWithResult(ctx, conn, req, struct{}{})
 	case "tools/list":
 		h.handleListTools(ctx, conn, req)
 	case "tools/call":
 		h.handleToolCall(ctx, conn, req)
+	case "prompts/list":
+		h.handleListPrompts(ctx, conn, req)
+	case "prompts/get":
+		h.handleGetPrompt(ctx, conn, req)
+	case "resources/list", "resources/templates/list", "resources/read":
+		// the resources of a proxy are those of its upstream unless it
+		// offers its own
+		if !h.servesResources() {
+			h.handleUnknown(ctx, conn, req)
+			return
+		}
+		switch req.Method {
+		case "resources/list":
+			h.handleListResources(ctx, conn, req)
+		case "resources/templates/list":
+			h.handleListResourceTemplates(ctx, conn, req)
+		default:
+			h.handleReadResource(ctx, conn, req)
+		}
 	default:
+		h.handleUnknown(ctx, conn, req)
+	}
+}
+
+// handleUnknown forwards requests for methods the server does not handle to
+// the upstream of a proxy, or replies that the method was not found.
+func (h *handler) handleUnknown(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if h.upstream != nil {
+		h.forward(ctx, conn, req)
+		return
+	}
+	h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+		Code:    jsonrpc2.CodeMethodNotFound,
+		Message: "Method not found",
+	})
+}
+
+// handleInitialize replies to an initialize request and returns the protocol
+// version negotiated, or "" if the request was rejected.
+func (h *handler) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) string {
+	var params map[string]json.RawMessage
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
 		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
-			Code:    jsonrpc2.CodeMethodNotFound,
-			Message: "Method not found",
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "Invalid params",
 		})
+		return ""
 	}
-}
 
-func (h *handler) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var requested string
+	json.Unmarshal(params["protocolVersion"], &requested)
+
 	var unsupported bool
 	response := InitializeResult{
-		ProtocolVersion: SupportedProtocolVersion,
+		ProtocolVersion: negotiateProtocolVersion(requested),
 		ServerInfo:      h.serverInfo,
 		Capabilities: ServerCapabilities{
 			Experimental: map[string]map[string]any{},
@@ -76,7 +302,24 @@ func (h *handler) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 			},
 		},
 	}
+	if len(h.prompts) > 0 {
+		response.Capabilities.Prompts = &ServerCapabilitiesPrompts{
+			ListChanged: &unsupported,
+		}
+	}
+	if h.servesResources() || (h.upstream != nil && h.upstream.InitializeResult().Capabilities.Resources != nil) {
+		// notifications from the upstream are not forwarded
+		response.Capabilities.Resources = &ServerCapabilitiesResources{
+			ListChanged: &unsupported,
+			Subscribe:   &unsupported,
+		}
+	}
+	for _, f := range h.capabilities {
+		f(&response.Capabilities)
+	}
+	ctx = contextWithProtocolVersion(ctx, response.ProtocolVersion)
 	h.replyWithResult(ctx, conn, req, response)
+	return response.ProtocolVersion
 }
 
 func (h *handler) handleListTools(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
@@ -91,12 +334,73 @@ func (h *handler) handleListTools(ctx context.Context, conn *jsonrpc2.Conn, req
 			return
 		}
 	}
-	h.replyWithResult(ctx, conn, req, ListToolsResult{Tools: h.toolMetadata})
+	tools := make([]Tool, 0, len(h.toolMetadata))
+	for _, t := range h.toolMetadata {
+		if def := h.tools[t.Name]; h.available(ctx, t.Name, def.RequiredScopes) {
+			t.Description = localize(ctx, t.Description, def.Descriptions)
+			tools = append(tools, t)
+		}
+	}
+	// tools passed to NewServer and earlier mirrors and tool sets take
+	// precedence
+	seen := make(map[string]bool, len(h.tools))
+	for name := range h.tools {
+		seen[name] = true
+	}
+	for _, m := range h.toolSources {
+		for _, t := range m.definitions() {
+			if !seen[t.Metadata.Name] && h.available(ctx, t.Metadata.Name, t.RequiredScopes) {
+				tools = append(tools, t.Metadata)
+			}
+			seen[t.Metadata.Name] = true
+		}
+	}
+	h.replyWithResult(ctx, conn, req, ListToolsResult{Tools: tools})
+}
+
+// tool returns the definition of the tool called name, looking in any
+// mirrors and tool sets if it was not passed to NewServer.
+func (h *handler) tool(name string) (ToolDefinition, bool) {
+	if t, ok := h.tools[name]; ok {
+		return t, true
+	}
+	for _, m := range h.toolSources {
+		if t, ok := m.tool(name); ok {
+			return t, true
+		}
+	}
+	return ToolDefinition{}, false
+}
+
+// available reports whether the caller holds requiredScopes and is permitted
+// to use the tool or prompt called name by the policy. Unavailable tools and
+// prompts are hidden from the caller entirely.
+func (h *handler) available(ctx context.Context, name string, requiredScopes []string) bool {
+	p, _ := PrincipalFromContext(ctx)
+	if h.policy != nil && !h.policy.Allowed(p, name) {
+		return false
+	}
+	return h.holdsScopes(ctx, requiredScopes)
+}
+
+// holdsScopes reports whether the caller holds requiredScopes.
+func (h *handler) holdsScopes(ctx context.Context, requiredScopes []string) bool {
+	if len(requiredScopes) == 0 {
+		return true
+	}
+	p, _ := PrincipalFromContext(ctx)
+	scopes := h.scopeMapper(p)
+	for _, s := range requiredScopes {
+		if !slices.Contains(scopes, s) {
+			return false
+		}
+	}
+	return true
 }
 
 func (h *handler) handleToolCall(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
 	var params CallToolRequestParams
-	if err := json.Unmarshal(*req.Params, &params); err != nil {
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
 		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
 			Code:    jsonrpc2.CodeInvalidParams,
 			Message: "Invalid params",
@@ -104,8 +408,8 @@ func (h *handler) handleToolCall(ctx context.Context, conn *jsonrpc2.Conn, req *
 		return
 	}
 
-	t, ok := h.tools[params.Name]
-	if !ok {
+	t, ok := h.tool(params.Name)
+	if !ok || !h.available(ctx, t.Metadata.Name, t.RequiredScopes) {
 		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
 			Code:    jsonrpc2.CodeInvalidParams,
 			Message: fmt.Sprintf("Unknown tool: %s", params.Name),
@@ -113,7 +417,19 @@ func (h *handler) handleToolCall(ctx context.Context, conn *jsonrpc2.Conn, req *
 		return
 	}
 
-	if !t.RateLimit.Allow() {
+	secretArgs := secretArguments(t, params)
+
+	if t.Authorize != nil {
+		principal, _ := PrincipalFromContext(ctx)
+		if err := t.Authorize(ctx, principal, params); err != nil {
+			h.audit(ctx, params, secretArgs, AuditOutcomeDenied, err.Error())
+			h.replyWithToolError(ctx, conn, req, h.secrets.Mask(fmt.Sprintf("permission denied: %s", err), secretArgs...))
+			return
+		}
+	}
+
+	if (t.RateLimit != nil && !t.RateLimit.Allow()) || (h.rateLimit != nil && !h.rateLimit.Allow()) {
+		h.audit(ctx, params, secretArgs, AuditOutcomeDenied, "rate limit exceeded")
 		h.replyWithToolError(ctx, conn, req, "rate limit exceeded")
 		return
 	}
@@ -128,31 +444,251 @@ func (h *handler) handleToolCall(ctx context.Context, conn *jsonrpc2.Conn, req *
 		}
 	}
 
-	response, err := t.Execute(params)
+	sessionID, _ := SessionIDFromContext(ctx)
+	if h.quota != nil {
+		usage, err := h.quota.store.Usage(ctx, sessionID)
+		if err != nil {
+			slog.Error("problem loading session usage", "session", sessionID, "error", err)
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInternalError,
+				Message: "Internal error",
+			})
+			return
+		}
+		if reason := h.quota.budget.exhausted(usage); reason != "" {
+			h.audit(ctx, params, secretArgs, AuditOutcomeDenied, "quota exceeded: "+reason)
+			h.replyWithToolError(ctx, conn, req, fmt.Sprintf("quota exceeded: %s", reason))
+			return
+		}
+	}
+
+	for _, sc := range h.screeners {
+		args, err := sc.ScreenArguments(ctx, params.Name, params.Arguments)
+		if err != nil {
+			h.audit(ctx, params, secretArgs, AuditOutcomeDenied, err.Error())
+			h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
+			return
+		}
+		params.Arguments = args
+	}
+
+	requestID, _ := RequestIDFromContext(ctx)
+	slog.Debug("calling tool", "tool", params.Name, "session", sessionID, "request", requestID.String(), "arguments", h.secrets.maskValue(h.redactor.Arguments(params.Arguments), secretArgs...))
+
+	var callID string
+	if h.journal != nil {
+		var err error
+		if callID, err = h.beginCall(ctx, params, secretArgs); err != nil {
+			h.replyUnjournaled(ctx, conn, req, params.Name, err)
+			return
+		}
+	}
+	start := time.Now()
+	response, err := t.execute(ctx, params, connNotifier{conn: conn, secrets: h.secrets, secretArgs: secretArgs})
+	if h.journal != nil {
+		h.finishCall(ctx, callID, err, secretArgs)
+	}
+	if h.quota != nil {
+		h.recordUsage(ctx, sessionID, time.Since(start), response, err)
+	}
+	if err != nil {
+		h.audit(ctx, params, secretArgs, AuditOutcomeError, err.Error())
+		h.replyWithCallError(ctx, conn, req, params.Name, err, secretArgs)
+		return
+	}
+
+	// content is screened once transformed, as transforming it can reveal
+	// text, such as HTML entities decoded by HTMLToMarkdown
+	response.Content, err = transformContent(response.Content, t.Transformers, h.transformers)
 	if err != nil {
-		h.replyWithToolError(ctx, conn, req, err.Error())
+		h.audit(ctx, params, secretArgs, AuditOutcomeError, err.Error())
+		h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
+		return
+	}
+	if response, err = h.screenResult(ctx, params.Name, response); err != nil {
+		h.audit(ctx, params, secretArgs, AuditOutcomeDenied, err.Error())
+		h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
 		return
 	}
 
+	if h.redactor != nil && h.redactor.Content {
+		response = h.redactor.Result(response)
+	}
+	response, err = t.ContentLimit.apply(response)
+	if err != nil {
+		h.audit(ctx, params, secretArgs, AuditOutcomeError, err.Error())
+		h.replyWithToolError(ctx, conn, req, h.secrets.Mask(err.Error(), secretArgs...))
+		return
+	}
+	h.audit(ctx, params, secretArgs, AuditOutcomeSuccess, "")
 	h.replyWithResult(ctx, conn, req, response)
 }
 
+// screenResult passes the result of a call of tool through each screener.
+func (h *handler) screenResult(ctx context.Context, tool string, result CallToolResult) (CallToolResult, error) {
+	for _, sc := range h.screeners {
+		screened, err := sc.ScreenResult(ctx, tool, result)
+		if err != nil {
+			return CallToolResult{}, err
+		}
+		result = screened
+	}
+	return result, nil
+}
+
+func (h *handler) handleListPrompts(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params ListPromptsRequestParams
+	if req.Params != nil {
+		// cursors are not supported so any cursor provided is invalid
+		if err := json.Unmarshal(*req.Params, &params); err != nil || params.Cursor != nil {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInvalidParams,
+				Message: "Invalid params",
+			})
+			return
+		}
+	}
+	prompts := make([]Prompt, 0, len(h.promptMetadata))
+	for _, p := range h.promptMetadata {
+		def := h.prompts[p.Name]
+		if !h.available(ctx, p.Name, def.RequiredScopes) {
+			continue
+		}
+		p.Description = localize(ctx, p.Description, def.Descriptions)
+		prompts = append(prompts, p)
+	}
+	h.replyWithResult(ctx, conn, req, ListPromptsResult{Prompts: prompts})
+}
+
+func (h *handler) handleGetPrompt(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	var params GetPromptRequestParams
+	if req.Params == nil || json.Unmarshal(*req.Params, &params) != nil {
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: "Invalid params",
+		})
+		return
+	}
+
+	p, ok := h.prompts[params.Name]
+	if !ok || !h.available(ctx, params.Name, p.RequiredScopes) {
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidParams,
+			Message: fmt.Sprintf("Unknown prompt: %s", params.Name),
+		})
+		return
+	}
+
+	if p.Authorize != nil {
+		principal, _ := PrincipalFromContext(ctx)
+		if err := p.Authorize(ctx, principal, params); err != nil {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInvalidRequest,
+				Message: h.secrets.Mask(fmt.Sprintf("permission denied: %s", err)),
+			})
+			return
+		}
+	}
+
+	if h.rateLimit != nil && !h.rateLimit.Allow() {
+		h.replyWithJSONRPCError(ctx, conn, req, errRateLimited)
+		return
+	}
+
+	for _, arg := range p.Metadata.Arguments {
+		if _, ok := params.Arguments[arg.Name]; !ok && arg.Required != nil && *arg.Required {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInvalidParams,
+				Message: fmt.Sprintf("Missing required argument: %s", arg.Name),
+			})
+			return
+		}
+	}
+
+	result, err := p.render(ctx, params)
+	if err == nil {
+		err = result.Validate()
+	}
+	if err != nil {
+		msg := h.secrets.Mask(err.Error())
+		slog.Error("problem getting prompt", "prompt", params.Name, "error", msg)
+		if !h.promptErrorMessages {
+			msg = "Internal error"
+		}
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInternalError,
+			Message: msg,
+		})
+		return
+	}
+	if result.Description == nil {
+		result.Description = localize(ctx, p.Metadata.Description, p.Descriptions)
+	}
+	for _, sc := range h.screeners {
+		screened, err := sc.ScreenPrompt(ctx, params.Name, result)
+		if err != nil {
+			h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+				Code:    jsonrpc2.CodeInternalError,
+				Message: h.secrets.Mask(err.Error()),
+			})
+			return
+		}
+		result = screened
+	}
+	h.replyWithResult(ctx, conn, req, result)
+}
+
+func (t ToolDefinition) execute(ctx context.Context, params CallToolRequestParams, n Notifier) (CallToolResult, error) {
+	if t.Process != nil {
+		return t.Process(ctx, params, n)
+	}
+	return t.Execute(params)
+}
+
+func (h *handler) recordUsage(ctx context.Context, sessionID string, elapsed time.Duration, response CallToolResult, err error) {
+	usage := Usage{Calls: 1, ExecutionTime: elapsed}
+	if err == nil {
+		if b, err := json.Marshal(response); err == nil {
+			usage.Bytes = int64(len(b))
+		}
+	}
+	if err := h.quota.store.AddUsage(ctx, sessionID, usage); err != nil {
+		slog.Error("problem recording session usage", "session", sessionID, "error", err)
+	}
+}
+
 func (h *handler) replyWithJSONRPCError(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, rpcErr *jsonrpc2.Error) {
 	if err := conn.ReplyWithError(ctx, req.ID, rpcErr); err != nil {
 		slog.Error("problem replying with error", "method", req.Method, "error", err)
 	}
 }
 
+// replyWithResult replies with result as defined by the protocol version
+// negotiated by the session of ctx.
 func (h *handler) replyWithResult(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, result any) {
+	version, _ := ProtocolVersionFromContext(ctx)
+	result, err := encodeResult(version, req.Method, result)
+	if err != nil {
+		slog.Error("problem encoding result", "method", req.Method, "protocolVersion", version, "error", err)
+		h.replyWithJSONRPCError(ctx, conn, req, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInternalError,
+			Message: "Internal error",
+		})
+		return
+	}
 	if err := conn.Reply(ctx, req.ID, result); err != nil {
 		slog.Error("problem replying with result", "method", req.Method, "error", err)
 	}
 }
 
 func (h *handler) replyWithToolError(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request, errMsg string) {
+	errMsg = h.secrets.Mask(errMsg)
+	if h.redactor != nil && h.redactor.Content {
+		errMsg = h.redactor.String(errMsg)
+	}
 	errorOccurred := true
 	result := CallToolResult{
-		Content: []any{TextContent{Type: "text", Text: errMsg}},
+		Content: []Content{TextContent{Type: "text", Text: errMsg}},
 		IsError: &errorOccurred,
 	}
 	h.replyWithResult(ctx, conn, req, result)
@@ -1,173 +1,356 @@
 package mcp_test
 
 import (
-	"bytes"
+	"context"
 	"errors"
-	"io"
+	"net"
 	"os/exec"
+	"slices"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gbytes"
-	"github.com/onsi/gomega/gexec"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
 )
 
 var _ = Describe("Server", func() {
 
-	var (
-		stdin   *bytes.Buffer
-		session *gexec.Session
-		stop    chan struct{}
-	)
+	var client *mcptest.StdioClient
 
 	BeforeEach(func() {
-		stop = make(chan struct{})
-
-		stdin = &bytes.Buffer{}
 		command := exec.Command(exampleServerPath)
-		command.Stdin = &blockingReader{r: stdin, done: stop}
-		var err error
-		session, err = gexec.Start(command, nil, GinkgoWriter)
-		Expect(err).ToNot(HaveOccurred())
+		command.Stderr = GinkgoWriter
+		client = mcptest.StartStdio(GinkgoT(), command)
 	})
 
-	AfterEach(func() {
-		stop <- struct{}{}
-		session.Wait()
-		Eventually(session).Should(gexec.Exit())
-	})
+	request := func(msg string) []byte {
+		GinkgoHelper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		response, err := client.Request(ctx, msg)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
 
 	It("responds to an initialization request", func() {
-		stdin.WriteString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
-		Eventually(session.Out.Contents).Should(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"ExampleServer","version":"1.0.0"}}}`))
+		Expect(request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"ExampleServer","version":"1.0.0"}}}`))
 	})
 
 	It("responds to pings", func() {
-		stdin.WriteString(`{"jsonrpc":"2.0","id":"123","method":"ping"}`)
-		Eventually(session.Out.Contents).Should(MatchJSON(`{"jsonrpc":"2.0","id":"123","result":{}}`))
+		Expect(request(`{"jsonrpc":"2.0","id":"123","method":"ping"}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":"123","result":{}}`))
 	})
 
 	It("receives notification that the client is initialized", func() {
-		stdin.WriteString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
-		Eventually(session.Out).Should(gbytes.Say("ExampleServer"))
+		request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
+		Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
+
+		Expect(request(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{}}`))
+		Expect(client.Messages()).To(HaveLen(2))
+		Expect(client.Exited()).ToNot(BeClosed())
+	})
 
-		stdin.WriteString(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	Context("when the client initializes again", func() {
+		It("responds with an error giving the version already negotiated", func() {
+			request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
+			Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
 
-		Consistently(session).ShouldNot(gbytes.Say("error"))
-		Consistently(session).ShouldNot(gexec.Exit())
+			Expect(request(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"3000-01-01","capabilities":{},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"error":{"code":-32600,"message":"Session already initialized","data":{"protocolVersion":"2024-11-05"}}}`))
+		})
+	})
+
+	Context("when the initialized notification is repeated or arrives before initialize", func() {
+		It("ignores it", func() {
+			Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
+			request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
+			Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
+			Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
+
+			Expect(request(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{}}`))
+			Expect(client.Messages()).To(HaveLen(2))
+			Expect(client.Exited()).ToNot(BeClosed())
+		})
 	})
 
 	It("delimits messages with newlines", func() {
-		stdin.WriteString(`{"jsonrpc":"2.0","id":"123","method":"ping"}`)
-		Eventually(session).Should(gbytes.Say("}\n"))
-		stdin.WriteString(`{"jsonrpc":"2.0","id":"234","method":"ping"}`)
-		Eventually(session).Should(gbytes.Say("}\n"))
-		stdin.WriteString(`{"jsonrpc":"2.0","id":"456","method":"ping"}`)
-		Eventually(session).Should(gbytes.Say("}\n"))
+		Expect(client.Send(`{"jsonrpc":"2.0","id":"123","method":"ping"}`)).To(Succeed())
+		Expect(client.Send(`{"jsonrpc":"2.0","id":"234","method":"ping"}`)).To(Succeed())
+		Expect(client.Send(`{"jsonrpc":"2.0","id":"456","method":"ping"}`)).To(Succeed())
+		Eventually(client.Messages).Should(ConsistOf(
+			MatchJSON(`{"jsonrpc":"2.0","id":"123","result":{}}`),
+			MatchJSON(`{"jsonrpc":"2.0","id":"234","result":{}}`),
+			MatchJSON(`{"jsonrpc":"2.0","id":"456","result":{}}`),
+		))
 	})
 
 	Context("when the method is not recognized", func() {
 		It("responds with an error", func() {
-			stdin.WriteString(`{"jsonrpc":"2.0","method":"foobar","id":"1"}`)
-			Eventually(session.Out.Contents).Should(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"1"}`))
+			Expect(request(`{"jsonrpc":"2.0","method":"foobar","id":"1"}`)).To(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"1"}`))
 		})
 	})
 
 	Context("when the client protocol version is newer", func() {
 		It("responds with the latest version supported by the server", func() {
-			stdin.WriteString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"3000-01-01","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
-			Eventually(session.Out.Contents).Should(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"ExampleServer","version":"1.0.0"}}}`))
+			Expect(request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"3000-01-01","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"ExampleServer","version":"1.0.0"}}}`))
 		})
 	})
 
 	Describe("tools", func() {
 		BeforeEach(func() {
-			stdin.WriteString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
-			Eventually(session.Out).Should(gbytes.Say("ExampleServer"))
-			stdin.WriteString(`{"jsonrpc":"2.0","method":"initialized"}`)
-			Eventually(session.Out).Should(gbytes.Say("\n"))
+			request(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"roots":{"listChanged":true},"sampling":{}},"clientInfo":{"name":"ExampleClient","version":"1.0.0"}}}`)
+			Expect(client.Send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).To(Succeed())
 		})
 		Context("when the client requests the list of tools", func() {
 			It("responds", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
-				Eventually(session.Out).Should(gbytes.Say("tools"))
-
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"sha256sum","description":"Compute a SHA-256 checksum","inputSchema":{"type":"object","properties":{"text":{"type":"string","description":"Text to compute a checksum for"}},"required":["text"]}}]}}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"sha256sum","description":"Compute a SHA-256 checksum","inputSchema":{"type":"object","properties":{"text":{"type":"string","description":"Text to compute a checksum for"}},"required":["text"]}}]}}`))
 			})
 		})
 		Context("when the client requests the list of tools with an invalid cursor", func() {
 			It("responds with a protocol error", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{"cursor":"invalid-cursor"}}`)
-				Eventually(session.Out).Should(gbytes.Say("\n"))
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":2}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{"cursor":"invalid-cursor"}}`)).To(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":2}`))
 			})
 		})
 		Context("when the client calls a tool", func() {
 			It("invokes the tool", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":"the rain in spain falls mainly on the plains"}}}`)
-				Eventually(session.Out).Should(gbytes.Say("\n"))
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"b65aacbdd951ff4cd8acef585d482ca4baef81fa0e32132b842fddca3b5590e9"}],"isError":false}}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":"the rain in spain falls mainly on the plains"}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"b65aacbdd951ff4cd8acef585d482ca4baef81fa0e32132b842fddca3b5590e9"}],"isError":false}}`))
 			})
 		})
 		Context("when the client calls a tool without providing the required arguments", func() {
 			It("responds with a protocol error", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{}}}`)
-				Eventually(session.Out).Should(gbytes.Say("\n"))
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":2}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":2}`))
 			})
 		})
 		Context("when the client calls a tool numerous times in a short period", func() {
 			It("triggers the rate limit error", func() {
+				var response []byte
 				for i := 0; i < 10; i++ {
-					stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":"the rain in spain falls mainly on the plains"}}}`)
-					Eventually(session.Out).Should(gbytes.Say("\n"))
+					response = request(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":"the rain in spain falls mainly on the plains"}}}`)
 				}
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"rate limit exceeded"}],"isError":true}}`))
+				Expect(response).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"rate limit exceeded"}],"isError":true}}`))
 			})
 		})
 		Context("when the client calls a tool that errors", func() {
 			It("responds with a tool execution error", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":""}}}`)
-				Eventually(session.Out).Should(gbytes.Say("\n"))
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"failed to compute checksum: text cannot be empty"}],"isError":true}}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"sha256sum","arguments":{"text":""}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"failed to compute checksum: text cannot be empty"}],"isError":true}}`))
 			})
 		})
 		Context("when the client calls a tool that does not exist", func() {
 			It("responds with an error", func() {
-				stdin.WriteString(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing-tool","arguments":{"text":"does not matter"}}}`)
-				Eventually(session.Out).Should(gbytes.Say("\n"))
-				Expect(lastResponse(session.Out.Contents())).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"Unknown tool: missing-tool"}}`))
+				Expect(request(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing-tool","arguments":{"text":"does not matter"}}}`)).To(MatchJSON(`{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"Unknown tool: missing-tool"}}`))
 			})
 		})
 	})
+
+	It("exits when stdin is closed", func() {
+		Expect(client.Close()).To(Succeed())
+		Expect(client.Exited()).To(BeClosed())
+	})
 })
 
-func lastResponse(responses []byte) []byte {
-	newline := []byte("\n")
-	rs := bytes.Split(bytes.TrimSuffix(responses, newline), newline)
-	return rs[len(rs)-1]
-}
+var _ = Describe("Tool authorization", func() {
+
+	var (
+		conn       *jsonrpc2.Conn
+		executed   bool
+		authorized *mcp.Principal
+	)
 
-type blockingReader struct {
-	r    io.Reader
-	done chan struct{}
-}
+	BeforeEach(func() {
+		executed = false
+		authorized = nil
+		tools := []mcp.ToolDefinition{
+			{
+				Metadata: mcp.Tool{Name: "delete-repo", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+				Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+					executed = true
+					return textResult("deleted"), nil
+				},
+				Authorize: func(_ context.Context, p *mcp.Principal, params mcp.CallToolRequestParams) error {
+					authorized = p
+					if p == nil || p.Subject != "admin" {
+						return errors.New("only admins may delete repositories")
+					}
+					return nil
+				},
+			},
+		}
+		ctx := mcp.ContextWithPrincipal(context.Background(), &mcp.Principal{Subject: "alice"})
+		conn = startServer(ctx, tools)
+	})
 
-func (b *blockingReader) Read(p []byte) (int, error) {
-	n, err := b.r.Read(p)
+	It("passes the principal to the callback", func() {
+		_, err := callTool(conn, "delete-repo", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authorized.Subject).To(Equal("alice"))
+	})
 
-	if err != nil && !errors.Is(err, io.EOF) {
-		return n, err
+	Context("when the callback denies the call", func() {
+		It("responds with a permission denied tool error without executing the tool", func() {
+			result, err := callTool(conn, "delete-repo", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*result.IsError).To(BeTrue())
+			Expect(result.Content).To(Equal(mcp.Contents{mcp.TextContent{Type: "text", Text: "permission denied: only admins may delete repositories"}}))
+			Expect(executed).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Tool scopes", func() {
+
+	var (
+		principal *mcp.Principal
+		opts      []mcp.ServerOption
+		conn      *jsonrpc2.Conn
+	)
+
+	tools := []mcp.ToolDefinition{
+		{
+			Metadata: mcp.Tool{Name: "read-issue", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult("issue"), nil
+			},
+		},
+		{
+			Metadata: mcp.Tool{Name: "close-issue", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult("closed"), nil
+			},
+			RequiredScopes: []string{"issues:write"},
+		},
 	}
 
-	if errors.Is(err, io.EOF) {
-		select {
-		case <-b.done:
-			return n, err
-		case <-time.After(50 * time.Millisecond):
-			return n, nil
+	BeforeEach(func() {
+		principal = &mcp.Principal{Subject: "alice", Scopes: []string{"issues:read"}}
+		opts = nil
+	})
+
+	JustBeforeEach(func() {
+		conn = startServer(mcp.ContextWithPrincipal(context.Background(), principal), tools, opts...)
+	})
+
+	listTools := func() []string {
+		var result mcp.ListToolsResult
+		Expect(conn.Call(context.Background(), "tools/list", nil, &result)).To(Succeed())
+		var names []string
+		for _, t := range result.Tools {
+			names = append(names, t.Name)
 		}
+		return names
 	}
-	return n, nil
-}
+
+	Context("when the principal lacks the required scopes", func() {
+		It("hides the tool from the list", func() {
+			Expect(listTools()).To(Equal([]string{"read-issue"}))
+		})
+
+		It("rejects calls to the tool as if it did not exist", func() {
+			_, err := callTool(conn, "close-issue", nil)
+			Expect(err).To(MatchError(&jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "Unknown tool: close-issue"}))
+		})
+	})
+
+	Context("when the principal holds the required scopes", func() {
+		BeforeEach(func() {
+			principal.Scopes = append(principal.Scopes, "issues:write")
+		})
+
+		It("lists and calls the tool", func() {
+			Expect(listTools()).To(Equal([]string{"read-issue", "close-issue"}))
+			result, err := callTool(conn, "close-issue", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Content).To(Equal(mcp.Contents{mcp.TextContent{Type: "text", Text: "closed"}}))
+		})
+	})
+
+	Context("when principals are mapped to scopes", func() {
+		BeforeEach(func() {
+			principal.Claims = map[string]any{"groups": []any{"maintainers"}}
+			opts = append(opts, mcp.WithScopeMapper(func(p *mcp.Principal) []string {
+				if slices.Contains(p.Claims["groups"].([]any), "maintainers") {
+					return []string{"issues:read", "issues:write"}
+				}
+				return p.Scopes
+			}))
+		})
+
+		It("uses the mapped scopes", func() {
+			Expect(listTools()).To(Equal([]string{"read-issue", "close-issue"}))
+		})
+	})
+})
+
+var _ = Describe("Request IDs", func() {
+
+	It("are available to tool handlers", func() {
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "request-id", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+				if id, ok := mcp.RequestIDFromContext(ctx); ok {
+					return textResult(id.String()), nil
+				}
+				return textResult("no request ID"), nil
+			},
+		}}
+		conn := startServer(context.Background(), tools)
+		var result mcp.CallToolResult
+		err := conn.Call(context.Background(), "tools/call", mcp.CallToolRequestParams{Name: "request-id"}, &result,
+			jsonrpc2.PickID(jsonrpc2.ID{Str: "abc", IsString: true}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(textResult(`"abc"`)))
+	})
+})
+
+var _ = Describe("Disconnection", func() {
+
+	It("cancels the handler in progress and then ends the session", func() {
+		started, cancelled, ended := make(chan bool, 1), make(chan bool, 1), make(chan bool, 1)
+		tools := []mcp.ToolDefinition{{
+			Metadata: mcp.Tool{Name: "wait", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+				started <- true
+				<-ctx.Done()
+				cancelled <- true
+				return textResult("cancelled"), nil
+			},
+		}}
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, tools,
+			mcp.WithSessionCleanup(func(context.Context) { ended <- true }))
+		serverSide, clientSide := net.Pipe()
+		go s.ServeStream(context.Background(), jsonrpc2.NewPlainObjectStream(serverSide))
+		conn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewPlainObjectStream(clientSide), nil)
+
+		go callTool(conn, "wait", nil)
+		Eventually(started).Should(Receive())
+		Consistently(cancelled).ShouldNot(Receive())
+		Expect(conn.Close()).To(Succeed())
+		Eventually(cancelled).Should(Receive())
+		Eventually(ended).Should(Receive())
+	})
+})
+
+var _ = Describe("Capabilities", func() {
+
+	It("advertises the capabilities of what the server offers", func() {
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil))
+		capabilities := c.InitializeResult().Capabilities
+		Expect(capabilities.Tools).ToNot(BeNil())
+		Expect(capabilities.Prompts).To(BeNil())
+		Expect(capabilities.Logging).To(BeNil())
+	})
+
+	It("lets options change the capabilities advertised", func() {
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil,
+			mcp.WithCapabilities(func(c *mcp.ServerCapabilities) {
+				c.Logging = mcp.ServerCapabilitiesLogging{}
+			}),
+			mcp.WithCapabilities(func(c *mcp.ServerCapabilities) {
+				c.Tools = nil
+			}),
+		))
+		capabilities := c.InitializeResult().Capabilities
+		Expect(capabilities.Tools).To(BeNil())
+		Expect(capabilities.Logging).ToNot(BeNil())
+	})
+})
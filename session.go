@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+type sessionIDKey struct{}
+
+// ContextWithSessionID returns a copy of ctx identifying the session it
+// belongs to. Transports that assign their own session IDs set them on the
+// context passed to Server.ServeStream; otherwise one is generated.
+func ContextWithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
+}
+
+// SessionIDFromContext returns the ID of the session ctx belongs to.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithSessionCleanup calls f when a session ends because its transport
+// disconnected, such as when stdin is closed or an HTTP session is deleted
+// or expires, so that state kept for the session can be released. ctx
+// carries the values of the session, such as its ID and Principal, and is
+// not cancelled.
+func WithSessionCleanup(f func(ctx context.Context)) ServerOption {
+	return func(h *handler) {
+		h.sessionCleanups = append(h.sessionCleanups, f)
+	}
+}
+
+// endSession releases the state kept for the session of ctx.
+func (h *handler) endSession(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	if h.quota != nil {
+		id, _ := SessionIDFromContext(ctx)
+		h.quota.release(ctx, id)
+	}
+	for _, f := range h.sessionCleanups {
+		f(ctx)
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID of the request being handled, so that
+// notifications such as progress and log messages can be correlated with it.
+func RequestIDFromContext(ctx context.Context) (jsonrpc2.ID, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(jsonrpc2.ID)
+	return id, ok
+}
+
+func contextWithRequestID(ctx context.Context, id jsonrpc2.ID) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// session serves the messages of one connection, tracking its lifecycle: a
+// single initialize request followed by the initialized notification.
+// jsonrpc2 delivers the messages of a connection one at a time, so the
+// lifecycle needs no locking.
+type session struct {
+	*handler
+	protocolVersion string
+	initialized     bool
+	locale          string
+	// sampling is whether the client offered the sampling capability.
+	sampling bool
+	// calls are the tool calls in progress, which run concurrently with
+	// the handling of later messages so that tools may make requests of
+	// the client, such as for sampling.
+	calls sync.WaitGroup
+}
+
+func (s *session) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if !req.Notif {
+		ctx = contextWithRequestID(ctx, req.ID)
+	}
+	if locale := requestLocale(req.Params); locale != "" {
+		ctx = ContextWithLocale(ctx, locale)
+	} else if s.locale != "" {
+		ctx = ContextWithLocale(ctx, s.locale)
+	}
+	if s.protocolVersion != "" {
+		ctx = contextWithProtocolVersion(ctx, s.protocolVersion)
+	}
+	switch req.Method {
+	case "initialize":
+		if s.protocolVersion != "" {
+			err := &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: "Session already initialized"}
+			err.SetError(map[string]string{"protocolVersion": s.protocolVersion})
+			s.replyWithJSONRPCError(ctx, conn, req, err)
+			return
+		}
+		s.protocolVersion = s.handleInitialize(ctx, conn, req)
+		if s.protocolVersion != "" {
+			s.locale = requestLocale(req.Params)
+			var params struct {
+				Capabilities ClientCapabilities `json:"capabilities"`
+			}
+			json.Unmarshal(*req.Params, &params)
+			s.sampling = params.Capabilities.Sampling != nil
+		}
+	case "notifications/initialized":
+		id, _ := SessionIDFromContext(ctx)
+		switch {
+		case s.protocolVersion == "":
+			slog.Warn("ignoring initialized notification received before initialize", "session", id)
+		case s.initialized:
+			slog.Warn("ignoring repeated initialized notification", "session", id)
+		default:
+			s.initialized = true
+		}
+	case "tools/call":
+		ctx = contextWithSampler(ctx, connSampler{conn: conn, supported: s.sampling})
+		s.calls.Add(1)
+		go func() {
+			defer s.calls.Done()
+			s.handler.Handle(ctx, conn, req)
+		}()
+	default:
+		s.handler.Handle(ctx, conn, req)
+	}
+}
+
+// drainStream holds back the end of stream until the tool calls in progress
+// have replied, as jsonrpc2 closes the connection once reading fails.
+type drainStream struct {
+	jsonrpc2.ObjectStream
+	calls *sync.WaitGroup
+}
+
+func (s drainStream) ReadObject(v any) error {
+	err := s.ObjectStream.ReadObject(v)
+	if err != nil {
+		s.calls.Wait()
+	}
+	return err
+}
+
+// disconnectStream reads the messages of stream in the background, calling
+// disconnected once reading fails. jsonrpc2 handles a request before reading
+// the next message, so it would otherwise only notice that the client has
+// gone once the handler in progress returns.
+type disconnectStream struct {
+	jsonrpc2.ObjectStream
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	msgs []json.RawMessage
+	err  error
+}
+
+func newDisconnectStream(stream jsonrpc2.ObjectStream, disconnected func()) *disconnectStream {
+	s := &disconnectStream{ObjectStream: stream}
+	s.cond = sync.NewCond(&s.mu)
+	go s.read(disconnected)
+	return s
+}
+
+func (s *disconnectStream) read(disconnected func()) {
+	for {
+		var msg json.RawMessage
+		err := s.ObjectStream.ReadObject(&msg)
+		if err != nil {
+			disconnected()
+		}
+		s.mu.Lock()
+		if err != nil {
+			s.err = err
+		} else {
+			s.msgs = append(s.msgs, msg)
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *disconnectStream) ReadObject(v any) error {
+	s.mu.Lock()
+	for len(s.msgs) == 0 && s.err == nil {
+		s.cond.Wait()
+	}
+	if len(s.msgs) == 0 {
+		defer s.mu.Unlock()
+		return s.err
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	s.mu.Unlock()
+	return json.Unmarshal(msg, v)
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ErrInvalidSignature is returned when a message signature does not verify.
+var ErrInvalidSignature = errors.New("invalid message signature")
+
+// ErrReplayedMessage is logged when a signed stream drops a message whose
+// sequence number is not greater than that of the last message accepted.
+var ErrReplayedMessage = errors.New("replayed message")
+
+// MessageSigner signs outbound messages.
+type MessageSigner interface {
+	Sign(msg []byte) ([]byte, error)
+}
+
+// MessageVerifier verifies the signatures of inbound messages.
+type MessageVerifier interface {
+	Verify(msg, sig []byte) error
+}
+
+// HMACKey signs and verifies messages with HMAC-SHA256.
+type HMACKey []byte
+
+func (k HMACKey) Sign(msg []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(msg)
+	return mac.Sum(nil), nil
+}
+
+func (k HMACKey) Verify(msg, sig []byte) error {
+	expected, _ := k.Sign(msg)
+	if !hmac.Equal(expected, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ed25519Signer signs messages with an Ed25519 private key.
+type Ed25519Signer ed25519.PrivateKey
+
+func (k Ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(k), msg), nil
+}
+
+// Ed25519Verifier verifies messages signed with the private key
+// corresponding to an Ed25519 public key.
+type Ed25519Verifier ed25519.PublicKey
+
+func (k Ed25519Verifier) Verify(msg, sig []byte) error {
+	if !ed25519.Verify(ed25519.PublicKey(k), msg, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Side is the end of a session a signed stream belongs to.
+type Side int
+
+const (
+	// ClientSide is the end of a session that sends requests to a server.
+	ClientSide Side = iota
+	// ServerSide is the end of a session served by a Server.
+	ServerSide
+)
+
+// label names the direction of the messages sent by side, which is bound
+// into their signatures.
+func (side Side) label() string {
+	if side == ServerSide {
+		return "server-to-client"
+	}
+	return "client-to-server"
+}
+
+func (side Side) peer() Side {
+	if side == ServerSide {
+		return ClientSide
+	}
+	return ServerSide
+}
+
+// derive derives the key for the messages sent by side from k, so that each
+// direction of a session is signed with a separate key.
+func (k HMACKey) derive(side Side) HMACKey {
+	mac := hmac.New(sha256.New, k)
+	mac.Write([]byte("mcp message signing " + side.label()))
+	return mac.Sum(nil)
+}
+
+// signedMessage is the envelope a signed stream exchanges. The signature
+// covers the direction of the message, its sequence number and the exact
+// bytes of the message.
+type signedMessage struct {
+	Sequence  uint64          `json:"sequence"`
+	Message   json.RawMessage `json:"message"`
+	Signature []byte          `json:"signature"`
+}
+
+// signedBytes returns the bytes signed for msg, sent by side with sequence
+// number seq.
+func signedBytes(side Side, seq uint64, msg []byte) []byte {
+	b := append([]byte(side.label()), 0)
+	b = binary.BigEndian.AppendUint64(b, seq)
+	return append(b, msg...)
+}
+
+type signedStream struct {
+	stream   jsonrpc2.ObjectStream
+	side     Side
+	signer   MessageSigner
+	verifier MessageVerifier
+
+	mu      sync.Mutex
+	sent    uint64
+	lastSeq uint64
+}
+
+// NewSignedStream wraps stream, which belongs to side of a session, so that
+// outbound messages are signed by signer and inbound messages are verified
+// by verifier. Either may be nil to leave that direction unsigned. Each
+// message carries a sequence number that must increase, so that messages
+// cannot be replayed. Inbound messages that fail verification, or are
+// replayed, are logged and dropped. Both ends of the stream must be wrapped,
+// with keys chosen per session. An HMACKey is used to derive a separate key
+// for each direction, so one end accepts no message the other signs for it.
+func NewSignedStream(stream jsonrpc2.ObjectStream, side Side, signer MessageSigner, verifier MessageVerifier) jsonrpc2.ObjectStream {
+	if k, ok := signer.(HMACKey); ok {
+		signer = k.derive(side)
+	}
+	if k, ok := verifier.(HMACKey); ok {
+		verifier = k.derive(side.peer())
+	}
+	return &signedStream{stream: stream, side: side, signer: signer, verifier: verifier}
+}
+
+func (s *signedStream) WriteObject(obj interface{}) error {
+	if s.signer == nil {
+		return s.stream.WriteObject(obj)
+	}
+	msg, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	// messages must be written in the order of their sequence numbers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seq := s.sent + 1
+	sig, err := s.signer.Sign(signedBytes(s.side, seq, msg))
+	if err != nil {
+		return fmt.Errorf("signing message: %w", err)
+	}
+	s.sent = seq
+	return s.stream.WriteObject(signedMessage{Sequence: seq, Message: msg, Signature: sig})
+}
+
+func (s *signedStream) ReadObject(v interface{}) error {
+	if s.verifier == nil {
+		return s.stream.ReadObject(v)
+	}
+	for {
+		var m signedMessage
+		if err := s.stream.ReadObject(&m); err != nil {
+			return err
+		}
+		if err := s.verifier.Verify(signedBytes(s.side.peer(), m.Sequence, m.Message), m.Signature); err != nil {
+			slog.Warn("dropping message", "error", err)
+			continue
+		}
+		if m.Sequence <= s.lastSeq {
+			slog.Warn("dropping message", "error", ErrReplayedMessage, "sequence", m.Sequence)
+			continue
+		}
+		s.lastSeq = m.Sequence
+		return json.Unmarshal(m.Message, v)
+	}
+}
+
+func (s *signedStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,136 @@
+package mcp_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("Signed streams", func() {
+
+	var (
+		serverPub  ed25519.PublicKey
+		serverPriv ed25519.PrivateKey
+		clientKey  mcp.HMACKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		serverPub, serverPriv, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		clientKey = mcp.HMACKey("per-session-key")
+	})
+
+	connect := func(clientSigner mcp.MessageSigner) *jsonrpc2.Conn {
+		serverSide, clientSide := net.Pipe()
+		s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil)
+		go s.ServeStream(context.Background(), mcp.NewSignedStream(
+			jsonrpc2.NewPlainObjectStream(serverSide),
+			mcp.ServerSide,
+			mcp.Ed25519Signer(serverPriv),
+			clientKey,
+		))
+		conn := jsonrpc2.NewConn(context.Background(), mcp.NewSignedStream(
+			jsonrpc2.NewPlainObjectStream(clientSide),
+			mcp.ClientSide,
+			clientSigner,
+			mcp.Ed25519Verifier(serverPub),
+		), jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+			return nil, nil
+		}))
+		DeferCleanup(conn.Close)
+		return conn
+	}
+
+	It("exchanges messages when both ends sign and verify", func() {
+		conn := connect(clientKey)
+		var result map[string]any
+		Expect(conn.Call(context.Background(), "ping", nil, &result)).To(Succeed())
+		Expect(result).To(BeEmpty())
+	})
+
+	It("drops messages with invalid signatures", func() {
+		conn := connect(mcp.HMACKey("attacker-key"))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		Expect(conn.Call(ctx, "ping", nil, nil)).To(MatchError(context.DeadlineExceeded))
+	})
+
+	Describe("with a key shared by both ends", func() {
+
+		var (
+			key      mcp.HMACKey
+			attacker jsonrpc2.ObjectStream
+			replies  chan json.RawMessage
+		)
+
+		BeforeEach(func() {
+			key = mcp.HMACKey("shared-key")
+			serverSide, attackerSide := net.Pipe()
+			s := mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil)
+			go s.ServeStream(context.Background(), mcp.NewSignedStream(
+				jsonrpc2.NewPlainObjectStream(serverSide), mcp.ServerSide, key, key,
+			))
+			attacker = jsonrpc2.NewPlainObjectStream(attackerSide)
+			DeferCleanup(attacker.Close)
+
+			replies = make(chan json.RawMessage, 10)
+			go func(stream jsonrpc2.ObjectStream, replies chan<- json.RawMessage) {
+				defer GinkgoRecover()
+				for {
+					var reply json.RawMessage
+					if stream.ReadObject(&reply) != nil {
+						return
+					}
+					replies <- reply
+				}
+			}(attacker, replies)
+		})
+
+		// signedPing returns the envelope a client signs for a ping request.
+		signedPing := func(id int) json.RawMessage {
+			clientSide, relay := net.Pipe()
+			defer relay.Close()
+			client := mcp.NewSignedStream(jsonrpc2.NewPlainObjectStream(clientSide), mcp.ClientSide, key, key)
+			go client.WriteObject(map[string]any{"jsonrpc": "2.0", "id": id, "method": "ping"})
+			var envelope json.RawMessage
+			Expect(jsonrpc2.NewPlainObjectStream(relay).ReadObject(&envelope)).To(Succeed())
+			return envelope
+		}
+
+		It("drops replayed messages", func() {
+			ping := signedPing(1)
+			Expect(attacker.WriteObject(ping)).To(Succeed())
+			Eventually(replies).Should(Receive())
+
+			Expect(attacker.WriteObject(ping)).To(Succeed())
+			Consistently(replies, 200*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("drops messages reflected back to their sender", func() {
+			Expect(attacker.WriteObject(signedPing(1))).To(Succeed())
+			var reply json.RawMessage
+			Eventually(replies).Should(Receive(&reply))
+
+			Expect(attacker.WriteObject(reply)).To(Succeed())
+			Consistently(replies, 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+
+	It("rejects tampered messages", func() {
+		msg := []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+		sig, err := clientKey.Sign(msg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientKey.Verify(msg, sig)).To(Succeed())
+		Expect(clientKey.Verify([]byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}`), sig)).To(MatchError(mcp.ErrInvalidSignature))
+	})
+})
@@ -0,0 +1,205 @@
+package spec20241105
+
+import (
+	"fmt"
+
+	"github.com/acrmp/mcp/spec20250618"
+)
+
+// FromSpec20250618 converts a result of revision 2025-06-18, as returned by
+// spec20250618.NewResult, to the result of this revision.
+func FromSpec20250618(v any) (any, error) {
+	switch v := v.(type) {
+	case *spec20250618.InitializeResult:
+		return FromInitializeResult(*v), nil
+	case *spec20250618.ListToolsResult:
+		return FromListToolsResult(*v), nil
+	case *spec20250618.CallToolResult:
+		return FromCallToolResult(*v)
+	case *spec20250618.ListPromptsResult:
+		return FromListPromptsResult(*v), nil
+	case *spec20250618.GetPromptResult:
+		return FromGetPromptResult(*v), nil
+	case *spec20250618.ListResourcesResult:
+		return FromListResourcesResult(*v), nil
+	case *spec20250618.ListResourceTemplatesResult:
+		return FromListResourceTemplatesResult(*v), nil
+	case *spec20250618.ReadResourceResult:
+		return FromReadResourceResult(*v), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T", v)
+}
+
+// FromInitializeResult drops the completions capability and the title of
+// the server.
+func FromInitializeResult(r spec20250618.InitializeResult) InitializeResult {
+	c := r.Capabilities
+	out := InitializeResult{
+		Meta:            Meta(r.Meta),
+		ProtocolVersion: r.ProtocolVersion,
+		Capabilities: ServerCapabilities{
+			Logging: (*Object)(c.Logging),
+		},
+		ServerInfo:   Implementation{Name: r.ServerInfo.Name, Version: r.ServerInfo.Version},
+		Instructions: r.Instructions,
+	}
+	if c.Experimental != nil {
+		out.Capabilities.Experimental = make(map[string]Object, len(c.Experimental))
+		for k, v := range c.Experimental {
+			out.Capabilities.Experimental[k] = Object(v)
+		}
+	}
+	if c.Prompts != nil {
+		out.Capabilities.Prompts = &ListChangedCapability{ListChanged: c.Prompts.ListChanged}
+	}
+	if c.Resources != nil {
+		out.Capabilities.Resources = &ResourcesCapability{Subscribe: c.Resources.Subscribe, ListChanged: c.Resources.ListChanged}
+	}
+	if c.Tools != nil {
+		out.Capabilities.Tools = &ListChangedCapability{ListChanged: c.Tools.ListChanged}
+	}
+	return out
+}
+
+// FromTool drops the title, output schema and annotations of t.
+func FromTool(t spec20250618.Tool) Tool {
+	return Tool{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: fromSchema(t.InputSchema),
+	}
+}
+
+func fromSchema(s spec20250618.Schema) Schema {
+	out := Schema{Type: s.Type, Required: s.Required}
+	if s.Properties != nil {
+		out.Properties = make(map[string]Object, len(s.Properties))
+		for k, v := range s.Properties {
+			out.Properties[k] = Object(v)
+		}
+	}
+	return out
+}
+
+func FromListToolsResult(r spec20250618.ListToolsResult) ListToolsResult {
+	out := ListToolsResult{Meta: Meta(r.Meta), NextCursor: r.NextCursor, Tools: make([]Tool, len(r.Tools))}
+	for i, t := range r.Tools {
+		out.Tools[i] = FromTool(t)
+	}
+	return out
+}
+
+// FromCallToolResult converts the content of r with FromContent. Structured
+// content is dropped, and if r has no other content it is sent as JSON text
+// instead, which is how tools returned it before structured content.
+func FromCallToolResult(r spec20250618.CallToolResult) (CallToolResult, error) {
+	out := CallToolResult{Meta: Meta(r.Meta), Content: make([]Content, len(r.Content)), IsError: r.IsError}
+	for i, c := range r.Content {
+		out.Content[i] = FromContent(c)
+	}
+	if len(out.Content) == 0 && r.StructuredContent != nil {
+		text, err := structuredText(r.StructuredContent)
+		if err != nil {
+			return CallToolResult{}, err
+		}
+		out.Content = []Content{TextContent{Type: "text", Text: text}}
+	}
+	return out, nil
+}
+
+// FromContent converts a content block. Audio, which this revision does not
+// define, becomes text noting the audio was left out, and a resource link
+// becomes text holding its URI.
+func FromContent(c spec20250618.ContentBlock) Content {
+	switch c := c.(type) {
+	case spec20250618.TextContent:
+		return TextContent{Type: "text", Text: c.Text, Annotations: fromAnnotations(c.Annotations)}
+	case spec20250618.ImageContent:
+		return ImageContent{Type: "image", Data: c.Data, MimeType: c.MimeType, Annotations: fromAnnotations(c.Annotations)}
+	case spec20250618.AudioContent:
+		return TextContent{Type: "text", Text: fmt.Sprintf("[%s audio omitted]", c.MimeType), Annotations: fromAnnotations(c.Annotations)}
+	case spec20250618.ResourceLink:
+		return TextContent{Type: "text", Text: c.URI, Annotations: fromAnnotations(c.Annotations)}
+	case spec20250618.EmbeddedResource:
+		return EmbeddedResource{Type: "resource", Resource: fromResourceContents(c.Resource), Annotations: fromAnnotations(c.Annotations)}
+	}
+	return nil
+}
+
+func fromAnnotations(a *spec20250618.Annotations) *Annotations {
+	if a == nil || (a.Audience == nil && a.Priority == nil) {
+		return nil
+	}
+	out := &Annotations{Priority: a.Priority}
+	for _, r := range a.Audience {
+		out.Audience = append(out.Audience, Role(r))
+	}
+	return out
+}
+
+func fromResourceContents(c spec20250618.ResourceContents) ResourceContents {
+	return ResourceContents{URI: c.URI, MimeType: c.MimeType, Text: c.Text, Blob: c.Blob}
+}
+
+func FromListPromptsResult(r spec20250618.ListPromptsResult) ListPromptsResult {
+	out := ListPromptsResult{Meta: Meta(r.Meta), NextCursor: r.NextCursor, Prompts: make([]Prompt, len(r.Prompts))}
+	for i, p := range r.Prompts {
+		out.Prompts[i] = Prompt{Name: p.Name, Description: p.Description}
+		for _, a := range p.Arguments {
+			out.Prompts[i].Arguments = append(out.Prompts[i].Arguments, PromptArgument{
+				Name:        a.Name,
+				Description: a.Description,
+				Required:    a.Required,
+			})
+		}
+	}
+	return out
+}
+
+func FromGetPromptResult(r spec20250618.GetPromptResult) GetPromptResult {
+	out := GetPromptResult{Meta: Meta(r.Meta), Description: r.Description, Messages: make([]PromptMessage, len(r.Messages))}
+	for i, m := range r.Messages {
+		out.Messages[i] = PromptMessage{Role: Role(m.Role), Content: FromContent(m.Content)}
+	}
+	return out
+}
+
+func FromListResourcesResult(r spec20250618.ListResourcesResult) ListResourcesResult {
+	out := ListResourcesResult{Meta: Meta(r.Meta), NextCursor: r.NextCursor, Resources: make([]Resource, len(r.Resources))}
+	for i, res := range r.Resources {
+		out.Resources[i] = Resource{
+			URI:         res.URI,
+			Name:        res.Name,
+			Description: res.Description,
+			MimeType:    res.MimeType,
+			Annotations: fromAnnotations(res.Annotations),
+		}
+	}
+	return out
+}
+
+func FromListResourceTemplatesResult(r spec20250618.ListResourceTemplatesResult) ListResourceTemplatesResult {
+	out := ListResourceTemplatesResult{
+		Meta:              Meta(r.Meta),
+		NextCursor:        r.NextCursor,
+		ResourceTemplates: make([]ResourceTemplate, len(r.ResourceTemplates)),
+	}
+	for i, t := range r.ResourceTemplates {
+		out.ResourceTemplates[i] = ResourceTemplate{
+			URITemplate: t.URITemplate,
+			Name:        t.Name,
+			Description: t.Description,
+			MimeType:    t.MimeType,
+			Annotations: fromAnnotations(t.Annotations),
+		}
+	}
+	return out
+}
+
+func FromReadResourceResult(r spec20250618.ReadResourceResult) ReadResourceResult {
+	out := ReadResourceResult{Meta: Meta(r.Meta), Contents: make([]ResourceContents, len(r.Contents))}
+	for i, c := range r.Contents {
+		out.Contents[i] = fromResourceContents(c)
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package spec20241105_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp/spec20241105"
+	"github.com/acrmp/mcp/spec20250618"
+)
+
+var _ = Describe("FromSpec20250618", func() {
+
+	// convert decodes a result of revision 2025-06-18 for method and returns
+	// it converted, as JSON.
+	convert := func(method, result string) string {
+		GinkgoHelper()
+		v, ok := spec20250618.NewResult(method)
+		Expect(ok).To(BeTrue())
+		Expect(json.Unmarshal([]byte(result), v)).To(Succeed())
+		converted, err := spec20241105.FromSpec20250618(v)
+		Expect(err).ToNot(HaveOccurred())
+		b, err := json.Marshal(converted)
+		Expect(err).ToNot(HaveOccurred())
+		return string(b)
+	}
+
+	It("drops the completions capability and the title of the server", func() {
+		Expect(convert("initialize", `{"protocolVersion":"2024-11-05","capabilities":{"completions":{},"logging":{},"tools":{"listChanged":false}},"serverInfo":{"name":"s","title":"S","version":"1.0.0"}}`)).To(MatchJSON(
+			`{"protocolVersion":"2024-11-05","capabilities":{"logging":{},"tools":{"listChanged":false}},"serverInfo":{"name":"s","version":"1.0.0"}}`,
+		))
+	})
+
+	It("drops the fields of tools added since", func() {
+		Expect(convert("tools/list", `{"tools":[{"name":"add","title":"Add","inputSchema":{"type":"object","properties":{"a":{"type":"number"}},"required":["a"]},"outputSchema":{"type":"object"},"annotations":{"readOnlyHint":true}}]}`)).To(MatchJSON(
+			`{"tools":[{"name":"add","inputSchema":{"type":"object","properties":{"a":{"type":"number"}},"required":["a"]}}]}`,
+		))
+	})
+
+	Describe("tool results", func() {
+		It("drops structured content alongside other content", func() {
+			Expect(convert("tools/call", `{"content":[{"type":"text","text":"{\"sum\":3}"}],"structuredContent":{"sum":3}}`)).To(MatchJSON(
+				`{"content":[{"type":"text","text":"{\"sum\":3}"}]}`,
+			))
+		})
+
+		It("sends structured content as text when there is no other content", func() {
+			Expect(convert("tools/call", `{"content":[],"structuredContent":{"sum":3},"isError":false}`)).To(MatchJSON(
+				`{"content":[{"type":"text","text":"{\"sum\":3}"}],"isError":false}`,
+			))
+		})
+
+		It("converts content the revision does not define to text", func() {
+			Expect(convert("tools/call", `{"content":[{"type":"audio","data":"AAAA","mimeType":"audio/wav"},{"type":"resource_link","uri":"file:///a.txt","name":"a.txt"}]}`)).To(MatchJSON(
+				`{"content":[{"type":"text","text":"[audio/wav audio omitted]"},{"type":"text","text":"file:///a.txt"}]}`,
+			))
+		})
+
+		It("drops the last modified time of annotations", func() {
+			Expect(convert("tools/call", `{"content":[{"type":"text","text":"a","annotations":{"priority":0.5,"lastModified":"2025-01-01T00:00:00Z"}},{"type":"text","text":"b","annotations":{"lastModified":"2025-01-01T00:00:00Z"}}]}`)).To(MatchJSON(
+				`{"content":[{"type":"text","text":"a","annotations":{"priority":0.5}},{"type":"text","text":"b"}]}`,
+			))
+		})
+	})
+
+	It("converts the content of prompt messages", func() {
+		Expect(convert("prompts/get", `{"messages":[{"role":"user","content":{"type":"resource","resource":{"uri":"file:///a.txt","text":"a","_meta":{"k":"v"}}}}]}`)).To(MatchJSON(
+			`{"messages":[{"role":"user","content":{"type":"resource","resource":{"uri":"file:///a.txt","text":"a"}}}]}`,
+		))
+	})
+
+	It("drops the title and size of resources", func() {
+		Expect(convert("resources/list", `{"resources":[{"uri":"file:///a.txt","name":"a.txt","title":"A","size":1}]}`)).To(MatchJSON(
+			`{"resources":[{"uri":"file:///a.txt","name":"a.txt"}]}`,
+		))
+	})
+})
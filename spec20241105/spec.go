@@ -0,0 +1,190 @@
+// Package spec20241105 holds the results a server sends in revision
+// 2024-11-05 of the Model Context Protocol, and converts results of later
+// revisions to them, so that a session which negotiated this revision is
+// not sent fields it does not define.
+package spec20241105
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the protocol version of this revision.
+const ProtocolVersion = "2024-11-05"
+
+// Meta is the _meta field reserved by the protocol for metadata.
+type Meta map[string]any
+
+// Object is a JSON object, such as a capability that has no settings.
+type Object map[string]any
+
+type Role string
+
+type Annotations struct {
+	Audience []Role   `json:"audience,omitempty"`
+	Priority *float64 `json:"priority,omitempty"`
+}
+
+// Content is a TextContent, ImageContent or EmbeddedResource.
+type Content interface {
+	content()
+}
+
+func (TextContent) content()      {}
+func (ImageContent) content()     {}
+func (EmbeddedResource) content() {}
+
+type TextContent struct {
+	Type        string       `json:"type"`
+	Text        string       `json:"text"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+}
+
+type ImageContent struct {
+	Type        string       `json:"type"`
+	Data        string       `json:"data"`
+	MimeType    string       `json:"mimeType"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+}
+
+type EmbeddedResource struct {
+	Type        string           `json:"type"`
+	Resource    ResourceContents `json:"resource"`
+	Annotations *Annotations     `json:"annotations,omitempty"`
+}
+
+// ResourceContents are the contents of a resource, holding either Text or
+// Blob.
+type ResourceContents struct {
+	URI      string  `json:"uri"`
+	MimeType *string `json:"mimeType,omitempty"`
+	Text     *string `json:"text,omitempty"`
+	Blob     *string `json:"blob,omitempty"`
+}
+
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type ListChangedCapability struct {
+	ListChanged *bool `json:"listChanged,omitempty"`
+}
+
+type ResourcesCapability struct {
+	Subscribe   *bool `json:"subscribe,omitempty"`
+	ListChanged *bool `json:"listChanged,omitempty"`
+}
+
+type ServerCapabilities struct {
+	Experimental map[string]Object      `json:"experimental,omitempty"`
+	Logging      *Object                `json:"logging,omitempty"`
+	Prompts      *ListChangedCapability `json:"prompts,omitempty"`
+	Resources    *ResourcesCapability   `json:"resources,omitempty"`
+	Tools        *ListChangedCapability `json:"tools,omitempty"`
+}
+
+type InitializeResult struct {
+	Meta            Meta               `json:"_meta,omitempty"`
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    *string            `json:"instructions,omitempty"`
+}
+
+// Schema is the JSON Schema of the input of a tool.
+type Schema struct {
+	Type       string            `json:"type"`
+	Properties map[string]Object `json:"properties,omitempty"`
+	Required   []string          `json:"required,omitempty"`
+}
+
+type Tool struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	InputSchema Schema  `json:"inputSchema"`
+}
+
+type ListToolsResult struct {
+	Meta       Meta    `json:"_meta,omitempty"`
+	NextCursor *string `json:"nextCursor,omitempty"`
+	Tools      []Tool  `json:"tools"`
+}
+
+type CallToolResult struct {
+	Meta    Meta      `json:"_meta,omitempty"`
+	Content []Content `json:"content"`
+	IsError *bool     `json:"isError,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Required    *bool   `json:"required,omitempty"`
+}
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type ListPromptsResult struct {
+	Meta       Meta     `json:"_meta,omitempty"`
+	NextCursor *string  `json:"nextCursor,omitempty"`
+	Prompts    []Prompt `json:"prompts"`
+}
+
+type PromptMessage struct {
+	Role    Role    `json:"role"`
+	Content Content `json:"content"`
+}
+
+type GetPromptResult struct {
+	Meta        Meta            `json:"_meta,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+type Resource struct {
+	URI         string       `json:"uri"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description,omitempty"`
+	MimeType    *string      `json:"mimeType,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+}
+
+type ListResourcesResult struct {
+	Meta       Meta       `json:"_meta,omitempty"`
+	NextCursor *string    `json:"nextCursor,omitempty"`
+	Resources  []Resource `json:"resources"`
+}
+
+type ResourceTemplate struct {
+	URITemplate string       `json:"uriTemplate"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description,omitempty"`
+	MimeType    *string      `json:"mimeType,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+}
+
+type ListResourceTemplatesResult struct {
+	Meta              Meta               `json:"_meta,omitempty"`
+	NextCursor        *string            `json:"nextCursor,omitempty"`
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+type ReadResourceResult struct {
+	Meta     Meta               `json:"_meta,omitempty"`
+	Contents []ResourceContents `json:"contents"`
+}
+
+// structuredText returns structured content as JSON text, as revisions
+// without structured content expect tools to return it.
+func structuredText(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encoding structured content: %w", err)
+	}
+	return string(b), nil
+}
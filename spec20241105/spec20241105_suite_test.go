@@ -0,0 +1,13 @@
+package spec20241105_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSpec20241105(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "spec20241105 Suite")
+}
@@ -0,0 +1,332 @@
+// Package spec20250618 holds the results a server sends in revision
+// 2025-06-18 of the Model Context Protocol, so that a session which
+// negotiated this revision is sent exactly the fields it defines.
+//
+// The types of package mcp hold the results the server builds. Encoding
+// one as JSON and decoding it into the value NewResult returns for its
+// method yields the result of this revision.
+package spec20250618
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the protocol version of this revision.
+const ProtocolVersion = "2025-06-18"
+
+// Meta is the _meta field reserved by the protocol for metadata.
+type Meta map[string]any
+
+// Object is a JSON object, such as a capability that has no settings.
+type Object map[string]any
+
+type Role string
+
+type Annotations struct {
+	Audience     []Role   `json:"audience,omitempty"`
+	Priority     *float64 `json:"priority,omitempty"`
+	LastModified *string  `json:"lastModified,omitempty"`
+}
+
+// ContentBlock is a TextContent, ImageContent, AudioContent, ResourceLink
+// or EmbeddedResource.
+type ContentBlock interface {
+	contentBlock()
+}
+
+func (TextContent) contentBlock()      {}
+func (ImageContent) contentBlock()     {}
+func (AudioContent) contentBlock()     {}
+func (ResourceLink) contentBlock()     {}
+func (EmbeddedResource) contentBlock() {}
+
+type TextContent struct {
+	Type        string       `json:"type"`
+	Text        string       `json:"text"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type ImageContent struct {
+	Type        string       `json:"type"`
+	Data        string       `json:"data"`
+	MimeType    string       `json:"mimeType"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type AudioContent struct {
+	Type        string       `json:"type"`
+	Data        string       `json:"data"`
+	MimeType    string       `json:"mimeType"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type ResourceLink struct {
+	Type        string       `json:"type"`
+	URI         string       `json:"uri"`
+	Name        string       `json:"name"`
+	Title       *string      `json:"title,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	MimeType    *string      `json:"mimeType,omitempty"`
+	Size        *int64       `json:"size,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type EmbeddedResource struct {
+	Type        string           `json:"type"`
+	Resource    ResourceContents `json:"resource"`
+	Annotations *Annotations     `json:"annotations,omitempty"`
+	Meta        Meta             `json:"_meta,omitempty"`
+}
+
+// ResourceContents are the contents of a resource, holding either Text or
+// Blob.
+type ResourceContents struct {
+	URI      string  `json:"uri"`
+	MimeType *string `json:"mimeType,omitempty"`
+	Text     *string `json:"text,omitempty"`
+	Blob     *string `json:"blob,omitempty"`
+	Meta     Meta    `json:"_meta,omitempty"`
+}
+
+// DecodeContent decodes a content block, as told apart by its type.
+func DecodeContent(b []byte) (ContentBlock, error) {
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &typed); err != nil {
+		return nil, err
+	}
+	var c ContentBlock
+	var err error
+	switch typed.Type {
+	case "text":
+		var t TextContent
+		err = json.Unmarshal(b, &t)
+		c = t
+	case "image":
+		var i ImageContent
+		err = json.Unmarshal(b, &i)
+		c = i
+	case "audio":
+		var a AudioContent
+		err = json.Unmarshal(b, &a)
+		c = a
+	case "resource_link":
+		var l ResourceLink
+		err = json.Unmarshal(b, &l)
+		c = l
+	case "resource":
+		var r EmbeddedResource
+		err = json.Unmarshal(b, &r)
+		c = r
+	default:
+		return nil, fmt.Errorf("unknown content type %q", typed.Type)
+	}
+	return c, err
+}
+
+// Contents is a list of content blocks.
+type Contents []ContentBlock
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Contents) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*c = make(Contents, len(raw))
+	for i, r := range raw {
+		block, err := DecodeContent(r)
+		if err != nil {
+			return err
+		}
+		(*c)[i] = block
+	}
+	return nil
+}
+
+type Implementation struct {
+	Name    string  `json:"name"`
+	Title   *string `json:"title,omitempty"`
+	Version string  `json:"version"`
+}
+
+type ListChangedCapability struct {
+	ListChanged *bool `json:"listChanged,omitempty"`
+}
+
+type ResourcesCapability struct {
+	Subscribe   *bool `json:"subscribe,omitempty"`
+	ListChanged *bool `json:"listChanged,omitempty"`
+}
+
+type ServerCapabilities struct {
+	Experimental map[string]Object      `json:"experimental,omitempty"`
+	Logging      *Object                `json:"logging,omitempty"`
+	Completions  *Object                `json:"completions,omitempty"`
+	Prompts      *ListChangedCapability `json:"prompts,omitempty"`
+	Resources    *ResourcesCapability   `json:"resources,omitempty"`
+	Tools        *ListChangedCapability `json:"tools,omitempty"`
+}
+
+type InitializeResult struct {
+	Meta            Meta               `json:"_meta,omitempty"`
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    *string            `json:"instructions,omitempty"`
+}
+
+// Schema is the JSON Schema of the input or output of a tool.
+type Schema struct {
+	Type       string            `json:"type"`
+	Properties map[string]Object `json:"properties,omitempty"`
+	Required   []string          `json:"required,omitempty"`
+}
+
+type ToolAnnotations struct {
+	Title           *string `json:"title,omitempty"`
+	ReadOnlyHint    *bool   `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool   `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool   `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool   `json:"openWorldHint,omitempty"`
+}
+
+type Tool struct {
+	Name         string           `json:"name"`
+	Title        *string          `json:"title,omitempty"`
+	Description  *string          `json:"description,omitempty"`
+	InputSchema  Schema           `json:"inputSchema"`
+	OutputSchema *Schema          `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations `json:"annotations,omitempty"`
+	Meta         Meta             `json:"_meta,omitempty"`
+}
+
+type ListToolsResult struct {
+	Meta       Meta    `json:"_meta,omitempty"`
+	NextCursor *string `json:"nextCursor,omitempty"`
+	Tools      []Tool  `json:"tools"`
+}
+
+type CallToolResult struct {
+	Meta              Meta     `json:"_meta,omitempty"`
+	Content           Contents `json:"content"`
+	StructuredContent Object   `json:"structuredContent,omitempty"`
+	IsError           *bool    `json:"isError,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string  `json:"name"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Required    *bool   `json:"required,omitempty"`
+}
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       *string          `json:"title,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+	Meta        Meta             `json:"_meta,omitempty"`
+}
+
+type ListPromptsResult struct {
+	Meta       Meta     `json:"_meta,omitempty"`
+	NextCursor *string  `json:"nextCursor,omitempty"`
+	Prompts    []Prompt `json:"prompts"`
+}
+
+type PromptMessage struct {
+	Role    Role         `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *PromptMessage) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Role    Role            `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	content, err := DecodeContent(raw.Content)
+	if err != nil {
+		return err
+	}
+	*m = PromptMessage{Role: raw.Role, Content: content}
+	return nil
+}
+
+type GetPromptResult struct {
+	Meta        Meta            `json:"_meta,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+type Resource struct {
+	URI         string       `json:"uri"`
+	Name        string       `json:"name"`
+	Title       *string      `json:"title,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	MimeType    *string      `json:"mimeType,omitempty"`
+	Size        *int64       `json:"size,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type ListResourcesResult struct {
+	Meta       Meta       `json:"_meta,omitempty"`
+	NextCursor *string    `json:"nextCursor,omitempty"`
+	Resources  []Resource `json:"resources"`
+}
+
+type ResourceTemplate struct {
+	URITemplate string       `json:"uriTemplate"`
+	Name        string       `json:"name"`
+	Title       *string      `json:"title,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	MimeType    *string      `json:"mimeType,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Meta        Meta         `json:"_meta,omitempty"`
+}
+
+type ListResourceTemplatesResult struct {
+	Meta              Meta               `json:"_meta,omitempty"`
+	NextCursor        *string            `json:"nextCursor,omitempty"`
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+type ReadResourceResult struct {
+	Meta     Meta               `json:"_meta,omitempty"`
+	Contents []ResourceContents `json:"contents"`
+}
+
+// NewResult returns a pointer to the zero result of method, for decoding
+// into, or false if the result of method is not defined by this package.
+func NewResult(method string) (any, bool) {
+	switch method {
+	case "initialize":
+		return &InitializeResult{}, true
+	case "tools/list":
+		return &ListToolsResult{}, true
+	case "tools/call":
+		return &CallToolResult{}, true
+	case "prompts/list":
+		return &ListPromptsResult{}, true
+	case "prompts/get":
+		return &GetPromptResult{}, true
+	case "resources/list":
+		return &ListResourcesResult{}, true
+	case "resources/templates/list":
+		return &ListResourceTemplatesResult{}, true
+	case "resources/read":
+		return &ReadResourceResult{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// EOFBehavior is what Serve does once its standard input is closed. Hosts
+// close stdin at different times: some to stop the server, others as soon
+// as they have sent their last message.
+type EOFBehavior int
+
+const (
+	// EOFWaitForCalls finishes the calls in progress before returning. This
+	// is the default.
+	EOFWaitForCalls EOFBehavior = iota
+	// EOFExit returns as soon as stdin is closed, cancelling the calls in
+	// progress.
+	EOFExit
+	// EOFWaitForParent finishes the calls in progress and then waits for
+	// the parent process to exit before returning. The parent is watched by
+	// its process ID, which changes once it exits on Unix systems.
+	EOFWaitForParent
+)
+
+// parentPollInterval is how often EOFWaitForParent checks the parent process.
+const parentPollInterval = time.Second
+
+// WithStdinEOF sets what Serve does once its standard input is closed.
+func WithStdinEOF(b EOFBehavior) ServerOption {
+	return func(h *handler) {
+		h.stdinEOF = b
+	}
+}
+
+// serveStdio serves the messages read from stdin, replying on stdout, and
+// returns as set by WithStdinEOF.
+func (s *Server) serveStdio(stdin io.Reader, stdout io.Writer) {
+	parent := os.Getppid()
+	// stdin is read in the background so that its end is noticed while a
+	// message is being handled rather than once the next message is read
+	eof := make(chan struct{})
+	stream := newDisconnectStream(jsonrpc2.NewPlainObjectStream(stdioReadWriter{stdin, stdout}), func() {
+		close(eof)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan struct{})
+	go func() {
+		// calls in progress are cancelled on EOF only as set by WithStdinEOF
+		s.serveStream(ctx, stream, false)
+		close(served)
+	}()
+
+	switch s.handler.stdinEOF {
+	case EOFExit:
+		select {
+		case <-eof:
+		case <-served:
+		}
+	case EOFWaitForParent:
+		<-served
+		for os.Getppid() == parent {
+			time.Sleep(parentPollInterval)
+		}
+	default:
+		<-served
+	}
+}
+
+type stdioReadWriter struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioReadWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package mcp_test
+
+import (
+	"os/exec"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp/mcptest"
+)
+
+var _ = Describe("Serving over stdio", func() {
+
+	const sleep = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"sleep"}}`
+
+	start := func(behavior string) *mcptest.StdioClient {
+		command := exec.Command(sleepServerPath, behavior)
+		command.Stderr = GinkgoWriter
+		return mcptest.StartStdio(GinkgoT(), command)
+	}
+
+	It("finishes calls in progress once stdin is closed by default", func() {
+		client := start("wait")
+		Expect(client.Send(sleep)).To(Succeed())
+		Expect(client.Close()).To(Succeed())
+		Expect(client.Messages()).To(ConsistOf(MatchJSON(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"slept"}]}}`)))
+	})
+
+	It("exits as soon as stdin is closed", func() {
+		client := start("exit")
+		Expect(client.Send(sleep)).To(Succeed())
+		time.Sleep(50 * time.Millisecond)
+		closed := time.Now()
+		Expect(client.Close()).To(Succeed())
+		Expect(time.Since(closed)).To(BeNumerically("<", 400*time.Millisecond))
+		Expect(client.Messages()).To(BeEmpty())
+	})
+
+	It("keeps running after stdin is closed until the parent exits", func() {
+		command := exec.Command(sleepServerPath, "parent")
+		stdin, err := command.StdinPipe()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(command.Start()).To(Succeed())
+		exited := make(chan error, 1)
+		go func() {
+			exited <- command.Wait()
+		}()
+		DeferCleanup(func() {
+			command.Process.Kill()
+			<-exited
+		})
+
+		Expect(stdin.Close()).To(Succeed())
+		Consistently(exited, "300ms").ShouldNot(Receive())
+	})
+})
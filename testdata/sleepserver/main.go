@@ -0,0 +1,33 @@
+// Command sleepserver serves a tool that sleeps, for testing what Serve does
+// once stdin is closed. It takes the behavior as its only argument: wait,
+// exit or parent.
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/acrmp/mcp"
+)
+
+func main() {
+	behaviors := map[string]mcp.EOFBehavior{
+		"wait":   mcp.EOFWaitForCalls,
+		"exit":   mcp.EOFExit,
+		"parent": mcp.EOFWaitForParent,
+	}
+	tools := []mcp.ToolDefinition{{
+		Metadata: mcp.Tool{Name: "sleep", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+		Process: func(ctx context.Context, _ mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+			select {
+			case <-time.After(500 * time.Millisecond):
+				return mcp.NewResult().Text("slept").Build()
+			case <-ctx.Done():
+				return mcp.CallToolResult{}, ctx.Err()
+			}
+		},
+	}}
+	s := mcp.NewServer(mcp.Implementation{Name: "SleepServer", Version: "1.0.0"}, tools, mcp.WithStdinEOF(behaviors[os.Args[1]]))
+	s.Serve()
+}
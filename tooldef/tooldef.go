@@ -0,0 +1,265 @@
+// Package tooldef loads tools declared in YAML, so that a server can be
+// extended without writing Go. Each tool declares its metadata and an action,
+// either a command to run or an HTTP request to make:
+//
+//	tools:
+//	  - name: disk_usage
+//	    description: Show the disk usage of a directory
+//	    inputSchema:
+//	      type: object
+//	      properties:
+//	        path: {type: string}
+//	      required: [path]
+//	    command:
+//	      path: du
+//	      args: ["-sh", "{{.path}}"]
+//	      sandbox: {timeout: 5s, noNetwork: true}
+//	  - name: weather
+//	    inputSchema:
+//	      type: object
+//	      properties:
+//	        city: {type: string}
+//	    http:
+//	      url: https://api.example.com/weather?city={{urlquery .city}}
+//	      headers:
+//	        Authorization: Bearer {{env "WEATHER_TOKEN"}}
+//
+// Command arguments, URLs, headers and bodies are text/template templates
+// rendered with the tool call arguments. Referring to an argument that was
+// not passed is an error; use index, as in {{index . "limit"}}, for optional
+// arguments. HTTP templates may also call env to read an environment
+// variable of the server and json to encode a value.
+package tooldef
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/acrmp/mcp"
+)
+
+type file struct {
+	Tools []tool `yaml:"tools"`
+}
+
+type tool struct {
+	mcp.Tool `yaml:",inline"`
+	Command  *command     `yaml:"command"`
+	HTTP     *httpRequest `yaml:"http"`
+}
+
+type command struct {
+	Path    string   `yaml:"path"`
+	Args    []string `yaml:"args"`
+	Sandbox sandbox  `yaml:"sandbox"`
+}
+
+type sandbox struct {
+	Timeout        time.Duration `yaml:"timeout"`
+	CPUTime        time.Duration `yaml:"cpuTime"`
+	MemoryBytes    uint64        `yaml:"memoryBytes"`
+	Dir            string        `yaml:"dir"`
+	Chroot         bool          `yaml:"chroot"`
+	ScrubEnv       bool          `yaml:"scrubEnv"`
+	InheritEnv     []string      `yaml:"inheritEnv"`
+	Env            []string      `yaml:"env"`
+	NoNetwork      bool          `yaml:"noNetwork"`
+	MaxOutputBytes int           `yaml:"maxOutputBytes"`
+}
+
+type httpRequest struct {
+	// Method defaults to GET.
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Timeout bounds the time taken by the request.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Load reads the tools declared in the YAML file at filename.
+func Load(filename string) ([]mcp.ToolDefinition, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("loading tools from %s: %w", filename, err)
+	}
+	return tools, nil
+}
+
+// Parse returns the tools declared in the YAML document b.
+func Parse(b []byte) ([]mcp.ToolDefinition, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	tools := make([]mcp.ToolDefinition, 0, len(f.Tools))
+	names := map[string]bool{}
+	for i, t := range f.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tool %d has no name", i+1)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tool %s is declared more than once", t.Name)
+		}
+		names[t.Name] = true
+		if t.InputSchema.Type == "" {
+			t.InputSchema.Type = "object"
+		}
+		if t.InputSchema.Type != "object" {
+			return nil, fmt.Errorf("tool %s: input schema must be an object, not %q", t.Name, t.InputSchema.Type)
+		}
+		def, err := t.definition()
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		tools = append(tools, def)
+	}
+	return tools, nil
+}
+
+func (t tool) definition() (mcp.ToolDefinition, error) {
+	switch {
+	case t.Command != nil && t.HTTP != nil:
+		return mcp.ToolDefinition{}, errors.New("declares both a command and an HTTP request")
+	case t.Command != nil:
+		if t.Command.Path == "" {
+			return mcp.ToolDefinition{}, errors.New("command has no path")
+		}
+		s := t.Command.Sandbox
+		return mcp.CommandTool(t.Tool, mcp.Command{
+			Path: t.Command.Path,
+			Args: t.Command.Args,
+			Sandbox: mcp.Sandbox{
+				Timeout:        s.Timeout,
+				CPUTime:        s.CPUTime,
+				MemoryBytes:    s.MemoryBytes,
+				Dir:            s.Dir,
+				Chroot:         s.Chroot,
+				ScrubEnv:       s.ScrubEnv,
+				InheritEnv:     s.InheritEnv,
+				Env:            s.Env,
+				NoNetwork:      s.NoNetwork,
+				MaxOutputBytes: s.MaxOutputBytes,
+			},
+		})
+	case t.HTTP != nil:
+		return t.HTTP.definition(t.Tool)
+	}
+	return mcp.ToolDefinition{}, errors.New("declares no command or HTTP request")
+}
+
+var funcs = template.FuncMap{
+	"env": os.Getenv,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return t, nil
+}
+
+func render(t *template.Template, args map[string]any) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, args); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", t.Name(), err)
+	}
+	return b.String(), nil
+}
+
+func (r httpRequest) definition(metadata mcp.Tool) (mcp.ToolDefinition, error) {
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if r.URL == "" {
+		return mcp.ToolDefinition{}, errors.New("HTTP request has no URL")
+	}
+	url, err := parseTemplate("url", r.URL)
+	if err != nil {
+		return mcp.ToolDefinition{}, err
+	}
+	headers := make(map[string]*template.Template, len(r.Headers))
+	for name, value := range r.Headers {
+		if headers[name], err = parseTemplate("header "+name, value); err != nil {
+			return mcp.ToolDefinition{}, err
+		}
+	}
+	var body *template.Template
+	if r.Body != "" {
+		if body, err = parseTemplate("body", r.Body); err != nil {
+			return mcp.ToolDefinition{}, err
+		}
+	}
+
+	return mcp.ToolDefinition{
+		Metadata: metadata,
+		Process: func(ctx context.Context, params mcp.CallToolRequestParams, _ mcp.Notifier) (mcp.CallToolResult, error) {
+			if r.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+				defer cancel()
+			}
+			args := map[string]any(params.Arguments)
+			u, err := render(url, args)
+			if err != nil {
+				return mcp.CallToolResult{}, err
+			}
+			var reqBody io.Reader
+			if body != nil {
+				b, err := render(body, args)
+				if err != nil {
+					return mcp.CallToolResult{}, err
+				}
+				reqBody = strings.NewReader(b)
+			}
+			req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+			if err != nil {
+				return mcp.CallToolResult{}, err
+			}
+			for name, t := range headers {
+				v, err := render(t, args)
+				if err != nil {
+					return mcp.CallToolResult{}, err
+				}
+				req.Header.Set(name, v)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return mcp.CallToolResult{}, err
+			}
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return mcp.CallToolResult{}, fmt.Errorf("reading response: %w", err)
+			}
+			if resp.StatusCode >= 400 {
+				return mcp.NewResult().Text(fmt.Sprintf("%s: %s", resp.Status, b)).Error(true).Build()
+			}
+			return mcp.NewResult().Text(string(b)).Build()
+		},
+	}, nil
+}
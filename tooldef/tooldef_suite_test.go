@@ -0,0 +1,13 @@
+package tooldef_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTooldef(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "tooldef Suite")
+}
@@ -0,0 +1,115 @@
+package tooldef_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/tooldef"
+)
+
+var _ = Describe("Load", func() {
+
+	var (
+		tools    []mcp.ToolDefinition
+		requests chan *http.Request
+		body     string
+	)
+
+	BeforeEach(func() {
+		requests = make(chan *http.Request, 1)
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+			requests <- r
+			if r.URL.Query().Get("title") == "" {
+				http.Error(w, "title required", http.StatusUnprocessableEntity)
+				return
+			}
+			w.Write([]byte(`{"number":1}`))
+		}))
+		DeferCleanup(api.Close)
+		GinkgoT().Setenv("TOOLDEF_TEST_URL", api.URL)
+		GinkgoT().Setenv("TOOLDEF_TEST_TOKEN", "s3cr3t")
+
+		var err error
+		tools, err = tooldef.Load("testdata/tools.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tools).To(HaveLen(2))
+	})
+
+	call := func(t mcp.ToolDefinition, args map[string]any) (mcp.CallToolResult, error) {
+		params := mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: args}
+		if t.Process != nil {
+			return t.Process(context.Background(), params, nil)
+		}
+		return t.Execute(params)
+	}
+
+	It("declares tool metadata", func() {
+		Expect(tools[0].Metadata.Name).To(Equal("echo"))
+		Expect(*tools[0].Metadata.Description).To(Equal("Echo a message"))
+		Expect(tools[0].Metadata.InputSchema).To(Equal(mcp.ToolInputSchema{
+			Type:       "object",
+			Properties: mcp.ToolInputSchemaProperties{"message": {"type": "string", "description": "The message to echo"}},
+			Required:   []string{"message"},
+		}))
+		Expect(tools[1].Metadata.InputSchema.Type).To(Equal("object"))
+	})
+
+	It("runs commands", func() {
+		result, err := call(tools[0], map[string]any{"message": "hello"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("hello\n")}))
+	})
+
+	It("makes HTTP requests", func() {
+		result, err := call(tools[1], map[string]any{"title": "Broken build", "labels": []any{"ci"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent(`{"number":1}`)}))
+
+		r := <-requests
+		Expect(r.Method).To(Equal(http.MethodPost))
+		Expect(r.URL.RawQuery).To(Equal("title=Broken+build"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer s3cr3t"))
+		Expect(body).To(MatchJSON(`{"title":"Broken build","labels":["ci"]}`))
+	})
+
+	It("reports error responses as tool errors", func() {
+		result, err := call(tools[1], map[string]any{"title": ""})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*result.IsError).To(BeTrue())
+		Expect(result.Content).To(Equal(mcp.Contents{mcp.NewTextContent("422 Unprocessable Entity: title required\n")}))
+	})
+
+	It("fails calls missing arguments used by templates", func() {
+		_, err := call(tools[1], nil)
+		Expect(err).To(MatchError(ContainSubstring(`rendering url`)))
+	})
+})
+
+var _ = Describe("Parse", func() {
+
+	DescribeTable("rejects invalid declarations",
+		func(doc, message string) {
+			_, err := tooldef.Parse([]byte(doc))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown fields", "tools:\n  - name: a\n    shell: ls\n", "field shell not found"),
+		Entry("no name", "tools:\n  - command: {path: ls}\n", "tool 1 has no name"),
+		Entry("duplicate names", "tools:\n  - {name: a, command: {path: ls}}\n  - {name: a, command: {path: ls}}\n", "tool a is declared more than once"),
+		Entry("no action", "tools:\n  - name: a\n", "tool a: declares no command or HTTP request"),
+		Entry("two actions", "tools:\n  - {name: a, command: {path: ls}, http: {url: x}}\n", "tool a: declares both a command and an HTTP request"),
+		Entry("non-object schemas", "tools:\n  - {name: a, inputSchema: {type: string}, command: {path: ls}}\n", `input schema must be an object, not "string"`),
+		Entry("invalid templates", "tools:\n  - {name: a, http: {url: '{{.x'}}\n", "tool a: parsing url"),
+	)
+
+	It("accepts an empty document", func() {
+		Expect(tooldef.Parse(nil)).To(BeEmpty())
+	})
+})
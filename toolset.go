@@ -0,0 +1,71 @@
+package mcp
+
+import "sync"
+
+// ToolSet is a set of tools that may change while the server runs, such as
+// tools loaded from files, for serving with WithToolSet.
+type ToolSet struct {
+	mu    sync.RWMutex
+	defs  []ToolDefinition
+	tools map[string]ToolDefinition
+}
+
+// NewToolSet returns a set of tools.
+func NewToolSet(tools []ToolDefinition) *ToolSet {
+	s := &ToolSet{}
+	s.Set(tools)
+	return s
+}
+
+// Set replaces the tools of the set. Calls already in progress complete
+// with the tool they started with.
+func (s *ToolSet) Set(tools []ToolDefinition) {
+	defs := append([]ToolDefinition(nil), tools...)
+	byName := make(map[string]ToolDefinition, len(defs))
+	for _, t := range defs {
+		byName[t.Metadata.Name] = t
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defs = defs
+	s.tools = byName
+}
+
+// Tools returns the metadata of the tools currently in the set.
+func (s *ToolSet) Tools() []Tool {
+	defs := s.definitions()
+	tools := make([]Tool, len(defs))
+	for i, t := range defs {
+		tools[i] = t.Metadata
+	}
+	return tools
+}
+
+func (s *ToolSet) definitions() []ToolDefinition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.defs
+}
+
+func (s *ToolSet) tool(name string) (ToolDefinition, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tools[name]
+	return t, ok
+}
+
+// WithToolSet offers the tools of s alongside those passed to NewServer,
+// which take precedence over tools of the same name in the set. Clients are
+// not notified when the tools of the set change.
+func WithToolSet(s *ToolSet) ServerOption {
+	return func(h *handler) {
+		h.toolSources = append(h.toolSources, s)
+	}
+}
+
+// toolSource is a set of tools offered alongside those passed to NewServer,
+// such as a Mirror or ToolSet.
+type toolSource interface {
+	definitions() []ToolDefinition
+	tool(name string) (ToolDefinition, bool)
+}
@@ -0,0 +1,65 @@
+package mcp_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+)
+
+var _ = Describe("ToolSet", func() {
+
+	tool := func(name, text string) mcp.ToolDefinition {
+		return mcp.ToolDefinition{
+			Metadata: mcp.Tool{Name: name, InputSchema: mcp.ToolInputSchema{Type: "object"}},
+			Execute: func(mcp.CallToolRequestParams) (mcp.CallToolResult, error) {
+				return textResult(text), nil
+			},
+		}
+	}
+
+	listedNames := func(c *mcp.Client) []string {
+		tools, err := c.ListTools(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, len(tools))
+		for i, t := range tools {
+			names[i] = t.Name
+		}
+		return names
+	}
+
+	callText := func(c *mcp.Client, name string) string {
+		result, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: name})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Content).To(HaveLen(1))
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	It("offers the tools of the set alongside local ones, preferring local tools", func() {
+		set := mcp.NewToolSet([]mcp.ToolDefinition{tool("search", "set search"), tool("fetch", "set fetch")})
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"},
+			[]mcp.ToolDefinition{tool("search", "local search")}, mcp.WithToolSet(set)))
+
+		Expect(listedNames(c)).To(Equal([]string{"search", "fetch"}))
+		Expect(callText(c, "search")).To(Equal("local search"))
+		Expect(callText(c, "fetch")).To(Equal("set fetch"))
+	})
+
+	It("offers the tools the set is changed to", func() {
+		set := mcp.NewToolSet([]mcp.ToolDefinition{tool("fetch", "old fetch")})
+		c := newClient(mcp.NewServer(mcp.Implementation{Name: "TestServer", Version: "1.0.0"}, nil, mcp.WithToolSet(set)))
+		Expect(callText(c, "fetch")).To(Equal("old fetch"))
+
+		set.Set([]mcp.ToolDefinition{tool("fetch", "new fetch"), tool("search", "new search")})
+		Expect(set.Tools()).To(HaveLen(2))
+		Expect(listedNames(c)).To(Equal([]string{"fetch", "search"}))
+		Expect(callText(c, "fetch")).To(Equal("new fetch"))
+
+		set.Set(nil)
+		Expect(listedNames(c)).To(BeEmpty())
+		_, err := c.CallTool(context.Background(), mcp.CallToolRequestParams{Name: "fetch"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,385 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ContentTransformer rewrites an item of content returned by a tool.
+// Returning nil content drops the item.
+type ContentTransformer func(Content) (Content, error)
+
+// WithContentTransformers applies transformers, in order, to the content
+// returned by every tool, after those configured on the tool itself.
+func WithContentTransformers(transformers ...ContentTransformer) ServerOption {
+	return func(h *handler) {
+		h.transformers = append(h.transformers, transformers...)
+	}
+}
+
+func transformContent(content Contents, transformers ...[]ContentTransformer) (Contents, error) {
+	if len(content) == 0 {
+		return content, nil
+	}
+	out := make(Contents, 0, len(content))
+	for _, c := range content {
+		for _, ts := range transformers {
+			for _, t := range ts {
+				if c == nil {
+					break
+				}
+				var err error
+				if c, err = t(c); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if c != nil {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// contentValue returns the content c points to if c is a pointer, so that
+// transformers treat both forms of content alike.
+func contentValue(c Content) Content {
+	switch c := c.(type) {
+	case *TextContent:
+		return *c
+	case *ImageContent:
+		return *c
+	case *AudioContent:
+		return *c
+	case *EmbeddedResource:
+		return *c
+	case *ResourceLink:
+		return *c
+	}
+	return c
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[@-Z\\-_])`)
+
+// StripANSI removes ANSI terminal escape sequences, such as colours, from
+// text content.
+func StripANSI() ContentTransformer {
+	return func(c Content) (Content, error) {
+		if tc, ok := contentValue(c).(TextContent); ok {
+			tc.Text = ansiEscape.ReplaceAllString(tc.Text, "")
+			return tc, nil
+		}
+		return c, nil
+	}
+}
+
+// maxImagePixels bounds the images DownscaleImages decodes, as a small file
+// can hold an image that takes gigabytes of memory once decoded.
+const maxImagePixels = 40_000_000
+
+// DownscaleImages shrinks PNG and JPEG images larger than maxWidth by
+// maxHeight to fit, preserving their aspect ratio. Images of more than 40
+// million pixels are rejected without being decoded.
+func DownscaleImages(maxWidth, maxHeight int) ContentTransformer {
+	return func(c Content) (Content, error) {
+		ic, ok := contentValue(c).(ImageContent)
+		if !ok || (ic.MimeType != "image/png" && ic.MimeType != "image/jpeg") {
+			return c, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(ic.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding image: %w", err)
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding image: %w", err)
+		}
+		if config.Width <= 0 || config.Height <= 0 {
+			return nil, fmt.Errorf("decoding image: invalid size %dx%d", config.Width, config.Height)
+		}
+		scale := min(float64(maxWidth)/float64(config.Width), float64(maxHeight)/float64(config.Height))
+		if scale >= 1 {
+			return c, nil
+		}
+		if int64(config.Width)*int64(config.Height) > maxImagePixels {
+			return nil, fmt.Errorf("%w: image of %dx%d pixels", ErrContentTooLarge, config.Width, config.Height)
+		}
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding image: %w", err)
+		}
+
+		b := src.Bounds()
+		w := max(1, int(float64(b.Dx())*scale))
+		h := max(1, int(float64(b.Dy())*scale))
+
+		scaled, err := EncodeImageContent(boxScale(src, w, h), ic.MimeType)
+		if err != nil {
+			return nil, err
+		}
+		scaled.Annotations = ic.Annotations
+		return scaled, nil
+	}
+}
+
+// boxScale scales src down to w by h, averaging the source pixels covered by
+// each destination pixel.
+func boxScale(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// HTMLToMarkdown converts HTML documents to Markdown. It applies to text
+// content holding a whole HTML document and to embedded "text/html"
+// resources.
+func HTMLToMarkdown() ContentTransformer {
+	return func(c Content) (Content, error) {
+		switch c := contentValue(c).(type) {
+		case TextContent:
+			lower := strings.ToLower(strings.TrimSpace(c.Text))
+			if !strings.HasPrefix(lower, "<!doctype html") && !strings.HasPrefix(lower, "<html") {
+				return c, nil
+			}
+			md, err := htmlToMarkdown(c.Text)
+			if err != nil {
+				return nil, err
+			}
+			c.Text = md
+			return c, nil
+		case EmbeddedResource:
+			var r TextResourceContents
+			switch res := c.Resource.(type) {
+			case TextResourceContents:
+				r = res
+			case *TextResourceContents:
+				r = *res
+			}
+			if r.MimeType == nil || *r.MimeType != "text/html" {
+				return c, nil
+			}
+			md, err := htmlToMarkdown(r.Text)
+			if err != nil {
+				return nil, err
+			}
+			r.Text = md
+			r.MimeType = optionalString("text/markdown")
+			c.Resource = r
+			return c, nil
+		}
+		return c, nil
+	}
+}
+
+func htmlToMarkdown(s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", fmt.Errorf("parsing HTML: %w", err)
+	}
+	var m markdownWriter
+	m.node(doc)
+	lines := strings.Split(strings.TrimSpace(m.b.String()), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+type markdownWriter struct {
+	b       strings.Builder
+	lists   []int // item counters of enclosing lists, -1 for unordered
+	pre     bool
+	newline bool // whether the output ends at the start of a line
+}
+
+func (m *markdownWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	m.b.WriteString(s)
+	m.newline = strings.HasSuffix(s, "\n")
+}
+
+// block starts a new block separated from preceding content by a blank line.
+func (m *markdownWriter) block() {
+	if m.b.Len() == 0 {
+		return
+	}
+	text := m.b.String()
+	switch {
+	case strings.HasSuffix(text, "\n\n"):
+	case strings.HasSuffix(text, "\n"):
+		m.write("\n")
+	default:
+		m.write("\n\n")
+	}
+}
+
+func (m *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.node(c)
+	}
+}
+
+func (m *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if m.pre {
+			m.write(n.Data)
+			return
+		}
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if strings.TrimLeft(n.Data, " \t\r\n\f") != n.Data {
+			text = " " + text
+		}
+		if text != " " && strings.TrimRight(n.Data, " \t\r\n\f") != n.Data {
+			text += " "
+		}
+		if m.newline || m.b.Len() == 0 {
+			text = strings.TrimLeft(text, " ")
+		}
+		m.write(text)
+		return
+	case html.DocumentNode:
+		m.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "head", "noscript", "template":
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		m.block()
+		m.write(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		m.children(n)
+		m.write("\n\n")
+	case "p", "div", "section", "article", "header", "footer", "main", "table":
+		m.block()
+		m.children(n)
+		m.block()
+	case "tr":
+		m.children(n)
+		m.write("\n")
+	case "td", "th":
+		m.children(n)
+		m.write(" ")
+	case "br":
+		m.write("\n")
+	case "hr":
+		m.block()
+		m.write("---\n\n")
+	case "strong", "b":
+		m.wrap(n, "**")
+	case "em", "i":
+		m.wrap(n, "_")
+	case "code":
+		if m.pre {
+			m.children(n)
+		} else {
+			m.wrap(n, "`")
+		}
+	case "pre":
+		m.block()
+		m.write("```\n")
+		m.pre = true
+		m.children(n)
+		m.pre = false
+		if !m.newline {
+			m.write("\n")
+		}
+		m.write("```\n\n")
+	case "a":
+		href := attr(n, "href")
+		if href == "" {
+			m.children(n)
+			return
+		}
+		m.write("[")
+		m.children(n)
+		m.write("](" + href + ")")
+	case "img":
+		m.write("![" + attr(n, "alt") + "](" + attr(n, "src") + ")")
+	case "ul", "ol":
+		if len(m.lists) == 0 {
+			m.block()
+		} else if !m.newline {
+			m.write("\n")
+		}
+		counter := -1
+		if n.Data == "ol" {
+			counter = 0
+		}
+		m.lists = append(m.lists, counter)
+		m.children(n)
+		m.lists = m.lists[:len(m.lists)-1]
+		if len(m.lists) == 0 {
+			m.block()
+		}
+	case "li":
+		if !m.newline && m.b.Len() > 0 {
+			m.write("\n")
+		}
+		depth := len(m.lists) - 1
+		marker := "- "
+		if depth >= 0 && m.lists[depth] >= 0 {
+			m.lists[depth]++
+			marker = fmt.Sprintf("%d. ", m.lists[depth])
+		}
+		m.write(strings.Repeat("  ", max(depth, 0)) + marker)
+		m.children(n)
+		if !m.newline {
+			m.write("\n")
+		}
+	case "blockquote":
+		m.block()
+		var inner markdownWriter
+		inner.children(n)
+		for _, line := range strings.Split(strings.TrimSpace(inner.b.String()), "\n") {
+			m.write(strings.TrimRight("> "+line, " ") + "\n")
+		}
+		m.write("\n")
+	default:
+		m.children(n)
+	}
+}
+
+func (m *markdownWriter) wrap(n *html.Node, marker string) {
+	m.write(marker)
+	m.children(n)
+	m.write(marker)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
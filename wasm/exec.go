@@ -0,0 +1,860 @@
+package wasm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Opcodes. Those prefixed by 0xfc are held as 0xfc00 plus their index.
+const (
+	opUnreachable  = 0x00
+	opNop          = 0x01
+	opBlock        = 0x02
+	opLoop         = 0x03
+	opIf           = 0x04
+	opElse         = 0x05
+	opEnd          = 0x0b
+	opBr           = 0x0c
+	opBrIf         = 0x0d
+	opBrTable      = 0x0e
+	opReturn       = 0x0f
+	opCall         = 0x10
+	opCallIndirect = 0x11
+	opDrop         = 0x1a
+	opSelect       = 0x1b
+	opSelectTyped  = 0x1c
+	opLocalGet     = 0x20
+	opLocalSet     = 0x21
+	opLocalTee     = 0x22
+	opGlobalGet    = 0x23
+	opGlobalSet    = 0x24
+	opI32Load      = 0x28
+	opI64Store32   = 0x3e
+	opMemorySize   = 0x3f
+	opMemoryGrow   = 0x40
+	opI32Const     = 0x41
+	opI64Const     = 0x42
+	opF32Const     = 0x43
+	opF64Const     = 0x44
+	opI32Eqz       = 0x45
+	opI64Extend32S = 0xc4
+	opPrefix       = 0xfc
+	opTruncSat     = 0xfc00
+	opTruncSatEnd  = 0xfc07
+	opMemoryCopy   = 0xfc0a
+	opMemoryFill   = 0xfc0b
+)
+
+// maxCallDepth limits the nesting of calls, so that runaway recursion traps
+// rather than exhausting the host's stack.
+const maxCallDepth = 10000
+
+// maxLocals limits the locals of a function.
+const maxLocals = 50000
+
+// inst is a decoded instruction. For blocks, loops and ifs, end is the index
+// of the matching end and, for ifs with an else, alt is the index of the
+// else; for elses, end is the index of the end of the if.
+type inst struct {
+	op              uint16
+	imm             uint64
+	end, alt        int
+	params, results int
+	targets         []uint32
+}
+
+// compile decodes the body of f.
+func (m *module) compile(f *function, body []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	r := &reader{b: body}
+	for n := r.u32(); n > 0; n-- {
+		count := r.u32()
+		r.valueType()
+		if f.numLocals += int(count); f.numLocals > maxLocals {
+			return fmt.Errorf("more than %d locals", maxLocals)
+		}
+	}
+
+	var open []int
+	for {
+		if len(r.b) == 0 {
+			return errors.New("missing end of function")
+		}
+		i := inst{op: uint16(r.byte())}
+		switch {
+		case i.op == opBlock || i.op == opLoop || i.op == opIf:
+			i.params, i.results = m.blockType(r)
+			open = append(open, len(f.code))
+		case i.op == opElse:
+			if len(open) == 0 || f.code[open[len(open)-1]].op != opIf || f.code[open[len(open)-1]].alt != 0 {
+				return errors.New("else outside an if")
+			}
+			f.code[open[len(open)-1]].alt = len(f.code)
+		case i.op == opEnd:
+			if len(open) == 0 {
+				f.code = append(f.code, i)
+				if len(r.b) > 0 {
+					return errors.New("instructions after the end of the function")
+				}
+				return nil
+			}
+			start := &f.code[open[len(open)-1]]
+			open = open[:len(open)-1]
+			start.end = len(f.code)
+			if start.op == opIf && start.alt != 0 {
+				f.code[start.alt].end = len(f.code)
+			}
+		case i.op == opBr || i.op == opBrIf || i.op == opCall || i.op == opLocalGet ||
+			i.op == opLocalSet || i.op == opLocalTee || i.op == opGlobalGet || i.op == opGlobalSet:
+			i.imm = uint64(r.u32())
+		case i.op == opBrTable:
+			for n := r.u32(); n > 0; n-- {
+				i.targets = append(i.targets, r.u32())
+			}
+			i.imm = uint64(r.u32())
+		case i.op == opCallIndirect:
+			i.imm = uint64(r.u32())
+			if r.byte() != 0 {
+				return errors.New("call_indirect refers to a table other than 0")
+			}
+		case i.op == opSelectTyped:
+			r.valueTypes()
+			i.op = opSelect
+		case i.op >= opI32Load && i.op <= opI64Store32:
+			r.u32()
+			i.imm = uint64(r.u32())
+		case i.op == opMemorySize || i.op == opMemoryGrow:
+			r.byte()
+		case i.op == opI32Const:
+			i.imm = uint64(uint32(int32(r.sleb(32))))
+		case i.op == opI64Const:
+			i.imm = uint64(r.sleb(64))
+		case i.op == opF32Const:
+			i.imm = uint64(binary.LittleEndian.Uint32(r.bytes(4)))
+		case i.op == opF64Const:
+			i.imm = binary.LittleEndian.Uint64(r.bytes(8))
+		case i.op == opPrefix:
+			i.op = opPrefix<<8 | uint16(r.u32())
+			switch {
+			case i.op >= opTruncSat && i.op <= opTruncSatEnd:
+			case i.op == opMemoryCopy:
+				r.bytes(2)
+			case i.op == opMemoryFill:
+				r.byte()
+			default:
+				return fmt.Errorf("unsupported instruction 0xfc %d", i.op&0xff)
+			}
+		case i.op == opUnreachable || i.op == opNop || i.op == opReturn || i.op == opDrop ||
+			i.op == opSelect || i.op >= opI32Eqz && i.op <= opI64Extend32S:
+		default:
+			return fmt.Errorf("unsupported instruction 0x%02x", i.op)
+		}
+		f.code = append(f.code, i)
+	}
+}
+
+// blockType reads the type of a block, returning the number of its params
+// and results.
+func (m *module) blockType(r *reader) (int, int) {
+	switch r.b[0] {
+	case 0x40:
+		r.byte()
+		return 0, 0
+	case typeI32, typeI64, typeF32, typeF64:
+		r.byte()
+		return 0, 1
+	}
+	i := r.sleb(33)
+	if i < 0 {
+		panic(fmt.Errorf("invalid block type %d", i))
+	}
+	t := m.typ(uint32(i))
+	return len(t.params), len(t.results)
+}
+
+// trap is raised by panicking while a module runs, and returned as an
+// error.
+type trap struct {
+	err error
+}
+
+var (
+	errUnreachable      = errors.New("wasm trap: unreachable")
+	errOutOfBounds      = errors.New("wasm trap: out of bounds memory access")
+	errDivideByZero     = errors.New("wasm trap: integer divide by zero")
+	errIntegerOverflow  = errors.New("wasm trap: integer overflow")
+	errInvalidConv      = errors.New("wasm trap: invalid conversion to integer")
+	errUndefinedElement = errors.New("wasm trap: undefined element")
+	errTypeMismatch     = errors.New("wasm trap: indirect call type mismatch")
+	errCallStack        = errors.New("wasm trap: call stack exhausted")
+)
+
+// ErrInstructionLimit is returned by calls that execute more instructions
+// than Config.MaxInstructions allows.
+var ErrInstructionLimit = errors.New("wasm: instruction limit exceeded")
+
+type hostFunc func(in *instance, args []uint64) []uint64
+
+type instance struct {
+	m        *module
+	ctx      context.Context
+	host     []hostFunc
+	memory   []byte
+	maxPages uint32
+	globals  []uint64
+	table    []int64
+	budget   uint64
+	depth    int
+	// state holds what host functions need of the call.
+	state any
+}
+
+type label struct {
+	// cont is the index of the instruction that a branch to the label
+	// continues at.
+	cont          int
+	height, arity int
+	loop          bool
+}
+
+// run calls the function fn, returning traps as errors.
+func (in *instance) run(fn uint32, args ...uint64) (results []uint64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if t, ok := r.(trap); ok {
+				err = t.err
+				return
+			}
+			// the module is not validated, so invalid code fails here
+			err = fmt.Errorf("wasm trap: %v", r)
+		}
+	}()
+	return in.invoke(fn, args), nil
+}
+
+func (in *instance) invoke(fn uint32, args []uint64) []uint64 {
+	f := in.m.funcs[fn]
+	if in.host[fn] != nil {
+		return in.host[fn](in, args)
+	}
+	if in.depth++; in.depth > maxCallDepth {
+		panic(trap{errCallStack})
+	}
+	defer func() { in.depth-- }()
+
+	locals := make([]uint64, len(f.typ.params)+f.numLocals)
+	copy(locals, args)
+	stack := make([]uint64, 0, 16)
+	labels := []label{{cont: len(f.code), arity: len(f.typ.results)}}
+	code := f.code
+	for pc := 0; pc < len(code); pc++ {
+		in.tick()
+		i := &code[pc]
+		n := len(stack)
+		switch i.op {
+		case opUnreachable:
+			panic(trap{errUnreachable})
+		case opNop:
+		case opBlock:
+			labels = append(labels, label{cont: i.end + 1, height: n - i.params, arity: i.results})
+		case opLoop:
+			labels = append(labels, label{cont: pc + 1, height: n - i.params, arity: i.params, loop: true})
+		case opIf:
+			cond := uint32(stack[n-1])
+			stack = stack[:n-1]
+			labels = append(labels, label{cont: i.end + 1, height: n - 1 - i.params, arity: i.results})
+			if cond == 0 {
+				if i.alt != 0 {
+					pc = i.alt
+				} else {
+					pc = i.end - 1
+				}
+			}
+		case opElse:
+			pc = i.end - 1
+		case opEnd:
+			labels = labels[:len(labels)-1]
+		case opBr, opBrIf, opBrTable, opReturn:
+			depth := i.imm
+			switch i.op {
+			case opBrIf:
+				cond := uint32(stack[n-1])
+				stack = stack[:n-1]
+				if cond == 0 {
+					continue
+				}
+			case opBrTable:
+				index := uint32(stack[n-1])
+				stack = stack[:n-1]
+				if index < uint32(len(i.targets)) {
+					depth = uint64(i.targets[index])
+				}
+			case opReturn:
+				depth = uint64(len(labels) - 1)
+			}
+			l := labels[len(labels)-1-int(depth)]
+			stack = append(stack[:l.height], stack[len(stack)-l.arity:]...)
+			if l.loop {
+				labels = labels[:len(labels)-int(depth)]
+			} else {
+				labels = labels[:len(labels)-1-int(depth)]
+			}
+			pc = l.cont - 1
+		case opCall, opCallIndirect:
+			callee := uint32(i.imm)
+			if i.op == opCallIndirect {
+				index := uint32(stack[n-1])
+				stack = stack[:n-1]
+				if index >= uint32(len(in.table)) || in.table[index] < 0 {
+					panic(trap{errUndefinedElement})
+				}
+				callee = uint32(in.table[index])
+				if !in.m.funcs[callee].typ.equal(in.m.typ(uint32(i.imm))) {
+					panic(trap{errTypeMismatch})
+				}
+			}
+			params := len(in.m.funcs[callee].typ.params)
+			args := make([]uint64, params)
+			copy(args, stack[len(stack)-params:])
+			stack = append(stack[:len(stack)-params], in.invoke(callee, args)...)
+		case opDrop:
+			stack = stack[:n-1]
+		case opSelect:
+			if uint32(stack[n-1]) == 0 {
+				stack[n-3] = stack[n-2]
+			}
+			stack = stack[:n-2]
+		case opLocalGet:
+			stack = append(stack, locals[i.imm])
+		case opLocalSet:
+			locals[i.imm] = stack[n-1]
+			stack = stack[:n-1]
+		case opLocalTee:
+			locals[i.imm] = stack[n-1]
+		case opGlobalGet:
+			stack = append(stack, in.globals[i.imm])
+		case opGlobalSet:
+			in.globals[i.imm] = stack[n-1]
+			stack = stack[:n-1]
+		case opMemorySize:
+			stack = append(stack, uint64(len(in.memory)/pageSize))
+		case opMemoryGrow:
+			pages := uint64(uint32(stack[n-1]))
+			old := uint64(len(in.memory) / pageSize)
+			if old+pages > uint64(in.maxPages) {
+				stack[n-1] = uint64(math.MaxUint32)
+				break
+			}
+			in.memory = append(in.memory, make([]byte, pages*pageSize)...)
+			stack[n-1] = old
+		case opI32Const, opI64Const, opF32Const, opF64Const:
+			stack = append(stack, i.imm)
+		case opMemoryCopy:
+			size := uint64(uint32(stack[n-1]))
+			src := in.address(stack[n-2], 0, size)
+			dst := in.address(stack[n-3], 0, size)
+			stack = stack[:n-3]
+			copy(in.memory[dst:dst+size], in.memory[src:src+size])
+		case opMemoryFill:
+			size := uint64(uint32(stack[n-1]))
+			value := byte(stack[n-2])
+			dst := in.address(stack[n-3], 0, size)
+			stack = stack[:n-3]
+			for k := dst; k < dst+size; k++ {
+				in.memory[k] = value
+			}
+		default:
+			if i.op >= opI32Load && i.op <= opI64Store32 {
+				stack = in.memoryOp(i, stack)
+			} else {
+				stack = numeric(i.op, stack)
+			}
+		}
+	}
+	return stack[len(stack)-len(f.typ.results):]
+}
+
+// tick counts an instruction against the budget of the call, checking for
+// cancellation every few thousand.
+func (in *instance) tick() {
+	if in.budget == 0 {
+		panic(trap{ErrInstructionLimit})
+	}
+	in.budget--
+	if in.budget%4096 == 0 && in.ctx.Err() != nil {
+		panic(trap{in.ctx.Err()})
+	}
+}
+
+// address returns the address of the size bytes at offset from base,
+// trapping if they are out of bounds.
+func (in *instance) address(base, offset, size uint64) uint64 {
+	a := uint64(uint32(base)) + offset
+	if a+size > uint64(len(in.memory)) {
+		panic(trap{errOutOfBounds})
+	}
+	return a
+}
+
+func (in *instance) memoryOp(i *inst, stack []uint64) []uint64 {
+	m := in.memory
+	if i.op >= 0x36 {
+		v := stack[len(stack)-1]
+		base := stack[len(stack)-2]
+		stack = stack[:len(stack)-2]
+		switch i.op {
+		case 0x36, 0x38, 0x3e: // i32.store, f32.store, i64.store32
+			binary.LittleEndian.PutUint32(m[in.address(base, i.imm, 4):], uint32(v))
+		case 0x37, 0x39: // i64.store, f64.store
+			binary.LittleEndian.PutUint64(m[in.address(base, i.imm, 8):], v)
+		case 0x3a, 0x3c: // i32.store8, i64.store8
+			m[in.address(base, i.imm, 1)] = byte(v)
+		case 0x3b, 0x3d: // i32.store16, i64.store16
+			binary.LittleEndian.PutUint16(m[in.address(base, i.imm, 2):], uint16(v))
+		}
+		return stack
+	}
+	base := stack[len(stack)-1]
+	var v uint64
+	switch i.op {
+	case 0x28, 0x2a: // i32.load, f32.load
+		v = uint64(binary.LittleEndian.Uint32(m[in.address(base, i.imm, 4):]))
+	case 0x29, 0x2b: // i64.load, f64.load
+		v = binary.LittleEndian.Uint64(m[in.address(base, i.imm, 8):])
+	case 0x2c: // i32.load8_s
+		v = uint64(uint32(int32(int8(m[in.address(base, i.imm, 1)]))))
+	case 0x2d, 0x31: // i32.load8_u, i64.load8_u
+		v = uint64(m[in.address(base, i.imm, 1)])
+	case 0x2e: // i32.load16_s
+		v = uint64(uint32(int32(int16(binary.LittleEndian.Uint16(m[in.address(base, i.imm, 2):])))))
+	case 0x2f, 0x33: // i32.load16_u, i64.load16_u
+		v = uint64(binary.LittleEndian.Uint16(m[in.address(base, i.imm, 2):]))
+	case 0x30: // i64.load8_s
+		v = uint64(int64(int8(m[in.address(base, i.imm, 1)])))
+	case 0x32: // i64.load16_s
+		v = uint64(int64(int16(binary.LittleEndian.Uint16(m[in.address(base, i.imm, 2):]))))
+	case 0x34: // i64.load32_s
+		v = uint64(int64(int32(binary.LittleEndian.Uint32(m[in.address(base, i.imm, 4):]))))
+	case 0x35: // i64.load32_u
+		v = uint64(binary.LittleEndian.Uint32(m[in.address(base, i.imm, 4):]))
+	}
+	stack[len(stack)-1] = v
+	return stack
+}
+
+func f32(v uint64) float32     { return math.Float32frombits(uint32(v)) }
+func f64(v uint64) float64     { return math.Float64frombits(v) }
+func fromF32(f float32) uint64 { return uint64(math.Float32bits(f)) }
+func fromF64(f float64) uint64 { return math.Float64bits(f) }
+
+// fmin and fmax differ from math.Min and math.Max in returning NaN when
+// either operand is NaN, even if the other is infinite.
+func fmin(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+	return math.Min(a, b)
+}
+
+func fmax(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+	return math.Max(a, b)
+}
+
+func boolean(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// numeric executes the numeric instruction op on stack.
+func numeric(op uint16, stack []uint64) []uint64 {
+	n := len(stack)
+	switch op {
+	// unary operations replace the top of the stack
+	case 0x45: // i32.eqz
+		stack[n-1] = boolean(uint32(stack[n-1]) == 0)
+	case 0x50: // i64.eqz
+		stack[n-1] = boolean(stack[n-1] == 0)
+	case 0x67: // i32.clz
+		stack[n-1] = uint64(bits.LeadingZeros32(uint32(stack[n-1])))
+	case 0x68: // i32.ctz
+		stack[n-1] = uint64(bits.TrailingZeros32(uint32(stack[n-1])))
+	case 0x69: // i32.popcnt
+		stack[n-1] = uint64(bits.OnesCount32(uint32(stack[n-1])))
+	case 0x79: // i64.clz
+		stack[n-1] = uint64(bits.LeadingZeros64(stack[n-1]))
+	case 0x7a: // i64.ctz
+		stack[n-1] = uint64(bits.TrailingZeros64(stack[n-1]))
+	case 0x7b: // i64.popcnt
+		stack[n-1] = uint64(bits.OnesCount64(stack[n-1]))
+	case 0x8b: // f32.abs
+		stack[n-1] = stack[n-1] &^ (1 << 31)
+	case 0x8c: // f32.neg
+		stack[n-1] = uint64(uint32(stack[n-1]) ^ (1 << 31))
+	case 0x8d: // f32.ceil
+		stack[n-1] = fromF32(float32(math.Ceil(float64(f32(stack[n-1])))))
+	case 0x8e: // f32.floor
+		stack[n-1] = fromF32(float32(math.Floor(float64(f32(stack[n-1])))))
+	case 0x8f: // f32.trunc
+		stack[n-1] = fromF32(float32(math.Trunc(float64(f32(stack[n-1])))))
+	case 0x90: // f32.nearest
+		stack[n-1] = fromF32(float32(math.RoundToEven(float64(f32(stack[n-1])))))
+	case 0x91: // f32.sqrt
+		stack[n-1] = fromF32(float32(math.Sqrt(float64(f32(stack[n-1])))))
+	case 0x99: // f64.abs
+		stack[n-1] = stack[n-1] &^ (1 << 63)
+	case 0x9a: // f64.neg
+		stack[n-1] = stack[n-1] ^ (1 << 63)
+	case 0x9b: // f64.ceil
+		stack[n-1] = fromF64(math.Ceil(f64(stack[n-1])))
+	case 0x9c: // f64.floor
+		stack[n-1] = fromF64(math.Floor(f64(stack[n-1])))
+	case 0x9d: // f64.trunc
+		stack[n-1] = fromF64(math.Trunc(f64(stack[n-1])))
+	case 0x9e: // f64.nearest
+		stack[n-1] = fromF64(math.RoundToEven(f64(stack[n-1])))
+	case 0x9f: // f64.sqrt
+		stack[n-1] = fromF64(math.Sqrt(f64(stack[n-1])))
+	case 0xa7: // i32.wrap_i64
+		stack[n-1] = uint64(uint32(stack[n-1]))
+	case 0xa8: // i32.trunc_f32_s
+		stack[n-1] = uint64(uint32(int32(truncS(float64(f32(stack[n-1])), 32))))
+	case 0xa9: // i32.trunc_f32_u
+		stack[n-1] = truncU(float64(f32(stack[n-1])), 32)
+	case 0xaa: // i32.trunc_f64_s
+		stack[n-1] = uint64(uint32(int32(truncS(f64(stack[n-1]), 32))))
+	case 0xab: // i32.trunc_f64_u
+		stack[n-1] = truncU(f64(stack[n-1]), 32)
+	case 0xac: // i64.extend_i32_s
+		stack[n-1] = uint64(int64(int32(stack[n-1])))
+	case 0xad: // i64.extend_i32_u
+		stack[n-1] = uint64(uint32(stack[n-1]))
+	case 0xae: // i64.trunc_f32_s
+		stack[n-1] = uint64(truncS(float64(f32(stack[n-1])), 64))
+	case 0xaf: // i64.trunc_f32_u
+		stack[n-1] = truncU(float64(f32(stack[n-1])), 64)
+	case 0xb0: // i64.trunc_f64_s
+		stack[n-1] = uint64(truncS(f64(stack[n-1]), 64))
+	case 0xb1: // i64.trunc_f64_u
+		stack[n-1] = truncU(f64(stack[n-1]), 64)
+	case 0xb2: // f32.convert_i32_s
+		stack[n-1] = fromF32(float32(int32(stack[n-1])))
+	case 0xb3: // f32.convert_i32_u
+		stack[n-1] = fromF32(float32(uint32(stack[n-1])))
+	case 0xb4: // f32.convert_i64_s
+		stack[n-1] = fromF32(float32(int64(stack[n-1])))
+	case 0xb5: // f32.convert_i64_u
+		stack[n-1] = fromF32(float32(stack[n-1]))
+	case 0xb6: // f32.demote_f64
+		stack[n-1] = fromF32(float32(f64(stack[n-1])))
+	case 0xb7: // f64.convert_i32_s
+		stack[n-1] = fromF64(float64(int32(stack[n-1])))
+	case 0xb8: // f64.convert_i32_u
+		stack[n-1] = fromF64(float64(uint32(stack[n-1])))
+	case 0xb9: // f64.convert_i64_s
+		stack[n-1] = fromF64(float64(int64(stack[n-1])))
+	case 0xba: // f64.convert_i64_u
+		stack[n-1] = fromF64(float64(stack[n-1]))
+	case 0xbb: // f64.promote_f32
+		stack[n-1] = fromF64(float64(f32(stack[n-1])))
+	case 0xbc, 0xbe: // i32.reinterpret_f32, f32.reinterpret_i32
+		stack[n-1] = uint64(uint32(stack[n-1]))
+	case 0xbd, 0xbf: // i64.reinterpret_f64, f64.reinterpret_i64
+	case 0xc0: // i32.extend8_s
+		stack[n-1] = uint64(uint32(int32(int8(stack[n-1]))))
+	case 0xc1: // i32.extend16_s
+		stack[n-1] = uint64(uint32(int32(int16(stack[n-1]))))
+	case 0xc2: // i64.extend8_s
+		stack[n-1] = uint64(int64(int8(stack[n-1])))
+	case 0xc3: // i64.extend16_s
+		stack[n-1] = uint64(int64(int16(stack[n-1])))
+	case 0xc4: // i64.extend32_s
+		stack[n-1] = uint64(int64(int32(stack[n-1])))
+	case 0xfc00: // i32.trunc_sat_f32_s
+		stack[n-1] = uint64(uint32(int32(truncSatS(float64(f32(stack[n-1])), 32))))
+	case 0xfc01: // i32.trunc_sat_f32_u
+		stack[n-1] = truncSatU(float64(f32(stack[n-1])), 32)
+	case 0xfc02: // i32.trunc_sat_f64_s
+		stack[n-1] = uint64(uint32(int32(truncSatS(f64(stack[n-1]), 32))))
+	case 0xfc03: // i32.trunc_sat_f64_u
+		stack[n-1] = truncSatU(f64(stack[n-1]), 32)
+	case 0xfc04: // i64.trunc_sat_f32_s
+		stack[n-1] = uint64(truncSatS(float64(f32(stack[n-1])), 64))
+	case 0xfc05: // i64.trunc_sat_f32_u
+		stack[n-1] = truncSatU(float64(f32(stack[n-1])), 64)
+	case 0xfc06: // i64.trunc_sat_f64_s
+		stack[n-1] = uint64(truncSatS(f64(stack[n-1]), 64))
+	case 0xfc07: // i64.trunc_sat_f64_u
+		stack[n-1] = truncSatU(f64(stack[n-1]), 64)
+	default:
+		// binary operations replace their operands with the result
+		stack[n-2] = binaryOp(op, stack[n-2], stack[n-1])
+		return stack[:n-1]
+	}
+	return stack
+}
+
+func binaryOp(op uint16, a, b uint64) uint64 {
+	a32, b32 := uint32(a), uint32(b)
+	switch op {
+	case 0x46: // i32.eq
+		return boolean(a32 == b32)
+	case 0x47: // i32.ne
+		return boolean(a32 != b32)
+	case 0x48: // i32.lt_s
+		return boolean(int32(a32) < int32(b32))
+	case 0x49: // i32.lt_u
+		return boolean(a32 < b32)
+	case 0x4a: // i32.gt_s
+		return boolean(int32(a32) > int32(b32))
+	case 0x4b: // i32.gt_u
+		return boolean(a32 > b32)
+	case 0x4c: // i32.le_s
+		return boolean(int32(a32) <= int32(b32))
+	case 0x4d: // i32.le_u
+		return boolean(a32 <= b32)
+	case 0x4e: // i32.ge_s
+		return boolean(int32(a32) >= int32(b32))
+	case 0x4f: // i32.ge_u
+		return boolean(a32 >= b32)
+	case 0x51: // i64.eq
+		return boolean(a == b)
+	case 0x52: // i64.ne
+		return boolean(a != b)
+	case 0x53: // i64.lt_s
+		return boolean(int64(a) < int64(b))
+	case 0x54: // i64.lt_u
+		return boolean(a < b)
+	case 0x55: // i64.gt_s
+		return boolean(int64(a) > int64(b))
+	case 0x56: // i64.gt_u
+		return boolean(a > b)
+	case 0x57: // i64.le_s
+		return boolean(int64(a) <= int64(b))
+	case 0x58: // i64.le_u
+		return boolean(a <= b)
+	case 0x59: // i64.ge_s
+		return boolean(int64(a) >= int64(b))
+	case 0x5a: // i64.ge_u
+		return boolean(a >= b)
+	case 0x5b: // f32.eq
+		return boolean(f32(a) == f32(b))
+	case 0x5c: // f32.ne
+		return boolean(f32(a) != f32(b))
+	case 0x5d: // f32.lt
+		return boolean(f32(a) < f32(b))
+	case 0x5e: // f32.gt
+		return boolean(f32(a) > f32(b))
+	case 0x5f: // f32.le
+		return boolean(f32(a) <= f32(b))
+	case 0x60: // f32.ge
+		return boolean(f32(a) >= f32(b))
+	case 0x61: // f64.eq
+		return boolean(f64(a) == f64(b))
+	case 0x62: // f64.ne
+		return boolean(f64(a) != f64(b))
+	case 0x63: // f64.lt
+		return boolean(f64(a) < f64(b))
+	case 0x64: // f64.gt
+		return boolean(f64(a) > f64(b))
+	case 0x65: // f64.le
+		return boolean(f64(a) <= f64(b))
+	case 0x66: // f64.ge
+		return boolean(f64(a) >= f64(b))
+	case 0x6a: // i32.add
+		return uint64(a32 + b32)
+	case 0x6b: // i32.sub
+		return uint64(a32 - b32)
+	case 0x6c: // i32.mul
+		return uint64(a32 * b32)
+	case 0x6d: // i32.div_s
+		if b32 == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int32(a32) == math.MinInt32 && int32(b32) == -1 {
+			panic(trap{errIntegerOverflow})
+		}
+		return uint64(uint32(int32(a32) / int32(b32)))
+	case 0x6e: // i32.div_u
+		if b32 == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return uint64(a32 / b32)
+	case 0x6f: // i32.rem_s
+		if b32 == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int32(b32) == -1 {
+			return 0
+		}
+		return uint64(uint32(int32(a32) % int32(b32)))
+	case 0x70: // i32.rem_u
+		if b32 == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return uint64(a32 % b32)
+	case 0x71: // i32.and
+		return uint64(a32 & b32)
+	case 0x72: // i32.or
+		return uint64(a32 | b32)
+	case 0x73: // i32.xor
+		return uint64(a32 ^ b32)
+	case 0x74: // i32.shl
+		return uint64(a32 << (b32 & 31))
+	case 0x75: // i32.shr_s
+		return uint64(uint32(int32(a32) >> (b32 & 31)))
+	case 0x76: // i32.shr_u
+		return uint64(a32 >> (b32 & 31))
+	case 0x77: // i32.rotl
+		return uint64(bits.RotateLeft32(a32, int(b32&31)))
+	case 0x78: // i32.rotr
+		return uint64(bits.RotateLeft32(a32, -int(b32&31)))
+	case 0x7c: // i64.add
+		return a + b
+	case 0x7d: // i64.sub
+		return a - b
+	case 0x7e: // i64.mul
+		return a * b
+	case 0x7f: // i64.div_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			panic(trap{errIntegerOverflow})
+		}
+		return uint64(int64(a) / int64(b))
+	case 0x80: // i64.div_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a / b
+	case 0x81: // i64.rem_s
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case 0x82: // i64.rem_u
+		if b == 0 {
+			panic(trap{errDivideByZero})
+		}
+		return a % b
+	case 0x83: // i64.and
+		return a & b
+	case 0x84: // i64.or
+		return a | b
+	case 0x85: // i64.xor
+		return a ^ b
+	case 0x86: // i64.shl
+		return a << (b & 63)
+	case 0x87: // i64.shr_s
+		return uint64(int64(a) >> (b & 63))
+	case 0x88: // i64.shr_u
+		return a >> (b & 63)
+	case 0x89: // i64.rotl
+		return bits.RotateLeft64(a, int(b&63))
+	case 0x8a: // i64.rotr
+		return bits.RotateLeft64(a, -int(b&63))
+	case 0x92: // f32.add
+		return fromF32(f32(a) + f32(b))
+	case 0x93: // f32.sub
+		return fromF32(f32(a) - f32(b))
+	case 0x94: // f32.mul
+		return fromF32(f32(a) * f32(b))
+	case 0x95: // f32.div
+		return fromF32(f32(a) / f32(b))
+	case 0x96: // f32.min
+		return fromF32(float32(fmin(float64(f32(a)), float64(f32(b)))))
+	case 0x97: // f32.max
+		return fromF32(float32(fmax(float64(f32(a)), float64(f32(b)))))
+	case 0x98: // f32.copysign
+		return uint64(a32&^(1<<31) | b32&(1<<31))
+	case 0xa0: // f64.add
+		return fromF64(f64(a) + f64(b))
+	case 0xa1: // f64.sub
+		return fromF64(f64(a) - f64(b))
+	case 0xa2: // f64.mul
+		return fromF64(f64(a) * f64(b))
+	case 0xa3: // f64.div
+		return fromF64(f64(a) / f64(b))
+	case 0xa4: // f64.min
+		return fromF64(fmin(f64(a), f64(b)))
+	case 0xa5: // f64.max
+		return fromF64(fmax(f64(a), f64(b)))
+	case 0xa6: // f64.copysign
+		return a&^(1<<63) | b&(1<<63)
+	}
+	panic(fmt.Errorf("unsupported instruction 0x%02x", op))
+}
+
+// truncS truncates x to a signed integer of size bits, trapping if it is
+// not representable.
+func truncS(x float64, size int) int64 {
+	if math.IsNaN(x) {
+		panic(trap{errInvalidConv})
+	}
+	x = math.Trunc(x)
+	limit := math.Ldexp(1, size-1)
+	if x < -limit || x >= limit {
+		panic(trap{errIntegerOverflow})
+	}
+	return int64(x)
+}
+
+// truncU truncates x to an unsigned integer of size bits, trapping if it is
+// not representable.
+func truncU(x float64, size int) uint64 {
+	if math.IsNaN(x) {
+		panic(trap{errInvalidConv})
+	}
+	x = math.Trunc(x)
+	if x < 0 || x >= math.Ldexp(1, size) {
+		panic(trap{errIntegerOverflow})
+	}
+	return uint64(x)
+}
+
+// truncSatS truncates x to a signed integer of size bits, saturating at
+// its bounds.
+func truncSatS(x float64, size int) int64 {
+	limit := math.Ldexp(1, size-1)
+	switch {
+	case math.IsNaN(x):
+		return 0
+	case x < -limit:
+		return -1 << (size - 1)
+	case x >= limit:
+		return 1<<(size-1) - 1
+	}
+	return int64(x)
+}
+
+// truncSatU truncates x to an unsigned integer of size bits, saturating at
+// its bounds.
+func truncSatU(x float64, size int) uint64 {
+	switch {
+	case math.IsNaN(x) || x <= 0:
+		return 0
+	case x >= math.Ldexp(1, size):
+		return math.MaxUint64 >> (64 - size)
+	}
+	return uint64(x)
+}
@@ -0,0 +1,357 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Value types.
+const (
+	typeI32 = 0x7f
+	typeI64 = 0x7e
+	typeF32 = 0x7d
+	typeF64 = 0x7c
+)
+
+// Kinds of imports and exports.
+const (
+	externFunc   = 0
+	externMemory = 2
+)
+
+const pageSize = 64 << 10
+
+var magic = []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+type funcType struct {
+	params, results []byte
+}
+
+func (t funcType) equal(o funcType) bool {
+	return bytes.Equal(t.params, o.params) && bytes.Equal(t.results, o.results)
+}
+
+func (t funcType) String() string {
+	names := map[byte]string{typeI32: "i32", typeI64: "i64", typeF32: "f32", typeF64: "f64"}
+	s := "("
+	for i, p := range t.params {
+		if i > 0 {
+			s += ", "
+		}
+		s += names[p]
+	}
+	s += ")"
+	for i, r := range t.results {
+		if i == 0 {
+			s += " -> "
+		} else {
+			s += ", "
+		}
+		s += names[r]
+	}
+	return s
+}
+
+type function struct {
+	typ funcType
+	// importModule and importName name the host function of imported
+	// functions, which have no code.
+	importModule, importName string
+	numLocals                int
+	code                     []inst
+}
+
+type global struct {
+	typ     byte
+	mutable bool
+	init    constExpr
+}
+
+// constExpr is an initializer: a constant, or the value of an imported
+// global when fromGlobal is set.
+type constExpr struct {
+	value      uint64
+	fromGlobal bool
+	global     uint32
+}
+
+type segment struct {
+	offset constExpr
+	funcs  []uint32
+	data   []byte
+}
+
+type export struct {
+	kind  byte
+	index uint32
+}
+
+// module is a decoded WebAssembly module.
+type module struct {
+	types     []funcType
+	funcs     []*function
+	tableMin  uint32
+	hasTable  bool
+	memMin    uint32
+	memMax    uint32
+	hasMemMax bool
+	hasMemory bool
+	globals   []global
+	exports   map[string]export
+	start     *uint32
+	elements  []segment
+	data      []segment
+}
+
+type reader struct {
+	b []byte
+}
+
+var errEOF = errors.New("unexpected end of module")
+
+func (r *reader) byte() byte {
+	if len(r.b) == 0 {
+		panic(errEOF)
+	}
+	c := r.b[0]
+	r.b = r.b[1:]
+	return c
+}
+
+func (r *reader) bytes(n uint32) []byte {
+	if uint64(n) > uint64(len(r.b)) {
+		panic(errEOF)
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *reader) u32() uint32 {
+	v := r.uleb(32)
+	return uint32(v)
+}
+
+func (r *reader) uleb(bits uint) uint64 {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		if shift >= bits+7 {
+			panic(errors.New("integer representation too long"))
+		}
+		c := r.byte()
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return v
+		}
+	}
+}
+
+func (r *reader) sleb(bits uint) int64 {
+	var v int64
+	var shift uint
+	for {
+		if shift >= bits+7 {
+			panic(errors.New("integer representation too long"))
+		}
+		c := r.byte()
+		v |= int64(c&0x7f) << shift
+		shift += 7
+		if c < 0x80 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v
+		}
+	}
+}
+
+func (r *reader) name() string {
+	return string(r.bytes(r.u32()))
+}
+
+func (r *reader) valueTypes() []byte {
+	n := r.u32()
+	types := make([]byte, 0, min(n, 1024))
+	for i := uint32(0); i < n; i++ {
+		types = append(types, r.valueType())
+	}
+	return types
+}
+
+func (r *reader) valueType() byte {
+	t := r.byte()
+	switch t {
+	case typeI32, typeI64, typeF32, typeF64:
+		return t
+	}
+	panic(fmt.Errorf("unsupported value type 0x%x", t))
+}
+
+func (r *reader) limits() (uint32, uint32, bool) {
+	switch flag := r.byte(); flag {
+	case 0:
+		return r.u32(), 0, false
+	case 1:
+		return r.u32(), r.u32(), true
+	default:
+		panic(fmt.Errorf("unsupported limits flag 0x%x", flag))
+	}
+}
+
+func (r *reader) constExpr() constExpr {
+	var e constExpr
+	switch op := r.byte(); op {
+	case opI32Const:
+		e.value = uint64(uint32(int32(r.sleb(32))))
+	case opI64Const:
+		e.value = uint64(r.sleb(64))
+	case opF32Const:
+		e.value = uint64(binary.LittleEndian.Uint32(r.bytes(4)))
+	case opF64Const:
+		e.value = binary.LittleEndian.Uint64(r.bytes(8))
+	case opGlobalGet:
+		e.fromGlobal = true
+		e.global = r.u32()
+	default:
+		panic(fmt.Errorf("unsupported constant expression opcode 0x%x", op))
+	}
+	if end := r.byte(); end != opEnd {
+		panic(errors.New("constant expression is too complex"))
+	}
+	return e
+}
+
+// decode decodes the binary module b.
+func decode(b []byte) (m *module, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	if !bytes.HasPrefix(b, magic) {
+		return nil, errors.New("not a WebAssembly 1.0 module")
+	}
+	m = &module{exports: map[string]export{}}
+	r := &reader{b: b[len(magic):]}
+	var funcTypes []uint32
+	var bodies [][]byte
+	for len(r.b) > 0 {
+		id := r.byte()
+		s := &reader{b: r.bytes(r.u32())}
+		switch id {
+		case 0:
+			// custom sections, such as names, are ignored
+		case 1:
+			for n := s.u32(); n > 0; n-- {
+				if form := s.byte(); form != 0x60 {
+					return nil, fmt.Errorf("unsupported type form 0x%x", form)
+				}
+				m.types = append(m.types, funcType{params: s.valueTypes(), results: s.valueTypes()})
+			}
+		case 2:
+			for n := s.u32(); n > 0; n-- {
+				module, name := s.name(), s.name()
+				if kind := s.byte(); kind != externFunc {
+					return nil, fmt.Errorf("import %s.%s: only functions may be imported", module, name)
+				}
+				m.funcs = append(m.funcs, &function{typ: m.typ(s.u32()), importModule: module, importName: name})
+			}
+		case 3:
+			for n := s.u32(); n > 0; n-- {
+				funcTypes = append(funcTypes, s.u32())
+			}
+		case 4:
+			if n := s.u32(); n > 1 {
+				return nil, errors.New("modules may have one table")
+			} else if n == 1 {
+				if t := s.byte(); t != 0x70 {
+					return nil, fmt.Errorf("unsupported table type 0x%x", t)
+				}
+				m.tableMin, _, _ = s.limits()
+				m.hasTable = true
+			}
+		case 5:
+			if n := s.u32(); n > 1 {
+				return nil, errors.New("modules may have one memory")
+			} else if n == 1 {
+				m.memMin, m.memMax, m.hasMemMax = s.limits()
+				m.hasMemory = true
+			}
+		case 6:
+			for n := s.u32(); n > 0; n-- {
+				g := global{typ: s.valueType(), mutable: s.byte() == 1}
+				g.init = s.constExpr()
+				m.globals = append(m.globals, g)
+			}
+		case 7:
+			for n := s.u32(); n > 0; n-- {
+				name := s.name()
+				m.exports[name] = export{kind: s.byte(), index: s.u32()}
+			}
+		case 8:
+			start := s.u32()
+			m.start = &start
+		case 9:
+			for n := s.u32(); n > 0; n-- {
+				if flag := s.u32(); flag != 0 {
+					return nil, fmt.Errorf("unsupported element segment kind %d", flag)
+				}
+				seg := segment{offset: s.constExpr()}
+				for k := s.u32(); k > 0; k-- {
+					seg.funcs = append(seg.funcs, s.u32())
+				}
+				m.elements = append(m.elements, seg)
+			}
+		case 10:
+			for n := s.u32(); n > 0; n-- {
+				bodies = append(bodies, s.bytes(s.u32()))
+			}
+		case 11:
+			for n := s.u32(); n > 0; n-- {
+				switch flag := s.u32(); flag {
+				case 0:
+				case 2:
+					if s.u32() != 0 {
+						return nil, errors.New("data segment refers to a memory other than 0")
+					}
+				default:
+					return nil, fmt.Errorf("unsupported data segment kind %d", flag)
+				}
+				seg := segment{offset: s.constExpr()}
+				seg.data = s.bytes(s.u32())
+				m.data = append(m.data, seg)
+			}
+		case 12:
+			// the data count is only needed by single pass validators
+		default:
+			return nil, fmt.Errorf("unknown section %d", id)
+		}
+	}
+	if len(funcTypes) != len(bodies) {
+		return nil, errors.New("function and code section lengths differ")
+	}
+	for i, t := range funcTypes {
+		f := &function{typ: m.typ(t)}
+		if err := m.compile(f, bodies[i]); err != nil {
+			return nil, fmt.Errorf("function %d: %w", len(m.funcs), err)
+		}
+		m.funcs = append(m.funcs, f)
+	}
+	if m.start != nil && *m.start >= uint32(len(m.funcs)) {
+		return nil, errors.New("start function does not exist")
+	}
+	return m, nil
+}
+
+func (m *module) typ(i uint32) funcType {
+	if i >= uint32(len(m.types)) {
+		panic(fmt.Errorf("type %d does not exist", i))
+	}
+	return m.types[i]
+}
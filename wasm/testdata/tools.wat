@@ -0,0 +1,84 @@
+;; tools.wasm is built from this file with:
+;;
+;;   wat2wasm --enable-bulk-memory tools.wat
+(module
+  (type $log (func (param i32 i32)))
+  (type $alloc (func (param i32) (result i32)))
+  (type $tool (func (param i32 i32) (result i64)))
+  (type $fib (func (param i64) (result i64)))
+
+  (import "mcp" "log" (func $log (type $log)))
+
+  (memory (export "memory") 1)
+  (table 1 funcref)
+  (elem (i32.const 0) $fib)
+  (global $heap (mut i32) (i32.const 1024))
+
+  (data (i32.const 0) "{\"content\":[],\"structuredContent\":")
+  (data (i32.const 64) "{\"content\":[],\"structuredContent\":{\"fibonacci\":")
+
+  ;; alloc is a bump allocator; instances live for a single call.
+  (func $alloc (export "alloc") (type $alloc) (local $p i32)
+    global.get $heap
+    local.set $p
+    global.get $heap
+    local.get 0
+    i32.add
+    global.set $heap
+    local.get $p)
+
+  ;; echo logs its arguments and returns them as structured content.
+  (func $echo (export "echo") (type $tool) (local $out i32)
+    local.get 0
+    local.get 1
+    call $log
+    i32.const 34
+    local.get 1
+    i32.add
+    i32.const 1
+    i32.add
+    call $alloc
+    local.set $out
+    (memory.copy (local.get $out) (i32.const 0) (i32.const 34))
+    (memory.copy (i32.add (local.get $out) (i32.const 34)) (local.get 0) (local.get 1))
+    (i32.store8 (i32.add (i32.add (local.get $out) (i32.const 34)) (local.get 1)) (i32.const 125))
+    (i64.shl (i64.extend_i32_u (local.get $out)) (i64.const 32))
+    (i64.extend_i32_u (i32.add (i32.add (i32.const 34) (local.get 1)) (i32.const 1)))
+    i64.or)
+
+  ;; fib calls itself through the table for n-1 and directly for n-2.
+  (func $fib (type $fib)
+    (block (block (block
+      (br_table 0 1 2 (i32.wrap_i64 (local.get 0))))
+      (return (i64.const 0)))
+      (return (i64.const 1)))
+    (call_indirect (type $fib) (i64.sub (local.get 0) (i64.const 1)) (i32.const 0))
+    (call $fib (i64.sub (local.get 0) (i64.const 2)))
+    i64.add)
+
+  ;; fibonacci returns {"fibonacci":N}, N being the Fibonacci number of the
+  ;; length of its arguments, writing the digits of N backwards.
+  (func $fibonacci (export "fibonacci") (type $tool) (local $n i64) (local $p i32) (local $end i32)
+    (local.set $n (call $fib (i64.extend_i32_u (local.get 1))))
+    (local.tee $end (i32.add (call $alloc (i32.const 64)) (i32.const 64)))
+    (local.tee $p (i32.sub (i32.const 2)))
+    (i32.store16 (i32.const 0x7d7d))
+    (loop
+      (i32.store8
+        (local.tee $p (i32.sub (local.get $p) (i32.const 1)))
+        (i32.add (i32.wrap_i64 (i64.rem_u (local.get $n) (i64.const 10))) (i32.const 48)))
+      (br_if 0 (i64.ne (local.tee $n (i64.div_u (local.get $n) (i64.const 10))) (i64.const 0))))
+    (memory.copy (local.tee $p (i32.sub (local.get $p) (i32.const 47))) (i32.const 64) (i32.const 47))
+    (i64.shl (i64.extend_i32_u (local.get $p)) (i64.const 32))
+    (i64.extend_i32_u (i32.sub (local.get $end) (local.get $p)))
+    i64.or)
+
+  (func $spin (export "spin") (type $tool)
+    (loop (br 0))
+    unreachable)
+
+  (func $trap (export "trap") (type $tool)
+    unreachable)
+
+  (func $bad_result (export "bad_result") (type $tool)
+    i64.const -1))
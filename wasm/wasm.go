@@ -0,0 +1,292 @@
+// Package wasm offers tools implemented by WebAssembly modules, so that tool
+// logic written in any language that compiles to WebAssembly runs isolated
+// from the server. Modules run in an interpreter with no access to the host
+// beyond the functions of the ABI described by Compile, and each call runs
+// in a fresh instance of the module, limited in memory and instructions.
+//
+// The interpreter supports WebAssembly 1.0 with the sign extension,
+// non-trapping conversion and bulk memory copy and fill instructions.
+// Modules are not validated beyond decoding; invalid code traps.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/acrmp/mcp"
+)
+
+// DefaultMaxMemoryPages is the number of 64KiB pages of memory an instance
+// may use when Config.MaxMemoryPages is not set, which is 16MiB.
+const DefaultMaxMemoryPages = 256
+
+// DefaultMaxInstructions is the number of instructions a call may execute
+// when Config.MaxInstructions is not set.
+const DefaultMaxInstructions = 100_000_000
+
+// HostModule is the name of the module whose functions modules may import.
+const HostModule = "mcp"
+
+// Config limits the instances of a module.
+type Config struct {
+	// MaxMemoryPages limits the memory of an instance, in 64KiB pages. It
+	// defaults to DefaultMaxMemoryPages.
+	MaxMemoryPages uint32
+	// MaxInstructions limits the instructions executed by a call, including
+	// those of the module's start function. It defaults to
+	// DefaultMaxInstructions.
+	MaxInstructions uint64
+}
+
+var (
+	typeAlloc = funcType{params: []byte{typeI32}, results: []byte{typeI32}}
+	typeCall  = funcType{params: []byte{typeI32, typeI32}, results: []byte{typeI64}}
+	typeLog   = funcType{params: []byte{typeI32, typeI32}}
+)
+
+// callState is what the host functions of an instance need of the call.
+type callState struct {
+	tool     string
+	notifier mcp.Notifier
+}
+
+// hostFuncs are the functions modules may import from HostModule, with
+// their types.
+var hostFuncs = map[string]struct {
+	typ funcType
+	fn  hostFunc
+}{
+	// log(ptr, len i32) sends the UTF-8 text at ptr to the client as a log
+	// message.
+	"log": {typeLog, func(in *instance, args []uint64) []uint64 {
+		text := in.bytes(args[0], args[1])
+		s := in.state.(callState)
+		if s.notifier != nil {
+			s.notifier.Notify(in.ctx, "notifications/message", mcp.LoggingMessageNotificationParams{
+				Level:  mcp.LoggingLevelInfo,
+				Logger: &s.tool,
+				Data:   string(text),
+			})
+		}
+		return nil
+	}},
+}
+
+// Module is a compiled module, from which tools are offered.
+type Module struct {
+	m    *module
+	cfg  Config
+	host []hostFunc
+}
+
+// Compile decodes the WebAssembly module b. Modules follow this ABI:
+//
+//   - The module exports its memory as "memory", and a function "alloc" of
+//     type (i32) -> i32 returning the address of the given number of bytes
+//     the host may write to.
+//   - A tool is a function of type (i32, i32) -> i64, called with the
+//     address and length of the JSON arguments of the call. It returns the
+//     address of the JSON tool result, such as {"content":[...]}, in the high
+//     32 bits and its length in the low 32 bits.
+//   - Modules may import functions from HostModule. log(ptr, len: i32) sends
+//     the UTF-8 text at ptr to the client as a log message.
+func Compile(b []byte, cfg Config) (*Module, error) {
+	if cfg.MaxMemoryPages == 0 {
+		cfg.MaxMemoryPages = DefaultMaxMemoryPages
+	}
+	if cfg.MaxInstructions == 0 {
+		cfg.MaxInstructions = DefaultMaxInstructions
+	}
+	m, err := decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("decoding module: %w", err)
+	}
+	host := make([]hostFunc, len(m.funcs))
+	for i, f := range m.funcs {
+		if f.code != nil {
+			continue
+		}
+		h, ok := hostFuncs[f.importName]
+		if f.importModule != HostModule || !ok {
+			return nil, fmt.Errorf("module imports unknown function %s.%s", f.importModule, f.importName)
+		}
+		if !f.typ.equal(h.typ) {
+			return nil, fmt.Errorf("module imports %s.%s as %s, want %s", f.importModule, f.importName, f.typ, h.typ)
+		}
+		host[i] = h.fn
+	}
+	if e, ok := m.exports["memory"]; !ok || e.kind != externMemory || !m.hasMemory {
+		return nil, errors.New(`module does not export its memory as "memory"`)
+	}
+	if err := m.checkExport("alloc", typeAlloc); err != nil {
+		return nil, err
+	}
+	return &Module{m: m, cfg: cfg, host: host}, nil
+}
+
+func (m *module) checkExport(name string, typ funcType) error {
+	e, ok := m.exports[name]
+	if !ok || e.kind != externFunc || e.index >= uint32(len(m.funcs)) {
+		return fmt.Errorf("module does not export function %s", name)
+	}
+	if t := m.funcs[e.index].typ; !t.equal(typ) {
+		return fmt.Errorf("function %s is %s, want %s", name, t, typ)
+	}
+	return nil
+}
+
+// Tool returns a tool described by metadata that calls the function export
+// of the module.
+func (m *Module) Tool(metadata mcp.Tool, export string) (mcp.ToolDefinition, error) {
+	if err := m.m.checkExport(export, typeCall); err != nil {
+		return mcp.ToolDefinition{}, err
+	}
+	return mcp.ToolDefinition{
+		Metadata: metadata,
+		Process: func(ctx context.Context, params mcp.CallToolRequestParams, n mcp.Notifier) (mcp.CallToolResult, error) {
+			return m.call(ctx, export, params, callState{tool: metadata.Name, notifier: n})
+		},
+	}, nil
+}
+
+func (m *Module) call(ctx context.Context, export string, params mcp.CallToolRequestParams, state callState) (mcp.CallToolResult, error) {
+	args := params.Arguments
+	if args == nil {
+		args = map[string]any{}
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+
+	in, err := m.instantiate(ctx, state)
+	if err != nil {
+		return mcp.CallToolResult{}, fmt.Errorf("instantiating module: %w", err)
+	}
+	results, err := in.run(m.m.exports["alloc"].index, uint64(len(b)))
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	ptr := results[0]
+	dst, ok := in.slice(ptr, uint64(len(b)))
+	if !ok {
+		return mcp.CallToolResult{}, errors.New("alloc returned memory out of bounds")
+	}
+	copy(dst, b)
+	results, err = in.run(m.m.exports[export].index, ptr, uint64(len(b)))
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+
+	out, ok := in.slice(results[0]>>32, results[0]&0xffffffff)
+	if !ok {
+		return mcp.CallToolResult{}, fmt.Errorf("result of %s is out of bounds", export)
+	}
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		return mcp.CallToolResult{}, fmt.Errorf("decoding result of %s: %w", export, err)
+	}
+	if result.Content == nil {
+		result.Content = mcp.Contents{}
+	}
+	return result, nil
+}
+
+// bytes returns the length bytes of memory at ptr, trapping if they are out
+// of bounds.
+func (in *instance) bytes(ptr, length uint64) []byte {
+	b, ok := in.slice(ptr, length)
+	if !ok {
+		panic(trap{errOutOfBounds})
+	}
+	return b
+}
+
+// slice returns the length bytes of memory at ptr, or false if they are out
+// of bounds.
+func (in *instance) slice(ptr, length uint64) ([]byte, bool) {
+	ptr, length = uint64(uint32(ptr)), uint64(uint32(length))
+	if ptr+length > uint64(len(in.memory)) {
+		return nil, false
+	}
+	return in.memory[ptr : ptr+length], true
+}
+
+// instantiate returns a new instance of the module, with its memory, table
+// and globals initialized and its start function run.
+func (m *Module) instantiate(ctx context.Context, state any) (in *instance, err error) {
+	mod := m.m
+	in = &instance{
+		m:        mod,
+		ctx:      ctx,
+		host:     m.host,
+		maxPages: m.cfg.MaxMemoryPages,
+		budget:   m.cfg.MaxInstructions,
+		state:    state,
+	}
+	if mod.hasMemMax && mod.memMax < in.maxPages {
+		in.maxPages = mod.memMax
+	}
+	if mod.memMin > in.maxPages {
+		return nil, fmt.Errorf("module needs %d pages of memory, more than the limit of %d", mod.memMin, in.maxPages)
+	}
+	in.memory = make([]byte, int(mod.memMin)*pageSize)
+
+	in.globals = make([]uint64, len(mod.globals))
+	for i, g := range mod.globals {
+		if in.globals[i], err = in.eval(g.init, i); err != nil {
+			return nil, err
+		}
+	}
+
+	in.table = make([]int64, mod.tableMin)
+	for i := range in.table {
+		in.table[i] = -1
+	}
+	for _, seg := range mod.elements {
+		offset, err := in.eval(seg.offset, len(in.globals))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(uint32(offset))+uint64(len(seg.funcs)) > uint64(len(in.table)) {
+			return nil, errors.New("element segment does not fit the table")
+		}
+		for k, f := range seg.funcs {
+			if f >= uint32(len(mod.funcs)) {
+				return nil, fmt.Errorf("element segment refers to function %d, which does not exist", f)
+			}
+			in.table[uint32(offset)+uint32(k)] = int64(f)
+		}
+	}
+	for _, seg := range mod.data {
+		offset, err := in.eval(seg.offset, len(in.globals))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(uint32(offset))+uint64(len(seg.data)) > uint64(len(in.memory)) {
+			return nil, errors.New("data segment does not fit the memory")
+		}
+		copy(in.memory[uint32(offset):], seg.data)
+	}
+
+	if mod.start != nil {
+		if _, err := in.run(*mod.start); err != nil {
+			return nil, fmt.Errorf("running start function: %w", err)
+		}
+	}
+	return in, nil
+}
+
+// eval returns the value of the initializer e, which may refer to the first
+// initialized globals.
+func (in *instance) eval(e constExpr, initialized int) (uint64, error) {
+	if !e.fromGlobal {
+		return e.value, nil
+	}
+	if e.global >= uint32(initialized) {
+		return 0, fmt.Errorf("initializer refers to global %d before it is initialized", e.global)
+	}
+	return in.globals[e.global], nil
+}
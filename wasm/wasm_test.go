@@ -0,0 +1,149 @@
+package wasm_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/acrmp/mcp"
+	"github.com/acrmp/mcp/mcptest"
+	"github.com/acrmp/mcp/wasm"
+)
+
+var _ = Describe("Module", func() {
+
+	var (
+		module []byte
+		cfg    wasm.Config
+	)
+
+	BeforeEach(func() {
+		var err error
+		module, err = os.ReadFile("testdata/tools.wasm")
+		Expect(err).ToNot(HaveOccurred())
+		cfg = wasm.Config{}
+	})
+
+	tool := func(export string) mcp.ToolDefinition {
+		m, err := wasm.Compile(module, cfg)
+		Expect(err).ToNot(HaveOccurred())
+		t, err := m.Tool(mcp.Tool{
+			Name:        export,
+			InputSchema: mcp.ToolInputSchema{Type: "object"},
+		}, export)
+		Expect(err).ToNot(HaveOccurred())
+		return t
+	}
+
+	call := func(ctx context.Context, t mcp.ToolDefinition, args map[string]any, n mcp.Notifier) (mcp.CallToolResult, error) {
+		return t.Process(ctx, mcp.CallToolRequestParams{Name: t.Metadata.Name, Arguments: args}, n)
+	}
+
+	It("returns the result of the function", func() {
+		result, err := call(context.Background(), tool("echo"), map[string]any{"name": "a", "count": 2}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsError).To(BeNil())
+		Expect(result.Content).To(BeEmpty())
+		Expect(result.StructuredContent).To(Equal(map[string]any{"name": "a", "count": 2.0}))
+	})
+
+	It("passes empty arguments when there are none", func() {
+		result, err := call(context.Background(), tool("echo"), nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.StructuredContent).To(BeEmpty())
+	})
+
+	It("sends the messages the module logs", func() {
+		n := &mcptest.FakeNotifier{}
+		_, err := call(context.Background(), tool("echo"), map[string]any{"name": "a"}, n)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(n.Methods()).To(Equal([]string{"notifications/message"}))
+		var params mcp.LoggingMessageNotificationParams
+		Expect(json.Unmarshal(n.Notifications()[0].Params, &params)).To(Succeed())
+		Expect(params.Level).To(Equal(mcp.LoggingLevelInfo))
+		Expect(*params.Logger).To(Equal("echo"))
+		Expect(params.Data).To(Equal(`{"name":"a"}`))
+	})
+
+	It("runs calls through the table and recursion", func() {
+		// the arguments are 18 bytes long
+		result, err := call(context.Background(), tool("fibonacci"), map[string]any{"n": "0123456789"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.StructuredContent).To(Equal(map[string]any{"fibonacci": 2584.0}))
+	})
+
+	It("runs each call in a fresh instance", func() {
+		t := tool("echo")
+		for range 3 {
+			result, err := call(context.Background(), t, map[string]any{"name": "a"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.StructuredContent).To(Equal(map[string]any{"name": "a"}))
+		}
+	})
+
+	It("fails calls that trap", func() {
+		_, err := call(context.Background(), tool("trap"), nil, nil)
+		Expect(err).To(MatchError("wasm trap: unreachable"))
+	})
+
+	It("fails calls that return a result out of bounds", func() {
+		_, err := call(context.Background(), tool("bad_result"), nil, nil)
+		Expect(err).To(MatchError("result of bad_result is out of bounds"))
+	})
+
+	Context("when the instruction limit is reached", func() {
+		BeforeEach(func() {
+			cfg.MaxInstructions = 100_000
+		})
+
+		It("fails the call", func() {
+			_, err := call(context.Background(), tool("spin"), nil, nil)
+			Expect(err).To(MatchError(wasm.ErrInstructionLimit))
+		})
+	})
+
+	It("stops when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := call(ctx, tool("spin"), nil, nil)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("refuses exports that are not tools", func() {
+		m, err := wasm.Compile(module, cfg)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.Tool(mcp.Tool{Name: "alloc"}, "alloc")
+		Expect(err).To(MatchError("function alloc is (i32) -> i32, want (i32, i32) -> i64"))
+		_, err = m.Tool(mcp.Tool{Name: "missing"}, "missing")
+		Expect(err).To(MatchError("module does not export function missing"))
+	})
+
+	It("refuses modules that are not WebAssembly", func() {
+		_, err := wasm.Compile([]byte("not a module"), cfg)
+		Expect(err).To(MatchError("decoding module: not a WebAssembly 1.0 module"))
+	})
+
+	It("refuses modules that import other functions", func() {
+		_, err := wasm.Compile([]byte{
+			0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00,
+			// a type section with (i32) -> ()
+			0x01, 0x05, 0x01, 0x60, 0x01, 0x7f, 0x00,
+			// an import section with env.abort of that type
+			0x02, 0x0d, 0x01, 0x03, 'e', 'n', 'v', 0x05, 'a', 'b', 'o', 'r', 't', 0x00, 0x00,
+		}, cfg)
+		Expect(err).To(MatchError("module imports unknown function env.abort"))
+	})
+
+	It("refuses modules that do not export their memory", func() {
+		_, err := wasm.Compile([]byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}, cfg)
+		Expect(err).To(MatchError(`module does not export its memory as "memory"`))
+	})
+
+	It("offers tools with valid schemas", func() {
+		Expect(mcptest.ValidateToolSchemas(tool("echo").Metadata, tool("fibonacci").Metadata)).To(Succeed())
+	})
+})